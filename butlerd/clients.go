@@ -0,0 +1,90 @@
+package butlerd
+
+import (
+	"context"
+	"sync"
+
+	"github.com/itchio/butler/butlerd/jsonrpc2"
+	"github.com/pkg/errors"
+)
+
+// clients keeps track of connections that have made at least one
+// request, until they close, so background tasks can notify them.
+type clients struct {
+	conns map[jsonrpc2.Conn]struct{}
+	lock  sync.Mutex
+}
+
+func newClients() *clients {
+	return &clients{
+		conns: make(map[jsonrpc2.Conn]struct{}),
+	}
+}
+
+func (c *clients) add(conn jsonrpc2.Conn) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, ok := c.conns[conn]; ok {
+		return
+	}
+	c.conns[conn] = struct{}{}
+
+	go func() {
+		<-conn.Context().Done()
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		delete(c.conns, conn)
+	}()
+}
+
+func (c *clients) list() []jsonrpc2.Conn {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var res []jsonrpc2.Conn
+	for conn := range c.conns {
+		res = append(res, conn)
+	}
+	return res
+}
+
+// broadcastConn is the connection of background tasks: notifications
+// are sent to every connected client, but there's nobody to call.
+type broadcastConn struct {
+	ctx     context.Context
+	clients *clients
+}
+
+var _ jsonrpc2.Conn = (*broadcastConn)(nil)
+
+func (bc *broadcastConn) Call(method string, params interface{}, result interface{}) error {
+	return errors.Errorf("Background tasks cannot call (%s), no client to answer", method)
+}
+
+func (bc *broadcastConn) Notify(method string, params interface{}) error {
+	var firstErr error
+	for _, conn := range bc.clients.list() {
+		err := conn.Notify(method, params)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (bc *broadcastConn) Context() context.Context {
+	return bc.ctx
+}
+
+func (bc *broadcastConn) Close() {
+	// nothing to close
+}
+
+func (r *Router) broadcast(method string, params interface{}) error {
+	bc := &broadcastConn{
+		ctx:     r.backgroundContext,
+		clients: r.clients,
+	}
+	return bc.Notify(method, params)
+}
@@ -16,6 +16,12 @@ var codeMessages = map[Code]string{
 
 	CodeNoLaunchCandidates: "Nothing that can be launched was found.",
 
+	CodePreLaunchHookFailed: "A pre-launch hook failed, so the game was not launched.",
+
+	CodeAlreadyRunning: "This game is already running.",
+
+	CodeSandboxUnsupported: "This game's package format can't be launched in the sandbox.",
+
 	CodeJavaRuntimeNeeded: "Java Runtime Environment is required to launch this title.",
 
 	CodeNetworkDisconnected: "There is no Internet connection",
@@ -24,7 +30,15 @@ var codeMessages = map[Code]string{
 
 	CodeDatabaseBusy: "The database is busy",
 
+	CodeDatabaseTooNew: "The database was written by a newer version of butler",
+
 	CodeCantRemoveLocationBecauseOfActiveDownloads: "An install location could not be removed because it has active downloads",
+
+	CodeProfileDataConflict: "The profile data was changed by someone else",
+
+	CodeProfileDataTooLarge: "The profile data value is too large",
+
+	CodeProfileSecretUnavailable: "The profile's API key could not be read from the secret store",
 }
 
 func (code Code) RpcErrorMessage() string {
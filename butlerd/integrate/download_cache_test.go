@@ -0,0 +1,82 @@
+package integrate
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DownloadCacheInstallLove(t *testing.T) {
+	assert := assert.New(t)
+
+	cacheDir, err := ioutil.TempDir("", "download-cache")
+	must(err)
+	defer os.RemoveAll(cacheDir)
+
+	bi := newInstance(t, withDaemonArgs(
+		"--download-cache-dir", cacheDir,
+		"--download-cache-size", "64",
+	))
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+	logs := captureLogs(bi)
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Kernel Panic")
+	_game := _developer.MakeGame("dot love")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContentsCustom(func(ac *mitch.ArchiveContext) {
+		ac.SetName("hello.love")
+		ac.Entry("main.lua").String("print 'hello lua'")
+	})
+
+	game := bi.FetchGame(_game.ID)
+
+	install := func() string {
+		queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+			Game:              game,
+			InstallLocationID: "tmp",
+		})
+		must(err)
+
+		_, err = messages.InstallPerform.TestCall(rc, butlerd.InstallPerformParams{
+			ID:            queueRes.ID,
+			StagingFolder: queueRes.StagingFolder,
+		})
+		must(err)
+
+		// the .love file must keep its name rather
+		// than be named after its hash
+		names, err := ioutil.ReadDir(queueRes.InstallFolder)
+		must(err)
+		foundLove := false
+		for _, fi := range names {
+			if strings.EqualFold(fi.Name(), "hello.love") {
+				foundLove = true
+			}
+		}
+		assert.True(foundLove, "should have found hello.love in install folder")
+		return queueRes.CaveID
+	}
+
+	caveID := install()
+	assert.False(logs.contains("from download cache"))
+
+	_, err = messages.UninstallPerform.TestCall(rc, butlerd.UninstallPerformParams{
+		CaveID: caveID,
+	})
+	must(err)
+
+	install()
+	assert.True(logs.contains("from download cache"), "the reinstall should come out of the download cache")
+}
@@ -0,0 +1,128 @@
+package integrate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/mitch"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DownloadsHistory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping downloads history in short mode")
+	}
+
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, h, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	finished := make(chan struct{}, 1)
+	messages.DownloadsDriveFinished.Register(h, func(params butlerd.DownloadsDriveFinishedNotification) {
+		finished <- struct{}{}
+	})
+	messages.DownloadsDriveErrored.Register(h, func(params butlerd.DownloadsDriveErroredNotification) {
+		bi.Logf("Got unexpected DriveErrored")
+		t.FailNow()
+	})
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Chronicler")
+
+	// queues a download for a new game, and drives it until it's done
+	download := func(title string) (int64, string) {
+		_game := _developer.MakeGame(title)
+		_game.Publish()
+		_upload := _game.MakeUpload("web version")
+		_upload.SetAllPlatforms()
+		_upload.PushBuild(func(ac *mitch.ArchiveContext) {
+			ac.SetName("html5.zip")
+			ac.Entry("index.html").String("<p>" + title + "</p>")
+		})
+
+		queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+			Game:              bi.FetchGame(_game.ID),
+			InstallLocationID: "tmp",
+			QueueDownload:     true,
+		})
+		must(err)
+
+		driveDone := make(chan error, 1)
+		go func() {
+			_, err := messages.DownloadsDrive.TestCall(rc, butlerd.DownloadsDriveParams{})
+			driveDone <- err
+		}()
+
+		select {
+		case <-finished:
+		case <-time.After(10 * time.Second):
+			must(errors.New("timed out waiting for download to finish"))
+		}
+		_, err = messages.DownloadsDriveCancel.TestCall(rc, butlerd.DownloadsDriveCancelParams{})
+		must(err)
+		must(<-driveDone)
+
+		return _game.ID, queueRes.CaveID
+	}
+
+	firstGameID, firstCaveID := download("First game")
+	secondGameID, _ := download("Second game")
+
+	// clearing finished downloads keeps their history
+	_, err := messages.DownloadsClearFinished.TestCall(rc, butlerd.DownloadsClearFinishedParams{})
+	must(err)
+
+	res, err := messages.DownloadsHistory.TestCall(rc, butlerd.DownloadsHistoryParams{})
+	must(err)
+	if assert.Len(res.Items, 2) {
+		// newest first
+		assert.EqualValues(secondGameID, res.Items[0].Game.ID)
+		assert.EqualValues(firstGameID, res.Items[1].Game.ID)
+
+		item := res.Items[1]
+		assert.EqualValues(firstCaveID, item.CaveID)
+		assert.EqualValues(butlerd.DownloadReasonInstall, item.Reason)
+		assert.EqualValues(butlerd.DownloadOutcomeSucceeded, item.Outcome)
+		assert.Nil(item.ErrorCode)
+		assert.NotNil(item.Upload)
+		assert.NotNil(item.Build)
+		// small archives are extracted straight from the server
+		assert.True(item.Timings.Install > 0)
+		if assert.NotNil(item.StartedAt) && assert.NotNil(item.FinishedAt) {
+			assert.False(item.FinishedAt.Before(*item.StartedAt))
+		}
+	}
+	assert.Empty(res.NextCursor)
+
+	// filters
+	res, err = messages.DownloadsHistory.TestCall(rc, butlerd.DownloadsHistoryParams{GameID: firstGameID})
+	must(err)
+	if assert.Len(res.Items, 1) {
+		assert.EqualValues(firstGameID, res.Items[0].Game.ID)
+	}
+	res, err = messages.DownloadsHistory.TestCall(rc, butlerd.DownloadsHistoryParams{CaveID: firstCaveID})
+	must(err)
+	if assert.Len(res.Items, 1) {
+		assert.EqualValues(firstCaveID, res.Items[0].CaveID)
+	}
+
+	// pages
+	res, err = messages.DownloadsHistory.TestCall(rc, butlerd.DownloadsHistoryParams{Limit: 1})
+	must(err)
+	if assert.Len(res.Items, 1) && assert.NotEmpty(res.NextCursor) {
+		assert.EqualValues(secondGameID, res.Items[0].Game.ID)
+		res, err = messages.DownloadsHistory.TestCall(rc, butlerd.DownloadsHistoryParams{Limit: 1, Cursor: res.NextCursor})
+		must(err)
+		if assert.Len(res.Items, 1) {
+			assert.EqualValues(firstGameID, res.Items[0].Game.ID)
+		}
+		assert.Empty(res.NextCursor)
+	}
+}
@@ -0,0 +1,80 @@
+package integrate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InstallAdopt(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Foster Parent")
+	_game := _developer.MakeGame("Adopted")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.PushBuild(func(ac *mitch.ArchiveContext) {
+		ac.SetName("adopted.zip")
+		ac.Entry("index.html").String("<p>Adopted</p>")
+		ac.Entry("data.bin").Random(0xad0b7, 256*1024)
+	})
+
+	game := bi.FetchGame(_game.ID)
+
+	wd, err := os.Getwd()
+	must(err)
+	adoptFolder := filepath.Join(wd, "tmp", "adopted-elsewhere")
+	must(os.MkdirAll(adoptFolder, 0o755))
+	must(ioutil.WriteFile(filepath.Join(adoptFolder, "index.html"), []byte("<p>Corrupted</p>"), 0o644))
+
+	// relative, with a trailing separator and a redundant element:
+	// stored as an absolute, clean path regardless
+	queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+		AdoptFolder:       filepath.Join("tmp", ".", "adopted-elsewhere") + string(filepath.Separator),
+	})
+	must(err)
+	assert.EqualValues(adoptFolder, queueRes.InstallFolder)
+
+	_, err = messages.InstallPerform.TestCall(rc, butlerd.InstallPerformParams{
+		ID:            queueRes.ID,
+		StagingFolder: queueRes.StagingFolder,
+	})
+	must(err)
+
+	index, err := ioutil.ReadFile(filepath.Join(adoptFolder, "index.html"))
+	must(err)
+	assert.EqualValues("<p>Adopted</p>", string(index))
+
+	caveRes, err := messages.FetchCave.TestCall(rc, butlerd.FetchCaveParams{
+		CaveID: queueRes.CaveID,
+	})
+	must(err)
+	assert.EqualValues(adoptFolder, caveRes.Cave.InstallInfo.InstallFolder)
+
+	// same folder, spelled differently
+	_, err = messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+		AdoptFolder:       filepath.Join(adoptFolder, "..", "adopted-elsewhere"),
+	})
+	assert.Error(err)
+	if err != nil {
+		assert.Contains(err.Error(), "A cave already exists")
+	}
+}
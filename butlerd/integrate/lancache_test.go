@@ -0,0 +1,253 @@
+package integrate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/butler/lancache"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LANCache(t *testing.T) {
+	assert := assert.New(t)
+
+	dirA, err := ioutil.TempDir("", "lancache-a")
+	must(err)
+	defer os.RemoveAll(dirA)
+
+	dirB, err := ioutil.TempDir("", "lancache-b")
+	must(err)
+	defer os.RemoveAll(dirB)
+
+	oldSecret, hadSecret := os.LookupEnv(lancache.SecretEnvironmentVariable)
+	os.Setenv(lancache.SecretEnvironmentVariable, "correct horse")
+	defer func() {
+		if hadSecret {
+			os.Setenv(lancache.SecretEnvironmentVariable, oldSecret)
+		} else {
+			os.Unsetenv(lancache.SecretEnvironmentVariable)
+		}
+	}()
+
+	portA := freePort()
+	a := newInstance(t, withDaemonArgs(
+		"--lan-cache-dir", dirA,
+		"--lan-cache-port", fmt.Sprintf("%d", portA),
+		"--lan-cache-host", "127.0.0.1",
+	))
+	defer a.Cancel()
+	a.Authenticate()
+
+	b := newInstance(t, withServer(a.Server), withDaemonArgs(
+		"--lan-cache-dir", dirB,
+		"--lan-cache-port", fmt.Sprintf("%d", freePort()),
+		"--lan-cache-host", "127.0.0.1",
+		"--lan-cache-peer", fmt.Sprintf("127.0.0.1:%d", portA),
+	))
+	defer b.Cancel()
+	b.Authenticate()
+	logsB := captureLogs(b)
+
+	store := a.Server.Store()
+	_developer := store.MakeUser("Peer Pressure")
+	pushBuild := func(_upload *mitch.Upload, title string) *mitch.Build {
+		return _upload.PushBuild(func(ac *mitch.ArchiveContext) {
+			ac.SetName("game.zip")
+			ac.Entry("index.html").String(fmt.Sprintf("<p>%s</p>", title))
+			ac.Entry("data.bin").Random(0x1a2c, 512*1024)
+		})
+	}
+	makeUpload := func(title string) (*itchio.Game, *mitch.Upload) {
+		_game := _developer.MakeGame(title)
+		_game.Publish()
+		_upload := _game.MakeUpload("All platforms")
+		_upload.SetAllPlatforms()
+		return a.FetchGame(_game.ID), _upload
+	}
+	pushGame := func(title string) (*itchio.Game, string, int64) {
+		game, _upload := makeUpload(title)
+		_build := pushBuild(_upload, title)
+		key := lancache.BuildFileKey(_build.ID, itchio.BuildFileTypeArchive, itchio.BuildFileSubTypeDefault)
+		return game, key, _build.GetFile("archive", "default").Size
+	}
+
+	// installs (or upgrades) and checks which build ended up installed
+	perform := func(bi *ButlerInstance, params butlerd.InstallQueueParams, title string) string {
+		rc := bi.Conn.RequestContext
+		queueRes, err := messages.InstallQueue.TestCall(rc, params)
+		must(err)
+
+		_, err = messages.InstallPerform.TestCall(rc, butlerd.InstallPerformParams{
+			ID:            queueRes.ID,
+			StagingFolder: queueRes.StagingFolder,
+		})
+		must(err)
+
+		index, err := ioutil.ReadFile(filepath.Join(queueRes.InstallFolder, "index.html"))
+		must(err)
+		assert.EqualValues(fmt.Sprintf("<p>%s</p>", title), string(index))
+		return queueRes.CaveID
+	}
+	install := func(bi *ButlerInstance, game *itchio.Game, title string) string {
+		return perform(bi, butlerd.InstallQueueParams{
+			Game:              game,
+			InstallLocationID: "tmp",
+		}, title)
+	}
+	uninstall := func(bi *ButlerInstance, caveID string) {
+		_, err := messages.UninstallPerform.TestCall(bi.Conn.RequestContext, butlerd.UninstallPerformParams{
+			CaveID: caveID,
+		})
+		must(err)
+	}
+	readCached := func(dir string, key string) []byte {
+		contents, err := ioutil.ReadFile(filepath.Join(dir, key))
+		if err != nil {
+			return nil
+		}
+		return contents
+	}
+
+	{
+		bi := b
+		bi.Logf("Peer hit: A downloads from itch.io, B gets it from A")
+		game, key, _ := pushGame("Peer hit")
+
+		caveID := install(a, game, "Peer hit")
+		assert.NotNil(readCached(dirA, key), "A should have cached the archive")
+		uninstall(a, caveID)
+
+		caveID = install(b, game, "Peer hit")
+		assert.True(logsB.contains("from LAN peer"), "B should have downloaded from A")
+		assert.True(logsB.contains("Verified "+key), "B should have verified the archive")
+		assert.EqualValues(readCached(dirA, key), readCached(dirB, key), "B should have cached what A served")
+		uninstall(b, caveID)
+	}
+
+	{
+		bi := b
+		bi.Logf("Peer miss: A doesn't have it, B gets it from itch.io")
+		game, key, _ := pushGame("Peer miss")
+		logsB.reset()
+
+		caveID := install(b, game, "Peer miss")
+		assert.False(logsB.contains("from LAN peer"), "A didn't have the archive")
+		assert.Nil(readCached(dirA, key))
+		assert.NotNil(readCached(dirB, key), "B should have cached the archive")
+		uninstall(b, caveID)
+	}
+
+	{
+		bi := b
+		bi.Logf("Hash mismatch: A serves garbage, B rejects it")
+		game, key, size := pushGame("Hash mismatch")
+		logsB.reset()
+
+		garbage := []byte(strings.Repeat("x", int(size)))
+		must(ioutil.WriteFile(filepath.Join(dirA, key), garbage, 0o644))
+
+		install(b, game, "Hash mismatch")
+		assert.True(logsB.contains("from LAN peer"), "B should have tried A")
+		assert.True(logsB.contains("Using itch.io instead"), "B should have rejected what A served")
+		assert.Nil(readCached(dirB, key), "B must not cache or share what A served")
+		_, err := os.Stat(filepath.Join(dirB, ".incoming", key))
+		assert.True(os.IsNotExist(err), "B should have discarded what A served")
+	}
+
+	{
+		bi := b
+		bi.Logf("Patches: B gets them from A, and checks them before applying")
+		game, _upload := makeUpload("Patch v1")
+		pushBuild(_upload, "Patch v1")
+		caveA := install(a, game, "Patch v1")
+		caveB := install(b, game, "Patch v1")
+
+		// A upgrades first, then B, which finds the patch on A
+		upgradeTo := func(title string, corrupt bool) string {
+			_build := pushBuild(_upload, title)
+			key := lancache.BuildFileKey(_build.ID, itchio.BuildFileTypePatch, itchio.BuildFileSubTypeDefault)
+			logsB.reset()
+			for _, bi := range []*ButlerInstance{a, b} {
+				caveID := caveA
+				if bi == b {
+					caveID = caveB
+					if patch := readCached(dirA, key); corrupt && patch != nil {
+						garbage := []byte(strings.Repeat("x", len(patch)))
+						must(ioutil.WriteFile(filepath.Join(dirA, key), garbage, 0o644))
+					}
+				}
+				perform(bi, butlerd.InstallQueueParams{
+					Game:   game,
+					CaveID: caveID,
+					Upload: bi.FetchUpload(_upload.ID),
+					Build:  bi.FetchBuild(_build.ID),
+				}, title)
+			}
+			return key
+		}
+
+		key := upgradeTo("Patch v2", false)
+		assert.NotNil(readCached(dirA, key), "A should have cached the patch")
+		assert.True(logsB.contains("Downloading "+key+" from LAN peer"), "B should have downloaded from A")
+		assert.True(logsB.contains("Verified "+key), "B should have verified the patch")
+		assert.EqualValues(readCached(dirA, key), readCached(dirB, key), "B should have cached what A served")
+
+		key = upgradeTo("Patch v3", true)
+		assert.True(logsB.contains("Downloading "+key+" from LAN peer"), "B should have tried A")
+		assert.True(logsB.contains("Using itch.io instead"), "B should have rejected the patch before applying it")
+		assert.Nil(readCached(dirB, key), "B must not cache or share what A served")
+	}
+}
+
+func freePort() int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	must(err)
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+type capturedLogs struct {
+	lock     sync.Mutex
+	messages []string
+}
+
+// captureLogs records messages logged by an instance, including
+// the ones forwarded from butlerd
+func captureLogs(bi *ButlerInstance) *capturedLogs {
+	cl := &capturedLogs{}
+	onMessage := bi.Consumer.OnMessage
+	bi.Consumer.OnMessage = func(lvl string, msg string) {
+		cl.lock.Lock()
+		cl.messages = append(cl.messages, msg)
+		cl.lock.Unlock()
+		onMessage(lvl, msg)
+	}
+	return cl
+}
+
+func (cl *capturedLogs) contains(substr string) bool {
+	cl.lock.Lock()
+	defer cl.lock.Unlock()
+	for _, msg := range cl.messages {
+		if strings.Contains(msg, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (cl *capturedLogs) reset() {
+	cl.lock.Lock()
+	defer cl.lock.Unlock()
+	cl.messages = nil
+}
@@ -0,0 +1,137 @@
+package integrate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/jsonrpc2"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/mitch"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LaunchKill(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the game is a shell script")
+	}
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Kill Switch")
+	_game := _developer.MakeGame("Forever Game")
+	_game.Publish()
+	_upload := _game.MakeUpload("All platforms")
+	_upload.SetAllPlatforms()
+	_upload.SetZipContentsCustom(func(ac *mitch.ArchiveContext) {
+		ac.Entry("forever.sh").String("#!/bin/sh\nexec sleep 600\n")
+	})
+
+	game := bi.FetchGame(_game.ID)
+
+	queueRes, err := messages.InstallQueue.TestCall(rc, butlerd.InstallQueueParams{
+		Game:              game,
+		InstallLocationID: "tmp",
+	})
+	must(err)
+
+	_, err = messages.InstallPerform.TestCall(rc, butlerd.InstallPerformParams{
+		ID:            queueRes.ID,
+		StagingFolder: queueRes.StagingFolder,
+	})
+	must(err)
+
+	outcomeDir, err := ioutil.TempDir("", "launch-kill-test")
+	must(err)
+	defer os.RemoveAll(outcomeDir)
+	outcomePath := filepath.Join(outcomeDir, "outcome.txt")
+
+	_, err = messages.LaunchHooksSave.TestCall(rc, butlerd.LaunchHooksSaveParams{
+		CaveID:  queueRes.CaveID,
+		Stage:   butlerd.LaunchHookStagePostExit,
+		Name:    "Record outcome",
+		Command: "/bin/sh",
+		Args:    []string{"-c", `echo "$ITCHIO_SESSION_OUTCOME" > "$0"`, outcomePath},
+	})
+	must(err)
+
+	launchParams := butlerd.LaunchParams{
+		CaveID:           queueRes.CaveID,
+		PrereqsDir:       "/tmp/prereqs",
+		ConcurrentLaunch: butlerd.ConcurrentLaunchPolicyReject,
+	}
+
+	launchDone := make(chan error, 1)
+	go func() {
+		_, err := messages.Launch.TestCall(rc, launchParams)
+		launchDone <- err
+	}()
+
+	bi.Logf("Waiting for the game to start...")
+	var launches []*butlerd.RunningLaunch
+	for deadline := time.Now().Add(30 * time.Second); time.Now().Before(deadline); {
+		listRes, err := messages.LaunchList.TestCall(rc, butlerd.LaunchListParams{
+			CaveID: queueRes.CaveID,
+		})
+		must(err)
+		launches = listRes.Launches
+		if len(launches) > 0 {
+			break
+		}
+
+		select {
+		case err := <-launchDone:
+			t.Fatalf("launch ended early: %+v", err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	if !assert.Len(launches, 1) {
+		return
+	}
+
+	bi.Logf("Making sure a second launch is rejected...")
+	_, err = messages.Launch.TestCall(rc, launchParams)
+	assert.Error(err)
+	if je, ok := err.(*jsonrpc2.Error); assert.True(ok) {
+		assert.EqualValues(butlerd.CodeAlreadyRunning, je.Code)
+	}
+
+	bi.Logf("Killing the game...")
+	killRes, err := messages.LaunchKill.TestCall(rc, butlerd.LaunchKillParams{
+		ID: launches[0].ID,
+	})
+	must(err)
+	assert.EqualValues(1, killRes.Killed)
+
+	select {
+	case err := <-launchDone:
+		assert.NoError(err, "a killed session isn't a failed launch")
+	case <-time.After(30 * time.Second):
+		t.Fatalf("timed out waiting for the launch to end")
+	}
+
+	outcome, err := ioutil.ReadFile(outcomePath)
+	must(err)
+	assert.EqualValues("killed", strings.TrimSpace(string(outcome)))
+
+	crashesRes, err := messages.CavesListCrashes.TestCall(rc, butlerd.CavesListCrashesParams{
+		CaveID: queueRes.CaveID,
+	})
+	must(err)
+	assert.Empty(crashesRes.Reports, "a killed session isn't a crash")
+
+	listRes, err := messages.LaunchList.TestCall(rc, butlerd.LaunchListParams{})
+	must(err)
+	assert.Empty(listRes.Launches)
+}
@@ -1,10 +1,13 @@
 package integrate
 
 import (
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 
 	"github.com/itchio/butler/butlerd"
 	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/mitch"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -38,3 +41,90 @@ func Test_Plan(t *testing.T) {
 	assert.NotNil(res.Info.Upload)
 	assert.NotEqual(_untaggedUpload.ID, res.Info.Upload.ID)
 }
+
+func Test_PlanPrereqs(t *testing.T) {
+	assert := assert.New(t)
+
+	bi := newInstance(t)
+	rc, _, cancel := bi.Unwrap()
+	defer cancel()
+
+	bi.Authenticate()
+
+	// imports MFC42.dll, msvcrt.dll and MSSIGN32.dll, which
+	// don't ship with all versions of Windows
+	exe, err := ioutil.ReadFile(filepath.Join("..", "..", "tools", "signtool.exe"))
+	must(err)
+
+	store := bi.Server.Store()
+	_developer := store.MakeUser("Jenny Block")
+
+	planPrereqs := func(f func(ac *mitch.ArchiveContext)) *butlerd.InstallPlanPrereqs {
+		t.Helper()
+		_game := _developer.MakeGame("Prereqs")
+		_game.Publish()
+		_upload := _game.MakeUpload("windows.zip")
+		_upload.SetAllPlatforms()
+		_upload.SetZipContentsCustom(f)
+
+		res, err := messages.InstallPlan.TestCall(rc, butlerd.InstallPlanParams{
+			GameID:   _game.ID,
+			UploadID: _upload.ID,
+			Detailed: true,
+		})
+		must(err)
+		assert.EqualValues(_upload.ID, res.Info.Upload.ID)
+		if assert.NotNil(res.Info.Details) {
+			return res.Info.Details.Prereqs
+		}
+		return &butlerd.InstallPlanPrereqs{}
+	}
+
+	bi.Logf("App manifest listing prereqs")
+	prereqs := planPrereqs(func(ac *mitch.ArchiveContext) {
+		ac.Entry(".itch.toml").String("[[prereqs]]\nname = \"vcredist-2010-x86\"\n")
+		ac.Entry("game.exe").Write(exe)
+	})
+	assert.True(prereqs.Needed)
+	assert.EqualValues([]string{"vcredist-2010-x86"}, prereqs.Names)
+	assert.False(prereqs.Auto)
+
+	bi.Logf("App manifest without prereqs")
+	prereqs = planPrereqs(func(ac *mitch.ArchiveContext) {
+		ac.Entry(".itch.toml").String("[[actions]]\nname = \"play\"\npath = \"game.exe\"\n")
+		ac.Entry("game.exe").Write(exe)
+	})
+	assert.False(prereqs.Needed)
+	assert.False(prereqs.Auto)
+
+	bi.Logf("No app manifest, executable with imports to look for")
+	prereqs = planPrereqs(func(ac *mitch.ArchiveContext) {
+		ac.Entry("bin/game.exe").Write(exe)
+	})
+	assert.False(prereqs.Needed)
+	assert.True(prereqs.Auto)
+
+	bi.Logf("No app manifest, imports ship with the executable")
+	prereqs = planPrereqs(func(ac *mitch.ArchiveContext) {
+		ac.Entry("bin/game.exe").Write(exe)
+		ac.Entry("bin/mfc42.dll").String("mfc")
+		ac.Entry("bin/MSVCRT.dll").String("crt")
+		ac.Entry("bin/mssign32.dll").String("sign")
+	})
+	assert.False(prereqs.Auto)
+
+	bi.Logf("No app manifest, imports ship elsewhere")
+	prereqs = planPrereqs(func(ac *mitch.ArchiveContext) {
+		ac.Entry("bin/game.exe").Write(exe)
+		ac.Entry("mfc42.dll").String("mfc")
+		ac.Entry("msvcrt.dll").String("crt")
+		ac.Entry("mssign32.dll").String("sign")
+	})
+	assert.True(prereqs.Auto)
+
+	bi.Logf("No app manifest, executables that aren't")
+	prereqs = planPrereqs(func(ac *mitch.ArchiveContext) {
+		ac.Entry("game.exe").String("#!/bin/sh\necho hi\n")
+	})
+	assert.False(prereqs.Auto)
+}
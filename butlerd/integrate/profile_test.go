@@ -1,7 +1,9 @@
 package integrate
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/itchio/butler/butlerd"
@@ -13,9 +15,14 @@ func Test_Profile(t *testing.T) {
 	assert := assert.New(t)
 
 	bi := newInstance(t)
-	rc, _, cancel := bi.Unwrap()
+	rc, h, cancel := bi.Unwrap()
 	defer cancel()
 
+	changes := make(chan butlerd.ProfileDataChangedNotification, 32)
+	messages.ProfileDataChanged.Register(h, func(params butlerd.ProfileDataChangedNotification) {
+		changes <- params
+	})
+
 	_, err := messages.ProfileLoginWithAPIKey.TestCall(rc, butlerd.ProfileLoginWithAPIKeyParams{
 		APIKey: "meh",
 	})
@@ -51,4 +58,101 @@ func Test_Profile(t *testing.T) {
 	})
 	must(err)
 	assert.False(dgr.OK)
+
+	assert.Nil(dgr.Entry)
+
+	// compare-and-swap
+	_, err = messages.ProfileDataPut.TestCall(rc, butlerd.ProfileDataPutParams{
+		ProfileID: prof.ID,
+		Key:       "@integrate/hello",
+		Value:     "nope",
+		IfVersion: new(int64),
+	})
+	assert.Error(err)
+	assert.Contains(err.Error(), "changed by someone else")
+
+	one := int64(1)
+	dpr, err := messages.ProfileDataPut.TestCall(rc, butlerd.ProfileDataPutParams{
+		ProfileID: prof.ID,
+		Key:       "@integrate/hello",
+		Value:     `{"hello": "world"}`,
+		JSON:      true,
+		IfVersion: &one,
+	})
+	must(err)
+	assert.EqualValues(2, dpr.Version)
+
+	_, err = messages.ProfileDataPut.TestCall(rc, butlerd.ProfileDataPutParams{
+		ProfileID: prof.ID,
+		Key:       "@integrate/bad-json",
+		Value:     `{"hello": `,
+		JSON:      true,
+	})
+	assert.Error(err)
+
+	_, err = messages.ProfileDataPut.TestCall(rc, butlerd.ProfileDataPutParams{
+		ProfileID: prof.ID,
+		Key:       "@integrate/too-large",
+		Value:     strings.Repeat("a", butlerd.ProfileDataMaxValueSize+1),
+	})
+	assert.Error(err)
+	assert.Contains(err.Error(), "too large")
+
+	_, err = messages.ProfileDataPut.TestCall(rc, butlerd.ProfileDataPutParams{
+		ProfileID:  prof.ID,
+		Key:        "@integrate/sidebar/width",
+		Value:      "200",
+		TTLSeconds: 3600,
+	})
+	must(err)
+
+	_, err = messages.ProfileDataPut.TestCall(rc, butlerd.ProfileDataPutParams{
+		ProfileID: prof.ID,
+		Key:       "@other/hello",
+		Value:     "bye",
+	})
+	must(err)
+
+	// prefix scan
+	dlr, err := messages.ProfileDataList.TestCall(rc, butlerd.ProfileDataListParams{
+		ProfileID: prof.ID,
+		Prefix:    "@integrate/",
+	})
+	must(err)
+	if assert.Len(dlr.Entries, 2) {
+		assert.EqualValues("@integrate/hello", dlr.Entries[0].Key)
+		assert.True(dlr.Entries[0].JSON)
+		assert.EqualValues("@integrate/sidebar/width", dlr.Entries[1].Key)
+		assert.NotNil(dlr.Entries[1].ExpiresAt)
+	}
+
+	ddr, err := messages.ProfileDataDelete.TestCall(rc, butlerd.ProfileDataDeleteParams{
+		ProfileID: prof.ID,
+		Prefix:    "@integrate/",
+	})
+	must(err)
+	assert.EqualValues(2, ddr.Deleted)
+
+	deleted := make(map[string]bool)
+	for len(deleted) < 2 {
+		select {
+		case change := <-changes:
+			if change.Deleted {
+				deleted[change.Key] = true
+			} else {
+				assert.NotNil(change.Entry)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for Profile.Data.Changed, got %v", deleted)
+		}
+	}
+	assert.True(deleted["@integrate/sidebar/width"])
+
+	dlr, err = messages.ProfileDataList.TestCall(rc, butlerd.ProfileDataListParams{
+		ProfileID: prof.ID,
+	})
+	must(err)
+	if assert.Len(dlr.Entries, 1) {
+		assert.EqualValues("@other/hello", dlr.Entries[0].Key)
+	}
 }
@@ -44,10 +44,27 @@ type ButlerInstance struct {
 }
 
 type instanceOpts struct {
+	daemonArgs []string
+	server     mitch.Server
 }
 
 type instanceOpt func(o *instanceOpts)
 
+// withDaemonArgs passes extra command-line arguments to butler daemon
+func withDaemonArgs(args ...string) instanceOpt {
+	return func(o *instanceOpts) {
+		o.daemonArgs = append(o.daemonArgs, args...)
+	}
+}
+
+// withServer uses the mock server of another instance, for
+// tests involving several butlerd instances
+func withServer(server mitch.Server) instanceOpt {
+	return func(o *instanceOpts) {
+		o.server = server
+	}
+}
+
 func init() {
 	color.NoColor = false
 }
@@ -109,8 +126,12 @@ func newInstance(t *testing.T, options ...instanceOpt) *ButlerInstance {
 		},
 	}
 
-	server, err := mitch.NewServer(ctx, mitch.WithConsumer(consumer))
-	must(err)
+	server := opts.server
+	if server == nil {
+		var err error
+		server, err = mitch.NewServer(ctx, mitch.WithConsumer(consumer))
+		must(err)
+	}
 
 	args := []string{
 		"daemon",
@@ -126,6 +147,7 @@ func newInstance(t *testing.T, options ...instanceOpt) *ButlerInstance {
 		args = append(args, "--address", addressString)
 		logf("Using mock server %s", addressString)
 	}
+	args = append(args, opts.daemonArgs...)
 	bExec := exec.CommandContext(ctx, conf.ButlerPath, args...)
 
 	stdout, err := bExec.StdoutPipe()
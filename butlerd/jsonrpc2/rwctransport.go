@@ -12,6 +12,10 @@ type ReadWriteClose interface {
 	io.Closer
 }
 
+// maxMessageSize is the largest message we'll read. bufio.Scanner's
+// default (64KiB) is too small for some profile data & fetch results.
+const maxMessageSize = 16 * 1024 * 1024
+
 type rwcTransport struct {
 	inner      ReadWriteClose
 	scanner    *bufio.Scanner
@@ -21,9 +25,12 @@ type rwcTransport struct {
 }
 
 func NewRwcTransport(rwc ReadWriteClose) Transport {
+	scanner := bufio.NewScanner(rwc)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxMessageSize)
+
 	return &rwcTransport{
 		inner:     rwc,
-		scanner:   bufio.NewScanner(rwc),
+		scanner:   scanner,
 		closed:    false,
 		closeChan: make(chan struct{}),
 	}
@@ -0,0 +1,125 @@
+package butlerd
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// Launches keeps track of the game sessions started by
+// Launch requests, so they can be listed and killed from
+// other requests.
+type Launches struct {
+	lock     sync.Mutex
+	launches map[string]*launchEntry
+}
+
+type launchEntry struct {
+	launch RunningLaunch
+	cancel context.CancelFunc
+	// set once the session has actually started
+	started bool
+}
+
+func newLaunches() *Launches {
+	return &Launches{
+		launches: make(map[string]*launchEntry),
+	}
+}
+
+// Add registers a launch of caveID that's being prepared. If exclusive
+// is set, it fails with CodeAlreadyRunning if there are other launches
+// of that cave, being prepared or running. Launches only show up in
+// List, and can only be killed, once they've been started with Start.
+func (l *Launches) Add(caveID string, exclusive bool) (string, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if exclusive {
+		for _, e := range l.launches {
+			if e.launch.CaveID == caveID {
+				return "", errors.WithStack(CodeAlreadyRunning)
+			}
+		}
+	}
+
+	id := uuid.New().String()
+	l.launches[id] = &launchEntry{
+		launch: RunningLaunch{
+			ID:     id,
+			CaveID: caveID,
+		},
+	}
+	return id, nil
+}
+
+// Start marks a launch as running. cancel must terminate it.
+func (l *Launches) Start(id string, strategy LaunchStrategy, cancel context.CancelFunc) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if e, ok := l.launches[id]; ok {
+		now := time.Now().UTC()
+		e.launch.Strategy = strategy
+		e.launch.StartedAt = &now
+		e.cancel = cancel
+		e.started = true
+	}
+}
+
+// SetPID records the process ID of a running session, once known
+func (l *Launches) SetPID(id string, pid int64) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if e, ok := l.launches[id]; ok {
+		e.launch.PID = pid
+	}
+}
+
+func (l *Launches) Remove(id string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	delete(l.launches, id)
+}
+
+// List returns copies of the running sessions, oldest first.
+// If caveID is set, only sessions of that cave are returned.
+func (l *Launches) List(caveID string) []*RunningLaunch {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	var res []*RunningLaunch
+	for _, e := range l.launches {
+		if !e.started {
+			continue
+		}
+		if caveID != "" && e.launch.CaveID != caveID {
+			continue
+		}
+		launch := e.launch
+		res = append(res, &launch)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].StartedAt.Before(*res[j].StartedAt)
+	})
+	return res
+}
+
+// Kill terminates a running session, and returns false
+// if there's no such session.
+func (l *Launches) Kill(id string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if e, ok := l.launches[id]; ok && e.started {
+		e.cancel()
+		return true
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package butlerd
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Launches(t *testing.T) {
+	assert := assert.New(t)
+
+	l := newLaunches()
+
+	id, err := l.Add("cave-a", true)
+	assert.NoError(err)
+
+	// launches being prepared aren't listed, and can't be killed yet
+	assert.Empty(l.List(""))
+	assert.False(l.Kill(id))
+
+	// ...but already count for the reject policy
+	_, err = l.Add("cave-a", true)
+	assert.Equal(CodeAlreadyRunning, errors.Cause(err))
+
+	// other caves, or concurrent launches, are fine
+	idB, err := l.Add("cave-b", true)
+	assert.NoError(err)
+	idA2, err := l.Add("cave-a", false)
+	assert.NoError(err)
+
+	killed := false
+	l.Start(id, LaunchStrategyNative, func() { killed = true })
+	l.Start(idB, LaunchStrategyHTML, func() {})
+	launches := l.List("cave-a")
+	if assert.Len(launches, 1) {
+		assert.EqualValues(id, launches[0].ID)
+		assert.EqualValues(LaunchStrategyNative, launches[0].Strategy)
+		assert.NotNil(launches[0].StartedAt)
+	}
+	assert.Len(l.List(""), 2)
+
+	assert.True(l.Kill(id))
+	assert.True(killed)
+
+	l.Remove(id)
+	l.Remove(idA2)
+	_, err = l.Add("cave-a", true)
+	assert.NoError(err)
+}
+
+func Test_LaunchesReject(t *testing.T) {
+	l := newLaunches()
+
+	var wg sync.WaitGroup
+	var added int64
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := l.Add("cave-a", true); err == nil {
+				atomic.AddInt64(&added, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, 1, added)
+}
@@ -862,6 +862,115 @@ func (r *ProfileDataGetType) TestCall(rc *butlerd.RequestContext, params butlerd
 
 var ProfileDataGet *ProfileDataGetType
 
+// Profile.Data.List (Request)
+
+type ProfileDataListType struct {}
+
+var _ RequestMessage = (*ProfileDataListType)(nil)
+
+func (r *ProfileDataListType) Method() string {
+  return "Profile.Data.List"
+}
+
+func (r *ProfileDataListType) Register(router router, f func(*butlerd.RequestContext, butlerd.ProfileDataListParams) (*butlerd.ProfileDataListResult, error)) {
+  router.Register("Profile.Data.List", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.ProfileDataListParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Profile.Data.List")
+    }
+    return res, nil
+  })
+}
+
+func (r *ProfileDataListType) TestCall(rc *butlerd.RequestContext, params butlerd.ProfileDataListParams) (*butlerd.ProfileDataListResult, error) {
+  var result butlerd.ProfileDataListResult
+  err := rc.Call("Profile.Data.List", params, &result)
+  return &result, err
+}
+
+var ProfileDataList *ProfileDataListType
+
+// Profile.Data.Delete (Request)
+
+type ProfileDataDeleteType struct {}
+
+var _ RequestMessage = (*ProfileDataDeleteType)(nil)
+
+func (r *ProfileDataDeleteType) Method() string {
+  return "Profile.Data.Delete"
+}
+
+func (r *ProfileDataDeleteType) Register(router router, f func(*butlerd.RequestContext, butlerd.ProfileDataDeleteParams) (*butlerd.ProfileDataDeleteResult, error)) {
+  router.Register("Profile.Data.Delete", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.ProfileDataDeleteParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Profile.Data.Delete")
+    }
+    return res, nil
+  })
+}
+
+func (r *ProfileDataDeleteType) TestCall(rc *butlerd.RequestContext, params butlerd.ProfileDataDeleteParams) (*butlerd.ProfileDataDeleteResult, error) {
+  var result butlerd.ProfileDataDeleteResult
+  err := rc.Call("Profile.Data.Delete", params, &result)
+  return &result, err
+}
+
+var ProfileDataDelete *ProfileDataDeleteType
+
+// Profile.Data.Changed (Notification)
+
+type ProfileDataChangedType struct {}
+
+var _ NotificationMessage = (*ProfileDataChangedType)(nil)
+
+func (r *ProfileDataChangedType) Method() string {
+  return "Profile.Data.Changed"
+}
+
+func (r *ProfileDataChangedType) Notify(rc *butlerd.RequestContext, params butlerd.ProfileDataChangedNotification) (error) {
+  return rc.Notify("Profile.Data.Changed", params)
+}
+
+func (r *ProfileDataChangedType) Register(router router, f func(butlerd.ProfileDataChangedNotification)) {
+  router.RegisterNotification("Profile.Data.Changed", func (notif jsonrpc2.Notification) {
+    var params butlerd.ProfileDataChangedNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
+    }
+    f(params)
+  })
+}
+
+var ProfileDataChanged *ProfileDataChangedType
+
 
 //==============================
 // Search
@@ -947,11 +1056,80 @@ func (r *SearchUsersType) TestCall(rc *butlerd.RequestContext, params butlerd.Se
 
 var SearchUsers *SearchUsersType
 
+// Search.Library (Request)
+
+type SearchLibraryType struct {}
+
+var _ RequestMessage = (*SearchLibraryType)(nil)
+
+func (r *SearchLibraryType) Method() string {
+  return "Search.Library"
+}
+
+func (r *SearchLibraryType) Register(router router, f func(*butlerd.RequestContext, butlerd.SearchLibraryParams) (*butlerd.SearchLibraryResult, error)) {
+  router.Register("Search.Library", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.SearchLibraryParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Search.Library")
+    }
+    return res, nil
+  })
+}
+
+func (r *SearchLibraryType) TestCall(rc *butlerd.RequestContext, params butlerd.SearchLibraryParams) (*butlerd.SearchLibraryResult, error) {
+  var result butlerd.SearchLibraryResult
+  err := rc.Call("Search.Library", params, &result)
+  return &result, err
+}
+
+var SearchLibrary *SearchLibraryType
+
 
 //==============================
 // Fetch
 //==============================
 
+// Fetch.Refreshed (Notification)
+
+type FetchRefreshedType struct {}
+
+var _ NotificationMessage = (*FetchRefreshedType)(nil)
+
+func (r *FetchRefreshedType) Method() string {
+  return "Fetch.Refreshed"
+}
+
+func (r *FetchRefreshedType) Notify(rc *butlerd.RequestContext, params butlerd.FetchRefreshedNotification) (error) {
+  return rc.Notify("Fetch.Refreshed", params)
+}
+
+func (r *FetchRefreshedType) Register(router router, f func(butlerd.FetchRefreshedNotification)) {
+  router.RegisterNotification("Fetch.Refreshed", func (notif jsonrpc2.Notification) {
+    var params butlerd.FetchRefreshedNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
+    }
+    f(params)
+  })
+}
+
+var FetchRefreshed *FetchRefreshedType
+
 // Fetch.Game (Request)
 
 type FetchGameType struct {}
@@ -2718,6 +2896,46 @@ func (r *DownloadsDiscardType) TestCall(rc *butlerd.RequestContext, params butle
 
 var DownloadsDiscard *DownloadsDiscardType
 
+// Downloads.History (Request)
+
+type DownloadsHistoryType struct {}
+
+var _ RequestMessage = (*DownloadsHistoryType)(nil)
+
+func (r *DownloadsHistoryType) Method() string {
+  return "Downloads.History"
+}
+
+func (r *DownloadsHistoryType) Register(router router, f func(*butlerd.RequestContext, butlerd.DownloadsHistoryParams) (*butlerd.DownloadsHistoryResult, error)) {
+  router.Register("Downloads.History", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.DownloadsHistoryParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Downloads.History")
+    }
+    return res, nil
+  })
+}
+
+func (r *DownloadsHistoryType) TestCall(rc *butlerd.RequestContext, params butlerd.DownloadsHistoryParams) (*butlerd.DownloadsHistoryResult, error) {
+  var result butlerd.DownloadsHistoryResult
+  err := rc.Call("Downloads.History", params, &result)
+  return &result, err
+}
+
+var DownloadsHistory *DownloadsHistoryType
+
 
 //==============================
 // Update
@@ -2832,6 +3050,46 @@ func (r *SnoozeCaveType) TestCall(rc *butlerd.RequestContext, params butlerd.Sno
 
 var SnoozeCave *SnoozeCaveType
 
+// Caves.SetUpdatePolicy (Request)
+
+type CavesSetUpdatePolicyType struct {}
+
+var _ RequestMessage = (*CavesSetUpdatePolicyType)(nil)
+
+func (r *CavesSetUpdatePolicyType) Method() string {
+  return "Caves.SetUpdatePolicy"
+}
+
+func (r *CavesSetUpdatePolicyType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesSetUpdatePolicyParams) (*butlerd.CavesSetUpdatePolicyResult, error)) {
+  router.Register("Caves.SetUpdatePolicy", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesSetUpdatePolicyParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Caves.SetUpdatePolicy")
+    }
+    return res, nil
+  })
+}
+
+func (r *CavesSetUpdatePolicyType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesSetUpdatePolicyParams) (*butlerd.CavesSetUpdatePolicyResult, error) {
+  var result butlerd.CavesSetUpdatePolicyResult
+  err := rc.Call("Caves.SetUpdatePolicy", params, &result)
+  return &result, err
+}
+
+var CavesSetUpdatePolicy *CavesSetUpdatePolicyType
+
 
 //==============================
 // update
@@ -2882,6 +3140,86 @@ func (r *LaunchType) TestCall(rc *butlerd.RequestContext, params butlerd.LaunchP
 
 var Launch *LaunchType
 
+// Launch.List (Request)
+
+type LaunchListType struct {}
+
+var _ RequestMessage = (*LaunchListType)(nil)
+
+func (r *LaunchListType) Method() string {
+  return "Launch.List"
+}
+
+func (r *LaunchListType) Register(router router, f func(*butlerd.RequestContext, butlerd.LaunchListParams) (*butlerd.LaunchListResult, error)) {
+  router.Register("Launch.List", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.LaunchListParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Launch.List")
+    }
+    return res, nil
+  })
+}
+
+func (r *LaunchListType) TestCall(rc *butlerd.RequestContext, params butlerd.LaunchListParams) (*butlerd.LaunchListResult, error) {
+  var result butlerd.LaunchListResult
+  err := rc.Call("Launch.List", params, &result)
+  return &result, err
+}
+
+var LaunchList *LaunchListType
+
+// Launch.Kill (Request)
+
+type LaunchKillType struct {}
+
+var _ RequestMessage = (*LaunchKillType)(nil)
+
+func (r *LaunchKillType) Method() string {
+  return "Launch.Kill"
+}
+
+func (r *LaunchKillType) Register(router router, f func(*butlerd.RequestContext, butlerd.LaunchKillParams) (*butlerd.LaunchKillResult, error)) {
+  router.Register("Launch.Kill", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.LaunchKillParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Launch.Kill")
+    }
+    return res, nil
+  })
+}
+
+func (r *LaunchKillType) TestCall(rc *butlerd.RequestContext, params butlerd.LaunchKillParams) (*butlerd.LaunchKillResult, error) {
+  var result butlerd.LaunchKillResult
+  err := rc.Call("Launch.Kill", params, &result)
+  return &result, err
+}
+
+var LaunchKill *LaunchKillType
+
 // LaunchRunning (Notification)
 
 type LaunchRunningType struct {}
@@ -2940,35 +3278,93 @@ func (r *LaunchExitedType) Register(router router, f func(butlerd.LaunchExitedNo
 
 var LaunchExited *LaunchExitedType
 
-// AcceptLicense (Request)
+// LaunchCrashed (Notification)
 
-type AcceptLicenseType struct {}
+type LaunchCrashedType struct {}
 
-var _ RequestMessage = (*AcceptLicenseType)(nil)
+var _ NotificationMessage = (*LaunchCrashedType)(nil)
 
-func (r *AcceptLicenseType) Method() string {
-  return "AcceptLicense"
+func (r *LaunchCrashedType) Method() string {
+  return "LaunchCrashed"
 }
 
-func (r *AcceptLicenseType) TestRegister(router router, f func(*butlerd.RequestContext, butlerd.AcceptLicenseParams) (*butlerd.AcceptLicenseResult, error)) {
-  router.Register("AcceptLicense", func (rc *butlerd.RequestContext) (interface{}, error) {
-    var params butlerd.AcceptLicenseParams
-    err := json.Unmarshal(*rc.Params, &params)
-    if err != nil {
-    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
-    }
-    err = params.Validate()
-    if err != nil {
-    	return nil, err
-    }
-    res, err := f(rc, params)
-    if err != nil {
-    	return nil, err
-    }
-    if res == nil {
-    	return nil, errors.New("internal error: nil result for AcceptLicense")
+func (r *LaunchCrashedType) Notify(rc *butlerd.RequestContext, params butlerd.LaunchCrashedNotification) (error) {
+  return rc.Notify("LaunchCrashed", params)
+}
+
+func (r *LaunchCrashedType) Register(router router, f func(butlerd.LaunchCrashedNotification)) {
+  router.RegisterNotification("LaunchCrashed", func (notif jsonrpc2.Notification) {
+    var params butlerd.LaunchCrashedNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
     }
-    return res, nil
+    f(params)
+  })
+}
+
+var LaunchCrashed *LaunchCrashedType
+
+// LaunchOutputLine (Notification)
+
+type LaunchOutputLineType struct {}
+
+var _ NotificationMessage = (*LaunchOutputLineType)(nil)
+
+func (r *LaunchOutputLineType) Method() string {
+  return "LaunchOutputLine"
+}
+
+func (r *LaunchOutputLineType) Notify(rc *butlerd.RequestContext, params butlerd.LaunchOutputLineNotification) (error) {
+  return rc.Notify("LaunchOutputLine", params)
+}
+
+func (r *LaunchOutputLineType) Register(router router, f func(butlerd.LaunchOutputLineNotification)) {
+  router.RegisterNotification("LaunchOutputLine", func (notif jsonrpc2.Notification) {
+    var params butlerd.LaunchOutputLineNotification
+    if notif.Params != nil {
+      err := json.Unmarshal(*notif.Params, &params)
+      if err != nil {
+        return
+      }
+    }
+    f(params)
+  })
+}
+
+var LaunchOutputLine *LaunchOutputLineType
+
+// AcceptLicense (Request)
+
+type AcceptLicenseType struct {}
+
+var _ RequestMessage = (*AcceptLicenseType)(nil)
+
+func (r *AcceptLicenseType) Method() string {
+  return "AcceptLicense"
+}
+
+func (r *AcceptLicenseType) TestRegister(router router, f func(*butlerd.RequestContext, butlerd.AcceptLicenseParams) (*butlerd.AcceptLicenseResult, error)) {
+  router.Register("AcceptLicense", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.AcceptLicenseParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for AcceptLicense")
+    }
+    return res, nil
   })
 }
 
@@ -3180,6 +3576,46 @@ func (r *AllowSandboxSetupType) Call(rc *butlerd.RequestContext, params butlerd.
 
 var AllowSandboxSetup *AllowSandboxSetupType
 
+// AllowSnapInstall (Request)
+
+type AllowSnapInstallType struct {}
+
+var _ RequestMessage = (*AllowSnapInstallType)(nil)
+
+func (r *AllowSnapInstallType) Method() string {
+  return "AllowSnapInstall"
+}
+
+func (r *AllowSnapInstallType) TestRegister(router router, f func(*butlerd.RequestContext, butlerd.AllowSnapInstallParams) (*butlerd.AllowSnapInstallResult, error)) {
+  router.Register("AllowSnapInstall", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.AllowSnapInstallParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for AllowSnapInstall")
+    }
+    return res, nil
+  })
+}
+
+func (r *AllowSnapInstallType) Call(rc *butlerd.RequestContext, params butlerd.AllowSnapInstallParams) (*butlerd.AllowSnapInstallResult, error) {
+  var result butlerd.AllowSnapInstallResult
+  err := rc.Call("AllowSnapInstall", params, &result)
+  return &result, err
+}
+
+var AllowSnapInstall *AllowSnapInstallType
+
 // PrereqsStarted (Notification)
 
 type PrereqsStartedType struct {}
@@ -3307,6 +3743,526 @@ func (r *PrereqsFailedType) Call(rc *butlerd.RequestContext, params butlerd.Prer
 
 var PrereqsFailed *PrereqsFailedType
 
+// Launch.Wrappers.List (Request)
+
+type LaunchWrappersListType struct {}
+
+var _ RequestMessage = (*LaunchWrappersListType)(nil)
+
+func (r *LaunchWrappersListType) Method() string {
+  return "Launch.Wrappers.List"
+}
+
+func (r *LaunchWrappersListType) Register(router router, f func(*butlerd.RequestContext, butlerd.LaunchWrappersListParams) (*butlerd.LaunchWrappersListResult, error)) {
+  router.Register("Launch.Wrappers.List", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.LaunchWrappersListParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Launch.Wrappers.List")
+    }
+    return res, nil
+  })
+}
+
+func (r *LaunchWrappersListType) TestCall(rc *butlerd.RequestContext, params butlerd.LaunchWrappersListParams) (*butlerd.LaunchWrappersListResult, error) {
+  var result butlerd.LaunchWrappersListResult
+  err := rc.Call("Launch.Wrappers.List", params, &result)
+  return &result, err
+}
+
+var LaunchWrappersList *LaunchWrappersListType
+
+// Launch.Wrappers.Save (Request)
+
+type LaunchWrappersSaveType struct {}
+
+var _ RequestMessage = (*LaunchWrappersSaveType)(nil)
+
+func (r *LaunchWrappersSaveType) Method() string {
+  return "Launch.Wrappers.Save"
+}
+
+func (r *LaunchWrappersSaveType) Register(router router, f func(*butlerd.RequestContext, butlerd.LaunchWrappersSaveParams) (*butlerd.LaunchWrappersSaveResult, error)) {
+  router.Register("Launch.Wrappers.Save", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.LaunchWrappersSaveParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Launch.Wrappers.Save")
+    }
+    return res, nil
+  })
+}
+
+func (r *LaunchWrappersSaveType) TestCall(rc *butlerd.RequestContext, params butlerd.LaunchWrappersSaveParams) (*butlerd.LaunchWrappersSaveResult, error) {
+  var result butlerd.LaunchWrappersSaveResult
+  err := rc.Call("Launch.Wrappers.Save", params, &result)
+  return &result, err
+}
+
+var LaunchWrappersSave *LaunchWrappersSaveType
+
+// Launch.Wrappers.Remove (Request)
+
+type LaunchWrappersRemoveType struct {}
+
+var _ RequestMessage = (*LaunchWrappersRemoveType)(nil)
+
+func (r *LaunchWrappersRemoveType) Method() string {
+  return "Launch.Wrappers.Remove"
+}
+
+func (r *LaunchWrappersRemoveType) Register(router router, f func(*butlerd.RequestContext, butlerd.LaunchWrappersRemoveParams) (*butlerd.LaunchWrappersRemoveResult, error)) {
+  router.Register("Launch.Wrappers.Remove", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.LaunchWrappersRemoveParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Launch.Wrappers.Remove")
+    }
+    return res, nil
+  })
+}
+
+func (r *LaunchWrappersRemoveType) TestCall(rc *butlerd.RequestContext, params butlerd.LaunchWrappersRemoveParams) (*butlerd.LaunchWrappersRemoveResult, error) {
+  var result butlerd.LaunchWrappersRemoveResult
+  err := rc.Call("Launch.Wrappers.Remove", params, &result)
+  return &result, err
+}
+
+var LaunchWrappersRemove *LaunchWrappersRemoveType
+
+// Caves.SetWrapper (Request)
+
+type CavesSetWrapperType struct {}
+
+var _ RequestMessage = (*CavesSetWrapperType)(nil)
+
+func (r *CavesSetWrapperType) Method() string {
+  return "Caves.SetWrapper"
+}
+
+func (r *CavesSetWrapperType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesSetWrapperParams) (*butlerd.CavesSetWrapperResult, error)) {
+  router.Register("Caves.SetWrapper", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesSetWrapperParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Caves.SetWrapper")
+    }
+    return res, nil
+  })
+}
+
+func (r *CavesSetWrapperType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesSetWrapperParams) (*butlerd.CavesSetWrapperResult, error) {
+  var result butlerd.CavesSetWrapperResult
+  err := rc.Call("Caves.SetWrapper", params, &result)
+  return &result, err
+}
+
+var CavesSetWrapper *CavesSetWrapperType
+
+// Launch.Hooks.List (Request)
+
+type LaunchHooksListType struct {}
+
+var _ RequestMessage = (*LaunchHooksListType)(nil)
+
+func (r *LaunchHooksListType) Method() string {
+  return "Launch.Hooks.List"
+}
+
+func (r *LaunchHooksListType) Register(router router, f func(*butlerd.RequestContext, butlerd.LaunchHooksListParams) (*butlerd.LaunchHooksListResult, error)) {
+  router.Register("Launch.Hooks.List", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.LaunchHooksListParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Launch.Hooks.List")
+    }
+    return res, nil
+  })
+}
+
+func (r *LaunchHooksListType) TestCall(rc *butlerd.RequestContext, params butlerd.LaunchHooksListParams) (*butlerd.LaunchHooksListResult, error) {
+  var result butlerd.LaunchHooksListResult
+  err := rc.Call("Launch.Hooks.List", params, &result)
+  return &result, err
+}
+
+var LaunchHooksList *LaunchHooksListType
+
+// Launch.Hooks.Save (Request)
+
+type LaunchHooksSaveType struct {}
+
+var _ RequestMessage = (*LaunchHooksSaveType)(nil)
+
+func (r *LaunchHooksSaveType) Method() string {
+  return "Launch.Hooks.Save"
+}
+
+func (r *LaunchHooksSaveType) Register(router router, f func(*butlerd.RequestContext, butlerd.LaunchHooksSaveParams) (*butlerd.LaunchHooksSaveResult, error)) {
+  router.Register("Launch.Hooks.Save", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.LaunchHooksSaveParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Launch.Hooks.Save")
+    }
+    return res, nil
+  })
+}
+
+func (r *LaunchHooksSaveType) TestCall(rc *butlerd.RequestContext, params butlerd.LaunchHooksSaveParams) (*butlerd.LaunchHooksSaveResult, error) {
+  var result butlerd.LaunchHooksSaveResult
+  err := rc.Call("Launch.Hooks.Save", params, &result)
+  return &result, err
+}
+
+var LaunchHooksSave *LaunchHooksSaveType
+
+// Launch.Hooks.Remove (Request)
+
+type LaunchHooksRemoveType struct {}
+
+var _ RequestMessage = (*LaunchHooksRemoveType)(nil)
+
+func (r *LaunchHooksRemoveType) Method() string {
+  return "Launch.Hooks.Remove"
+}
+
+func (r *LaunchHooksRemoveType) Register(router router, f func(*butlerd.RequestContext, butlerd.LaunchHooksRemoveParams) (*butlerd.LaunchHooksRemoveResult, error)) {
+  router.Register("Launch.Hooks.Remove", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.LaunchHooksRemoveParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Launch.Hooks.Remove")
+    }
+    return res, nil
+  })
+}
+
+func (r *LaunchHooksRemoveType) TestCall(rc *butlerd.RequestContext, params butlerd.LaunchHooksRemoveParams) (*butlerd.LaunchHooksRemoveResult, error) {
+  var result butlerd.LaunchHooksRemoveResult
+  err := rc.Call("Launch.Hooks.Remove", params, &result)
+  return &result, err
+}
+
+var LaunchHooksRemove *LaunchHooksRemoveType
+
+// Caves.SetLaunchConfig (Request)
+
+type CavesSetLaunchConfigType struct {}
+
+var _ RequestMessage = (*CavesSetLaunchConfigType)(nil)
+
+func (r *CavesSetLaunchConfigType) Method() string {
+  return "Caves.SetLaunchConfig"
+}
+
+func (r *CavesSetLaunchConfigType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesSetLaunchConfigParams) (*butlerd.CavesSetLaunchConfigResult, error)) {
+  router.Register("Caves.SetLaunchConfig", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesSetLaunchConfigParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Caves.SetLaunchConfig")
+    }
+    return res, nil
+  })
+}
+
+func (r *CavesSetLaunchConfigType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesSetLaunchConfigParams) (*butlerd.CavesSetLaunchConfigResult, error) {
+  var result butlerd.CavesSetLaunchConfigResult
+  err := rc.Call("Caves.SetLaunchConfig", params, &result)
+  return &result, err
+}
+
+var CavesSetLaunchConfig *CavesSetLaunchConfigType
+
+// Caves.GetLaunchConfig (Request)
+
+type CavesGetLaunchConfigType struct {}
+
+var _ RequestMessage = (*CavesGetLaunchConfigType)(nil)
+
+func (r *CavesGetLaunchConfigType) Method() string {
+  return "Caves.GetLaunchConfig"
+}
+
+func (r *CavesGetLaunchConfigType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesGetLaunchConfigParams) (*butlerd.CavesGetLaunchConfigResult, error)) {
+  router.Register("Caves.GetLaunchConfig", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesGetLaunchConfigParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Caves.GetLaunchConfig")
+    }
+    return res, nil
+  })
+}
+
+func (r *CavesGetLaunchConfigType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesGetLaunchConfigParams) (*butlerd.CavesGetLaunchConfigResult, error) {
+  var result butlerd.CavesGetLaunchConfigResult
+  err := rc.Call("Caves.GetLaunchConfig", params, &result)
+  return &result, err
+}
+
+var CavesGetLaunchConfig *CavesGetLaunchConfigType
+
+// Caves.ListLaunchLogs (Request)
+
+type CavesListLaunchLogsType struct {}
+
+var _ RequestMessage = (*CavesListLaunchLogsType)(nil)
+
+func (r *CavesListLaunchLogsType) Method() string {
+  return "Caves.ListLaunchLogs"
+}
+
+func (r *CavesListLaunchLogsType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesListLaunchLogsParams) (*butlerd.CavesListLaunchLogsResult, error)) {
+  router.Register("Caves.ListLaunchLogs", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesListLaunchLogsParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Caves.ListLaunchLogs")
+    }
+    return res, nil
+  })
+}
+
+func (r *CavesListLaunchLogsType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesListLaunchLogsParams) (*butlerd.CavesListLaunchLogsResult, error) {
+  var result butlerd.CavesListLaunchLogsResult
+  err := rc.Call("Caves.ListLaunchLogs", params, &result)
+  return &result, err
+}
+
+var CavesListLaunchLogs *CavesListLaunchLogsType
+
+// Caves.ReadLaunchLog (Request)
+
+type CavesReadLaunchLogType struct {}
+
+var _ RequestMessage = (*CavesReadLaunchLogType)(nil)
+
+func (r *CavesReadLaunchLogType) Method() string {
+  return "Caves.ReadLaunchLog"
+}
+
+func (r *CavesReadLaunchLogType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesReadLaunchLogParams) (*butlerd.CavesReadLaunchLogResult, error)) {
+  router.Register("Caves.ReadLaunchLog", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesReadLaunchLogParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Caves.ReadLaunchLog")
+    }
+    return res, nil
+  })
+}
+
+func (r *CavesReadLaunchLogType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesReadLaunchLogParams) (*butlerd.CavesReadLaunchLogResult, error) {
+  var result butlerd.CavesReadLaunchLogResult
+  err := rc.Call("Caves.ReadLaunchLog", params, &result)
+  return &result, err
+}
+
+var CavesReadLaunchLog *CavesReadLaunchLogType
+
+// Caves.ListCrashes (Request)
+
+type CavesListCrashesType struct {}
+
+var _ RequestMessage = (*CavesListCrashesType)(nil)
+
+func (r *CavesListCrashesType) Method() string {
+  return "Caves.ListCrashes"
+}
+
+func (r *CavesListCrashesType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesListCrashesParams) (*butlerd.CavesListCrashesResult, error)) {
+  router.Register("Caves.ListCrashes", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesListCrashesParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Caves.ListCrashes")
+    }
+    return res, nil
+  })
+}
+
+func (r *CavesListCrashesType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesListCrashesParams) (*butlerd.CavesListCrashesResult, error) {
+  var result butlerd.CavesListCrashesResult
+  err := rc.Call("Caves.ListCrashes", params, &result)
+  return &result, err
+}
+
+var CavesListCrashes *CavesListCrashesType
+
+// Caves.PlaytimeStats (Request)
+
+type CavesPlaytimeStatsType struct {}
+
+var _ RequestMessage = (*CavesPlaytimeStatsType)(nil)
+
+func (r *CavesPlaytimeStatsType) Method() string {
+  return "Caves.PlaytimeStats"
+}
+
+func (r *CavesPlaytimeStatsType) Register(router router, f func(*butlerd.RequestContext, butlerd.CavesPlaytimeStatsParams) (*butlerd.CavesPlaytimeStatsResult, error)) {
+  router.Register("Caves.PlaytimeStats", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.CavesPlaytimeStatsParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Caves.PlaytimeStats")
+    }
+    return res, nil
+  })
+}
+
+func (r *CavesPlaytimeStatsType) TestCall(rc *butlerd.RequestContext, params butlerd.CavesPlaytimeStatsParams) (*butlerd.CavesPlaytimeStatsResult, error) {
+  var result butlerd.CavesPlaytimeStatsResult
+  err := rc.Call("Caves.PlaytimeStats", params, &result)
+  return &result, err
+}
+
+var CavesPlaytimeStats *CavesPlaytimeStatsType
+
 
 //==============================
 // Clean Downloads
@@ -3393,6 +4349,91 @@ func (r *CleanDownloadsApplyType) TestCall(rc *butlerd.RequestContext, params bu
 var CleanDownloadsApply *CleanDownloadsApplyType
 
 
+//==============================
+// Library
+//==============================
+
+// Library.Export (Request)
+
+type LibraryExportType struct {}
+
+var _ RequestMessage = (*LibraryExportType)(nil)
+
+func (r *LibraryExportType) Method() string {
+  return "Library.Export"
+}
+
+func (r *LibraryExportType) Register(router router, f func(*butlerd.RequestContext, butlerd.LibraryExportParams) (*butlerd.LibraryExportResult, error)) {
+  router.Register("Library.Export", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.LibraryExportParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Library.Export")
+    }
+    return res, nil
+  })
+}
+
+func (r *LibraryExportType) TestCall(rc *butlerd.RequestContext, params butlerd.LibraryExportParams) (*butlerd.LibraryExportResult, error) {
+  var result butlerd.LibraryExportResult
+  err := rc.Call("Library.Export", params, &result)
+  return &result, err
+}
+
+var LibraryExport *LibraryExportType
+
+// Library.Import (Request)
+
+type LibraryImportType struct {}
+
+var _ RequestMessage = (*LibraryImportType)(nil)
+
+func (r *LibraryImportType) Method() string {
+  return "Library.Import"
+}
+
+func (r *LibraryImportType) Register(router router, f func(*butlerd.RequestContext, butlerd.LibraryImportParams) (*butlerd.LibraryImportResult, error)) {
+  router.Register("Library.Import", func (rc *butlerd.RequestContext) (interface{}, error) {
+    var params butlerd.LibraryImportParams
+    err := json.Unmarshal(*rc.Params, &params)
+    if err != nil {
+    	return nil, &butlerd.RpcError{Code: jsonrpc2.CodeParseError, Message: err.Error()}
+    }
+    err = params.Validate()
+    if err != nil {
+    	return nil, err
+    }
+    res, err := f(rc, params)
+    if err != nil {
+    	return nil, err
+    }
+    if res == nil {
+    	return nil, errors.New("internal error: nil result for Library.Import")
+    }
+    return res, nil
+  })
+}
+
+func (r *LibraryImportType) TestCall(rc *butlerd.RequestContext, params butlerd.LibraryImportParams) (*butlerd.LibraryImportResult, error) {
+  var result butlerd.LibraryImportResult
+  err := rc.Call("Library.Import", params, &result)
+  return &result, err
+}
+
+var LibraryImport *LibraryImportType
+
+
 //==============================
 // System
 //==============================
@@ -3537,8 +4578,11 @@ func EnsureAllRequests(router *butlerd.Router) {
   if _, ok := router.Handlers["Profile.Forget"]; !ok { panic("missing request handler for (Profile.Forget)") }
   if _, ok := router.Handlers["Profile.Data.Put"]; !ok { panic("missing request handler for (Profile.Data.Put)") }
   if _, ok := router.Handlers["Profile.Data.Get"]; !ok { panic("missing request handler for (Profile.Data.Get)") }
+  if _, ok := router.Handlers["Profile.Data.List"]; !ok { panic("missing request handler for (Profile.Data.List)") }
+  if _, ok := router.Handlers["Profile.Data.Delete"]; !ok { panic("missing request handler for (Profile.Data.Delete)") }
   if _, ok := router.Handlers["Search.Games"]; !ok { panic("missing request handler for (Search.Games)") }
   if _, ok := router.Handlers["Search.Users"]; !ok { panic("missing request handler for (Search.Users)") }
+  if _, ok := router.Handlers["Search.Library"]; !ok { panic("missing request handler for (Search.Library)") }
   if _, ok := router.Handlers["Fetch.Game"]; !ok { panic("missing request handler for (Fetch.Game)") }
   if _, ok := router.Handlers["Fetch.GameRecords"]; !ok { panic("missing request handler for (Fetch.GameRecords)") }
   if _, ok := router.Handlers["Fetch.DownloadKey"]; !ok { panic("missing request handler for (Fetch.DownloadKey)") }
@@ -3577,11 +4621,30 @@ func EnsureAllRequests(router *butlerd.Router) {
   if _, ok := router.Handlers["Downloads.Drive.Cancel"]; !ok { panic("missing request handler for (Downloads.Drive.Cancel)") }
   if _, ok := router.Handlers["Downloads.Retry"]; !ok { panic("missing request handler for (Downloads.Retry)") }
   if _, ok := router.Handlers["Downloads.Discard"]; !ok { panic("missing request handler for (Downloads.Discard)") }
+  if _, ok := router.Handlers["Downloads.History"]; !ok { panic("missing request handler for (Downloads.History)") }
   if _, ok := router.Handlers["CheckUpdate"]; !ok { panic("missing request handler for (CheckUpdate)") }
   if _, ok := router.Handlers["SnoozeCave"]; !ok { panic("missing request handler for (SnoozeCave)") }
+  if _, ok := router.Handlers["Caves.SetUpdatePolicy"]; !ok { panic("missing request handler for (Caves.SetUpdatePolicy)") }
   if _, ok := router.Handlers["Launch"]; !ok { panic("missing request handler for (Launch)") }
+  if _, ok := router.Handlers["Launch.List"]; !ok { panic("missing request handler for (Launch.List)") }
+  if _, ok := router.Handlers["Launch.Kill"]; !ok { panic("missing request handler for (Launch.Kill)") }
+  if _, ok := router.Handlers["Launch.Wrappers.List"]; !ok { panic("missing request handler for (Launch.Wrappers.List)") }
+  if _, ok := router.Handlers["Launch.Wrappers.Save"]; !ok { panic("missing request handler for (Launch.Wrappers.Save)") }
+  if _, ok := router.Handlers["Launch.Wrappers.Remove"]; !ok { panic("missing request handler for (Launch.Wrappers.Remove)") }
+  if _, ok := router.Handlers["Caves.SetWrapper"]; !ok { panic("missing request handler for (Caves.SetWrapper)") }
+  if _, ok := router.Handlers["Launch.Hooks.List"]; !ok { panic("missing request handler for (Launch.Hooks.List)") }
+  if _, ok := router.Handlers["Launch.Hooks.Save"]; !ok { panic("missing request handler for (Launch.Hooks.Save)") }
+  if _, ok := router.Handlers["Launch.Hooks.Remove"]; !ok { panic("missing request handler for (Launch.Hooks.Remove)") }
+  if _, ok := router.Handlers["Caves.SetLaunchConfig"]; !ok { panic("missing request handler for (Caves.SetLaunchConfig)") }
+  if _, ok := router.Handlers["Caves.GetLaunchConfig"]; !ok { panic("missing request handler for (Caves.GetLaunchConfig)") }
+  if _, ok := router.Handlers["Caves.ListLaunchLogs"]; !ok { panic("missing request handler for (Caves.ListLaunchLogs)") }
+  if _, ok := router.Handlers["Caves.ReadLaunchLog"]; !ok { panic("missing request handler for (Caves.ReadLaunchLog)") }
+  if _, ok := router.Handlers["Caves.ListCrashes"]; !ok { panic("missing request handler for (Caves.ListCrashes)") }
+  if _, ok := router.Handlers["Caves.PlaytimeStats"]; !ok { panic("missing request handler for (Caves.PlaytimeStats)") }
   if _, ok := router.Handlers["CleanDownloads.Search"]; !ok { panic("missing request handler for (CleanDownloads.Search)") }
   if _, ok := router.Handlers["CleanDownloads.Apply"]; !ok { panic("missing request handler for (CleanDownloads.Apply)") }
+  if _, ok := router.Handlers["Library.Export"]; !ok { panic("missing request handler for (Library.Export)") }
+  if _, ok := router.Handlers["Library.Import"]; !ok { panic("missing request handler for (Library.Import)") }
   if _, ok := router.Handlers["System.StatFS"]; !ok { panic("missing request handler for (System.StatFS)") }
   if _, ok := router.Handlers["Test.DoubleTwice"]; !ok { panic("missing request handler for (Test.DoubleTwice)") }
 }
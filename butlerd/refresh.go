@@ -0,0 +1,102 @@
+package butlerd
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/helloeave/json"
+	"github.com/pkg/errors"
+)
+
+type staleSetter interface {
+	SetStale(stale bool)
+}
+
+// refreshInBackground runs a fetch request again with `fresh` set, once
+// the client has its (cached) result, and notifies clients if the
+// refreshed result differs. Used in offline-first mode.
+func (r *Router) refreshInBackground(method string, params *json.RawMessage) {
+	h, ok := r.Handlers[method]
+	if !ok {
+		return
+	}
+
+	var paramsMap map[string]interface{}
+	if params != nil {
+		err := json.Unmarshal(*params, &paramsMap)
+		if err != nil {
+			r.Logf("Not refreshing %s: %+v", method, err)
+			return
+		}
+	}
+	if paramsMap == nil {
+		paramsMap = make(map[string]interface{})
+	}
+	delete(paramsMap, "fresh")
+
+	key, err := json.Marshal(paramsMap)
+	if err != nil {
+		r.Logf("Not refreshing %s: %+v", method, err)
+		return
+	}
+	refreshKey := method + string(key)
+
+	r.inflightLock.Lock()
+	if r.refreshing[refreshKey] {
+		r.inflightLock.Unlock()
+		return
+	}
+	r.refreshing[refreshKey] = true
+	r.inflightLock.Unlock()
+
+	r.QueueBackgroundTask(BackgroundTask{
+		Desc: fmt.Sprintf("Refreshing %s", method),
+		Do: func(rc *RequestContext) error {
+			defer func() {
+				r.inflightLock.Lock()
+				delete(r.refreshing, refreshKey)
+				r.inflightLock.Unlock()
+			}()
+
+			call := func(fresh bool) ([]byte, error) {
+				paramsMap["fresh"] = fresh
+				payload, err := json.Marshal(paramsMap)
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				raw := json.RawMessage(payload)
+				rc.Params = &raw
+				rc.method = method
+
+				res, err := h(rc)
+				if err != nil {
+					return nil, err
+				}
+				if ss, ok := res.(staleSetter); ok {
+					ss.SetStale(false)
+				}
+				return json.Marshal(res)
+			}
+
+			before, err := call(false)
+			if err != nil {
+				return err
+			}
+			after, err := call(true)
+			if err != nil {
+				return err
+			}
+
+			if bytes.Equal(before, after) {
+				rc.Consumer.Debugf("%s %s is unchanged", method, key)
+				return nil
+			}
+
+			// cannot use autogenerated wrappers to avoid import cycles
+			return rc.Broadcast("Fetch.Refreshed", FetchRefreshedNotification{
+				Method: method,
+				Params: string(key),
+			})
+		},
+	})
+}
@@ -43,6 +43,9 @@ type InFlightBackgroundTask struct {
 type BackgroundTask struct {
 	Desc string
 	Do   func(rc *RequestContext) error
+	// If set, notifications sent by the task go to every connected
+	// client. Otherwise the task has no connection at all.
+	NotifyClients bool
 }
 
 type RequestHandler func(rc *RequestContext) (interface{}, error)
@@ -54,6 +57,8 @@ type Router struct {
 	Handlers             map[string]RequestHandler
 	NotificationHandlers map[string]NotificationHandler
 	CancelFuncs          *CancelFuncs
+	Launches             *Launches
+	clients              *clients
 	dbPool               *sqlitex.Pool
 	getClient            GetClientFunc
 	httpClient           *http.Client
@@ -73,6 +78,13 @@ type Router struct {
 
 	backgroundTaskIDSeed BackgroundTaskID
 
+	// fetch requests being refreshed in the background, see OfflineFirst
+	refreshing map[string]bool
+
+	// When set, stale data that was fetched before is returned as-is
+	// by fetch requests, and refreshed in the background.
+	OfflineFirst bool
+
 	globalConsumer *state.Consumer
 }
 
@@ -85,6 +97,8 @@ func NewRouter(dbPool *sqlitex.Pool, getClient GetClientFunc, httpClient *http.C
 		CancelFuncs: &CancelFuncs{
 			Funcs: make(map[string]context.CancelFunc),
 		},
+		Launches:      newLaunches(),
+		clients:       newClients(),
 		dbPool:        dbPool,
 		getClient:     getClient,
 		httpClient:    httpClient,
@@ -101,6 +115,8 @@ func NewRouter(dbPool *sqlitex.Pool, getClient GetClientFunc, httpClient *http.C
 
 		backgroundTaskIDSeed: 0,
 
+		refreshing: make(map[string]bool),
+
 		globalConsumer: &state.Consumer{
 			OnMessage: func(lvl string, msg string) {
 				comm.Logf("[router] [%s] %s", lvl, msg)
@@ -222,6 +238,8 @@ func (r *Router) HandleRequest(conn jsonrpc2.Conn, req jsonrpc2.Request) (interf
 		r.inflightLock.Unlock()
 	}()
 
+	r.clients.add(conn)
+
 	method := req.Method
 	var res interface{}
 
@@ -249,6 +267,7 @@ func (r *Router) HandleRequest(conn jsonrpc2.Conn, req jsonrpc2.Request) (interf
 			Params:      req.Params,
 			Conn:        conn,
 			CancelFuncs: r.CancelFuncs,
+			Launches:    r.Launches,
 			dbPool:      r.dbPool,
 			Client:      r.getClient,
 
@@ -261,6 +280,12 @@ func (r *Router) HandleRequest(conn jsonrpc2.Conn, req jsonrpc2.Request) (interf
 			method: method,
 
 			QueueBackgroundTask: r.QueueBackgroundTask,
+			Broadcast:           r.broadcast,
+
+			OfflineFirst: r.OfflineFirst,
+			RefreshInBackground: func() {
+				r.refreshInBackground(method, req.Params)
+			},
 		}
 
 		{
@@ -376,13 +401,22 @@ func (r *Router) doBackgroundTask(id BackgroundTaskID, bt BackgroundTask) {
 		r.inflightLock.Unlock()
 	}()
 
+	var conn jsonrpc2.Conn
+	if bt.NotifyClients {
+		conn = &broadcastConn{
+			ctx:     r.backgroundContext,
+			clients: r.clients,
+		}
+	}
+
 	consumer := r.globalConsumer
 	rc := &RequestContext{
 		Ctx:         r.backgroundContext,
 		Consumer:    consumer,
 		Params:      nil,
-		Conn:        nil,
+		Conn:        conn,
 		CancelFuncs: r.CancelFuncs,
+		Launches:    r.Launches,
 		dbPool:      r.dbPool,
 		Client:      r.getClient,
 
@@ -395,6 +429,7 @@ func (r *Router) doBackgroundTask(id BackgroundTaskID, bt BackgroundTask) {
 		method: "",
 
 		QueueBackgroundTask: r.QueueBackgroundTask,
+		Broadcast:           r.broadcast,
 	}
 
 	err := func() (retErr error) {
@@ -430,6 +465,15 @@ type RequestContext struct {
 	Consumer            *state.Consumer
 	Client              GetClientFunc
 	QueueBackgroundTask func(bt BackgroundTask)
+	// Sends a notification to every connected client,
+	// not just the one that made the request
+	Broadcast func(method string, params interface{}) error
+
+	// See Router.OfflineFirst. Always false for background tasks.
+	OfflineFirst bool
+	// Runs this request again in the background, with `fresh` set,
+	// and notifies clients with Fetch.Refreshed if the result changed
+	RefreshInBackground func()
 
 	HTTPClient    *http.Client
 	HTTPTransport *http.Transport
@@ -437,6 +481,7 @@ type RequestContext struct {
 	Params      *json.RawMessage
 	Conn        jsonrpc2.Conn
 	CancelFuncs *CancelFuncs
+	Launches    *Launches
 	dbPool      *sqlitex.Pool
 
 	Group    *singleflight.Group
@@ -483,9 +528,11 @@ func (rc *RequestContext) RootClient() *itchio.Client {
 	return rc.Client("<keyless>")
 }
 
-func (rc *RequestContext) ProfileClient(profileID int64) (*models.Profile, *itchio.Client) {
+// ProfileClient returns a profile, and an API client that uses its key.
+// Failing to read that key from the secret store is a CodeProfileSecretUnavailable.
+func (rc *RequestContext) ProfileClient(profileID int64) (*models.Profile, *itchio.Client, error) {
 	if profileID == 0 {
-		panic(errors.New("profileId must be non-zero"))
+		return nil, nil, errors.New("profileId must be non-zero")
 	}
 
 	conn := rc.GetConn()
@@ -493,14 +540,18 @@ func (rc *RequestContext) ProfileClient(profileID int64) (*models.Profile, *itch
 
 	profile := models.ProfileByID(conn, profileID)
 	if profile == nil {
-		panic(errors.Errorf("Could not find profile %d", profileID))
+		return nil, nil, errors.Errorf("Could not find profile %d", profileID)
 	}
 
-	if profile.APIKey == "" {
-		panic(errors.Errorf("Profile %d lacks API key", profileID))
+	apiKey, err := profile.GetAPIKey()
+	if err != nil {
+		return nil, nil, errors.Wrap(CodeProfileSecretUnavailable, err.Error())
+	}
+	if apiKey == "" {
+		return nil, nil, errors.Errorf("Profile %d lacks API key", profileID)
 	}
 
-	return profile, rc.Client(profile.APIKey)
+	return profile, rc.Client(apiKey), nil
 }
 
 func (rc *RequestContext) StartProgress() {
@@ -1,7 +1,88 @@
 package butlerd
 
-import "github.com/itchio/butler/manager"
+import (
+	"encoding/json"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/manager"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/ox"
+	"github.com/pkg/errors"
+)
 
 func (rc *RequestContext) HostEnumerator() manager.HostEnumerator {
-	return manager.DefaultHostEnumerator()
+	return &wrapperProfilesHostEnumerator{
+		rc:   rc,
+		base: manager.DefaultHostEnumerator(),
+	}
+}
+
+// wrapperProfilesHostEnumerator adds user-defined wrapper
+// profiles after the default hosts.
+type wrapperProfilesHostEnumerator struct {
+	rc   *RequestContext
+	base manager.HostEnumerator
+}
+
+var _ manager.HostEnumerator = (*wrapperProfilesHostEnumerator)(nil)
+
+func (e *wrapperProfilesHostEnumerator) Enumerate(consumer *state.Consumer) (manager.Hosts, error) {
+	hosts, err := e.base.Enumerate(consumer)
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles []*models.WrapperProfile
+	e.rc.WithConn(func(conn *sqlite.Conn) {
+		profiles = models.AllWrapperProfiles(conn)
+	})
+
+	for _, wp := range profiles {
+		host, err := WrapperProfileHost(wp)
+		if err != nil {
+			consumer.Warnf("Skipping wrapper profile (%s): %v", wp.Name, err)
+			continue
+		}
+		consumer.Debugf("Wrapper profile (%s): %s", wp.Name, host)
+		hosts = append(hosts, host)
+	}
+
+	return hosts, nil
+}
+
+// WrapperProfileHost returns the host provided by a wrapper profile
+func WrapperProfileHost(wp *models.WrapperProfile) (manager.Host, error) {
+	var wrapper manager.Wrapper
+	err := json.Unmarshal([]byte(wp.Wrapper), &wrapper)
+	if err != nil {
+		return manager.Host{}, errors.Wrap(err, "unmarshalling wrapper")
+	}
+
+	host := manager.Host{
+		Runtime: ox.Runtime{
+			Platform: ox.Platform(wp.Platform),
+			Is64:     wp.Is64,
+		},
+		Wrapper:          &wrapper,
+		WrapperProfileID: wp.ID,
+	}
+	return host, host.Validate()
+}
+
+// FormatWrapperProfile converts a wrapper profile for use in butlerd results
+func FormatWrapperProfile(wp *models.WrapperProfile) (*WrapperProfile, error) {
+	host, err := WrapperProfileHost(wp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WrapperProfile{
+		ID:        wp.ID,
+		Name:      wp.Name,
+		Runtime:   host.Runtime,
+		Wrapper:   host.Wrapper,
+		CreatedAt: wp.CreatedAt,
+		UpdatedAt: wp.UpdatedAt,
+	}, nil
 }
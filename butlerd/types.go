@@ -3,11 +3,14 @@ package butlerd
 import (
 	"time"
 
+	"github.com/itchio/butler/manager"
 	"github.com/itchio/hush"
 	"github.com/itchio/hush/manifest"
+	"github.com/itchio/ox"
 
 	validation "github.com/go-ozzo/ozzo-validation"
 	itchio "github.com/itchio/go-itchio"
+	"github.com/pkg/errors"
 )
 
 // When using TCP transport, must be the first message sent
@@ -306,26 +309,75 @@ type ProfileForgetResult struct {
 	Success bool `json:"success"`
 }
 
+// A value stored with @@ProfileDataPutParams
+//
+// @category Profile
+type ProfileDataEntry struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// True if value is a JSON document
+	JSON bool `json:"json"`
+	// Bumped every time the value changes, see @@ProfileDataPutParams
+	Version int64 `json:"version"`
+	// @optional
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+	// When the value stops being returned, if it expires
+	// @optional
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// Maximum length of profile data keys, in bytes
+const ProfileDataMaxKeyLength = 256
+
+// Maximum size of profile data values, in bytes
+const ProfileDataMaxValueSize = 64 * 1024
+
 // Stores some data associated to a profile, by key.
 //
+// Keys can be namespaced with slashes, like `plugin/sidebar/width`,
+// see @@ProfileDataListParams and @@ProfileDataDeleteParams. All
+// connected clients are notified with @@ProfileDataChangedNotification.
+//
 // @name Profile.Data.Put
 // @category Profile
 // @caller client
 type ProfileDataPutParams struct {
-	ProfileID int64  `json:"profileId"`
-	Key       string `json:"key"`
-	Value     string `json:"value"`
+	ProfileID int64 `json:"profileId"`
+	// At most 256 bytes
+	Key string `json:"key"`
+	// At most 64KiB
+	Value string `json:"value"`
+
+	// If true, value must be a valid JSON document
+	//
+	// @optional
+	JSON bool `json:"json"`
+
+	// Only store the value if the entry is still at this version
+	// (compare-and-swap). Use 0 to only store it if there is no
+	// entry yet. Fails with error code CodeProfileDataConflict otherwise.
+	//
+	// @optional
+	IfVersion *int64 `json:"ifVersion,omitempty"`
+
+	// If set, the value expires after this many seconds
+	//
+	// @optional
+	TTLSeconds int64 `json:"ttlSeconds"`
 }
 
 func (p ProfileDataPutParams) Validate() error {
 	return validation.ValidateStruct(&p,
 		validation.Field(&p.ProfileID, validation.Required),
-		validation.Field(&p.Key, validation.Required),
+		validation.Field(&p.Key, validation.Required, validation.Length(0, ProfileDataMaxKeyLength)),
 		validation.Field(&p.Value, validation.Required),
+		validation.Field(&p.TTLSeconds, validation.Min(0)),
 	)
 }
 
 type ProfileDataPutResult struct {
+	// Version of the entry after the put
+	Version int64 `json:"version"`
 }
 
 // Retrieves some data associated to a profile, by key.
@@ -349,6 +401,89 @@ type ProfileDataGetResult struct {
 	// True if the value existed
 	OK    bool   `json:"ok"`
 	Value string `json:"value"`
+
+	// The whole entry, if it existed
+	//
+	// @optional
+	Entry *ProfileDataEntry `json:"entry,omitempty"`
+}
+
+// Lists data associated to a profile, sorted by key.
+//
+// @name Profile.Data.List
+// @category Profile
+// @caller client
+type ProfileDataListParams struct {
+	ProfileID int64 `json:"profileId"`
+
+	// Only list keys that start with this, like `plugin/`
+	//
+	// @optional
+	Prefix string `json:"prefix"`
+}
+
+func (p ProfileDataListParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.ProfileID, validation.Required),
+	)
+}
+
+type ProfileDataListResult struct {
+	Entries []*ProfileDataEntry `json:"entries"`
+}
+
+// Deletes data associated to a profile, either one key,
+// or all keys that start with a prefix.
+//
+// @name Profile.Data.Delete
+// @category Profile
+// @caller client
+type ProfileDataDeleteParams struct {
+	ProfileID int64 `json:"profileId"`
+
+	// Key to delete. Either key or prefix must be set.
+	//
+	// @optional
+	Key string `json:"key"`
+
+	// Delete all keys that start with this instead
+	//
+	// @optional
+	Prefix string `json:"prefix"`
+
+	// Only delete key if its entry is still at this version.
+	// Fails with error code CodeProfileDataConflict otherwise.
+	//
+	// @optional
+	IfVersion *int64 `json:"ifVersion,omitempty"`
+}
+
+func (p ProfileDataDeleteParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.ProfileID, validation.Required),
+	)
+}
+
+type ProfileDataDeleteResult struct {
+	// Number of entries deleted
+	Deleted int64 `json:"deleted"`
+}
+
+// Sent to all connected clients when profile data is put or deleted,
+// by any of them. Not sent when values expire.
+//
+// @name Profile.Data.Changed
+// @category Profile
+// @caller server
+type ProfileDataChangedNotification struct {
+	ProfileID int64  `json:"profileId"`
+	Key       string `json:"key"`
+	// True if the key was deleted
+	Deleted bool `json:"deleted"`
+	// The new entry, unless it was deleted
+	//
+	// @optional
+	Entry *ProfileDataEntry `json:"entry,omitempty"`
 }
 
 //----------------------------------------------------------------------
@@ -399,10 +534,109 @@ type SearchUsersResult struct {
 	Users []*itchio.User `json:"users"`
 }
 
+// Searches the local library: games that are installed, owned
+// (via download keys), or in one of the profile's collections.
+// Only the local database is queried, so it works offline.
+//
+// Results are ranked, title matches first, and every word
+// of the query matches as a prefix.
+//
+// @name Search.Library
+// @category Search
+// @caller client
+type SearchLibraryParams struct {
+	ProfileID int64 `json:"profileId"`
+
+	// Words to look for in titles, short texts, tags and author names
+	Query string `json:"query"`
+
+	// @optional
+	Filters LibrarySearchFilters `json:"filters"`
+
+	// Maximum number of games to return at a time
+	// @optional
+	Limit int64 `json:"limit"`
+
+	// Used for pagination, if specified
+	// @optional
+	Cursor Cursor `json:"cursor"`
+}
+
+type LibrarySearchFilters struct {
+	// Only return games that have at least one cave
+	// @optional
+	Installed bool `json:"installed"`
+
+	// Only return games the profile has a download key for
+	// @optional
+	Owned bool `json:"owned"`
+
+	// Only return games available for this platform
+	// @optional
+	Platform ox.Platform `json:"platform"`
+}
+
+func (p LibrarySearchFilters) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Platform, validation.In(ox.PlatformWindows, ox.PlatformLinux, ox.PlatformOSX)),
+	)
+}
+
+func (p SearchLibraryParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.ProfileID, validation.Required),
+		validation.Field(&p.Query, validation.Required),
+		validation.Field(&p.Filters),
+	)
+}
+
+func (p SearchLibraryParams) GetLimit() int64 {
+	return p.Limit
+}
+
+func (p SearchLibraryParams) GetCursor() Cursor {
+	return p.Cursor
+}
+
+type SearchLibraryResult struct {
+	Items []*LibrarySearchItem `json:"items"`
+
+	// Use to fetch the next 'page' of results
+	// @optional
+	NextCursor Cursor `json:"nextCursor,omitempty"`
+}
+
+type LibrarySearchItem struct {
+	Game *itchio.Game `json:"game"`
+
+	// IDs of the caves this game is installed in, if any
+	CaveIDs []string `json:"caveIds"`
+
+	// True if the profile has a download key for this game
+	Owned bool `json:"owned"`
+}
+
 //----------------------------------------------------------------------
 // Fetch
 //----------------------------------------------------------------------
 
+// Sent when a fetch request, answered from the local database in
+// offline-first mode, was refreshed in the background and its result
+// changed. Send the same request again to get the new data.
+//
+// Offline-first mode is enabled with butlerd's `--offline-first` flag.
+//
+// @name Fetch.Refreshed
+// @category Fetch
+// @caller server
+type FetchRefreshedNotification struct {
+	// Method of the request that was refreshed, like `Fetch.Game`
+	Method string `json:"method"`
+
+	// JSON-encoded params of the request, without `fresh`
+	Params string `json:"params"`
+}
+
 // Fetches information for an itch.io game.
 //
 // @name Fetch.Game
@@ -1229,6 +1463,9 @@ type Cave struct {
 	Stats *CaveStats `json:"stats"`
 	// Information about where the cave is installed, how much space it takes up etc.
 	InstallInfo *CaveInstallInfo `json:"installInfo"`
+	// Wrapper profile assigned with @@CavesSetWrapperParams, if any
+	// @optional
+	WrapperProfileID string `json:"wrapperProfileId,omitempty"`
 }
 
 // CaveStats contains stats about cave usage and first install
@@ -1237,6 +1474,11 @@ type CaveStats struct {
 	InstalledAt   *time.Time `json:"installedAt"`
 	LastTouchedAt *time.Time `json:"lastTouchedAt"`
 	SecondsRun    int64      `json:"secondsRun"`
+
+	// Linux package format the cave was last launched as,
+	// if any: `appimage`, `flatpak` or `snap`
+	// @optional
+	PackageFormat PackageFormat `json:"packageFormat,omitempty"`
 }
 
 // CaveInstallInfo contains information about where the cave is installed, how
@@ -1252,6 +1494,10 @@ type CaveInstallInfo struct {
 	InstallFolder string `json:"installFolder"`
 	// If true, this cave is ignored while checking for updates
 	Pinned bool `json:"pinned,omitempty"`
+	// What background update checks do when they find an update
+	// for this cave, see @@CavesSetUpdatePolicyParams
+	// @optional
+	UpdatePolicy UpdatePolicy `json:"updatePolicy,omitempty"`
 }
 
 type InstallLocationSummary struct {
@@ -1434,6 +1680,14 @@ type InstallQueueParams struct {
 	// @optional
 	InstallFolder string `json:"installFolder"`
 
+	// If set, a new cave is created for files already present in
+	// that folder (copied from another computer, for example). They're
+	// verified against the build's signature, and only missing or
+	// corrupted files are downloaded. Requires a wharf-enabled upload,
+	// and cannot be used with CaveID or NoCave.
+	// @optional
+	AdoptFolder string `json:"adoptFolder,omitempty"`
+
 	// Which game to install.
 	//
 	// If unspecified and caveId is specified, the same game will be used.
@@ -1504,6 +1758,16 @@ type InstallPlanParams struct {
 
 	// @optional
 	UploadID int64 `json:"uploadId"`
+
+	// If true, also fetch archive indexes or build signatures to
+	// list exactly what the install will do. See @@InstallPlanDetails.
+	// @optional
+	Detailed bool `json:"detailed"`
+
+	// Custom install folder the game would be installed to, if any.
+	// Only used when detailed is true, to look for conflicts.
+	// @optional
+	InstallFolder string `json:"installFolder"`
 }
 
 func (p InstallPlanParams) Validate() error {
@@ -1525,6 +1789,10 @@ type InstallPlanInfo struct {
 	Type      string         `json:"type"`
 	DiskUsage *DiskUsageInfo `json:"diskUsage"`
 
+	// Only set if `detailed` was true in @@InstallPlanParams
+	// @optional
+	Details *InstallPlanDetails `json:"details,omitempty"`
+
 	Error        string `json:"error,omitempty"`
 	ErrorMessage string `json:"errorMessage,omitempty"`
 	ErrorCode    int64  `json:"errorCode,omitempty"`
@@ -1536,6 +1804,58 @@ type DiskUsageInfo struct {
 	Accuracy        string `json:"accuracy"`
 }
 
+// File-level preview of what an install would do
+type InstallPlanDetails struct {
+	// Files that would be written, patched or removed,
+	// sorted by path.
+	Files []*InstallPlanFile `json:"files"`
+
+	// True if files lists everything the install would do.
+	// False for installers whose contents can't be known in advance.
+	Complete bool `json:"complete"`
+
+	// Files already present in the install folder that don't belong
+	// to a previous install, and would be overwritten.
+	// @optional
+	Conflicts []string `json:"conflicts,omitempty"`
+
+	Prereqs *InstallPlanPrereqs `json:"prereqs"`
+}
+
+type InstallPlanFile struct {
+	// Slash-separated path, relative to the install folder
+	Path string `json:"path"`
+	// Size of the file once installed, in bytes (0 for removed files)
+	Size   int64                 `json:"size"`
+	Action InstallPlanFileAction `json:"action"`
+}
+
+type InstallPlanFileAction string
+
+const (
+	// The file doesn't exist yet and would be created
+	InstallPlanFileActionWrite InstallPlanFileAction = "write"
+	// The file belongs to a previous install and would be updated
+	InstallPlanFileActionPatch InstallPlanFileAction = "patch"
+	// The file belongs to a previous install and would be removed
+	InstallPlanFileActionRemove InstallPlanFileAction = "remove"
+)
+
+type InstallPlanPrereqs struct {
+	// True if the app manifest lists prerequisites, which
+	// will be installed on first launch
+	Needed bool `json:"needed"`
+	// Names of prerequisites listed in the app manifest
+	// @optional
+	Names []string `json:"names,omitempty"`
+	// True if there's no app manifest, and some executables import
+	// DLLs that neither ship with the game nor with Windows: the
+	// prerequisites providing them are determined on first launch
+	// (Windows only). Executables are read from the install source
+	// to find out, unless they're larger than 32MiB.
+	Auto bool `json:"auto"`
+}
+
 // @name Caves.SetPinned
 // @category Install
 // @caller client
@@ -2146,6 +2466,100 @@ func (p DownloadsDiscardParams) Validate() error {
 
 type DownloadsDiscardResult struct{}
 
+// Lists past download attempts (installs, updates, heals, etc.),
+// most recent first. Unlike @@DownloadsListParams, entries
+// are kept after @@DownloadsClearFinishedParams.
+//
+// @name Downloads.History
+// @category Downloads
+// @caller client
+type DownloadsHistoryParams struct {
+	// If specified, only show history for this cave
+	// @optional
+	CaveID string `json:"caveId"`
+
+	// If specified, only show history for this game
+	// @optional
+	GameID int64 `json:"gameId"`
+
+	// Maximum number of entries to return at a time.
+	// @optional
+	Limit int64 `json:"limit"`
+
+	// Used for pagination, if specified
+	// @optional
+	Cursor Cursor `json:"cursor"`
+}
+
+func (p DownloadsHistoryParams) Validate() error {
+	return nil
+}
+
+func (p DownloadsHistoryParams) GetLimit() int64 {
+	return p.Limit
+}
+
+func (p DownloadsHistoryParams) GetCursor() Cursor {
+	return p.Cursor
+}
+
+type DownloadsHistoryResult struct {
+	Items []*DownloadHistoryItem `json:"items"`
+
+	// Use to fetch the next 'page' of results
+	// @optional
+	NextCursor Cursor `json:"nextCursor,omitempty"`
+}
+
+type DownloadOutcome string
+
+const (
+	// The download was performed successfully
+	DownloadOutcomeSucceeded DownloadOutcome = "succeeded"
+	// The download errored, see error code & message
+	DownloadOutcomeErrored DownloadOutcome = "errored"
+	// The download was aborted (and removed from the queue)
+	DownloadOutcomeAborted DownloadOutcome = "aborted"
+	// The download was discarded by the user
+	DownloadOutcomeDiscarded DownloadOutcome = "discarded"
+	// The download was interrupted (network loss, drive cancelled),
+	// and will be resumed later
+	DownloadOutcomeInterrupted DownloadOutcome = "interrupted"
+)
+
+// Time spent in each stage of a download, in seconds
+type DownloadStageTimings struct {
+	Prepare  float64 `json:"prepare"`
+	Download float64 `json:"download"`
+	Install  float64 `json:"install"`
+	Patch    float64 `json:"patch"`
+	Heal     float64 `json:"heal"`
+}
+
+// A past attempt at performing a download
+type DownloadHistoryItem struct {
+	ID         string         `json:"id"`
+	DownloadID string         `json:"downloadId"`
+	CaveID     string         `json:"caveId"`
+	Reason     DownloadReason `json:"reason"`
+	Game       *itchio.Game   `json:"game"`
+	Upload     *itchio.Upload `json:"upload"`
+	// @optional
+	Build *itchio.Build `json:"build,omitempty"`
+	// Approximate number of bytes transferred over the network
+	BytesTransferred int64                 `json:"bytesTransferred"`
+	Timings          *DownloadStageTimings `json:"timings"`
+	Outcome          DownloadOutcome       `json:"outcome"`
+	// Standard butlerd error code, if any
+	// @optional
+	ErrorCode *int64 `json:"errorCode,omitempty"`
+	// Short error message, if any
+	// @optional
+	ErrorMessage *string    `json:"errorMessage,omitempty"`
+	StartedAt    *time.Time `json:"startedAt"`
+	FinishedAt   *time.Time `json:"finishedAt"`
+}
+
 //----------------------------------------------------------------------
 // CheckUpdate
 //----------------------------------------------------------------------
@@ -2216,6 +2630,10 @@ type GameUpdate struct {
 
 // One possible upload/build choice to upgrade a cave
 //
+// There's no changelog: the itch.io API doesn't expose devlogs
+// or build notes, so clients that want to show what changed
+// have to link to the game's page.
+//
 // @category update
 type GameUpdateChoice struct {
 	// Upload to be installed
@@ -2224,6 +2642,26 @@ type GameUpdateChoice struct {
 	Build *itchio.Build `json:"build"`
 	// How confident we are that this is the right upgrade
 	Confidence float64 `json:"confidence"`
+
+	// Total size of the patches on the upgrade path from the installed
+	// build to this one, if it can be applied by patching. If it's
+	// larger than `fullSize`, the full upload is downloaded instead.
+	// @optional
+	PatchSize int64 `json:"patchSize,omitempty"`
+	// Number of patches on the upgrade path
+	// @optional
+	PatchCount int64 `json:"patchCount,omitempty"`
+	// Size of the full upload
+	// @optional
+	FullSize int64 `json:"fullSize,omitempty"`
+
+	// Version of the installed build, as set by the developer
+	// with `butler push --userversion`
+	// @optional
+	FromUserVersion string `json:"fromUserVersion,omitempty"`
+	// Version of the build to be installed
+	// @optional
+	ToUserVersion string `json:"toUserVersion,omitempty"`
 }
 
 // Snoozing a cave means we ignore all new uploads (that would
@@ -2248,6 +2686,51 @@ func (p SnoozeCaveParams) Validate() error {
 type SnoozeCaveResult struct {
 }
 
+// UpdatePolicy decides what background update checks do with an
+// update they found. Automatic policies only apply to direct updates
+// (see @@GameUpdate) whose choice has a high enough confidence; other
+// updates are only notified.
+type UpdatePolicy string
+
+const (
+	// Send @@GameUpdateAvailableNotification to connected clients
+	UpdatePolicyNotify UpdatePolicy = "notify"
+	// Queue the update as a download, for @@DownloadsDriveParams to apply
+	UpdatePolicyDownload UpdatePolicy = "download"
+	// Install the update right away, in the background
+	UpdatePolicyInstall UpdatePolicy = "install"
+)
+
+var UpdatePolicyList = []interface{}{
+	UpdatePolicyNotify,
+	UpdatePolicyDownload,
+	UpdatePolicyInstall,
+}
+
+// Sets what background update checks do when they find an update
+// for a cave. Background checks are enabled with butlerd's
+// `--update-check-interval` flag.
+//
+// @name Caves.SetUpdatePolicy
+// @category Update
+// @caller client
+type CavesSetUpdatePolicyParams struct {
+	// ID of the cave to set the policy of
+	CaveID string `json:"caveId"`
+
+	// Policy the cave should have after this call
+	UpdatePolicy UpdatePolicy `json:"updatePolicy"`
+}
+
+func (p CavesSetUpdatePolicyParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CaveID, validation.Required),
+		validation.Field(&p.UpdatePolicy, validation.Required, validation.In(UpdatePolicyList...)),
+	)
+}
+
+type CavesSetUpdatePolicyResult struct{}
+
 //----------------------------------------------------------------------
 // Launch
 //----------------------------------------------------------------------
@@ -2268,40 +2751,172 @@ type LaunchParams struct {
 	// @optional
 	ForcePrereqs bool `json:"forcePrereqs,omitempty"`
 
-	// Enable sandbox (regardless of manifest opt-in)
+	// Enable sandbox (regardless of manifest opt-in). Launching
+	// flatpak and snap packages in the sandbox fails with
+	// CodeSandboxUnsupported, since they bring their own.
 	// @optional
 	Sandbox bool `json:"sandbox,omitempty"`
+
+	// Send the game's output as @@LaunchOutputLineNotification
+	// while it's running
+	// @optional
+	StreamOutput bool `json:"streamOutput,omitempty"`
+
+	// What to do if the cave is already running,
+	// defaults to `wait`
+	// @optional
+	ConcurrentLaunch ConcurrentLaunchPolicy `json:"concurrentLaunch,omitempty"`
 }
 
 func (p LaunchParams) Validate() error {
 	return validation.ValidateStruct(&p,
 		validation.Field(&p.CaveID, validation.Required),
 		validation.Field(&p.PrereqsDir, validation.Required),
+		validation.Field(&p.ConcurrentLaunch, validation.In(ConcurrentLaunchPolicyWait, ConcurrentLaunchPolicyReject, ConcurrentLaunchPolicyAllow)),
 	)
 }
 
 type LaunchResult struct {
 }
 
-// Sent during @@LaunchParams, when the game is configured, prerequisites are installed
-// sandbox is set up (if enabled), and the game is actually running.
-//
 // @category Launch
-type LaunchRunningNotification struct{}
+type ConcurrentLaunchPolicy string
 
-// Sent during @@LaunchParams, when the game has actually exited.
-//
-// @category Launch
-type LaunchExitedNotification struct{}
+const (
+	// Wait for the running session to end before launching
+	ConcurrentLaunchPolicyWait ConcurrentLaunchPolicy = "wait"
+	// Fail with error code CodeAlreadyRunning
+	ConcurrentLaunchPolicyReject ConcurrentLaunchPolicy = "reject"
+	// Launch another instance alongside the running one
+	ConcurrentLaunchPolicyAllow ConcurrentLaunchPolicy = "allow"
+)
 
-// Sent during @@LaunchParams if the game/application comes with a service license
-// agreement.
+// A game session started by @@LaunchParams, that
+// hasn't ended yet.
 //
-// @tags Dialogs
 // @category Launch
-// @caller server
-type AcceptLicenseParams struct {
-	// The full text of the license agreement, in its default
+type RunningLaunch struct {
+	// Unique identifier of the session, to pass to @@LaunchKillParams
+	ID string `json:"id"`
+
+	// Cave that was launched
+	CaveID string `json:"caveId"`
+
+	// Process ID of the game, if known. For some strategies
+	// (opening a web page, etc.) there is none.
+	//
+	// This is best-effort, and only available on Linux: it's missing
+	// if the game couldn't be found among butler's child processes
+	// (when run through flatpak, for example).
+	// @optional
+	PID int64 `json:"pid,omitempty"`
+
+	// How the cave was launched
+	Strategy LaunchStrategy `json:"strategy"`
+
+	// When the session started
+	StartedAt *time.Time `json:"startedAt"`
+}
+
+// List game sessions currently running, in the order
+// they were started in.
+//
+// @name Launch.List
+// @category Launch
+// @caller client
+type LaunchListParams struct {
+	// If set, only list sessions of this cave
+	// @optional
+	CaveID string `json:"caveId,omitempty"`
+}
+
+func (p LaunchListParams) Validate() error {
+	return nil
+}
+
+type LaunchListResult struct {
+	Launches []*RunningLaunch `json:"launches"`
+}
+
+// Terminate a running game session, and all the processes
+// it started. The corresponding @@LaunchParams call returns
+// once the game is gone.
+//
+// @name Launch.Kill
+// @category Launch
+// @caller client
+type LaunchKillParams struct {
+	// Identifier of the session, as returned by @@LaunchListParams
+	// @optional
+	ID string `json:"id,omitempty"`
+
+	// If set instead of ID, kill all sessions of this cave
+	// @optional
+	CaveID string `json:"caveId,omitempty"`
+}
+
+func (p LaunchKillParams) Validate() error {
+	if p.ID == "" && p.CaveID == "" {
+		return errors.New("one of id or caveId must be set")
+	}
+	return nil
+}
+
+type LaunchKillResult struct {
+	// Number of sessions that were terminated
+	Killed int64 `json:"killed"`
+}
+
+// Sent during @@LaunchParams, when the game is configured, prerequisites are installed
+// sandbox is set up (if enabled), and the game is actually running.
+//
+// @category Launch
+type LaunchRunningNotification struct {
+	// For HTML5 games, the localhost address the game is served at
+	//
+	// @optional
+	URL string `json:"url,omitempty"`
+}
+
+// Sent during @@LaunchParams, when the game has actually exited.
+//
+// @category Launch
+type LaunchExitedNotification struct{}
+
+// Sent during @@LaunchParams when the game looks like it crashed.
+// Exiting with a non-zero exit code isn't enough, many games do that
+// when quitting normally: it must have written crash dumps, been
+// terminated by a signal other than one asking it to quit (like
+// SIGTERM), exited with 128 plus a crash signal (like SIGSEGV), or
+// exited with a Windows exception code (like 0xC0000005). Sessions
+// killed with @@LaunchKillParams are never crashes. The report is
+// also stored, see @@CavesListCrashesParams.
+//
+// @category Launch
+type LaunchCrashedNotification struct {
+	Report *CrashReport `json:"report"`
+}
+
+// Sent during @@LaunchParams for every line the game writes
+// to its standard output or standard error, if `streamOutput`
+// was set in @@LaunchParams.
+//
+// @category Launch
+type LaunchOutputLineNotification struct {
+	// Which stream the line was written to
+	Stream LaunchLogStream `json:"stream"`
+	// Contents of the line, without the line ending
+	Line string `json:"line"`
+}
+
+// Sent during @@LaunchParams if the game/application comes with a service license
+// agreement.
+//
+// @tags Dialogs
+// @category Launch
+// @caller server
+type AcceptLicenseParams struct {
+	// The full text of the license agreement, in its default
 	// language, which is usually English.
 	Text string `json:"text"`
 }
@@ -2363,6 +2978,15 @@ type ShellLaunchResult struct {
 // Ask the client to perform an HTML launch, ie. open an HTML5
 // game, ideally in an embedded browser.
 //
+// If the client doesn't handle this request, butler opens `url`
+// in the system browser instead, and @@LaunchParams returns right
+// away. No play time is recorded for those launches, since there's
+// no telling when the tab is closed, and the game is served until
+// it hasn't made a request for 30 minutes.
+//
+// The play session starts once the client has had a couple seconds
+// to reply, so clients that don't handle it should reply right away.
+//
 // Sent during @@LaunchParams.
 //
 // @category Launch
@@ -2372,6 +2996,10 @@ type HTMLLaunchParams struct {
 	RootFolder string `json:"rootFolder"`
 	// Path of index file, relative to root folder
 	IndexPath string `json:"indexPath"`
+	// Address at which butler serves the index file, on localhost,
+	// with COOP/COEP headers set. Clients may load it instead of
+	// serving `rootFolder` themselves.
+	URL string `json:"url"`
 
 	// Command-line arguments, to pass as `global.Itch.args`
 	Args []string `json:"args"`
@@ -2413,12 +3041,25 @@ type URLLaunchResult struct{}
 // a UAC prompt (on Windows) or a pkexec dialog (on Linux) if
 // the user allows.
 //
+// Also sent on Linux when the bubblewrap backend was requested
+// but can't be used on this system: allowing falls back to
+// the firejail backend, refusing aborts the launch.
+//
 // Sent during @@LaunchParams.
 //
 // @category Launch
 // @tags Dialogs
 // @caller server
-type AllowSandboxSetupParams struct{}
+type AllowSandboxSetupParams struct {
+	// The sandbox backend about to be set up
+	// @optional
+	Backend SandboxBackend `json:"backend,omitempty"`
+
+	// Result of sandbox capability detection, if the
+	// requested backend turned out unusable
+	// @optional
+	Capabilities *SandboxCapabilities `json:"capabilities,omitempty"`
+}
 
 func (p AllowSandboxSetupParams) Validate() error {
 	return nil
@@ -2429,6 +3070,35 @@ type AllowSandboxSetupResult struct {
 	Allow bool `json:"allow"`
 }
 
+// Ask the user to allow installing a Snap package that comes with
+// a game. Those aren't signed, so installing them means running
+// `snap install --dangerous` as root, via a pkexec dialog if the
+// user allows.
+//
+// Sent during @@LaunchParams, when the snap isn't installed yet.
+// If refused, the launch fails with instructions to install it
+// manually.
+//
+// @category Launch
+// @tags Dialogs
+// @caller server
+type AllowSnapInstallParams struct {
+	// Name of the snap, like "my-game"
+	Name string `json:"name"`
+
+	// Absolute path of the .snap file
+	Path string `json:"path"`
+}
+
+func (p AllowSnapInstallParams) Validate() error {
+	return nil
+}
+
+type AllowSnapInstallResult struct {
+	// Set to true if the user allowed installing the snap, false otherwise
+	Allow bool `json:"allow"`
+}
+
 // Sent during @@LaunchParams, when some prerequisites are about to be installed.
 //
 // This is a good time to start showing a UI element with the state of prereq
@@ -2518,6 +3188,665 @@ type PrereqsFailedResult struct {
 	Continue bool `json:"continue"`
 }
 
+// A compatibility wrapper defined by the user (a Proton build,
+// a wine binary with its own prefix, box86, etc.)
+//
+// Wrapper profiles are included in host enumeration, so uploads
+// for their runtime are considered compatible, and they can be
+// assigned to a cave with @@CavesSetWrapperParams.
+//
+// @category Launch
+type WrapperProfile struct {
+	// Unique identifier of this profile (UUID)
+	ID string `json:"id"`
+
+	// Human-friendly name, like "Proton 5.0"
+	Name string `json:"name"`
+
+	// Runtime of the games this wrapper can launch, like windows-i386
+	Runtime ox.Runtime `json:"runtime"`
+
+	// How to invoke the wrapper
+	Wrapper *manager.Wrapper `json:"wrapper"`
+
+	CreatedAt *time.Time `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+// List all user-defined wrapper profiles.
+//
+// @name Launch.Wrappers.List
+// @category Launch
+// @caller client
+type LaunchWrappersListParams struct{}
+
+func (p LaunchWrappersListParams) Validate() error {
+	return nil
+}
+
+type LaunchWrappersListResult struct {
+	Wrappers []*WrapperProfile `json:"wrappers"`
+}
+
+// Create or update a wrapper profile.
+//
+// @name Launch.Wrappers.Save
+// @category Launch
+// @caller client
+type LaunchWrappersSaveParams struct {
+	// Identifier of the profile to update.
+	// If not specified, a new profile is created.
+	// @optional
+	ID string `json:"id"`
+
+	// Human-friendly name, like "Proton 5.0". Must not be
+	// blank, nor the name of another profile.
+	Name string `json:"name"`
+
+	// Runtime of the games this wrapper can launch
+	Runtime ox.Runtime `json:"runtime"`
+
+	// How to invoke the wrapper. WrapperBinary must be set.
+	Wrapper *manager.Wrapper `json:"wrapper"`
+}
+
+func (p LaunchWrappersSaveParams) Validate() error {
+	err := validation.ValidateStruct(&p,
+		validation.Field(&p.Name, validation.Required),
+		validation.Field(&p.Wrapper, validation.Required),
+	)
+	if err != nil {
+		return err
+	}
+	return validation.ValidateStruct(p.Wrapper,
+		validation.Field(&p.Wrapper.WrapperBinary, validation.Required),
+	)
+}
+
+type LaunchWrappersSaveResult struct {
+	Wrapper *WrapperProfile `json:"wrapper"`
+}
+
+// Remove a wrapper profile. Caves it was assigned to go back
+// to using the default hosts.
+//
+// @name Launch.Wrappers.Remove
+// @category Launch
+// @caller client
+type LaunchWrappersRemoveParams struct {
+	// Identifier of the profile to remove
+	ID string `json:"id"`
+}
+
+func (p LaunchWrappersRemoveParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.ID, validation.Required),
+	)
+}
+
+type LaunchWrappersRemoveResult struct{}
+
+// Assign a wrapper profile to a cave. When launching that cave,
+// the profile is used instead of any other wrapper (like wine
+// found on PATH) for its runtime.
+//
+// @name Caves.SetWrapper
+// @category Launch
+// @caller client
+type CavesSetWrapperParams struct {
+	// ID of the cave to assign a wrapper to
+	CaveID string `json:"caveId"`
+
+	// ID of the wrapper profile to use, or empty to
+	// go back to the default hosts.
+	// @optional
+	WrapperProfileID string `json:"wrapperProfileId"`
+}
+
+func (p CavesSetWrapperParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CaveID, validation.Required),
+	)
+}
+
+type CavesSetWrapperResult struct{}
+
+// @category Launch
+type LaunchHookStage string
+
+const (
+	// Run before the game is started
+	LaunchHookStagePreLaunch LaunchHookStage = "pre-launch"
+	// Run after the game has exited, whatever the outcome
+	LaunchHookStagePostExit LaunchHookStage = "post-exit"
+)
+
+// A command run around game sessions: to sync saves,
+// apply mods, toggle a VPN, etc.
+//
+// Hooks are run with the following environment variables
+// set: `ITCHIO_HOOK_STAGE`, `ITCHIO_CAVE_ID`, `ITCHIO_GAME_ID`,
+// `ITCHIO_GAME_TITLE`, `ITCHIO_UPLOAD_ID`, `ITCHIO_BUILD_ID`
+// (if any) and `ITCHIO_INSTALL_FOLDER`.
+//
+// Post-exit hooks also get `ITCHIO_SESSION_OUTCOME` (one of
+// `success`, `crashed`, `failed`, `killed`) and `ITCHIO_SECONDS_RUN`.
+// They're stopped if they run for more than 5 minutes.
+//
+// @category Launch
+type LaunchHook struct {
+	// Unique identifier of the hook
+	ID string `json:"id"`
+
+	// Cave the hook applies to, empty for hooks
+	// run for every game
+	CaveID string `json:"caveId"`
+
+	// When the hook is run
+	Stage LaunchHookStage `json:"stage"`
+
+	// Human-friendly name
+	Name string `json:"name"`
+
+	// Executable to run, absolute or looked up in PATH
+	Command string `json:"command"`
+
+	// Arguments passed to the executable
+	Args []string `json:"args"`
+
+	// Folder to run the hook in, defaults to the install folder.
+	// Relative paths are relative to the install folder.
+	WorkingDirectory string `json:"workingDirectory"`
+
+	// For pre-launch hooks: if true, the launch is aborted with
+	// error code CodePreLaunchHookFailed when the hook fails.
+	// Otherwise, the failure is only logged.
+	AbortOnFailure bool `json:"abortOnFailure"`
+
+	CreatedAt *time.Time `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+// List launch hooks.
+//
+// @name Launch.Hooks.List
+// @category Launch
+// @caller client
+type LaunchHooksListParams struct {
+	// If set, only list the global hooks and those of this cave.
+	// Otherwise, list all hooks.
+	// @optional
+	CaveID string `json:"caveId,omitempty"`
+}
+
+func (p LaunchHooksListParams) Validate() error {
+	return nil
+}
+
+type LaunchHooksListResult struct {
+	// Hooks in the order they're run in: global ones first,
+	// then the cave's own, oldest first
+	Hooks []*LaunchHook `json:"hooks"`
+}
+
+// Create or update a launch hook.
+//
+// @name Launch.Hooks.Save
+// @category Launch
+// @caller client
+type LaunchHooksSaveParams struct {
+	// Identifier of the hook to update.
+	// If not specified, a new hook is created.
+	// @optional
+	ID string `json:"id,omitempty"`
+
+	// Cave the hook applies to. If not specified,
+	// the hook is run for every game.
+	// @optional
+	CaveID string `json:"caveId,omitempty"`
+
+	Stage LaunchHookStage `json:"stage"`
+
+	// Human-friendly name, like "Sync saves"
+	Name string `json:"name"`
+
+	Command string `json:"command"`
+
+	// @optional
+	Args []string `json:"args,omitempty"`
+
+	// Folder to run the hook in, relative to the install folder
+	// unless absolute. Defaults to the install folder.
+	// @optional
+	WorkingDirectory string `json:"workingDirectory,omitempty"`
+
+	// @optional
+	AbortOnFailure bool `json:"abortOnFailure,omitempty"`
+}
+
+func (p LaunchHooksSaveParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Stage, validation.Required, validation.In(LaunchHookStagePreLaunch, LaunchHookStagePostExit)),
+		validation.Field(&p.Name, validation.Required),
+		validation.Field(&p.Command, validation.Required),
+	)
+}
+
+type LaunchHooksSaveResult struct {
+	Hook *LaunchHook `json:"hook"`
+}
+
+// Remove a launch hook.
+//
+// @name Launch.Hooks.Remove
+// @category Launch
+// @caller client
+type LaunchHooksRemoveParams struct {
+	// Identifier of the hook to remove
+	ID string `json:"id"`
+}
+
+func (p LaunchHooksRemoveParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.ID, validation.Required),
+	)
+}
+
+type LaunchHooksRemoveResult struct{}
+
+// Per-cave overrides applied by @@LaunchParams, on top of
+// what the app manifest (or launch target detection) says.
+//
+// @category Launch
+type CaveLaunchConfig struct {
+	// Extra command-line arguments, passed after the action's own
+	// @optional
+	Args []string `json:"args,omitempty"`
+
+	// Extra environment variables, like `DXVK_HUD`
+	// @optional
+	Env map[string]string `json:"env,omitempty"`
+
+	// Working directory to launch from, absolute or relative
+	// to the install folder. It must be an existing folder
+	// inside the install folder.
+	// @optional
+	WorkingDirectory string `json:"workingDirectory,omitempty"`
+
+	// Name of the manifest action to launch without asking,
+	// if there are several
+	// @optional
+	ActionName string `json:"actionName,omitempty"`
+
+	// Platform of the host to prefer, for example `windows` to
+	// launch a Windows build through a wrapper when there's also
+	// a native build
+	// @optional
+	HostPlatform ox.Platform `json:"hostPlatform,omitempty"`
+
+	// Wrapper profile to launch with, as assigned with
+	// @@CavesSetWrapperParams. When setting a launch config, the
+	// assigned profile is left alone if this is omitted, and
+	// unassigned if it's empty.
+	// @optional
+	WrapperProfileID *string `json:"wrapperProfileId,omitempty"`
+
+	// If true, launches in the sandbox even if neither the
+	// manifest nor @@LaunchParams ask for it. It can't turn the
+	// sandbox off.
+	// @optional
+	Sandbox bool `json:"sandbox,omitempty"`
+
+	// Sandbox policy to use instead of the one in the
+	// app manifest, if any
+	// @optional
+	SandboxPolicy *SandboxPolicy `json:"sandboxPolicy,omitempty"`
+
+	// For HTML5 games: serve them cross-origin isolated. That's
+	// needed for SharedArrayBuffer (threaded wasm builds), but
+	// prevents loading resources from other origins that don't
+	// opt in with CORS or Cross-Origin-Resource-Policy.
+	// @optional
+	CrossOriginIsolated bool `json:"crossOriginIsolated,omitempty"`
+}
+
+// Set the launch configuration of a cave, replacing the
+// previous one entirely.
+//
+// @name Caves.SetLaunchConfig
+// @category Launch
+// @caller client
+type CavesSetLaunchConfigParams struct {
+	// ID of the cave to configure
+	CaveID string `json:"caveId"`
+
+	// The new launch configuration
+	Config *CaveLaunchConfig `json:"config"`
+}
+
+func (p CavesSetLaunchConfigParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CaveID, validation.Required),
+		validation.Field(&p.Config, validation.Required),
+	)
+}
+
+type CavesSetLaunchConfigResult struct{}
+
+// @name Caves.GetLaunchConfig
+// @category Launch
+// @caller client
+type CavesGetLaunchConfigParams struct {
+	// ID of the cave to get the launch configuration of
+	CaveID string `json:"caveId"`
+}
+
+func (p CavesGetLaunchConfigParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CaveID, validation.Required),
+	)
+}
+
+type CavesGetLaunchConfigResult struct {
+	// The cave's launch configuration, empty if it was never set
+	Config *CaveLaunchConfig `json:"config"`
+}
+
+// @category Launch
+type SandboxBackend string
+
+const (
+	// Default on Linux. Needs a setuid root helper, installed
+	// as a prerequisite.
+	SandboxBackendFirejail SandboxBackend = "firejail"
+	// Linux only. Uses unprivileged user namespaces, no
+	// setup needed if the system allows them.
+	SandboxBackendBubblewrap SandboxBackend = "bubblewrap"
+)
+
+// Describes what a sandboxed game may access. Only
+// honored by the bubblewrap backend for now.
+//
+// It can be set per cave, with @@CavesSetLaunchConfigParams, or in the
+// app manifest, in a `[sandbox]` table:
+//
+// ```toml
+// [sandbox]
+// backend = "bubblewrap"
+// deny_network = true
+// gpu = true
+// audio = true
+// ```
+//
+// In all cases the install folder is read-only, and the game gets
+// a writable home folder of its own, that persists across launches.
+//
+// @category Launch
+type SandboxPolicy struct {
+	// Which sandbox backend to use, defaults to firejail on Linux
+	// @optional
+	Backend SandboxBackend `json:"backend,omitempty"`
+
+	// If true, the game has no network access at all
+	// @optional
+	DenyNetwork bool `json:"denyNetwork,omitempty"`
+
+	// If true, GPU devices are accessible for hardware acceleration
+	// @optional
+	GPU bool `json:"gpu,omitempty"`
+
+	// If true, sound devices and servers are accessible
+	// @optional
+	Audio bool `json:"audio,omitempty"`
+}
+
+// What a system can do in terms of sandboxing.
+//
+// @category Launch
+type SandboxCapabilities struct {
+	// Path of the bubblewrap binary, empty if it wasn't found
+	BubblewrapPath string `json:"bubblewrapPath"`
+
+	// True if unprivileged user namespaces can be created
+	UserNamespaces bool `json:"userNamespaces"`
+
+	// Why the sandbox can't be used as-is, if it can't
+	// @optional
+	Error string `json:"error,omitempty"`
+}
+
+// @category Launch
+type LaunchLogStream string
+
+const (
+	LaunchLogStreamStdout LaunchLogStream = "stdout"
+	LaunchLogStreamStderr LaunchLogStream = "stderr"
+)
+
+// A file containing the output of a launch session. Large
+// outputs are split over several parts.
+//
+// @category Launch
+type LaunchLog struct {
+	// Name of the file, to pass to @@CavesReadLaunchLogParams
+	Name string `json:"name"`
+	// Launch session the output is from
+	SessionID string `json:"sessionId"`
+	// Which stream the output is from
+	Stream LaunchLogStream `json:"stream"`
+	// 0 for the most recent part of the output, 1 for the
+	// part before that, etc.
+	Part int64 `json:"part"`
+	// Size of the file, in bytes
+	Size int64 `json:"size"`
+	// Last time the file was written to
+	ModifiedAt *time.Time `json:"modifiedAt"`
+}
+
+// List the logs captured for the last launch sessions
+// of a cave, most recent first.
+//
+// @name Caves.ListLaunchLogs
+// @category Launch
+// @caller client
+type CavesListLaunchLogsParams struct {
+	// ID of the cave to list logs for
+	CaveID string `json:"caveId"`
+}
+
+func (p CavesListLaunchLogsParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CaveID, validation.Required),
+	)
+}
+
+type CavesListLaunchLogsResult struct {
+	Logs []*LaunchLog `json:"logs"`
+}
+
+// Read (part of) a launch log.
+//
+// @name Caves.ReadLaunchLog
+// @category Launch
+// @caller client
+type CavesReadLaunchLogParams struct {
+	// ID of the cave the log belongs to
+	CaveID string `json:"caveId"`
+
+	// Name of the log, as returned by @@CavesListLaunchLogsParams
+	Name string `json:"name"`
+
+	// Where to start reading, in bytes
+	// @optional
+	Offset int64 `json:"offset"`
+
+	// Maximum number of bytes to read, defaults to 64KiB.
+	// Reads are capped at 1MiB, use NextOffset to read more.
+	// @optional
+	Limit int64 `json:"limit"`
+}
+
+func (p CavesReadLaunchLogParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CaveID, validation.Required),
+		validation.Field(&p.Name, validation.Required),
+		validation.Field(&p.Offset, validation.Min(0)),
+		validation.Field(&p.Limit, validation.Min(0)),
+	)
+}
+
+type CavesReadLaunchLogResult struct {
+	// Contents read
+	Content string `json:"content"`
+	// Offset to pass to read what comes next
+	NextOffset int64 `json:"nextOffset"`
+	// True if the end of the log was reached
+	EOF bool `json:"eof"`
+}
+
+// Information about a launch session that looked like a crash,
+// see @@LaunchCrashedNotification.
+//
+// @category Launch
+type CrashReport struct {
+	// Unique identifier of the report (UUID)
+	ID string `json:"id"`
+
+	// Cave that was launched
+	CaveID string `json:"caveId"`
+	// Upload that was installed in the cave at the time
+	UploadID int64 `json:"uploadId"`
+	// Build that was installed in the cave at the time, if any
+	// @optional
+	BuildID int64 `json:"buildId"`
+
+	// Exit code of the game
+	ExitCode int64 `json:"exitCode"`
+	// Description of the signal that terminated the game,
+	// like `segmentation fault` (not on Windows)
+	// @optional
+	Signal string `json:"signal,omitempty"`
+	// How long the game ran for, in seconds
+	SecondsRun float64 `json:"secondsRun"`
+
+	// Last lines written to standard output
+	Stdout []string `json:"stdout"`
+	// Last lines written to standard error
+	Stderr []string `json:"stderr"`
+	// Core dumps or crash dump files written during the session
+	DumpFiles []string `json:"dumpFiles"`
+
+	// Session whose full output can be read with
+	// @@CavesReadLaunchLogParams, if launch logs are enabled
+	// @optional
+	LaunchLogSessionID string `json:"launchLogSessionId,omitempty"`
+
+	// When the crash happened
+	CreatedAt *time.Time `json:"createdAt"`
+}
+
+// List crash reports for a cave, most recent first.
+//
+// @name Caves.ListCrashes
+// @category Launch
+// @caller client
+type CavesListCrashesParams struct {
+	// ID of the cave to list crashes for
+	CaveID string `json:"caveId"`
+
+	// Maximum number of reports to return at a time.
+	// @optional
+	Limit int64 `json:"limit"`
+
+	// Used for pagination, if specified
+	// @optional
+	Cursor Cursor `json:"cursor"`
+}
+
+func (p CavesListCrashesParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.CaveID, validation.Required),
+	)
+}
+
+func (p CavesListCrashesParams) GetLimit() int64 {
+	return p.Limit
+}
+
+func (p CavesListCrashesParams) GetCursor() Cursor {
+	return p.Cursor
+}
+
+type CavesListCrashesResult struct {
+	Reports []*CrashReport `json:"reports"`
+
+	// Use to fetch the next 'page' of results
+	// @optional
+	NextCursor Cursor `json:"nextCursor,omitempty"`
+}
+
+// Play time statistics, computed from launch sessions recorded
+// locally, including those that haven't been synced with itch.io yet.
+//
+// @name Caves.PlaytimeStats
+// @category Launch
+// @caller client
+type CavesPlaytimeStatsParams struct {
+	// If specified, only count sessions of this cave
+	// @optional
+	CaveID string `json:"caveId"`
+
+	// If specified, only count sessions of this game
+	// @optional
+	GameID int64 `json:"gameId"`
+
+	// If specified, only count sessions started at or after this time
+	// @optional
+	Since *time.Time `json:"since"`
+
+	// If specified, only count sessions started before this time
+	// @optional
+	Until *time.Time `json:"until"`
+
+	// Offset from UTC, in minutes, of the time zone days are
+	// delimited in. Defaults to 0 (UTC).
+	// @optional
+	UTCOffset int64 `json:"utcOffset"`
+}
+
+func (p CavesPlaytimeStatsParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.UTCOffset, validation.Min(-24*60), validation.Max(24*60)),
+	)
+}
+
+type CavesPlaytimeStatsResult struct {
+	// Total play time, in seconds
+	TotalSeconds int64 `json:"totalSeconds"`
+	// Number of sessions
+	NumSessions int64 `json:"numSessions"`
+	// Number of sessions that ended in a crash
+	NumCrashes int64 `json:"numCrashes"`
+	// Play time per day, oldest first. Days without
+	// any sessions are omitted.
+	Days []*PlaytimeDay `json:"days"`
+}
+
+// Play time for a single day. Sessions count towards
+// the day they started on.
+//
+// @category Launch
+type PlaytimeDay struct {
+	// Date, formatted as `YYYY-MM-DD`
+	Date string `json:"date"`
+	// Play time, in seconds
+	SecondsRun int64 `json:"secondsRun"`
+	// Number of sessions
+	NumSessions int64 `json:"numSessions"`
+	// Number of sessions that ended in a crash
+	NumCrashes int64 `json:"numCrashes"`
+}
+
 //----------------------------------------------------------------------
 // CleanDownloads
 //----------------------------------------------------------------------
@@ -2547,6 +3876,26 @@ func (p CleanDownloadsSearchParams) Validate() error {
 type CleanDownloadsSearchResult struct {
 	// Entries we found that could use some cleaning (with path and size information)
 	Entries []*CleanDownloadsEntry `json:"entries"`
+	// Information about the download cache, if it's enabled.
+	// It can be pruned with @@CleanDownloadsApplyParams.
+	// @optional
+	DownloadCache *DownloadCacheInfo `json:"downloadCache,omitempty"`
+}
+
+// Describes the download cache, which keeps recently downloaded
+// build files and uploads around so they can be installed again
+// without hitting the network.
+//
+// @category Clean Downloads
+type DownloadCacheInfo struct {
+	// Folder the cache is stored in
+	Path string `json:"path"`
+	// Number of cached items
+	NumEntries int64 `json:"numEntries"`
+	// Space taken up by the cache, in bytes
+	Size int64 `json:"size"`
+	// Maximum space the cache may take up, in bytes
+	MaxSize int64 `json:"maxSize"`
 }
 
 // @category Clean Downloads
@@ -2563,17 +3912,126 @@ type CleanDownloadsEntry struct {
 // @category Clean Downloads
 // @caller client
 type CleanDownloadsApplyParams struct {
+	// @optional
 	Entries []*CleanDownloadsEntry `json:"entries"`
+
+	// If specified, evict least recently used items from the
+	// download cache until it takes up at most this many bytes.
+	// Pass 0 to empty it.
+	// @optional
+	DownloadCacheTargetSize *int64 `json:"downloadCacheTargetSize,omitempty"`
 }
 
 func (p CleanDownloadsApplyParams) Validate() error {
+	if p.DownloadCacheTargetSize != nil {
+		return validation.ValidateStruct(&p,
+			validation.Field(&p.DownloadCacheTargetSize, validation.Min(0)),
+		)
+	}
+
 	return validation.ValidateStruct(&p,
 		validation.Field(&p.Entries, validation.Required),
 	)
 }
 
 // @category Clean Downloads
-type CleanDownloadsApplyResult struct{}
+type CleanDownloadsApplyResult struct {
+	// Bytes freed in the download cache, if it was pruned
+	// @optional
+	DownloadCacheFreed int64 `json:"downloadCacheFreed,omitempty"`
+}
+
+//----------------------------------------------------------------------
+// Library
+//----------------------------------------------------------------------
+
+// Writes what butlerd knows about the library to a JSON file:
+// install locations, caves, owned download keys, collections and
+// playtime. API keys are never exported.
+//
+// The file can be read back with @@LibraryImportParams, on the
+// same machine or another one, and is useful for support diagnostics.
+//
+// @name Library.Export
+// @category Library
+// @caller client
+type LibraryExportParams struct {
+	// Where to write the export. It's replaced if it already exists.
+	Path string `json:"path"`
+}
+
+func (p LibraryExportParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Path, validation.Required),
+	)
+}
+
+type LibraryExportResult struct {
+	// How many records of each kind were exported
+	Counts *LibraryCounts `json:"counts"`
+}
+
+// Merges a file written by @@LibraryExportParams into the local
+// database. Records that already exist are handled according to
+// the conflict policy.
+//
+// Imported install locations that have the same path as an existing
+// one are merged into it.
+//
+// @name Library.Import
+// @category Library
+// @caller client
+type LibraryImportParams struct {
+	// File to import
+	Path string `json:"path"`
+
+	// What to do with records that already exist. Defaults to "skip".
+	//
+	// @optional
+	ConflictPolicy LibraryConflictPolicy `json:"conflictPolicy,omitempty"`
+}
+
+func (p LibraryImportParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Path, validation.Required),
+		validation.Field(&p.ConflictPolicy, validation.In(LibraryConflictPolicyList...)),
+	)
+}
+
+type LibraryImportResult struct {
+	// Records that were imported
+	Imported *LibraryCounts `json:"imported"`
+
+	// Records that were skipped because they conflicted with
+	// existing ones
+	Skipped *LibraryCounts `json:"skipped"`
+}
+
+// LibraryConflictPolicy decides what happens when an imported record
+// has the same ID as an existing one.
+type LibraryConflictPolicy string
+
+const (
+	// Keep the existing record
+	LibraryConflictPolicySkip LibraryConflictPolicy = "skip"
+
+	// Replace the existing record with the imported one
+	LibraryConflictPolicyReplace LibraryConflictPolicy = "replace"
+)
+
+var LibraryConflictPolicyList = []interface{}{
+	LibraryConflictPolicySkip,
+	LibraryConflictPolicyReplace,
+}
+
+// Number of records of each kind in a library export
+type LibraryCounts struct {
+	InstallLocations   int64 `json:"installLocations"`
+	Caves              int64 `json:"caves"`
+	DownloadKeys       int64 `json:"downloadKeys"`
+	ProfileCollections int64 `json:"profileCollections"`
+	PlayTimes          int64 `json:"playTimes"`
+}
 
 //----------------------------------------------------------------------
 // System
@@ -2701,6 +4159,17 @@ const (
 	// Nothing that can be launched was found
 	CodeNoLaunchCandidates Code = 5000
 
+	// A pre-launch hook failed, and was set to abort the launch
+	CodePreLaunchHookFailed Code = 5001
+
+	// The cave is already running, and concurrent launches were refused
+	CodeAlreadyRunning Code = 5002
+
+	// A sandboxed launch was requested for a package format that
+	// can't run in the sandbox (flatpak, snap), since it brings its own.
+	// The client may offer to launch it without the sandbox instead.
+	CodeSandboxUnsupported Code = 5003
+
 	// Java Runtime Environment is required to launch this title.
 	CodeJavaRuntimeNeeded Code = 6000
 
@@ -2713,8 +4182,23 @@ const (
 	// The database is busy
 	CodeDatabaseBusy Code = 16000
 
+	// The database was migrated by a newer version of butler, which
+	// should be used to migrate it back down (`butler db migrate --to`)
+	CodeDatabaseTooNew Code = 16001
+
 	// An install location could not be removed because it has active downloads
 	CodeCantRemoveLocationBecauseOfActiveDownloads Code = 18000
+
+	// Profile data changed since it was read (compare-and-swap failed)
+	CodeProfileDataConflict Code = 19000
+
+	// A profile data value is too large
+	CodeProfileDataTooLarge Code = 19001
+
+	// The profile's API key couldn't be read from the secret store
+	// it's kept in: the store isn't enabled (see `--secret-store`),
+	// or can't be reached, or lost it. Logging in again fixes it.
+	CodeProfileSecretUnavailable Code = 19002
 )
 
 // Dates
@@ -30,11 +30,18 @@ type StrategyResult struct {
 
 	// If a local file, result of dash configure
 	Candidate *dash.Candidate `json:"candidate"`
+
+	// Set if the target is a Linux package rather
+	// than a plain executable
+	PackageFormat PackageFormat `json:"packageFormat,omitempty"`
 }
 
 func (sr *StrategyResult) String() string {
 	var lines []string
 	lines = append(lines, fmt.Sprintf("| (%s) (%s)", sr.FullTargetPath, sr.Strategy))
+	if sr.PackageFormat != PackageFormatNone {
+		lines = append(lines, fmt.Sprintf("| packaged as %s", sr.PackageFormat))
+	}
 	if sr.Candidate != nil {
 		lines = append(lines, sr.Candidate.String())
 	}
@@ -64,3 +71,12 @@ const (
 	LaunchStrategyURL     LaunchStrategy = "url"
 	LaunchStrategyShell   LaunchStrategy = "shell"
 )
+
+type PackageFormat string
+
+const (
+	PackageFormatNone     PackageFormat = ""
+	PackageFormatAppImage PackageFormat = "appimage"
+	PackageFormatFlatpak  PackageFormat = "flatpak"
+	PackageFormatSnap     PackageFormat = "snap"
+)
@@ -2,9 +2,11 @@ package daemon
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/itchio/butler/butlerd/horror"
 
@@ -13,10 +15,17 @@ import (
 	"github.com/google/uuid"
 	"github.com/itchio/butler/butlerd"
 	"github.com/itchio/butler/database"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/downloadcache"
+	"github.com/itchio/butler/endpoints/update"
+	"github.com/itchio/butler/lancache"
+	"github.com/itchio/butler/launchlogs"
+	"github.com/itchio/butler/manager/runlock"
 	"github.com/itchio/headway/state"
 
 	"github.com/itchio/butler/comm"
 	"github.com/itchio/butler/mansion"
+	"github.com/itchio/butler/sandbox"
 	"github.com/pkg/errors"
 )
 
@@ -25,6 +34,25 @@ var args = struct {
 	transport   string
 	keepAlive   bool
 	log         bool
+
+	downloadCacheDir  string
+	downloadCacheSize int64
+
+	lanCacheDir   string
+	lanCachePort  int
+	lanCacheHost  string
+	lanCachePeers []string
+
+	launchLogsDir      string
+	launchLogsSessions int
+
+	updateCheckInterval time.Duration
+	updateMinConfidence float64
+
+	forceSchema bool
+
+	fetchTTLs    map[string]string
+	offlineFirst bool
 }{}
 
 func Register(ctx *mansion.Context) {
@@ -33,6 +61,20 @@ func Register(ctx *mansion.Context) {
 	cmd.Flag("transport", "Which transport to use").Default("tcp").EnumVar(&args.transport, "http", "tcp")
 	cmd.Flag("keep-alive", "Accept multiple TCP connections, stay up until killed or a destiny PID shuts down").BoolVar(&args.keepAlive)
 	cmd.Flag("log", "Log all requests to stderr").BoolVar(&args.log)
+	cmd.Flag("download-cache-dir", "Where to keep recently downloaded files (defaults to next to the database)").StringVar(&args.downloadCacheDir)
+	cmd.Flag("download-cache-size", "Maximum size of the download cache, in MiB (0, the default, disables it)").Default("0").Int64Var(&args.downloadCacheSize)
+	cmd.Flag("lan-cache-dir", fmt.Sprintf("Share downloaded build archives & patches with other butlerd instances on the local network, storing them in this directory. Peers must share the secret in %s", lancache.SecretEnvironmentVariable)).StringVar(&args.lanCacheDir)
+	cmd.Flag("lan-cache-port", "Port to serve the LAN cache on").Default(fmt.Sprintf("%d", lancache.DefaultPort)).IntVar(&args.lanCachePort)
+	cmd.Flag("lan-cache-host", "Host or IP address to serve the LAN cache on (all interfaces by default)").StringVar(&args.lanCacheHost)
+	cmd.Flag("lan-cache-peer", "Address (host:port) of a LAN cache peer to use in addition to discovered ones").StringsVar(&args.lanCachePeers)
+	cmd.Flag("launch-logs-dir", "Where to keep the output of launched games (defaults to next to the database)").StringVar(&args.launchLogsDir)
+	cmd.Flag("launch-logs-sessions", "How many launch sessions to keep the output of, per cave (0 disables launch logs)").Default(fmt.Sprintf("%d", launchlogs.DefaultMaxSessions)).IntVar(&args.launchLogsSessions)
+	cmd.Flag("update-check-interval", "Check installed games for updates on this interval, from the daemon itself (0 disables background checks)").Default("0").DurationVar(&args.updateCheckInterval)
+	cmd.Flag("update-min-confidence", "How confident a direct update must be to be downloaded or installed automatically, per the cave's update policy").Default(fmt.Sprintf("%v", update.DefaultMinConfidence)).Float64Var(&args.updateMinConfidence)
+	args.fetchTTLs = make(map[string]string)
+	cmd.Flag("fetch-ttl", "How long fetched data of a type stays fresh, as type=duration, for example game=1h. Types are game, upload, game_uploads, user, profile_collections, profile_games, profile_owned_keys, collection and collection_games.").StringMapVar(&args.fetchTTLs)
+	cmd.Flag("offline-first", "Answer fetch requests from the local database when possible, and refresh stale data in the background").BoolVar(&args.offlineFirst)
+	cmd.Flag("force-schema", "Open the database even if it was migrated by a newer version of butler. Data only that version knows about may be lost.").BoolVar(&args.forceSchema)
 	ctx.Register(cmd, do)
 }
 
@@ -77,6 +119,17 @@ func do(ctx *mansion.Context) {
 		justCreated = true
 	}
 
+	// keeps `butler db restore` from replacing the database while we run
+	dbLock := runlock.NewForDatabase(comm.NewStateConsumer(), ctx.DBPath)
+	locked, err := dbLock.TryLock("butlerd")
+	if err != nil {
+		comm.Warnf("butlerd: Could not lock DB: %+v", err)
+	} else if !locked {
+		comm.Warnf("butlerd: DB is locked by another process, continuing")
+	} else {
+		defer dbLock.Unlock()
+	}
+
 	dbPool, err := sqlitex.Open(ctx.DBPath, 0, 100)
 	if err != nil {
 		ctx.Must(errors.WithMessage(err, "opening DB for the first time"))
@@ -92,9 +145,20 @@ func do(ctx *mansion.Context) {
 			OnMessage: func(lvl string, msg string) {
 				comm.Logf("[db prepare] [%s] %s", lvl, msg)
 			},
-		}, conn, justCreated)
+		}, conn, database.PrepareOptions{
+			JustCreated: justCreated,
+			ForceSchema: args.forceSchema,
+		})
 	}()
 	if err != nil {
+		if be, ok := butlerd.AsButlerdError(err); ok {
+			// let the client know why we can't start, so it can
+			// do better than showing a stack trace
+			comm.Object("butlerd/startup-error", map[string]interface{}{
+				"code":    be.RpcErrorCode(),
+				"message": err.Error(),
+			})
+		}
 		ctx.Must(errors.WithMessage(err, "preparing DB"))
 	}
 
@@ -106,6 +170,74 @@ func Do(mansionContext *mansion.Context, ctx context.Context, dbPool *sqlitex.Po
 	router := GetRouter(dbPool, mansionContext)
 	consumer := comm.NewStateConsumer()
 
+	err := setFetchTTLs(args.fetchTTLs)
+	if err != nil {
+		return err
+	}
+	router.OfflineFirst = args.offlineFirst
+
+	err = mansionContext.EnableProfileSecrets()
+	if err != nil {
+		return errors.WithMessage(err, "setting up secret store")
+	}
+
+	if args.downloadCacheSize > 0 {
+		dir := args.downloadCacheDir
+		if dir == "" {
+			dir = filepath.Join(filepath.Dir(mansionContext.DBPath), "download-cache")
+		}
+
+		_, err := downloadcache.Enable(downloadcache.Settings{
+			Dir:     dir,
+			MaxSize: args.downloadCacheSize * 1024 * 1024,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	sandbox.SetHomesDir(filepath.Join(filepath.Dir(mansionContext.DBPath), "sandbox-homes"))
+
+	if args.launchLogsSessions > 0 {
+		dir := args.launchLogsDir
+		if dir == "" {
+			dir = filepath.Join(filepath.Dir(mansionContext.DBPath), "launch-logs")
+		}
+
+		_, err := launchlogs.Enable(launchlogs.Settings{
+			Dir:         dir,
+			MaxSessions: args.launchLogsSessions,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if args.lanCacheDir != "" {
+		lc, err := lancache.Enable(consumer, lancache.Settings{
+			Dir:         args.lanCacheDir,
+			Port:        args.lanCachePort,
+			ListenHost:  args.lanCacheHost,
+			Secret:      os.Getenv(lancache.SecretEnvironmentVariable),
+			StaticPeers: args.lanCachePeers,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = lc.Start(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	if args.updateCheckInterval > 0 {
+		router.QueueBackgroundTask(update.ScheduleChecks(update.SchedulerSettings{
+			Interval:      args.updateCheckInterval,
+			MinConfidence: args.updateMinConfidence,
+		}))
+	}
+
 	switch args.transport {
 	case "tcp":
 		listener, err := net.Listen("tcp", "127.0.0.1:")
@@ -139,3 +271,19 @@ func Do(mansionContext *mansion.Context, ctx context.Context, dbPool *sqlitex.Po
 
 	return nil
 }
+
+// setFetchTTLs applies --fetch-ttl values, which map fetch target
+// types to durations, like "game" to "1h"
+func setFetchTTLs(ttls map[string]string) error {
+	for targetType, value := range ttls {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return errors.WithMessagef(err, "parsing TTL for (%s)", targetType)
+		}
+		err = models.SetTTL(targetType, ttl)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
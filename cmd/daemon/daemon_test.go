@@ -0,0 +1,47 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SetFetchTTLs(t *testing.T) {
+	assert := assert.New(t)
+
+	gameTTL := models.FetchTargetForGame(1).TTL
+	collectionGamesTTL := models.FetchTargetForCollectionGames(1).TTL
+	defer func() {
+		wtest.Must(t, models.SetTTL("game", gameTTL))
+		wtest.Must(t, models.SetTTL("collection_games", collectionGamesTTL))
+	}()
+
+	wtest.Must(t, setFetchTTLs(map[string]string{
+		"game":             "1h",
+		"collection_games": "90s",
+	}))
+	assert.EqualValues(time.Hour, models.FetchTargetForGame(1).TTL)
+	assert.EqualValues(90*time.Second, models.FetchTargetForCollectionGames(1).TTL)
+	// others are left alone
+	assert.EqualValues(models.FetchTargetForGameUploads(1).TTL, models.FetchTargetForUpload(1).TTL)
+
+	for value, message := range map[string]string{
+		"soon": "parsing TTL for (game)",
+		"0s":   "must be positive",
+		"-1m":  "must be positive",
+	} {
+		err := setFetchTTLs(map[string]string{"game": value})
+		if assert.Error(err, "value (%s)", value) {
+			assert.Contains(err.Error(), message, "value (%s)", value)
+		}
+	}
+	assert.EqualValues(time.Hour, models.FetchTargetForGame(1).TTL)
+
+	err := setFetchTTLs(map[string]string{"games": "1h"})
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "Unknown fetch target type (games)")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/itchio/butler/endpoints/fetch"
 	"github.com/itchio/butler/endpoints/install"
 	"github.com/itchio/butler/endpoints/launch"
+	"github.com/itchio/butler/endpoints/library"
 	"github.com/itchio/butler/endpoints/meta"
 	"github.com/itchio/butler/endpoints/profile"
 	"github.com/itchio/butler/endpoints/search"
@@ -39,6 +40,7 @@ func GetRouter(dbPool *sqlitex.Pool, mansionContext *mansion.Context) *butlerd.R
 	fetch.Register(mainRouter)
 	downloads.Register(mainRouter)
 	search.Register(mainRouter)
+	library.Register(mainRouter)
 	system.Register(mainRouter)
 
 	messages.EnsureAllRequests(mainRouter)
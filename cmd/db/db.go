@@ -0,0 +1,249 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/comm"
+	"github.com/itchio/butler/database"
+	"github.com/itchio/butler/database/backup"
+	"github.com/itchio/butler/manager/runlock"
+	"github.com/itchio/butler/mansion"
+	"github.com/pkg/errors"
+)
+
+var backupArgs = struct {
+	file string
+}{}
+
+var restoreArgs = struct {
+	file string
+}{}
+
+func Register(ctx *mansion.Context) {
+	parentCmd := ctx.App.Command("db", "(Advanced) Check, back up, restore, migrate or repair the butlerd database")
+
+	{
+		cmd := parentCmd.Command("check", "Check the database for corruption")
+		ctx.Register(cmd, doCheck)
+	}
+
+	{
+		cmd := parentCmd.Command("backup", "Make a copy of the database, even while butlerd is running")
+		cmd.Arg("file", "Where to write the backup").Required().StringVar(&backupArgs.file)
+		ctx.Register(cmd, doBackup)
+	}
+
+	{
+		cmd := parentCmd.Command("restore", "Replace the database with a backup or snapshot, once the app is closed")
+		cmd.Arg("file", "Backup or snapshot to restore").Required().ExistingFileVar(&restoreArgs.file)
+		ctx.Register(cmd, doRestore)
+	}
+
+	{
+		cmd := parentCmd.Command("vacuum", "Rebuild the database file, reclaiming unused space")
+		ctx.Register(cmd, doVacuum)
+	}
+
+	{
+		cmd := parentCmd.Command("migrate", "Run pending schema migrations, or undo them before going back to an older version of butler")
+		cmd.Flag("to", "Schema version to migrate to (defaults to the latest one)").StringVar(&migrateArgs.to)
+		cmd.Flag("dry-run", "Only show which migrations would run, and how many rows they would change").BoolVar(&migrateArgs.dryRun)
+		ctx.Register(cmd, doMigrate)
+	}
+
+	{
+		cmd := parentCmd.Command("repair", "Recreate missing caves from the receipts found in install locations, creating the database if it was lost")
+		cmd.Flag("location", "Path of an install location to register before scanning, if the database lost it").StringsVar(&repairArgs.locations)
+		ctx.Register(cmd, doRepair)
+	}
+}
+
+// Path returns the database butler commands work on: the one passed
+// with --dbpath, or the app's.
+func Path(mc *mansion.Context) string {
+	if mc.DBPath == "" {
+		comm.Debugf("DB path not specified (--dbpath), guessing...")
+		mc.DBPath = butlerd.GuessDBPath("")
+	}
+	comm.Debugf("Using database (%s)", mc.DBPath)
+	return mc.DBPath
+}
+
+// Open opens an existing database, never creating one
+func Open(mc *mansion.Context) (*sqlite.Conn, error) {
+	path := Path(mc)
+	_, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	conn, err := sqlite.OpenConn(path, 0)
+	if err != nil {
+		return nil, errors.WithMessage(err, "opening database")
+	}
+	return conn, nil
+}
+
+// OpenAndPrepare opens the database, creating it if needed, and brings
+// its schema up to date. Existing databases are snapshotted first, since
+// the caller is about to write to them.
+func OpenAndPrepare(mc *mansion.Context, snapshotReason string) (*sqlite.Conn, error) {
+	consumer := comm.NewStateConsumer()
+	path := Path(mc)
+
+	justCreated := false
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		err = os.MkdirAll(filepath.Dir(path), 0o755)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		justCreated = true
+	}
+
+	conn, err := sqlite.OpenConn(path, 0)
+	if err != nil {
+		return nil, errors.WithMessage(err, "opening database")
+	}
+
+	if !justCreated {
+		snapshotPath, err := backup.Snapshot(consumer, conn, snapshotReason)
+		if err != nil {
+			comm.Warnf("Could not snapshot database, continuing: %v", err)
+		} else if snapshotPath != "" {
+			comm.Logf("Previous database saved to (%s)", snapshotPath)
+		}
+	}
+
+	err = database.Prepare(consumer, conn, database.PrepareOptions{JustCreated: justCreated})
+	if err != nil {
+		conn.Close()
+		return nil, errors.WithMessage(err, "preparing database")
+	}
+	return conn, nil
+}
+
+func doCheck(mc *mansion.Context) {
+	mc.Must(check(mc))
+}
+
+func check(mc *mansion.Context) error {
+	conn, err := Open(mc)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	comm.Opf("Checking (%s)...", mc.DBPath)
+	problems, err := backup.Check(conn)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := backup.ListSnapshots(mc.DBPath)
+	if err != nil {
+		comm.Warnf("Could not list snapshots: %v", err)
+	}
+	for _, s := range snapshots {
+		comm.Logf("Snapshot available: %s", s)
+	}
+
+	if len(problems) > 0 {
+		for _, p := range problems {
+			comm.Warnf("%s", p)
+		}
+		return errors.Errorf("Found %d problems, restore a backup or snapshot with `butler db restore`", len(problems))
+	}
+
+	comm.Statf("No problems found")
+	return nil
+}
+
+func doBackup(mc *mansion.Context) {
+	mc.Must(doBackupInner(mc))
+}
+
+func doBackupInner(mc *mansion.Context) error {
+	conn, err := Open(mc)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	comm.Opf("Backing up (%s) to (%s)...", mc.DBPath, backupArgs.file)
+	err = backup.Backup(conn, backupArgs.file)
+	if err != nil {
+		return err
+	}
+	comm.Statf("Backup done")
+	return nil
+}
+
+func doRestore(mc *mansion.Context) {
+	mc.Must(restore(mc))
+}
+
+func restore(mc *mansion.Context) error {
+	path := Path(mc)
+
+	// butlerd keeps connections to the database, and wouldn't
+	// notice it's been replaced
+	dbLock := runlock.NewForDatabase(comm.NewStateConsumer(), path)
+	locked, err := dbLock.TryLock("restore")
+	if err != nil {
+		return errors.WithMessage(err, "locking database")
+	}
+	if !locked {
+		return errors.Errorf("butlerd is using (%s), quit the app before restoring", path)
+	}
+	defer dbLock.Unlock()
+
+	conn, err := sqlite.OpenConn(path, 0)
+	if err != nil {
+		return errors.WithMessage(err, "opening database")
+	}
+	defer conn.Close()
+
+	// the database we're replacing may be fine after all
+	snapshotPath, err := backup.Snapshot(comm.NewStateConsumer(), conn, "pre-restore")
+	if err != nil {
+		comm.Warnf("Could not snapshot database before restoring, continuing: %v", err)
+	} else if snapshotPath != "" {
+		comm.Logf("Previous database saved to (%s)", snapshotPath)
+	}
+
+	comm.Opf("Restoring (%s) from (%s)...", path, restoreArgs.file)
+	err = backup.Restore(conn, restoreArgs.file)
+	if err != nil {
+		return err
+	}
+	comm.Statf("Database restored")
+	return nil
+}
+
+func doVacuum(mc *mansion.Context) {
+	mc.Must(vacuum(mc))
+}
+
+func vacuum(mc *mansion.Context) error {
+	conn, err := Open(mc)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	before, _ := os.Stat(mc.DBPath)
+	comm.Opf("Vacuuming (%s)...", mc.DBPath)
+	err = backup.Vacuum(conn)
+	if err != nil {
+		return err
+	}
+
+	after, _ := os.Stat(mc.DBPath)
+	if before != nil && after != nil {
+		comm.Statf("Database went from %d to %d bytes", before.Size(), after.Size())
+	}
+	return nil
+}
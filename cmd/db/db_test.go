@@ -0,0 +1,173 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/itchio/butler/database/backup"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/manager/runlock"
+	"github.com/itchio/butler/mansion"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/hades"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/hush/bfs"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+	"xorm.io/builder"
+)
+
+func openDB(t *testing.T, path string) *sqlite.Conn {
+	conn, err := sqlite.OpenConn(path, 0)
+	wtest.Must(t, err)
+	return conn
+}
+
+func countRows(t *testing.T, conn *sqlite.Conn) int64 {
+	var count int64
+	wtest.Must(t, sqlitex.ExecTransient(conn, "SELECT COUNT(*) FROM things", func(stmt *sqlite.Stmt) error {
+		count = stmt.ColumnInt64(0)
+		return nil
+	}))
+	return count
+}
+
+func Test_RestoreLocked(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "db-test")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	dbPath := filepath.Join(dir, "butler.db")
+	backupPath := filepath.Join(dir, "backup.db")
+	func() {
+		conn := openDB(t, dbPath)
+		defer conn.Close()
+		wtest.Must(t, sqlitex.ExecScript(conn, "CREATE TABLE things (id INTEGER PRIMARY KEY); INSERT INTO things (id) VALUES (1);"))
+		wtest.Must(t, backup.Backup(conn, backupPath))
+		wtest.Must(t, sqlitex.ExecScript(conn, "DELETE FROM things;"))
+	}()
+
+	mc := &mansion.Context{DBPath: dbPath}
+	restoreArgs.file = backupPath
+	defer func() { restoreArgs.file = "" }()
+
+	// held by a running process, like butlerd
+	consumer := &state.Consumer{
+		OnMessage: func(lvl string, msg string) { t.Logf("[%s] %s", lvl, msg) },
+	}
+	dbLock := runlock.NewForDatabase(consumer, dbPath)
+	locked, err := dbLock.TryLock("test")
+	wtest.Must(t, err)
+	assert.True(locked)
+
+	assert.Error(restore(mc))
+	func() {
+		conn := openDB(t, dbPath)
+		defer conn.Close()
+		assert.EqualValues(0, countRows(t, conn), "database is left alone while locked")
+	}()
+
+	wtest.Must(t, dbLock.Unlock())
+	wtest.Must(t, restore(mc))
+	func() {
+		conn := openDB(t, dbPath)
+		defer conn.Close()
+		assert.EqualValues(1, countRows(t, conn))
+	}()
+
+	// and lets go of the lock once done
+	locked, err = dbLock.TryLock("test")
+	wtest.Must(t, err)
+	assert.True(locked)
+	wtest.Must(t, dbLock.Unlock())
+}
+
+func Test_Repair(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "db-test")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	locationPath := filepath.Join(dir, "apps")
+	mkdir := func(path string) {
+		wtest.Must(t, os.MkdirAll(filepath.Join(locationPath, filepath.FromSlash(path)), 0o755))
+	}
+
+	// a game installed by butler
+	mkdir("some-game")
+	wtest.Must(t, ioutil.WriteFile(filepath.Join(locationPath, "some-game", "index.html"), []byte("<p>Hi!</p>"), 0o644))
+	receipt := &bfs.Receipt{
+		Game:   &itchio.Game{ID: 10, Title: "Some Game"},
+		Upload: &itchio.Upload{ID: 1},
+		Build:  &itchio.Build{ID: 5, UserVersion: "v1.0"},
+	}
+	wtest.Must(t, receipt.WriteReceipt(filepath.Join(locationPath, "some-game")))
+
+	// installed by an old version of the app, without a receipt
+	mkdir("legacy-game/.itch")
+
+	// not a cave at all
+	mkdir("downloads")
+	mkdir("some-folder")
+
+	// the database was lost
+	dbPath := filepath.Join(dir, "db", "butler.db")
+	mc := &mansion.Context{DBPath: dbPath}
+	repairArgs.locations = []string{locationPath}
+	defer func() { repairArgs.locations = nil }()
+
+	listCaves := func() []*models.Cave {
+		conn := openDB(t, dbPath)
+		defer conn.Close()
+
+		var caves []*models.Cave
+		models.MustSelect(conn, &caves, builder.NewCond(), hades.Search{})
+		models.PreloadCaves(conn, caves)
+		return caves
+	}
+
+	wtest.Must(t, repair(mc))
+	caves := listCaves()
+	if assert.Len(caves, 1) {
+		cave := caves[0]
+		assert.EqualValues(10, cave.GameID)
+		assert.EqualValues(1, cave.UploadID)
+		assert.EqualValues(5, cave.BuildID)
+		assert.EqualValues("some-game", cave.InstallFolderName)
+		assert.NotNil(cave.InstalledAt)
+		if assert.NotNil(cave.InstallLocation) {
+			assert.EqualValues(locationPath, cave.InstallLocation.Path)
+		}
+		if assert.NotNil(cave.Game) {
+			assert.EqualValues("Some Game", cave.Game.Title)
+		}
+	}
+
+	// the database had just been created, so it wasn't snapshotted
+	snapshots, err := backup.ListSnapshots(dbPath)
+	wtest.Must(t, err)
+	assert.Empty(snapshots)
+
+	// repairing again doesn't duplicate caves, or install locations,
+	// and keeps a copy of the database it changed
+	wtest.Must(t, repair(mc))
+	assert.Len(listCaves(), 1)
+	func() {
+		conn := openDB(t, dbPath)
+		defer conn.Close()
+		assert.EqualValues(1, models.MustCount(conn, &models.InstallLocation{}, builder.NewCond()))
+	}()
+
+	snapshots, err = backup.ListSnapshots(dbPath)
+	wtest.Must(t, err)
+	if assert.Len(snapshots, 1) {
+		assert.Contains(filepath.Base(snapshots[0]), "pre-repair")
+	}
+}
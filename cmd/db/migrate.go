@@ -0,0 +1,115 @@
+package db
+
+import (
+	"fmt"
+	"strconv"
+
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/itchio/butler/comm"
+	"github.com/itchio/butler/database/backup"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/database/models/migrations"
+	"github.com/itchio/butler/mansion"
+	"github.com/pkg/errors"
+)
+
+var migrateArgs = struct {
+	to     string
+	dryRun bool
+}{}
+
+func doMigrate(mc *mansion.Context) {
+	mc.Must(migrate(mc))
+}
+
+func migrate(mc *mansion.Context) error {
+	consumer := comm.NewStateConsumer()
+
+	target := migrations.LatestSchemaVersion()
+	if migrateArgs.to != "" {
+		var err error
+		target, err = strconv.ParseInt(migrateArgs.to, 10, 64)
+		if err != nil {
+			return errors.Errorf("Invalid schema version (%s)", migrateArgs.to)
+		}
+	}
+
+	conn, err := Open(mc)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	err = migrations.CheckSchemaVersion(conn)
+	if err != nil {
+		return err
+	}
+
+	current := models.GetSchemaVersion(conn)
+	comm.Logf("Database is at schema version %d, latest is %d", current, migrations.LatestSchemaVersion())
+
+	if !migrateArgs.dryRun && target != current {
+		snapshotPath, err := backup.Snapshot(consumer, conn, fmt.Sprintf("pre-migration-%d", current))
+		if err != nil {
+			comm.Warnf("Could not snapshot database, continuing: %v", err)
+		} else if snapshotPath != "" {
+			comm.Logf("Previous database saved to (%s)", snapshotPath)
+		}
+	}
+
+	var steps []*migrations.Step
+	err = func() (retErr error) {
+		if target >= current {
+			// migrations expect the tables to be up-to-date. for dry runs,
+			// that's rolled back along with everything else.
+			if migrateArgs.dryRun {
+				defer func() {
+					if retErr == errDryRun {
+						retErr = nil
+					}
+				}()
+				defer sqlitex.Save(conn)(&retErr)
+			}
+
+			err := models.HadesContext().AutoMigrate(conn)
+			if err != nil {
+				return errors.WithMessage(err, "performing automatic DB migration")
+			}
+		}
+
+		steps, err = migrations.MigrateTo(consumer, conn, target, migrateArgs.dryRun)
+		if err != nil {
+			return err
+		}
+		if migrateArgs.dryRun && target >= current {
+			return errDryRun
+		}
+		return nil
+	}()
+	if err != nil {
+		return err
+	}
+
+	if len(steps) == 0 {
+		comm.Statf("Nothing to do, database is at schema version %d", current)
+		return nil
+	}
+
+	for _, step := range steps {
+		verb := "Run"
+		if step.Down {
+			verb = "Undo"
+		}
+		comm.Logf("%s migration %d (%d rows changed)", verb, step.Version, step.RowsChanged)
+	}
+
+	if migrateArgs.dryRun {
+		comm.Statf("Dry run: %d migrations would run to reach schema version %d", len(steps), target)
+	} else {
+		comm.Statf("Ran %d migrations, database is at schema version %d", len(steps), models.GetSchemaVersion(conn))
+	}
+	return nil
+}
+
+// errDryRun rolls back the automatic schema migration after a dry run
+var errDryRun = errors.New("dry run")
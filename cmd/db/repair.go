@@ -0,0 +1,151 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+
+	"crawshaw.io/sqlite"
+	"github.com/google/uuid"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/comm"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/manager"
+	"github.com/itchio/butler/mansion"
+	"github.com/itchio/hades"
+	"github.com/itchio/ox"
+	"github.com/pkg/errors"
+	"xorm.io/builder"
+)
+
+var repairArgs = struct {
+	locations []string
+}{}
+
+func doRepair(mc *mansion.Context) {
+	mc.Must(repair(mc))
+}
+
+// repair recreates caves from the receipts butler writes in every
+// install folder. Unlike InstallLocations.Scan, it doesn't need the
+// network: everything it needs is in the receipt.
+func repair(mc *mansion.Context) error {
+	consumer := comm.NewStateConsumer()
+
+	// a lost database is the main reason to repair, so create it if needed
+	conn, err := OpenAndPrepare(mc, "pre-repair")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for _, locationPath := range repairArgs.locations {
+		err := ensureInstallLocation(conn, locationPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	var installLocations []*models.InstallLocation
+	models.MustSelect(conn, &installLocations, builder.NewCond(), hades.Search{})
+
+	var caves []*models.Cave
+	models.MustSelect(conn, &caves, builder.NewCond(), hades.Search{})
+	existing := make(map[string]bool)
+	for _, c := range caves {
+		existing[filepath.Join(c.InstallLocationID, c.InstallFolderName)] = true
+	}
+
+	comm.Opf("Repairing (%s)...", mc.DBPath)
+
+	var numRestored, numLegacy int
+	for _, il := range installLocations {
+		comm.Logf("Scanning install location (%s)...", il.Path)
+		folders, err := operate.ScanCaveFolders(consumer, il)
+		if err != nil {
+			comm.Warnf("Could not scan install location (%s), skipping: %v", il.Path, err)
+			continue
+		}
+
+		for _, folder := range folders {
+			if existing[filepath.Join(il.ID, folder.Name)] {
+				continue
+			}
+
+			receipt := folder.Receipt
+			if receipt == nil {
+				comm.Logf("(%s) only has a legacy receipt, use InstallLocations.Scan from the app to import it", folder.Path)
+				numLegacy++
+				continue
+			}
+			if receipt.Game == nil || receipt.Upload == nil {
+				comm.Warnf("Receipt in (%s) is incomplete, skipping", folder.Path)
+				continue
+			}
+
+			installedAt := folder.ReceiptModTime
+			cave := &models.Cave{
+				ID:                uuid.New().String(),
+				GameID:            receipt.Game.ID,
+				Game:              receipt.Game,
+				UploadID:          receipt.Upload.ID,
+				Upload:            receipt.Upload,
+				Build:             receipt.Build,
+				InstallLocationID: il.ID,
+				InstallFolderName: folder.Name,
+				InstalledAt:       &installedAt,
+			}
+			if receipt.Build != nil {
+				cave.BuildID = receipt.Build.ID
+			}
+
+			verdict, err := manager.Configure(consumer, folder.Path, ox.CurrentRuntime())
+			if err != nil {
+				comm.Warnf("Could not configure (%s), saving cave without verdict: %v", folder.Path, err)
+			} else {
+				cave.SetVerdict(verdict)
+				cave.InstalledSize = verdict.TotalSize
+			}
+
+			cave.SaveWithAssocs(conn)
+			comm.Logf("Restored %s in (%s)", operate.GameToString(receipt.Game), folder.Path)
+			numRestored++
+		}
+	}
+
+	comm.Statf("Restored %d caves", numRestored)
+	if numLegacy > 0 {
+		comm.Warnf("%d folders need to be imported from the app", numLegacy)
+	}
+	return nil
+}
+
+// ensureInstallLocation registers locationPath as an install location,
+// unless one already points to it.
+func ensureInstallLocation(conn *sqlite.Conn, locationPath string) error {
+	locationPath, err := filepath.Abs(locationPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	stats, err := os.Stat(locationPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if !stats.IsDir() {
+		return errors.Errorf("(%s) is not a directory", locationPath)
+	}
+
+	var il models.InstallLocation
+	if models.MustSelectOne(conn, &il, builder.Eq{"path": locationPath}) {
+		comm.Debugf("(%s) is already install location (%s)", locationPath, il.ID)
+		return nil
+	}
+
+	il = models.InstallLocation{
+		ID:   uuid.New().String(),
+		Path: locationPath,
+	}
+	models.MustSave(conn, &il)
+	comm.Logf("Added install location (%s) at (%s)", il.ID, il.Path)
+	return nil
+}
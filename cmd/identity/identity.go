@@ -0,0 +1,184 @@
+package identity
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/itchio/butler/comm"
+	"github.com/itchio/butler/mansion"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+)
+
+var useArgs = struct {
+	name string
+}{}
+
+var removeArgs = struct {
+	name string
+}{}
+
+func Register(ctx *mansion.Context) {
+	parentCmd := ctx.App.Command("identity", "Manage named itch.io logins. Log into a new one with `butler login --as <name>`, use one for a single command with `--as <name>`.")
+
+	{
+		cmd := parentCmd.Command("list", "List identities, and which one is in use")
+		ctx.Register(cmd, doList)
+	}
+
+	{
+		cmd := parentCmd.Command("use", "Use an identity by default, unless another one is picked by --as, BUTLER_IDENTITY or "+mansion.ProjectConfigFileName)
+		cmd.Arg("name", "Name of the identity").Required().StringVar(&useArgs.name)
+		ctx.Register(cmd, doUse)
+	}
+
+	{
+		cmd := parentCmd.Command("remove", "Erase the saved API key of an identity, and forget it")
+		cmd.Arg("name", "Name of the identity").Required().StringVar(&removeArgs.name)
+		ctx.Register(cmd, doRemove)
+	}
+}
+
+type identityInfo struct {
+	Name           string `json:"name"`
+	Path           string `json:"path"`
+	Active         bool   `json:"active"`
+	HasCredentials bool   `json:"hasCredentials"`
+}
+
+func doList(ctx *mansion.Context) {
+	ctx.Must(list(ctx))
+}
+
+func list(ctx *mansion.Context) error {
+	names, err := ctx.ListIdentities()
+	if err != nil {
+		return err
+	}
+
+	var infos []*identityInfo
+	for _, name := range names {
+		sub, err := ctx.WithIdentity(name)
+		if err != nil {
+			return err
+		}
+		infos = append(infos, &identityInfo{
+			Name:           name,
+			Path:           sub.Identity,
+			Active:         name == ctx.IdentityName,
+			HasCredentials: sub.HasStoredCredentials(),
+		})
+	}
+
+	comm.ResultOrPrint(infos, func() {
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"", "Identity", "Credentials"})
+		for _, info := range infos {
+			active := ""
+			if info.Active {
+				active = "*"
+			}
+			credentials := "none"
+			if info.HasCredentials {
+				credentials = "saved"
+			}
+			table.Append([]string{active, info.Name, credentials})
+		}
+		table.Render()
+
+		if err := ctx.IdentityError(); err != nil {
+			comm.Warnf("Could not tell which identity is in use: %v", err)
+		} else if ctx.IdentityName == "" {
+			comm.Logf("Using credentials at %s (from %s)", ctx.Identity, ctx.IdentitySource)
+		} else {
+			comm.Logf("Using identity %s (from %s)", ctx.IdentityName, ctx.IdentitySource)
+		}
+		if os.Getenv(mansion.APIKeyEnvironmentVariable) != "" {
+			comm.Logf("%s is set, and used instead of any identity", mansion.APIKeyEnvironmentVariable)
+		}
+	})
+	return nil
+}
+
+func doUse(ctx *mansion.Context) {
+	ctx.Must(use(ctx, useArgs.name))
+}
+
+func use(ctx *mansion.Context, name string) error {
+	sub, err := ctx.WithIdentity(name)
+	if err != nil {
+		return err
+	}
+	if name != mansion.DefaultIdentityName && !sub.HasStoredCredentials() {
+		return errors.Errorf("No saved credentials for identity %s, log in with `butler login --as %s` first", name, name)
+	}
+
+	err = ctx.SetCurrentIdentity(name)
+	if err != nil {
+		return err
+	}
+	comm.Statf("Now using identity %s by default", name)
+	return nil
+}
+
+func doRemove(ctx *mansion.Context) {
+	ctx.Must(remove(ctx, removeArgs.name))
+}
+
+func remove(ctx *mansion.Context, name string) error {
+	sub, err := ctx.WithIdentity(name)
+	if err != nil {
+		return err
+	}
+
+	if name != mansion.DefaultIdentityName {
+		if _, err := os.Stat(filepath.Dir(sub.Identity)); os.IsNotExist(err) {
+			return errors.Errorf("No identity named %s", name)
+		}
+	}
+
+	hasCredentials := sub.HasStoredCredentials()
+	if name == mansion.DefaultIdentityName && !hasCredentials {
+		comm.Logf("No saved credentials for identity %s", name)
+		comm.Log("Nothing to do.")
+		return nil
+	}
+
+	if hasCredentials {
+		comm.Notice("Important note", []string{
+			"Note: this command will not invalidate the API key itself.",
+			"If you wish to revoke it (for example, because it's been compromised), you should do so in your user settings:",
+			"",
+			fmt.Sprintf("  %s/user/settings\n\n", ctx.WebAddress()),
+		})
+
+		if !comm.YesNo(fmt.Sprintf("Do you want to erase the saved API key of identity %s?", name)) {
+			comm.Log("Okay, not erasing credentials. Bye!")
+			return nil
+		}
+
+		err = sub.ForgetCredentials()
+		if err != nil {
+			return err
+		}
+	}
+
+	if name != mansion.DefaultIdentityName {
+		err = os.RemoveAll(filepath.Dir(sub.Identity))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	if ctx.CurrentIdentity() == name {
+		err = ctx.SetCurrentIdentity(mansion.DefaultIdentityName)
+		if err != nil {
+			return err
+		}
+		comm.Logf("Using identity %s by default again", mansion.DefaultIdentityName)
+	}
+
+	comm.Statf("Removed identity %s", name)
+	return nil
+}
@@ -0,0 +1,98 @@
+package library
+
+import (
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/db"
+	"github.com/itchio/butler/comm"
+	"github.com/itchio/butler/database/library"
+	"github.com/itchio/butler/mansion"
+)
+
+var exportArgs = struct {
+	file string
+}{}
+
+var importArgs = struct {
+	file    string
+	replace bool
+}{}
+
+func Register(ctx *mansion.Context) {
+	parentCmd := ctx.App.Command("library", "(Advanced) Export the library known to butlerd to JSON, or import it back")
+
+	{
+		cmd := parentCmd.Command("export", "Write install locations, caves, owned keys, collections and playtime to a JSON file")
+		cmd.Arg("file", "Where to write the export").Required().StringVar(&exportArgs.file)
+		ctx.Register(cmd, doExport)
+	}
+
+	{
+		cmd := parentCmd.Command("import", "Merge a JSON file written by 'butler library export' into the database")
+		cmd.Arg("file", "Export to import").Required().ExistingFileVar(&importArgs.file)
+		cmd.Flag("replace", "Replace existing records that have the same ID, instead of keeping them").BoolVar(&importArgs.replace)
+		ctx.Register(cmd, doImport)
+	}
+}
+
+func doExport(mc *mansion.Context) {
+	mc.Must(export(mc))
+}
+
+func export(mc *mansion.Context) error {
+	conn, err := db.Open(mc)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	lib, err := library.Export(conn)
+	if err != nil {
+		return err
+	}
+
+	err = library.Write(lib, exportArgs.file)
+	if err != nil {
+		return err
+	}
+
+	c := lib.Counts()
+	comm.Statf("Exported %d install locations, %d caves, %d download keys, %d collections and %d playtimes to (%s)",
+		c.InstallLocations, c.Caves, c.DownloadKeys, c.ProfileCollections, c.PlayTimes, exportArgs.file)
+	return nil
+}
+
+func doImport(mc *mansion.Context) {
+	mc.Must(importLibrary(mc))
+}
+
+func importLibrary(mc *mansion.Context) error {
+	lib, err := library.Read(importArgs.file)
+	if err != nil {
+		return err
+	}
+	comm.Logf("Importing export made by butler %s on %s", lib.ButlerVersion, lib.ExportedAt)
+
+	conn, err := db.OpenAndPrepare(mc, "pre-import")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	policy := butlerd.LibraryConflictPolicySkip
+	if importArgs.replace {
+		policy = butlerd.LibraryConflictPolicyReplace
+	}
+
+	imported, skipped, err := library.Import(comm.NewStateConsumer(), conn, lib, policy)
+	if err != nil {
+		return err
+	}
+
+	comm.Statf("Imported %d install locations, %d caves, %d download keys, %d collections and %d playtimes",
+		imported.InstallLocations, imported.Caves, imported.DownloadKeys, imported.ProfileCollections, imported.PlayTimes)
+	if *skipped != (butlerd.LibraryCounts{}) {
+		comm.Logf("Kept %d install locations, %d caves, %d download keys, %d collections and %d playtimes that already existed",
+			skipped.InstallLocations, skipped.Caves, skipped.DownloadKeys, skipped.ProfileCollections, skipped.PlayTimes)
+	}
+	return nil
+}
@@ -28,7 +28,7 @@ func Do(ctx *mansion.Context) error {
 		}
 
 		comm.Logf("Your local credentials are valid!\n")
-		comm.Logf("If you want to log in as another account, use the `butler logout` command first, or specify a different credentials path with the `-i` flag.")
+		comm.Logf("If you want to log in as another account, use the `butler logout` command first, or log into another identity with `butler login --as <name>`.")
 		comm.Result(map[string]string{"status": "success"})
 	} else {
 		// this does the full login flow + saves
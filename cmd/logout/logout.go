@@ -2,11 +2,9 @@ package logout
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/itchio/butler/comm"
 	"github.com/itchio/butler/mansion"
-	"github.com/pkg/errors"
 )
 
 func Register(ctx *mansion.Context) {
@@ -19,15 +17,15 @@ func do(ctx *mansion.Context) {
 }
 
 func Do(ctx *mansion.Context) error {
+	if err := ctx.IdentityError(); err != nil {
+		return err
+	}
 	var identity = ctx.Identity
 
-	_, err := os.Lstat(identity)
-	if err != nil {
-		if os.IsNotExist(err) {
-			comm.Logf("No saved credentials at %s", identity)
-			comm.Log("Nothing to do.")
-			return nil
-		}
+	if !ctx.HasStoredCredentials() {
+		comm.Logf("No saved credentials for %s", identity)
+		comm.Log("Nothing to do.")
+		return nil
 	}
 
 	comm.Notice("Important note", []string{
@@ -44,9 +42,9 @@ func Do(ctx *mansion.Context) error {
 		return nil
 	}
 
-	err = os.Remove(identity)
+	err := ctx.ForgetCredentials()
 	if err != nil {
-		return errors.Wrap(err, "deleting identity file")
+		return err
 	}
 
 	comm.Log("You've successfully erased the API key that was saved on your computer.")
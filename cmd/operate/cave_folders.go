@@ -0,0 +1,75 @@
+package operate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/hush/bfs"
+	"github.com/pkg/errors"
+)
+
+// CaveFolder is a folder of an install location that looks like
+// a cave, ie. it has an `.itch` folder.
+type CaveFolder struct {
+	// Name of the folder, relative to the install location
+	Name string
+	// Absolute path of the folder
+	Path string
+
+	// Receipt written by butler, nil if missing or unreadable
+	// (folders installed by older versions of the app only
+	// have a legacy `receipt.json`)
+	Receipt *bfs.Receipt
+	// When Receipt was last written
+	ReceiptModTime time.Time
+}
+
+// ScanCaveFolders lists the folders of an install location
+// that look like caves, and reads their receipts.
+func ScanCaveFolders(consumer *state.Consumer, il *models.InstallLocation) ([]*CaveFolder, error) {
+	entries, err := ioutil.ReadDir(il.Path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var folders []*CaveFolder
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == "downloads" {
+			// definitely not a cave folder, skip
+			continue
+		}
+
+		folder := &CaveFolder{
+			Name: name,
+			Path: filepath.Join(il.Path, name),
+		}
+
+		_, err := os.Stat(filepath.Join(folder.Path, ".itch"))
+		if err != nil {
+			// no .itch folder, skip
+			continue
+		}
+
+		receipt, err := bfs.ReadReceipt(folder.Path)
+		if err != nil {
+			consumer.Warnf("While reading receipt in (%s): %s", folder.Path, err.Error())
+		}
+		if receipt != nil {
+			receiptStats, err := os.Stat(bfs.ReceiptPath(folder.Path))
+			if err != nil {
+				consumer.Warnf("While reading receipt in (%s): %s", folder.Path, err.Error())
+				continue
+			}
+			folder.Receipt = receipt
+			folder.ReceiptModTime = receiptStats.ModTime().UTC()
+		}
+
+		folders = append(folders, folder)
+	}
+	return folders, nil
+}
@@ -34,6 +34,9 @@ type OperationContext struct {
 	loaded map[string]struct{}
 
 	pidFilePath string
+
+	// called on Release
+	releases []func()
 }
 
 type PidFileContents struct {
@@ -163,6 +166,11 @@ func (oc *OperationContext) Release() {
 		os.Remove(oc.pidFilePath)
 	}
 
+	for _, release := range oc.releases {
+		release()
+	}
+	oc.releases = nil
+
 	oc.logFile.Close()
 }
 
@@ -178,6 +186,12 @@ func (oc *OperationContext) Retire() {
 	}
 }
 
+// onRelease registers f to be called when oc is released,
+// for resources that must outlive a single step of the operation.
+func (oc *OperationContext) onRelease(f func()) {
+	oc.releases = append(oc.releases, f)
+}
+
 func (oc *OperationContext) StageFolder() string {
 	return oc.stageFolder
 }
@@ -0,0 +1,163 @@
+package operate
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/downloadcache"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/headway/state"
+	"github.com/pkg/errors"
+)
+
+// installSourceCacheKey returns the download cache key and expected size
+// of an install source, or an empty key if it can't be cached.
+func installSourceCacheKey(params *InstallParams) (string, int64) {
+	if params.Build != nil {
+		bf := FindBuildFile(params.Build.Files, itchio.BuildFileTypeArchive, itchio.BuildFileSubTypeDefault)
+		if bf != nil {
+			return downloadcache.BuildFileKey(bf), bf.Size
+		}
+		return "", 0
+	}
+
+	if params.Upload != nil && params.Upload.Storage == itchio.UploadStorageHosted {
+		return downloadcache.UploadKey(params.Upload), params.Upload.Size
+	}
+	return "", 0
+}
+
+// fromDownloadCache returns the local path of a cached file,
+// or an empty string if it's not in the download cache.
+// The file is kept in the cache until oc is released.
+func fromDownloadCache(oc *OperationContext, consumer *state.Consumer, key string) string {
+	dc := downloadcache.Get()
+	if dc == nil || key == "" {
+		return ""
+	}
+
+	var entry *models.DownloadCacheEntry
+	var release func()
+	oc.rc.WithConn(func(conn *sqlite.Conn) {
+		entry, release = dc.Hold(conn, key)
+	})
+	if entry == nil {
+		return ""
+	}
+
+	err := dc.Check(entry)
+	if err != nil {
+		consumer.Warnf("%v", err)
+		oc.rc.WithConn(func(conn *sqlite.Conn) {
+			dc.Remove(conn, entry)
+		})
+		release()
+		return ""
+	}
+	oc.onRelease(release)
+
+	oc.rc.WithConn(func(conn *sqlite.Conn) {
+		dc.Touch(conn, entry)
+	})
+	consumer.Infof("Using %s from download cache", key)
+	return dc.BlobPath(entry.Hash)
+}
+
+// intoDownloadCache downloads sourceURL into the download cache and
+// returns its local path, or an empty string if it shouldn't be cached.
+// The file is kept in the cache until oc is released.
+func intoDownloadCache(oc *OperationContext, consumer *state.Consumer, key string, size int64, sourceURL string) (string, error) {
+	dc := downloadcache.Get()
+	if dc == nil || key == "" || !dc.Fits(size) {
+		return "", nil
+	}
+
+	incomingPath := dc.IncomingPath(key)
+	err := downloadToPath(oc.ctx, consumer, oc.StageFolder(), "dlcache-"+key, sourceURL, incomingPath)
+	if err != nil {
+		return "", errors.WithMessage(err, "downloading to download cache")
+	}
+
+	stats, err := os.Stat(incomingPath)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if stats.Size() != size {
+		os.Remove(incomingPath)
+		return "", errors.Errorf("downloaded %s has size %d, expected %d", key, stats.Size(), size)
+	}
+
+	hash, err := downloadcache.HashFile(incomingPath)
+	if err != nil {
+		return "", err
+	}
+
+	var entry *models.DownloadCacheEntry
+	var release func()
+	oc.rc.WithConn(func(conn *sqlite.Conn) {
+		entry, release, err = dc.Commit(conn, consumer, key, hash, size)
+	})
+	if err != nil {
+		return "", errors.WithMessage(err, "committing to download cache")
+	}
+	oc.onRelease(release)
+	return dc.BlobPath(entry.Hash), nil
+}
+
+// namedInstallSource makes a download cache blob available under the
+// upload's filename, since blobs are named after their hash, and some
+// installers (naked, for one) use the install source's name.
+// It returns blobPath if that's not possible.
+func namedInstallSource(oc *OperationContext, consumer *state.Consumer, params *InstallParams, blobPath string) string {
+	if params.Upload == nil || params.Upload.Filename == "" {
+		return blobPath
+	}
+
+	// not "install-source", that's where doForceLocal copies to
+	destPath := filepath.Join(oc.StageFolder(), "cached-install-source", filepath.Base(params.Upload.Filename))
+	err := linkOrCopy(blobPath, destPath)
+	if err != nil {
+		consumer.Warnf("Could not name cached install source: %v", err)
+		return blobPath
+	}
+	return destPath
+}
+
+// linkOrCopy hardlinks src to dest, or copies it if hardlinks
+// aren't supported (or src and dest are on different volumes).
+func linkOrCopy(src string, dest string) error {
+	err := os.MkdirAll(filepath.Dir(dest), 0o755)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = os.Remove(dest)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.WithStack(err)
+	}
+
+	if os.Link(src, dest) == nil {
+		return nil
+	}
+
+	r, err := os.Open(src)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer r.Close()
+
+	w, err := os.Create(dest)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer w.Close()
+
+	_, err = io.Copy(w, r)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(w.Close())
+}
@@ -204,7 +204,7 @@ func AccessForGameID(conn *sqlite.Conn, gameID int64) *GameAccess {
 
 			if profile != nil {
 				access := &GameAccess{
-					APIKey: profile.APIKey,
+					APIKey: profile.MustGetAPIKey(),
 				}
 				return access
 			}
@@ -222,7 +222,7 @@ func AccessForGameID(conn *sqlite.Conn, gameID int64) *GameAccess {
 			}
 
 			access := &GameAccess{
-				APIKey: profile.APIKey,
+				APIKey: profile.MustGetAPIKey(),
 				Credentials: itchio.GameCredentials{
 					DownloadKeyID: dk.ID,
 				},
@@ -243,7 +243,7 @@ func AccessForGameID(conn *sqlite.Conn, gameID int64) *GameAccess {
 		for _, profile := range profiles {
 			if profile.PressUser {
 				access := &GameAccess{
-					APIKey: profile.APIKey,
+					APIKey: profile.MustGetAPIKey(),
 				}
 				return access
 			}
@@ -252,7 +252,7 @@ func AccessForGameID(conn *sqlite.Conn, gameID int64) *GameAccess {
 		// just take the most recent then
 		profile := profiles[0]
 		access := &GameAccess{
-			APIKey: profile.APIKey,
+			APIKey: profile.MustGetAPIKey(),
 		}
 		return access
 	}
@@ -294,3 +294,18 @@ func FindBuildFile(files []*itchio.BuildFile, fileType itchio.BuildFileType, sub
 	}
 	return nil
 }
+
+// UpgradePatchFile returns the patch to apply to upgrade to a build,
+// preferring its optimized version, or nil if the build has no usable patch.
+func UpgradePatchFile(b *itchio.Build) *itchio.BuildFile {
+	f := FindBuildFile(b.Files, itchio.BuildFileTypePatch, itchio.BuildFileSubTypeDefault)
+	if f == nil {
+		return nil
+	}
+
+	of := FindBuildFile(b.Files, itchio.BuildFileTypePatch, itchio.BuildFileSubTypeOptimized)
+	if of != nil {
+		f = of
+	}
+	return f
+}
@@ -44,6 +44,12 @@ func heal(oc *OperationContext, meta *MetaSubcontext, isub *InstallSubcontext, r
 	signatureURL := MakeSourceURL(client, consumer, istate.DownloadSessionID, params, "signature")
 	archiveURL := MakeSourceURL(client, consumer, istate.DownloadSessionID, params, "archive")
 
+	// heal from a local copy of the archive if we have one
+	key, _ := installSourceCacheKey(params)
+	if path := fromDownloadCache(oc, consumer, key); path != "" {
+		archiveURL = path
+	}
+
 	healSpec := fmt.Sprintf("archive,%s", archiveURL)
 
 	vc := &pwr.ValidatorContext{
@@ -211,6 +211,9 @@ func doInstallPerformInner(oc *OperationContext, meta *MetaSubcontext, isub *Ins
 					InstallFolderName: params.InstallFolderName,
 					InstallLocationID: params.InstallLocationID,
 				}
+				if params.Adopt {
+					cave.CustomInstallFolder = params.InstallFolder
+				}
 			}
 
 			oc.cave = cave
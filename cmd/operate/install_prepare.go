@@ -8,6 +8,7 @@ import (
 	"github.com/itchio/hush"
 	"github.com/itchio/hush/bfs"
 	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/headway/state"
 	"github.com/itchio/headway/united"
 	"github.com/itchio/httpkit/eos"
 	"github.com/itchio/httpkit/eos/option"
@@ -31,6 +32,41 @@ type InstallPrepareResult struct {
 
 type InstallTask func(res *InstallPrepareResult) error
 
+// localInstallSource tries to make the install source available locally:
+// from the download cache, from LAN peers, or by downloading it into the
+// download cache. If none of these apply, sourceURL is returned as-is.
+func localInstallSource(oc *OperationContext, consumer *state.Consumer, params *InstallParams, sourceURL string, signatureURL string) string {
+	key, size := installSourceCacheKey(params)
+	if path := fromDownloadCache(oc, consumer, key); path != "" {
+		return namedInstallSource(oc, consumer, params, path)
+	}
+
+	oc.rc.StartProgress()
+	defer oc.rc.EndProgress()
+
+	lcf, err := fetchFromLANCache(oc, consumer, params.Build, itchio.BuildFileTypeArchive, itchio.BuildFileSubTypeDefault, sourceURL)
+	if err != nil {
+		consumer.Warnf("LAN cache unavailable, using itch.io: %+v", err)
+	} else if lcf != nil {
+		err = lcf.Verify(oc, consumer, signatureURL)
+		if err == nil {
+			return lcf.Path
+		}
+		consumer.Warnf("%+v", err)
+		consumer.Warnf("Using itch.io instead")
+	}
+
+	path, err := intoDownloadCache(oc, consumer, key, size, sourceURL)
+	if err != nil {
+		consumer.Warnf("Download cache unavailable, using itch.io: %+v", err)
+		return sourceURL
+	}
+	if path != "" {
+		return namedInstallSource(oc, consumer, params, path)
+	}
+	return sourceURL
+}
+
 func InstallPrepare(oc *OperationContext, meta *MetaSubcontext, isub *InstallSubcontext, allowDownloads bool, task InstallTask) error {
 	rc := oc.rc
 	params := meta.Data
@@ -83,6 +119,15 @@ func InstallPrepare(oc *OperationContext, meta *MetaSubcontext, isub *InstallSub
 	consumer.Infof("→ To be installed:")
 	LogUpload(consumer, params.Upload, params.Build)
 
+	if params.Adopt {
+		if params.Build == nil {
+			return errors.New("Can only adopt existing files for wharf-enabled uploads")
+		}
+		consumer.Infof("⇲ Adopting existing files, will verify and heal")
+		res.Strategy = InstallPerformStrategyHeal
+		return task(res)
+	}
+
 	if receiptIn != nil && receiptIn.Upload != nil && receiptIn.Upload.ID == params.Upload.ID {
 		consumer.Infof("Installing over same upload")
 		if receiptIn.Build != nil && params.Build != nil {
@@ -116,20 +161,13 @@ func InstallPrepare(oc *OperationContext, meta *MetaSubcontext, isub *InstallSub
 				consumer.Infof("Found upgrade path with %d items: ", len(upgradePath.Builds))
 
 				for _, b := range upgradePath.Builds {
-					f := FindBuildFile(b.Files, itchio.BuildFileTypePatch, itchio.BuildFileSubTypeDefault)
+					f := UpgradePatchFile(b)
 					if f == nil {
 						consumer.Warnf("Whoops, build %d is missing a patch, falling back to heal...", b.ID)
 						res.Strategy = InstallPerformStrategyHeal
 						return task(res)
 					}
 
-					{
-						of := FindBuildFile(b.Files, itchio.BuildFileTypePatch, itchio.BuildFileSubTypeOptimized)
-						if of != nil {
-							f = of
-						}
-					}
-
 					consumer.Infof(" - Build %d (%s)", b.ID, united.FormatBytes(f.Size))
 					totalUpgradeSize += f.Size
 				}
@@ -184,6 +222,11 @@ func InstallPrepare(oc *OperationContext, meta *MetaSubcontext, isub *InstallSub
 	}
 	installSourceURL := MakeSourceURL(client, consumer, istate.DownloadSessionID, params, installSourceFileType)
 
+	if allowDownloads {
+		signatureURL := MakeSourceURL(client, consumer, istate.DownloadSessionID, params, "signature")
+		installSourceURL = localInstallSource(oc, consumer, params, installSourceURL, signatureURL)
+	}
+
 	beforeOpen := time.Now()
 	file, err := eos.Open(installSourceURL, option.WithConsumer(consumer))
 	consumer.Infof("(opening file took %s)", time.Since(beforeOpen))
@@ -0,0 +1,227 @@
+package operate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/itchio/butler/lancache"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/headway/united"
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/httpkit/eos/option"
+	"github.com/itchio/hush/download"
+	"github.com/itchio/lake/pools/fspool"
+	"github.com/itchio/lake/pools/nullpool"
+	"github.com/itchio/savior/filesource"
+	"github.com/itchio/savior/seeksource"
+	"github.com/itchio/wharf/pwr"
+	"github.com/itchio/wharf/pwr/bowl"
+	"github.com/itchio/wharf/pwr/patcher"
+	"github.com/pkg/errors"
+)
+
+type lanCacheFile struct {
+	// Local path of the file
+	Path string
+	// Set if the file was obtained from a peer and
+	// still needs to be verified, then committed.
+	Pending bool
+
+	key string
+}
+
+// fetchFromLANCache makes a build file available locally through the
+// LAN cache: it's either already cached, downloaded from a peer, or
+// downloaded from originURL (and then shared with peers).
+// It returns nil if the LAN cache is disabled or the build file is unknown.
+func fetchFromLANCache(oc *OperationContext, consumer *state.Consumer, build *itchio.Build, fileType itchio.BuildFileType, subType itchio.BuildFileSubType, originURL string) (*lanCacheFile, error) {
+	lc := lancache.Get()
+	if lc == nil || build == nil {
+		return nil, nil
+	}
+
+	// builds don't always come with their files listed (the head build
+	// of an upload doesn't), in which case the size is unknown. Files
+	// from peers are verified against the signature either way.
+	var size int64
+	if bf := FindBuildFile(build.Files, fileType, subType); bf != nil {
+		size = bf.Size
+	} else if len(build.Files) > 0 {
+		return nil, nil
+	}
+
+	key := lancache.BuildFileKey(build.ID, fileType, subType)
+	if path, ok := lc.Lookup(key, size); ok {
+		consumer.Infof("Using %s from LAN cache", key)
+		return &lanCacheFile{Path: path, key: key}, nil
+	}
+
+	sourceURL := originURL
+	fromPeer := false
+	if peerURL, ok := lc.FindPeer(oc.ctx, key, size); ok {
+		consumer.Infof("Downloading %s from LAN peer %s", key, peerURL)
+		sourceURL = peerURL
+		fromPeer = true
+	}
+
+	operationName := "lancache-" + key
+	err := downloadToPath(oc.ctx, consumer, oc.StageFolder(), operationName, sourceURL, lc.IncomingPath(key))
+	if err != nil && fromPeer {
+		consumer.Warnf("Downloading from LAN peer failed, falling back to itch.io: %v", err)
+		lc.Discard(key)
+		fromPeer = false
+		err = downloadToPath(oc.ctx, consumer, oc.StageFolder(), operationName, originURL, lc.IncomingPath(key))
+	}
+	if err != nil {
+		return nil, errors.WithMessage(err, "downloading to LAN cache")
+	}
+
+	if fromPeer {
+		return &lanCacheFile{Path: lc.IncomingPath(key), Pending: true, key: key}, nil
+	}
+
+	// files coming straight from itch.io are trusted
+	path, err := lc.Commit(key)
+	if err != nil {
+		return nil, errors.WithMessage(err, "committing to LAN cache")
+	}
+	return &lanCacheFile{Path: path, key: key}, nil
+}
+
+// downloadToPath downloads sourceURL to destPath, resuming if possible.
+func downloadToPath(ctx context.Context, consumer *state.Consumer, stageFolder string, operationName string, sourceURL string, destPath string) error {
+	file, err := eos.Open(sourceURL, option.WithConsumer(consumer))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer file.Close()
+
+	err = download.DownloadInstallSource(download.DownloadInstallSourceParams{
+		Context:       ctx,
+		Consumer:      consumer,
+		StageFolder:   stageFolder,
+		OperationName: operationName,
+		File:          file,
+		DestPath:      destPath,
+	})
+	if err != nil {
+		return err
+	}
+
+	// the state file would make a later download (say, from another
+	// source, after a failed verification) think it's already done.
+	os.Remove(filepath.Join(stageFolder, fmt.Sprintf("downsource-%s-state.dat", operationName)))
+	return nil
+}
+
+// Verify checks an archive obtained from a peer against the build's
+// signature, then commits it to the cache. Archives that don't match are
+// discarded, and an error is returned.
+func (lcf *lanCacheFile) Verify(oc *OperationContext, consumer *state.Consumer, signatureURL string) error {
+	return lcf.verify(oc, consumer, signatureURL, func(sigInfo *pwr.SignatureInfo) error {
+		vc := &pwr.ValidatorContext{
+			Consumer: consumer,
+			FailFast: true,
+		}
+		return vc.Validate(oc.ctx, lcf.Path, sigInfo)
+	})
+}
+
+// VerifyPatch checks a patch obtained from a peer before it's applied:
+// it's applied to installFolder without writing anything, and its output
+// is checked against the signature of the build it patches to. Patches
+// that don't match are discarded, and an error is returned.
+//
+// An install folder that doesn't match the old build fails this check
+// too, in which case the patch is simply downloaded from itch.io instead.
+func (lcf *lanCacheFile) VerifyPatch(oc *OperationContext, consumer *state.Consumer, signatureURL string, installFolder string) error {
+	return lcf.verify(oc, consumer, signatureURL, func(sigInfo *pwr.SignatureInfo) error {
+		patchSource, err := filesource.Open(lcf.Path, option.WithConsumer(consumer))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		p, err := patcher.New(patchSource, consumer)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		// the patcher closes it
+		targetPool := fspool.New(p.GetTargetContainer(), installFolder)
+
+		sourceContainer := p.GetSourceContainer()
+		bwl, err := bowl.NewPoolBowl(bowl.PoolBowlParams{
+			TargetContainer: p.GetTargetContainer(),
+			SourceContainer: sourceContainer,
+
+			TargetPool: targetPool,
+			OutputPool: &pwr.ValidatingPool{
+				Pool:      nullpool.New(sourceContainer),
+				Container: sourceContainer,
+				Signature: sigInfo,
+			},
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer bwl.Close()
+
+		err = p.Resume(nil, targetPool, bwl)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return bwl.Commit()
+	})
+}
+
+func (lcf *lanCacheFile) verify(oc *OperationContext, consumer *state.Consumer, signatureURL string, check func(sigInfo *pwr.SignatureInfo) error) error {
+	if !lcf.Pending {
+		return nil
+	}
+	lc := lancache.Get()
+
+	err := func() error {
+		signatureFile, err := eos.Open(signatureURL, option.WithConsumer(consumer))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer signatureFile.Close()
+
+		signatureSource := seeksource.FromFile(signatureFile)
+		_, err = signatureSource.Resume(nil)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		sigInfo, err := pwr.ReadSignature(oc.ctx, signatureSource)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		startTime := time.Now()
+		err = check(sigInfo)
+		if err != nil {
+			return err
+		}
+
+		consumer.Infof("✓ Verified %s from LAN peer in %s", lcf.key, united.FormatDuration(time.Since(startTime)))
+		return nil
+	}()
+	if err != nil {
+		lc.Discard(lcf.key)
+		lcf.Pending = false
+		return errors.WithMessage(err, "verifying file obtained from LAN peer")
+	}
+
+	path, err := lc.Commit(lcf.key)
+	if err != nil {
+		return errors.WithMessage(err, "committing to LAN cache")
+	}
+	lcf.Path = path
+	lcf.Pending = false
+	return nil
+}
@@ -19,6 +19,10 @@ type InstallParams struct {
 	NoCave    bool `json:"noCave"`
 	FastQueue bool `json:"fastQueue"`
 
+	// Set when adopting files already in InstallFolder:
+	// always heal, regardless of the receipt.
+	Adopt bool `json:"adopt,omitempty"`
+
 	Game   *itchio.Game   `json:"game"`
 	Upload *itchio.Upload `json:"upload"`
 	Build  *itchio.Build  `json:"build"`
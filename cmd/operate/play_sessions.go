@@ -0,0 +1,50 @@
+package operate
+
+import (
+	"context"
+
+	"github.com/itchio/butler/database/models"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/pkg/errors"
+)
+
+// SyncPlaySession brings the itch.io session corresponding to a local
+// play session up-to-date, creating it first if needed. RemoteSessionID
+// may have been set even if an error is returned, so the play session
+// should be saved either way.
+func SyncPlaySession(ctx context.Context, client *itchio.Client, access *GameAccess, ps *models.CavePlaySession) (*itchio.UserGameInteractionsSummary, error) {
+	if ps.RemoteSessionID == 0 {
+		res, err := client.CreateUserGameSession(ctx, itchio.CreateUserGameSessionParams{
+			GameID:       ps.GameID,
+			UploadID:     ps.UploadID,
+			BuildID:      ps.BuildID,
+			Credentials:  access.Credentials,
+			Platform:     itchio.SessionPlatform(ps.Platform),
+			Architecture: itchio.SessionArchitecture(ps.Architecture),
+
+			SecondsRun: ps.SecondsRun,
+			LastRunAt:  ps.LastRunAt,
+		})
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		ps.RemoteSessionID = res.UserGameSession.ID
+
+		if !ps.Crashed {
+			return res.Summary, nil
+		}
+		// sessions can't be created as crashed, update it right away
+	}
+
+	res, err := client.UpdateUserGameSession(ctx, itchio.UpdateUserGameSessionParams{
+		SessionID: ps.RemoteSessionID,
+
+		SecondsRun: ps.SecondsRun,
+		LastRunAt:  ps.LastRunAt,
+		Crashed:    ps.Crashed,
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return res.Summary, nil
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/itchio/butler/butlerd/messages"
 	"github.com/itchio/butler/cmd/wipe"
 	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/launchlogs"
 	"github.com/itchio/hush"
 	"github.com/itchio/hush/bfs"
 	"github.com/itchio/hush/installers"
@@ -86,6 +87,15 @@ func UninstallPerform(ctx context.Context, rc *butlerd.RequestContext, params bu
 
 	consumer.Infof("Clearing out downloads...")
 	models.DiscardDownloadsByCaveID(conn, cave.ID)
+	models.DeleteLaunchHooksByCaveID(conn, cave.ID)
+
+	if ll := launchlogs.Get(); ll != nil {
+		consumer.Infof("Removing launch logs...")
+		err := ll.RemoveCave(cave.ID)
+		if err != nil {
+			consumer.Warnf("While removing launch logs: %+v", err)
+		}
+	}
 
 	func() {
 		defer func() {
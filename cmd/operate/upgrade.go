@@ -28,6 +28,8 @@ import (
 	"github.com/itchio/wharf/pwr/bowl"
 	"github.com/itchio/wharf/pwr/patcher"
 
+	"github.com/itchio/butler/downloadcache"
+
 	"github.com/pkg/errors"
 )
 
@@ -138,6 +140,47 @@ func applyPatch(oc *OperationContext, meta *MetaSubcontext, isub *InstallSubcont
 		UUID:        istate.DownloadSessionID,
 	})
 
+	var patchCacheKey string
+	var patchSize int64
+	if bf := FindBuildFile(build.Files, itchio.BuildFileTypePatch, subType); bf != nil {
+		patchCacheKey = downloadcache.BuildFileKey(bf)
+		patchSize = bf.Size
+	}
+
+	if path := fromDownloadCache(oc, consumer, patchCacheKey); path != "" {
+		patchURL = path
+	} else {
+		lcf, err := fetchFromLANCache(oc, consumer, build, itchio.BuildFileTypePatch, subType, patchURL)
+		if err != nil {
+			consumer.Warnf("LAN cache unavailable, using itch.io: %+v", err)
+			lcf = nil
+		} else if lcf != nil {
+			signatureURL := client.MakeBuildDownloadURL(itchio.MakeBuildDownloadURLParams{
+				Credentials: params.Access.Credentials,
+				BuildID:     build.ID,
+				Type:        itchio.BuildFileTypeSignature,
+				UUID:        istate.DownloadSessionID,
+			})
+			err = lcf.VerifyPatch(oc, consumer, signatureURL, params.InstallFolder)
+			if err != nil {
+				consumer.Warnf("%+v", err)
+				consumer.Warnf("Using itch.io instead")
+				lcf = nil
+			}
+		}
+
+		if lcf != nil {
+			patchURL = lcf.Path
+		} else {
+			path, err := intoDownloadCache(oc, consumer, patchCacheKey, patchSize, patchURL)
+			if err != nil {
+				consumer.Warnf("Download cache unavailable, using itch.io: %+v", err)
+			} else if path != "" {
+				patchURL = path
+			}
+		}
+	}
+
 	patchSource, err := filesource.Open(patchURL, option.WithConsumer(consumer))
 	if err != nil {
 		return errors.Wrap(err, "opening remote patch")
@@ -51,6 +51,8 @@ func do(mc *mansion.Context) error {
 	}
 	consumer.Debugf("Using database (%s)", mc.DBPath)
 
+	mc.Must(mc.EnableProfileSecrets())
+
 	dbPool, err := sqlitex.Open(mc.DBPath, 0, 1)
 	if err != nil {
 		mc.Must(errors.WithMessage(err, "opening DB for the first time"))
@@ -98,7 +100,7 @@ func do(mc *mansion.Context) error {
 
 	rateLimited := 0
 	allowedViolations := burst
-	client := mc.NewClient(profile.APIKey)
+	client := mc.NewClient(profile.MustGetAPIKey())
 	client.Limiter = rate.NewLimiter(limit, burst)
 	client.OnRateLimited(func(req *http.Request, res *http.Response) {
 		fmt.Fprintf(os.Stderr, "!")
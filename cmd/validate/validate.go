@@ -21,6 +21,7 @@ import (
 	"github.com/itchio/butler/endpoints/launch"
 	"github.com/itchio/butler/mansion"
 	"github.com/itchio/butler/redist"
+	"github.com/itchio/butler/sandbox"
 	"github.com/itchio/hush/manifest"
 
 	"github.com/itchio/httpkit/eos"
@@ -166,6 +167,9 @@ func Validate(consumer *state.Consumer) error {
 	}
 	consumer.Debugf("Intermediate:\n%s", string(jsonIntermediate))
 
+	// not part of the manifest structure, validated separately
+	delete(intermediate, "sandbox")
+
 	appManifest := &manifest.Manifest{}
 	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
 		Result:      appManifest,
@@ -259,6 +263,20 @@ func Validate(consumer *state.Consumer) error {
 	}
 
 	consumer.Infof("")
+	sandboxPolicy, err := sandbox.ReadManifestPolicy(manifestPath)
+	if err != nil {
+		showError("Invalid sandbox policy: %s", err.Error())
+	} else if sandboxPolicy != nil {
+		consumer.Statf("Sandbox policy found")
+		backend := sandboxPolicy.Backend
+		if backend == "" {
+			backend = butlerd.SandboxBackendFirejail
+		}
+		consumer.Infof("    Backend: %s", backend)
+		consumer.Infof("    Network: %v, GPU: %v, Audio: %v", !sandboxPolicy.DenyNetwork, sandboxPolicy.GPU, sandboxPolicy.Audio)
+		consumer.Infof("")
+	}
+
 	if len(appManifest.Prereqs) > 0 {
 		consumer.Statf("Validating %d prereqs...", len(appManifest.Prereqs))
 		consumer.Infof("")
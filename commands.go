@@ -7,6 +7,7 @@ import (
 	"github.com/itchio/butler/cmd/configure"
 	"github.com/itchio/butler/cmd/cp"
 	"github.com/itchio/butler/cmd/daemon"
+	"github.com/itchio/butler/cmd/db"
 	"github.com/itchio/butler/cmd/diag"
 	"github.com/itchio/butler/cmd/diff"
 	"github.com/itchio/butler/cmd/ditto"
@@ -19,6 +20,8 @@ import (
 	"github.com/itchio/butler/cmd/file"
 	"github.com/itchio/butler/cmd/fujicmd"
 	"github.com/itchio/butler/cmd/heal"
+	"github.com/itchio/butler/cmd/identity"
+	"github.com/itchio/butler/cmd/library"
 	"github.com/itchio/butler/cmd/login"
 	"github.com/itchio/butler/cmd/logout"
 	"github.com/itchio/butler/cmd/ls"
@@ -57,6 +60,7 @@ func registerCommands(ctx *mansion.Context) {
 
 	login.Register(ctx)
 	logout.Register(ctx)
+	identity.Register(ctx)
 
 	push.Register(ctx)
 	fetch.Register(ctx)
@@ -109,6 +113,8 @@ func registerCommands(ctx *mansion.Context) {
 	configure.Register(ctx)
 
 	daemon.Register(ctx)
+	db.Register(ctx)
+	library.Register(ctx)
 
 	fujicmd.Register(ctx)
 	validate.Register(ctx)
@@ -0,0 +1,215 @@
+// Package backup copies butler's database while it's in use, using
+// the SQLite backup API, and keeps a few automatic snapshots around.
+package backup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/itchio/headway/state"
+	"github.com/pkg/errors"
+)
+
+// MaxSnapshots is how many automatic snapshots are kept,
+// older ones are removed when taking a new one.
+const MaxSnapshots = 5
+
+// SnapshotsDirName is the folder, next to the database, where
+// automatic snapshots are stored.
+const SnapshotsDirName = "snapshots"
+
+// Backup copies the database of conn to dstPath. The copy is written
+// next to dstPath first, so an interrupted backup never leaves a
+// truncated file behind.
+func Backup(conn *sqlite.Conn, dstPath string) error {
+	tmpPath := dstPath + ".tmp"
+	os.Remove(tmpPath)
+
+	dst, err := conn.BackupToDB("", tmpPath)
+	if err != nil {
+		os.Remove(tmpPath)
+		return errors.WithMessage(err, "backing up database")
+	}
+
+	// keep backups self-contained, so opening one never
+	// leaves -wal and -shm files around
+	err = sqlitex.ExecTransient(dst, "PRAGMA journal_mode=DELETE", nil)
+	if err != nil {
+		dst.Close()
+		os.Remove(tmpPath)
+		return errors.WithStack(err)
+	}
+
+	err = dst.Close()
+	if err != nil {
+		os.Remove(tmpPath)
+		return errors.WithStack(err)
+	}
+
+	err = os.Rename(tmpPath, dstPath)
+	if err != nil {
+		os.Remove(tmpPath)
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// Restore replaces the database of conn with the contents of srcPath.
+// The source is checked for integrity first.
+func Restore(conn *sqlite.Conn, srcPath string) error {
+	if _, err := os.Stat(srcPath); err != nil {
+		return errors.WithStack(err)
+	}
+
+	src, err := sqlite.OpenConn(srcPath, sqlite.SQLITE_OPEN_READONLY)
+	if err != nil {
+		return errors.WithMessage(err, "opening backup")
+	}
+	defer src.Close()
+
+	problems, err := Check(src)
+	if err != nil {
+		return errors.WithMessage(err, "checking backup")
+	}
+	if len(problems) > 0 {
+		return errors.Errorf("backup (%s) is corrupted: %s", srcPath, problems[0])
+	}
+
+	b, err := src.BackupInit("", "", conn)
+	if err != nil {
+		return errors.WithMessage(err, "restoring backup")
+	}
+	err = b.Step(-1)
+	finishErr := b.Finish()
+	if err != nil {
+		return errors.WithMessage(err, "restoring backup")
+	}
+	if finishErr != nil {
+		return errors.WithMessage(finishErr, "restoring backup")
+	}
+	return nil
+}
+
+// Check runs SQLite's integrity check and returns the problems found,
+// if any.
+func Check(conn *sqlite.Conn) ([]string, error) {
+	var problems []string
+	err := sqlitex.ExecTransient(conn, "PRAGMA integrity_check", func(stmt *sqlite.Stmt) error {
+		if msg := stmt.ColumnText(0); msg != "ok" {
+			problems = append(problems, msg)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return problems, nil
+}
+
+// Vacuum rebuilds the database file, reclaiming unused space.
+func Vacuum(conn *sqlite.Conn) error {
+	return errors.WithStack(sqlitex.ExecTransient(conn, "VACUUM", nil))
+}
+
+// Path returns the file the database of conn is stored in,
+// or an empty string for in-memory databases.
+func Path(conn *sqlite.Conn) (string, error) {
+	var path string
+	err := sqlitex.ExecTransient(conn, "PRAGMA database_list", func(stmt *sqlite.Stmt) error {
+		if stmt.GetText("name") == "main" {
+			path = stmt.GetText("file")
+		}
+		return nil
+	})
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return path, nil
+}
+
+// Snapshot backs up the database of conn to the snapshots folder,
+// then removes the oldest snapshots so at most MaxSnapshots are kept.
+// It returns the path of the snapshot, or an empty string if the
+// database is in memory.
+func Snapshot(consumer *state.Consumer, conn *sqlite.Conn, reason string) (string, error) {
+	dbPath, err := Path(conn)
+	if err != nil {
+		return "", err
+	}
+	if dbPath == "" {
+		return "", nil
+	}
+
+	dir := SnapshotsDir(dbPath)
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	name := fmt.Sprintf("%s-%s.db", time.Now().UTC().Format("20060102-150405"), reason)
+	snapshotPath := filepath.Join(dir, name)
+	consumer.Infof("Snapshotting database to (%s)", snapshotPath)
+	err = Backup(conn, snapshotPath)
+	if err != nil {
+		return "", err
+	}
+
+	rotate(consumer, dir)
+	return snapshotPath, nil
+}
+
+// SnapshotsDir returns where the automatic snapshots of a database are stored
+func SnapshotsDir(dbPath string) string {
+	return filepath.Join(filepath.Dir(dbPath), SnapshotsDirName)
+}
+
+// ListSnapshots returns the paths of the automatic snapshots
+// of a database, oldest first.
+func ListSnapshots(dbPath string) ([]string, error) {
+	return listSnapshotsIn(SnapshotsDir(dbPath))
+}
+
+func listSnapshotsIn(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".db") {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, entry.Name()))
+	}
+	// names start with a timestamp
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func rotate(consumer *state.Consumer, dir string) {
+	paths, err := listSnapshotsIn(dir)
+	if err != nil {
+		consumer.Warnf("Could not list snapshots: %v", err)
+		return
+	}
+
+	for len(paths) > MaxSnapshots {
+		consumer.Debugf("Removing old snapshot (%s)", paths[0])
+		err := os.Remove(paths[0])
+		if err != nil {
+			consumer.Warnf("Could not remove old snapshot: %v", err)
+		}
+		paths = paths[1:]
+	}
+}
@@ -0,0 +1,144 @@
+package backup_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/itchio/butler/database/backup"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func openDB(t *testing.T, path string) *sqlite.Conn {
+	conn, err := sqlite.OpenConn(path, 0)
+	wtest.Must(t, err)
+	return conn
+}
+
+func countRows(t *testing.T, conn *sqlite.Conn) int64 {
+	var count int64
+	wtest.Must(t, sqlitex.ExecTransient(conn, "SELECT COUNT(*) FROM things", func(stmt *sqlite.Stmt) error {
+		count = stmt.ColumnInt64(0)
+		return nil
+	}))
+	return count
+}
+
+func Test_BackupRestore(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "backup-test")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	conn := openDB(t, filepath.Join(dir, "butler.db"))
+	defer conn.Close()
+
+	wtest.Must(t, sqlitex.ExecScript(conn, "PRAGMA journal_mode=WAL; CREATE TABLE things (id INTEGER PRIMARY KEY, name TEXT);"))
+	for i := 0; i < 100; i++ {
+		wtest.Must(t, sqlitex.Exec(conn, "INSERT INTO things (name) VALUES (?)", nil, fmt.Sprintf("thing %d", i)))
+	}
+
+	problems, err := backup.Check(conn)
+	wtest.Must(t, err)
+	assert.Empty(problems)
+
+	backupPath := filepath.Join(dir, "backup.db")
+	wtest.Must(t, backup.Backup(conn, backupPath))
+
+	// backups stand on their own, and nothing is left next to them
+	_, err = os.Stat(backupPath + ".tmp")
+	assert.True(os.IsNotExist(err))
+	_, err = os.Stat(backupPath + "-wal")
+	assert.True(os.IsNotExist(err))
+	func() {
+		backupConn := openDB(t, backupPath)
+		defer backupConn.Close()
+		assert.EqualValues(100, countRows(t, backupConn))
+	}()
+
+	wtest.Must(t, sqlitex.Exec(conn, "DELETE FROM things WHERE id > 10", nil))
+	assert.EqualValues(10, countRows(t, conn))
+
+	wtest.Must(t, backup.Restore(conn, backupPath))
+	assert.EqualValues(100, countRows(t, conn))
+
+	assert.Error(backup.Restore(conn, filepath.Join(dir, "missing.db")))
+
+	// corrupted backups are refused, and leave the database alone
+	contents, err := ioutil.ReadFile(backupPath)
+	wtest.Must(t, err)
+	for i := 1024; i < len(contents); i++ {
+		contents[i] = 0x42
+	}
+	corruptedPath := filepath.Join(dir, "corrupted.db")
+	wtest.Must(t, ioutil.WriteFile(corruptedPath, contents, 0o644))
+
+	wtest.Must(t, sqlitex.Exec(conn, "DELETE FROM things WHERE id > 50", nil))
+	assert.Error(backup.Restore(conn, corruptedPath))
+	assert.EqualValues(50, countRows(t, conn))
+}
+
+func Test_Snapshot(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "backup-test")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	consumer := &state.Consumer{
+		OnMessage: func(lvl string, msg string) { t.Logf("[%s] %s", lvl, msg) },
+	}
+
+	dbPath := filepath.Join(dir, "db", "butler.db")
+	wtest.Must(t, os.MkdirAll(filepath.Dir(dbPath), 0o755))
+	conn := openDB(t, dbPath)
+	defer conn.Close()
+	wtest.Must(t, sqlitex.ExecScript(conn, "CREATE TABLE things (id INTEGER PRIMARY KEY);"))
+
+	snapshots, err := backup.ListSnapshots(dbPath)
+	wtest.Must(t, err)
+	assert.Empty(snapshots)
+
+	// older snapshots, and something that isn't one
+	snapshotsDir := backup.SnapshotsDir(dbPath)
+	wtest.Must(t, os.MkdirAll(snapshotsDir, 0o755))
+	var oldNames []string
+	for i := 0; i < backup.MaxSnapshots+1; i++ {
+		name := fmt.Sprintf("2019010%d-120000-test.db", i+1)
+		oldNames = append(oldNames, name)
+		wtest.Must(t, ioutil.WriteFile(filepath.Join(snapshotsDir, name), nil, 0o644))
+	}
+	wtest.Must(t, ioutil.WriteFile(filepath.Join(snapshotsDir, "notes.txt"), nil, 0o644))
+
+	snapshotPath, err := backup.Snapshot(consumer, conn, "pre-test")
+	wtest.Must(t, err)
+	assert.EqualValues(snapshotsDir, filepath.Dir(snapshotPath))
+	assert.Contains(filepath.Base(snapshotPath), "pre-test")
+
+	// only the newest are kept, oldest first
+	snapshots, err = backup.ListSnapshots(dbPath)
+	wtest.Must(t, err)
+	var expected []string
+	for _, name := range oldNames[len(oldNames)-(backup.MaxSnapshots-1):] {
+		expected = append(expected, filepath.Join(snapshotsDir, name))
+	}
+	expected = append(expected, snapshotPath)
+	assert.EqualValues(expected, snapshots)
+
+	_, err = os.Stat(filepath.Join(snapshotsDir, "notes.txt"))
+	assert.NoError(err)
+
+	// in-memory databases have nowhere to be snapshotted
+	memConn := openDB(t, ":memory:")
+	defer memConn.Close()
+	snapshotPath, err = backup.Snapshot(consumer, memConn, "pre-test")
+	wtest.Must(t, err)
+	assert.EqualValues("", snapshotPath)
+}
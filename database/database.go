@@ -1,24 +1,73 @@
 package database
 
 import (
+	"fmt"
+
 	"crawshaw.io/sqlite"
 	"github.com/itchio/butler/butlerd/horror"
+	"github.com/itchio/butler/database/backup"
 	"github.com/itchio/butler/database/models"
 	"github.com/itchio/butler/database/models/migrations"
 	"github.com/itchio/headway/state"
 	"github.com/pkg/errors"
 )
 
+type PrepareOptions struct {
+	// The database file didn't exist before
+	JustCreated bool
+	// Open databases written by a newer version of butler anyway.
+	// Columns and tables we don't know about may be lost.
+	ForceSchema bool
+}
+
 // Prepare synchronizes schemas, runs migrations etc.
-func Prepare(consumer *state.Consumer, conn *sqlite.Conn, justCreated bool) (retErr error) {
+func Prepare(consumer *state.Consumer, conn *sqlite.Conn, opts PrepareOptions) (retErr error) {
 	defer horror.RecoverInto(&retErr)
 
+	forced := false
+	if !opts.JustCreated {
+		err := migrations.CheckSchemaVersion(conn)
+		if err != nil {
+			if !opts.ForceSchema {
+				return err
+			}
+			forced = true
+			consumer.Warnf("%v", err)
+			consumer.Warnf("Opening it anyway, as requested")
+			_, err = backup.Snapshot(consumer, conn, "pre-force-schema")
+			if err != nil {
+				return errors.WithMessage(err, "snapshotting database before forcing schema")
+			}
+		}
+	}
+
+	if !opts.JustCreated && !forced {
+		version, err := migrations.StoredSchemaVersion(conn)
+		if err != nil {
+			return err
+		}
+		if version < migrations.LatestSchemaVersion() {
+			// synchronizing the schema changes the database too, so this
+			// must happen first for the snapshot to be usable by the
+			// version of butler that wrote it
+			_, err := backup.Snapshot(consumer, conn, fmt.Sprintf("pre-migration-%d", version))
+			if err != nil {
+				consumer.Warnf("Could not snapshot database before migrating: %+v", err)
+			}
+		}
+	}
+
 	err := models.HadesContext().AutoMigrate(conn)
 	if err != nil {
 		return errors.WithMessage(err, "performing automatic DB migration")
 	}
 
-	if justCreated {
+	err = models.PrepareGameSearch(conn)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if opts.JustCreated || forced {
 		models.SetSchemaVersion(conn, migrations.LatestSchemaVersion())
 	} else {
 		err := migrations.Do(consumer, conn)
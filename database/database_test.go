@@ -0,0 +1,87 @@
+package database_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/itchio/butler/database"
+	"github.com/itchio/butler/database/backup"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/database/models/migrations"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PrepareSnapshotsBeforeMigrating(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "database-test")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	consumer := &state.Consumer{
+		OnMessage: func(lvl string, msg string) { t.Logf("[%s] %s", lvl, msg) },
+	}
+	dbPath := filepath.Join(dir, "butler.db")
+
+	const oldVersion = 1542741863
+	func() {
+		conn, err := sqlite.OpenConn(dbPath, 0)
+		wtest.Must(t, err)
+		defer conn.Close()
+		wtest.Must(t, database.Prepare(consumer, conn, database.PrepareOptions{JustCreated: true}))
+
+		// as written by an older butler, with a column we no longer have
+		models.SetSchemaVersion(conn, oldVersion)
+		wtest.Must(t, sqlitex.ExecScript(conn, "ALTER TABLE caves ADD COLUMN old_column TEXT;"))
+	}()
+
+	hasOldColumn := func(conn *sqlite.Conn) bool {
+		found := false
+		wtest.Must(t, sqlitex.ExecTransient(conn, "PRAGMA table_info(caves)", func(stmt *sqlite.Stmt) error {
+			if stmt.GetText("name") == "old_column" {
+				found = true
+			}
+			return nil
+		}))
+		return found
+	}
+
+	func() {
+		conn, err := sqlite.OpenConn(dbPath, 0)
+		wtest.Must(t, err)
+		defer conn.Close()
+		wtest.Must(t, database.Prepare(consumer, conn, database.PrepareOptions{}))
+		assert.False(hasOldColumn(conn))
+		assert.EqualValues(migrations.LatestSchemaVersion(), models.GetSchemaVersion(conn))
+	}()
+
+	snapshots, err := backup.ListSnapshots(dbPath)
+	wtest.Must(t, err)
+	if assert.Len(snapshots, 1) {
+		assert.Contains(filepath.Base(snapshots[0]), "pre-migration-1542741863")
+
+		// the snapshot is from before the schema was synchronized
+		conn, err := sqlite.OpenConn(snapshots[0], 0)
+		wtest.Must(t, err)
+		defer conn.Close()
+		assert.True(hasOldColumn(conn))
+		assert.EqualValues(oldVersion, models.GetSchemaVersion(conn))
+	}
+
+	// up to date databases aren't snapshotted again
+	func() {
+		conn, err := sqlite.OpenConn(dbPath, 0)
+		wtest.Must(t, err)
+		defer conn.Close()
+		wtest.Must(t, database.Prepare(consumer, conn, database.PrepareOptions{}))
+	}()
+	snapshots, err = backup.ListSnapshots(dbPath)
+	wtest.Must(t, err)
+	assert.Len(snapshots, 1)
+}
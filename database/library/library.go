@@ -0,0 +1,274 @@
+// Package library exports what butler knows about a library to
+// versioned JSON, and merges such exports back into a database.
+package library
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/itchio/butler/buildinfo"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/horror"
+	"github.com/itchio/butler/database/models"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/hades"
+	"github.com/itchio/headway/state"
+	"github.com/pkg/errors"
+	"xorm.io/builder"
+)
+
+// FormatVersion is bumped whenever exports change in a way
+// older versions of butler can't read.
+const FormatVersion = 1
+
+// Library is the contents of an export
+type Library struct {
+	// See FormatVersion
+	Version int64 `json:"version"`
+	// Version of butler that wrote the export
+	ButlerVersion string    `json:"butlerVersion"`
+	ExportedAt    time.Time `json:"exportedAt"`
+
+	InstallLocations []*models.InstallLocation `json:"installLocations"`
+	// Caves, along with their game, upload and build
+	Caves []*models.Cave `json:"caves"`
+	// Download keys owned by any of the profiles, along with their game
+	DownloadKeys []*itchio.DownloadKey `json:"downloadKeys"`
+	// Collections of the profiles, along with their games
+	ProfileCollections []*models.ProfileCollection      `json:"profileCollections"`
+	PlayTimes          []*models.CaveHistoricalPlayTime `json:"playTimes"`
+}
+
+// Counts returns how many records of each kind lib has
+func (lib *Library) Counts() *butlerd.LibraryCounts {
+	return &butlerd.LibraryCounts{
+		InstallLocations:   int64(len(lib.InstallLocations)),
+		Caves:              int64(len(lib.Caves)),
+		DownloadKeys:       int64(len(lib.DownloadKeys)),
+		ProfileCollections: int64(len(lib.ProfileCollections)),
+		PlayTimes:          int64(len(lib.PlayTimes)),
+	}
+}
+
+// Export reads the library from the database
+func Export(conn *sqlite.Conn) (lib *Library, retErr error) {
+	defer horror.RecoverInto(&retErr)
+
+	lib = &Library{
+		Version:       FormatVersion,
+		ButlerVersion: buildinfo.Version,
+		ExportedAt:    time.Now().UTC(),
+	}
+
+	models.MustSelect(conn, &lib.InstallLocations, builder.NewCond(), hades.Search{})
+
+	models.MustSelect(conn, &lib.Caves, builder.NewCond(), hades.Search{})
+	models.MustPreload(conn, lib.Caves,
+		hades.Assoc("Game"),
+		hades.Assoc("Upload"),
+		hades.Assoc("Build"),
+	)
+
+	models.MustSelect(conn, &lib.DownloadKeys,
+		builder.In("owner_id", builder.Select("id").From("profiles")),
+		hades.Search{},
+	)
+	models.MustPreload(conn, lib.DownloadKeys, hades.Assoc("Game"))
+
+	models.MustSelect(conn, &lib.ProfileCollections, builder.NewCond(), hades.Search{})
+	models.MustPreload(conn, lib.ProfileCollections,
+		hades.Assoc("Collection",
+			hades.AssocWithSearch("CollectionGames", hades.Search{}.OrderBy("position ASC"),
+				hades.Assoc("Game"),
+			),
+		),
+	)
+
+	models.MustSelect(conn, &lib.PlayTimes, builder.NewCond(), hades.Search{})
+
+	return lib, nil
+}
+
+// Write saves lib to path as JSON, replacing it if it exists
+func Write(lib *Library, path string) error {
+	payload, err := json.MarshalIndent(lib, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	tmpPath := path + ".tmp"
+	err = ioutil.WriteFile(tmpPath, payload, 0o644)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = os.Rename(tmpPath, path)
+	if err != nil {
+		os.Remove(tmpPath)
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// Read loads an export, refusing ones written in a newer format
+func Read(path string) (*Library, error) {
+	payload, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	lib := &Library{}
+	err = json.Unmarshal(payload, lib)
+	if err != nil {
+		return nil, errors.WithMessage(err, "parsing library export")
+	}
+
+	if lib.Version == 0 {
+		return nil, errors.Errorf("(%s) is not a library export", path)
+	}
+	if lib.Version > FormatVersion {
+		return nil, errors.Errorf("(%s) is in format version %d, written by butler %s, but this version of butler only reads up to %d",
+			path, lib.Version, lib.ButlerVersion, FormatVersion)
+	}
+	return lib, nil
+}
+
+type importer struct {
+	consumer *state.Consumer
+	conn     *sqlite.Conn
+	replace  bool
+
+	imported *butlerd.LibraryCounts
+	skipped  *butlerd.LibraryCounts
+
+	// imported install location ID => local install location ID
+	locationIDs map[string]string
+}
+
+// Import merges lib into the database. Records with the same ID as an
+// existing one are handled according to policy. Either everything is
+// imported, or nothing is.
+func Import(consumer *state.Consumer, conn *sqlite.Conn, lib *Library, policy butlerd.LibraryConflictPolicy) (imported *butlerd.LibraryCounts, skipped *butlerd.LibraryCounts, retErr error) {
+	defer horror.RecoverInto(&retErr)
+	defer sqlitex.Save(conn)(&retErr)
+
+	im := &importer{
+		consumer:    consumer,
+		conn:        conn,
+		replace:     policy == butlerd.LibraryConflictPolicyReplace,
+		imported:    &butlerd.LibraryCounts{},
+		skipped:     &butlerd.LibraryCounts{},
+		locationIDs: make(map[string]string),
+	}
+
+	for _, il := range lib.InstallLocations {
+		im.importInstallLocation(il)
+	}
+	for _, cave := range lib.Caves {
+		im.importCave(cave)
+	}
+	for _, dk := range lib.DownloadKeys {
+		if im.keep(&itchio.DownloadKey{}, builder.Eq{"id": dk.ID}) {
+			im.skipped.DownloadKeys++
+			continue
+		}
+		models.MustSave(conn, dk, hades.Assoc("Game"))
+		im.imported.DownloadKeys++
+	}
+	for _, pc := range lib.ProfileCollections {
+		pc.Profile = nil
+		if im.keep(&models.ProfileCollection{}, builder.Eq{"profile_id": pc.ProfileID, "collection_id": pc.CollectionID}) {
+			im.skipped.ProfileCollections++
+			continue
+		}
+		models.MustSave(conn, pc,
+			hades.Assoc("Collection",
+				hades.Assoc("CollectionGames",
+					hades.Assoc("Game"),
+				),
+			),
+		)
+		im.imported.ProfileCollections++
+	}
+	for _, pt := range lib.PlayTimes {
+		if im.keep(&models.CaveHistoricalPlayTime{}, builder.Eq{"cave_id": pt.CaveID}) {
+			im.skipped.PlayTimes++
+			continue
+		}
+		models.MustSave(conn, pt)
+		im.imported.PlayTimes++
+	}
+
+	return im.imported, im.skipped, nil
+}
+
+// keep returns true if a record matching cond exists and
+// shouldn't be replaced
+func (im *importer) keep(model interface{}, cond builder.Cond) bool {
+	if im.replace {
+		return false
+	}
+	return models.MustCount(im.conn, model, cond) > 0
+}
+
+func (im *importer) importInstallLocation(il *models.InstallLocation) {
+	consumer := im.consumer
+	il.Caves = nil
+
+	if existing := models.InstallLocationByID(im.conn, il.ID); existing != nil {
+		im.locationIDs[il.ID] = il.ID
+		if !im.replace {
+			im.skipped.InstallLocations++
+			return
+		}
+	} else {
+		var samePath models.InstallLocation
+		if models.MustSelectOne(im.conn, &samePath, builder.Eq{"path": il.Path}) {
+			consumer.Infof("Merging install location (%s) into (%s), they're both at (%s)", il.ID, samePath.ID, il.Path)
+			im.locationIDs[il.ID] = samePath.ID
+			im.skipped.InstallLocations++
+			return
+		}
+		im.locationIDs[il.ID] = il.ID
+	}
+
+	if _, err := os.Stat(il.Path); err != nil {
+		consumer.Warnf("Install location (%s) doesn't exist on this machine, importing it anyway", il.Path)
+	}
+	models.MustSave(im.conn, il)
+	im.imported.InstallLocations++
+}
+
+func (im *importer) importCave(cave *models.Cave) {
+	consumer := im.consumer
+	cave.InstallLocation = nil
+	if id, ok := im.locationIDs[cave.InstallLocationID]; ok {
+		cave.InstallLocationID = id
+	}
+
+	if im.keep(&models.Cave{}, builder.Eq{"id": cave.ID}) {
+		im.skipped.Caves++
+		return
+	}
+
+	if cave.InstallLocationID != "" {
+		// two caves can't share an install folder, whatever the policy
+		var other models.Cave
+		if models.MustSelectOne(im.conn, &other, builder.And(
+			builder.Eq{"install_location_id": cave.InstallLocationID},
+			builder.Eq{"install_folder_name": cave.InstallFolderName},
+			builder.Neq{"id": cave.ID},
+		)) {
+			consumer.Warnf("Skipping cave (%s), cave (%s) is already installed in (%s)", cave.ID, other.ID, cave.InstallFolderName)
+			im.skipped.Caves++
+			return
+		}
+	}
+
+	cave.SaveWithAssocs(im.conn)
+	im.imported.Caves++
+}
@@ -0,0 +1,135 @@
+package library_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/database"
+	"github.com/itchio/butler/database/library"
+	"github.com/itchio/butler/database/models"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/hades"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+	"xorm.io/builder"
+)
+
+func openTestDB(t *testing.T, consumer *state.Consumer) *sqlite.Conn {
+	conn, err := sqlite.OpenConn(":memory:", 0)
+	wtest.Must(t, err)
+	wtest.Must(t, database.Prepare(consumer, conn, database.PrepareOptions{JustCreated: true}))
+	return conn
+}
+
+func Test_ExportImport(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "library-test")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	consumer := &state.Consumer{
+		OnMessage: func(lvl string, msg string) { t.Logf("[%s] %s", lvl, msg) },
+	}
+
+	src := openTestDB(t, consumer)
+	defer src.Close()
+
+	now := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+	models.MustSave(src, &models.Profile{ID: 1, APIKey: "secret-key"})
+	models.MustSave(src, &models.InstallLocation{ID: "loc-1", Path: dir})
+	cave := &models.Cave{
+		ID:                "cave-1",
+		GameID:            10,
+		Game:              &itchio.Game{ID: 10, Title: "Installed Game"},
+		UploadID:          100,
+		Upload:            &itchio.Upload{ID: 100, Filename: "game.zip"},
+		InstalledAt:       &now,
+		InstallLocationID: "loc-1",
+		InstallFolderName: "installed-game",
+	}
+	cave.SaveWithAssocs(src)
+	models.MustSave(src, &itchio.DownloadKey{
+		ID:      1000,
+		GameID:  11,
+		Game:    &itchio.Game{ID: 11, Title: "Owned Game"},
+		OwnerID: 1,
+	}, hades.Assoc("Game"))
+	models.MustSave(src, &models.ProfileCollection{
+		ProfileID:    1,
+		CollectionID: 50,
+		Collection:   &itchio.Collection{ID: 50, Title: "Favorites"},
+		Position:     0,
+	}, hades.Assoc("Collection"))
+	models.MustSave(src, &itchio.CollectionGame{CollectionID: 50, GameID: 12, Position: 2, Game: &itchio.Game{ID: 12, Title: "Second"}}, hades.Assoc("Game"))
+	models.MustSave(src, &itchio.CollectionGame{CollectionID: 50, GameID: 13, Position: 1, Game: &itchio.Game{ID: 13, Title: "First"}}, hades.Assoc("Game"))
+	models.MustSave(src, &models.CaveHistoricalPlayTime{CaveID: "cave-1", GameID: 10, SecondsRun: 3600, LastTouchedAt: &now})
+
+	lib, err := library.Export(src)
+	wtest.Must(t, err)
+	expectedCounts := &butlerd.LibraryCounts{
+		InstallLocations:   1,
+		Caves:              1,
+		DownloadKeys:       1,
+		ProfileCollections: 1,
+		PlayTimes:          1,
+	}
+	assert.EqualValues(expectedCounts, lib.Counts())
+
+	exportPath := filepath.Join(dir, "library.json")
+	wtest.Must(t, library.Write(lib, exportPath))
+	payload, err := ioutil.ReadFile(exportPath)
+	wtest.Must(t, err)
+	assert.NotContains(string(payload), "secret-key")
+
+	lib, err = library.Read(exportPath)
+	wtest.Must(t, err)
+
+	dst := openTestDB(t, consumer)
+	defer dst.Close()
+
+	imported, skipped, err := library.Import(consumer, dst, lib, butlerd.LibraryConflictPolicySkip)
+	wtest.Must(t, err)
+	assert.EqualValues(expectedCounts, imported)
+	assert.EqualValues(&butlerd.LibraryCounts{}, skipped)
+
+	importedCave := models.CaveByID(dst, "cave-1")
+	if assert.NotNil(importedCave) {
+		importedCave.Preload(dst)
+		assert.EqualValues("Installed Game", importedCave.Game.Title)
+		assert.EqualValues("game.zip", importedCave.Upload.Filename)
+		assert.EqualValues(filepath.Join(dir, "installed-game"), importedCave.GetInstallFolder(dst))
+	}
+	assert.EqualValues(1, models.MustCount(dst, &itchio.DownloadKey{}, builder.Eq{"id": 1000, "owner_id": 1}))
+	assert.NotNil(models.GameByID(dst, 11))
+	assert.EqualValues(1, models.MustCount(dst, &models.CaveHistoricalPlayTime{}, builder.Eq{"cave_id": "cave-1", "seconds_run": 3600}))
+
+	// collections come with their games, in order
+	collection := models.CollectionByID(dst, 50)
+	if assert.NotNil(collection) {
+		assert.EqualValues("Favorites", collection.Title)
+		models.CollectionExt(collection).PreloadCollectionGames(dst)
+		var titles []string
+		for _, cg := range collection.CollectionGames {
+			titles = append(titles, cg.Game.Title)
+		}
+		assert.EqualValues([]string{"First", "Second"}, titles)
+	}
+
+	// importing again changes nothing, unless asked to
+	imported, skipped, err = library.Import(consumer, dst, lib, butlerd.LibraryConflictPolicySkip)
+	wtest.Must(t, err)
+	assert.EqualValues(&butlerd.LibraryCounts{}, imported)
+	assert.EqualValues(expectedCounts, skipped)
+
+	imported, _, err = library.Import(consumer, dst, lib, butlerd.LibraryConflictPolicyReplace)
+	wtest.Must(t, err)
+	assert.EqualValues(expectedCounts, imported)
+	assert.EqualValues(2, models.MustCount(dst, &itchio.CollectionGame{}, builder.Eq{"collection_id": 50}))
+}
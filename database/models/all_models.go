@@ -26,4 +26,10 @@ var AllModels = []interface{}{
 	&FetchInfo{},
 	&GameUpload{},
 	&CaveHistoricalPlayTime{},
+	&DownloadHistoryItem{},
+	&DownloadCacheEntry{},
+	&WrapperProfile{},
+	&CaveCrash{},
+	&CavePlaySession{},
+	&LaunchHook{},
 }
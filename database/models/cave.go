@@ -32,6 +32,10 @@ type Cave struct {
 
 	SnoozedAt *time.Time `json:"snoozedAt"`
 
+	// What background update checks do with updates: notify (or empty),
+	// download or install
+	UpdatePolicy string `json:"updatePolicy"`
+
 	Verdict       JSON  `json:"verdict"`
 	InstalledSize int64 `json:"installedSize"`
 
@@ -43,6 +47,15 @@ type Cave struct {
 	// If set, InstallLocationID is empty and this is used
 	// for all operations instead
 	CustomInstallFolder string `json:"customInstallFolder"`
+
+	// If set, the WrapperProfile used to launch this cave
+	WrapperProfileID string `json:"wrapperProfileId"`
+
+	// JSON-encoded butlerd.CaveLaunchConfig, minus WrapperProfileID
+	LaunchConfig JSON `json:"launchConfig"`
+
+	// Linux package format of the last launched target, if any
+	PackageFormat string `json:"packageFormat"`
 }
 
 func (c *Cave) SetVerdict(verdict *dash.Verdict) {
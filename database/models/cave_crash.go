@@ -0,0 +1,68 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/hades"
+	"xorm.io/builder"
+)
+
+// CaveCrash records a launch session that looked like a crash:
+// see recordCrash in endpoints/launch for what counts as one.
+type CaveCrash struct {
+	// An UUID
+	ID string `json:"id" hades:"primary_key"`
+
+	CaveID   string `json:"caveId"`
+	GameID   int64  `json:"gameId"`
+	UploadID int64  `json:"uploadId"`
+	BuildID  int64  `json:"buildId"`
+
+	ExitCode int64 `json:"exitCode"`
+	// Name of the signal that terminated the process, if any
+	Signal string `json:"signal"`
+	// How long the game ran before crashing
+	SecondsRun float64 `json:"secondsRun"`
+
+	// JSON-encoded lists of the last lines of output
+	Stdout JSON `json:"stdout"`
+	Stderr JSON `json:"stderr"`
+	// JSON-encoded list of paths of core dumps / crash dumps
+	DumpFiles JSON `json:"dumpFiles"`
+
+	// Session whose full output is in the launch logs, if enabled
+	LaunchLogSessionID string `json:"launchLogSessionId"`
+
+	CreatedAt *time.Time `json:"createdAt"`
+}
+
+func (cc *CaveCrash) Save(conn *sqlite.Conn) {
+	MustSave(conn, cc)
+}
+
+// CaveCrashesNewestFirst returns the crashes matching cond,
+// most recent first
+func CaveCrashesNewestFirst(conn *sqlite.Conn, cond builder.Cond) []*CaveCrash {
+	var crashes []*CaveCrash
+	MustSelect(conn, &crashes, cond, hades.Search{})
+
+	// sorted here rather than with ORDER BY: timestamps are stored
+	// as text, and don't sort correctly within the same second
+	createdAt := func(cc *CaveCrash) time.Time {
+		if cc.CreatedAt == nil {
+			return time.Time{}
+		}
+		return *cc.CreatedAt
+	}
+	sort.SliceStable(crashes, func(i, j int) bool {
+		a, b := createdAt(crashes[i]), createdAt(crashes[j])
+		if !a.Equal(b) {
+			return a.After(b)
+		}
+		// stable across pages
+		return crashes[i].ID < crashes[j].ID
+	})
+	return crashes
+}
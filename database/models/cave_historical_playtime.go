@@ -9,15 +9,15 @@ import (
 )
 
 type CaveHistoricalPlayTime struct {
-	CaveID        string `hades:"primary_key"`
-	GameID        int64
-	UploadID      int64
-	BuildID       int64
-	SecondsRun    int64
-	LastTouchedAt *time.Time
+	CaveID        string     `json:"caveId" hades:"primary_key"`
+	GameID        int64      `json:"gameId"`
+	UploadID      int64      `json:"uploadId"`
+	BuildID       int64      `json:"buildId"`
+	SecondsRun    int64      `json:"secondsRun"`
+	LastTouchedAt *time.Time `json:"lastTouchedAt"`
 
-	CreatedAt  *time.Time
-	UploadedAt *time.Time
+	CreatedAt  *time.Time `json:"createdAt"`
+	UploadedAt *time.Time `json:"uploadedAt"`
 }
 
 func (chpt *CaveHistoricalPlayTime) MarkUploaded(conn *sqlite.Conn) {
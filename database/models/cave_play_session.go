@@ -0,0 +1,149 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/hades"
+	"xorm.io/builder"
+)
+
+// How long a session can go without being updated before it's
+// considered over (butler was killed while the game was running, etc.)
+const playSessionStaleAfter = 5 * time.Minute
+
+// CavePlaySession records a launch session locally, so that play time
+// is counted even when the itch.io API can't be reached. Sessions are
+// synced with the API later on, see tasks.FetchUserGameSessions and
+// tasks.SyncPlaySessions.
+type CavePlaySession struct {
+	// An UUID
+	ID string `json:"id" hades:"primary_key"`
+
+	CaveID   string `json:"caveId"`
+	GameID   int64  `json:"gameId"`
+	UploadID int64  `json:"uploadId"`
+	BuildID  int64  `json:"buildId"`
+
+	Platform     string `json:"platform"`
+	Architecture string `json:"architecture"`
+
+	StartedAt *time.Time `json:"startedAt"`
+	LastRunAt *time.Time `json:"lastRunAt"`
+	// Nil while the game is running
+	EndedAt    *time.Time `json:"endedAt"`
+	SecondsRun int64      `json:"secondsRun"`
+	Crashed    bool       `json:"crashed"`
+
+	// ID of the corresponding itch.io session, 0 if it
+	// hasn't been created yet
+	RemoteSessionID int64 `json:"remoteSessionId"`
+	// Set once the itch.io session is up-to-date
+	SyncedAt *time.Time `json:"syncedAt"`
+}
+
+func (cps *CavePlaySession) Save(conn *sqlite.Conn) {
+	MustSave(conn, cps)
+}
+
+func (cps *CavePlaySession) MarkSynced(conn *sqlite.Conn) {
+	now := time.Now().UTC()
+	cps.SyncedAt = &now
+	MustSave(conn, cps)
+}
+
+// PendingPlaySessionsForCaves returns sessions that are over but
+// haven't been synced with the itch.io API yet, oldest first.
+func PendingPlaySessionsForCaves(conn *sqlite.Conn, caves []*Cave) []*CavePlaySession {
+	var caveIDs []interface{}
+	for _, cave := range caves {
+		caveIDs = append(caveIDs, cave.ID)
+	}
+
+	var sessions []*CavePlaySession
+	MustSelect(conn, &sessions, builder.And(
+		builder.IsNull{"synced_at"},
+		builder.In("cave_id", caveIDs...),
+	), hades.Search{})
+
+	// filtered and sorted here rather than in SQL: timestamps
+	// are stored as text, and don't compare correctly
+	staleBefore := time.Now().UTC().Add(-playSessionStaleAfter)
+	var pending []*CavePlaySession
+	for _, ps := range sessions {
+		if ps.EndedAt != nil || ps.LastRunAt == nil || ps.LastRunAt.Before(staleBefore) {
+			pending = append(pending, ps)
+		}
+	}
+	sortPlaySessions(pending)
+	return pending
+}
+
+// PlaySessionsStartedBetween returns the sessions matching cond that
+// were started at or after since, and before until, oldest first.
+// since and until may be nil.
+func PlaySessionsStartedBetween(conn *sqlite.Conn, cond builder.Cond, since *time.Time, until *time.Time) []*CavePlaySession {
+	var sessions []*CavePlaySession
+	MustSelect(conn, &sessions, cond, hades.Search{})
+
+	// filtered and sorted here rather than in SQL: timestamps
+	// are stored as text, and don't compare correctly
+	var res []*CavePlaySession
+	for _, ps := range sessions {
+		if ps.StartedAt == nil {
+			continue
+		}
+		if since != nil && ps.StartedAt.Before(*since) {
+			continue
+		}
+		if until != nil && !ps.StartedAt.Before(*until) {
+			continue
+		}
+		res = append(res, ps)
+	}
+	sortPlaySessions(res)
+	return res
+}
+
+// GameIDsWithPendingPlayTime returns the games that have play
+// sessions or historical play time that haven't been synced
+// with the itch.io API yet.
+func GameIDsWithPendingPlayTime(conn *sqlite.Conn) []int64 {
+	var sessions []*CavePlaySession
+	MustSelect(conn, &sessions, builder.IsNull{"synced_at"}, hades.Search{})
+	var playtimes []*CaveHistoricalPlayTime
+	MustSelect(conn, &playtimes, builder.IsNull{"uploaded_at"}, hades.Search{})
+
+	var gameIDs []int64
+	seen := make(map[int64]bool)
+	add := func(gameID int64) {
+		if !seen[gameID] {
+			seen[gameID] = true
+			gameIDs = append(gameIDs, gameID)
+		}
+	}
+	for _, ps := range sessions {
+		add(ps.GameID)
+	}
+	for _, playtime := range playtimes {
+		add(playtime.GameID)
+	}
+	return gameIDs
+}
+
+func sortPlaySessions(sessions []*CavePlaySession) {
+	startedAt := func(ps *CavePlaySession) time.Time {
+		if ps.StartedAt == nil {
+			return time.Time{}
+		}
+		return *ps.StartedAt
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		a, b := startedAt(sessions[i]), startedAt(sessions[j])
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+}
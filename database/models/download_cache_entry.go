@@ -0,0 +1,59 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/hades"
+	"xorm.io/builder"
+)
+
+// DownloadCacheEntry maps something we download (a build file,
+// an upload) to a blob in the download cache, named after its MD5 hash.
+// Several entries may share the same blob.
+type DownloadCacheEntry struct {
+	Key string `json:"key" hades:"primary_key"`
+
+	// Hex-encoded MD5 hash of the contents
+	Hash string `json:"hash"`
+	Size int64  `json:"size"`
+
+	CreatedAt  *time.Time `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+}
+
+func DownloadCacheEntryByKey(conn *sqlite.Conn, key string) *DownloadCacheEntry {
+	var dce DownloadCacheEntry
+	if MustSelectOne(conn, &dce, builder.Eq{"key": key}) {
+		return &dce
+	}
+	return nil
+}
+
+// DownloadCacheEntriesByLastUse returns all entries, least recently used first
+func DownloadCacheEntriesByLastUse(conn *sqlite.Conn) []*DownloadCacheEntry {
+	var entries []*DownloadCacheEntry
+	MustSelect(conn, &entries, builder.NewCond(), hades.Search{})
+
+	// sorted here rather than with ORDER BY: timestamps are stored
+	// as text, and don't sort correctly within the same second
+	lastUsedAt := func(dce *DownloadCacheEntry) time.Time {
+		if dce.LastUsedAt == nil {
+			return time.Time{}
+		}
+		return *dce.LastUsedAt
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return lastUsedAt(entries[i]).Before(lastUsedAt(entries[j]))
+	})
+	return entries
+}
+
+func (dce *DownloadCacheEntry) Save(conn *sqlite.Conn) {
+	MustSave(conn, dce)
+}
+
+func (dce *DownloadCacheEntry) Delete(conn *sqlite.Conn) {
+	MustDelete(conn, &DownloadCacheEntry{}, builder.Eq{"key": dce.Key})
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/hades"
+	itchio "github.com/itchio/go-itchio"
+	"xorm.io/builder"
+)
+
+// DownloadHistoryItem records a single attempt at performing
+// a download (install, reinstall, update, heal, etc.). Unlike
+// downloads, they are kept when finished downloads are cleared.
+type DownloadHistoryItem struct {
+	// An UUID
+	ID string `json:"id" hades:"primary_key"`
+
+	DownloadID string `json:"downloadId"`
+	CaveID     string `json:"caveId"`
+	Reason     string `json:"reason"`
+
+	GameID int64        `json:"gameId"`
+	Game   *itchio.Game `json:"game"`
+
+	UploadID int64          `json:"uploadId"`
+	Upload   *itchio.Upload `json:"upload"`
+
+	BuildID int64         `json:"buildId"`
+	Build   *itchio.Build `json:"build"`
+
+	// Bytes transferred over the network (approximate)
+	BytesTransferred int64 `json:"bytesTransferred"`
+
+	// Time spent in each stage, in seconds
+	PrepareSeconds  float64 `json:"prepareSeconds"`
+	DownloadSeconds float64 `json:"downloadSeconds"`
+	InstallSeconds  float64 `json:"installSeconds"`
+	PatchSeconds    float64 `json:"patchSeconds"`
+	HealSeconds     float64 `json:"healSeconds"`
+
+	Outcome string `json:"outcome"`
+	// Standard butlerd error code
+	ErrorCode *int64 `json:"errorCode"`
+	// Short error message (hopefully human-readable)
+	ErrorMessage *string `json:"errorMessage"`
+
+	StartedAt  *time.Time `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt"`
+}
+
+// DownloadHistoryItemsNewestFirst returns the items matching cond, most
+// recently started first
+func DownloadHistoryItemsNewestFirst(conn *sqlite.Conn, cond builder.Cond) []*DownloadHistoryItem {
+	var items []*DownloadHistoryItem
+	MustSelect(conn, &items, cond, hades.Search{})
+
+	// sorted here rather than with ORDER BY: timestamps are stored
+	// as text, and don't sort correctly within the same second
+	startedAt := func(dhi *DownloadHistoryItem) time.Time {
+		if dhi.StartedAt == nil {
+			return time.Time{}
+		}
+		return *dhi.StartedAt
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := startedAt(items[i]), startedAt(items[j])
+		if !a.Equal(b) {
+			return a.After(b)
+		}
+		// stable across pages
+		return items[i].ID < items[j].ID
+	})
+	return items
+}
+
+func (dhi *DownloadHistoryItem) Save(conn *sqlite.Conn) {
+	MustSave(conn, dhi)
+}
+
+func PreloadDownloadHistoryItems(conn *sqlite.Conn, itemOrItems interface{}) {
+	MustPreload(conn, itemOrItems,
+		hades.Assoc("Game"),
+		hades.Assoc("Upload"),
+		hades.Assoc("Build"),
+	)
+}
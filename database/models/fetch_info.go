@@ -106,6 +106,11 @@ func (ft FetchTarget) IsStale(conn *sqlite.Conn) (bool, error) {
 	return false, nil
 }
 
+func (ft FetchTarget) MustHasBeenFetched(conn *sqlite.Conn) bool {
+	fi := ft.MustGetInfo(conn)
+	return fi != nil && fi.FetchedAt != nil
+}
+
 func (ft FetchTarget) MarkFresh(conn *sqlite.Conn) error {
 	err := ft.Validate()
 	if err != nil {
@@ -1,17 +1,65 @@
 package models
 
 import (
+	"sort"
+	"sync"
 	"time"
+
+	"github.com/pkg/errors"
 )
 
 const defaultTTL = 2 * time.Minute
 const longTTL = 10 * time.Minute
 
+var ttls = map[string]time.Duration{
+	"game":                defaultTTL,
+	"upload":              defaultTTL,
+	"game_uploads":        defaultTTL,
+	"user":                longTTL,
+	"profile_collections": defaultTTL,
+	"profile_games":       defaultTTL,
+	"profile_owned_keys":  defaultTTL,
+	"collection":          defaultTTL,
+	"collection_games":    longTTL,
+}
+var ttlsLock sync.RWMutex
+
+// SetTTL changes how long fetched data of a given type stays fresh,
+// for example "game" or "profile_owned_keys".
+func SetTTL(targetType string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return errors.Errorf("TTL for (%s) must be positive, got %s", targetType, ttl)
+	}
+
+	ttlsLock.Lock()
+	defer ttlsLock.Unlock()
+	if _, ok := ttls[targetType]; !ok {
+		return errors.Errorf("Unknown fetch target type (%s), expected one of %v", targetType, fetchTargetTypes())
+	}
+	ttls[targetType] = ttl
+	return nil
+}
+
+func ttlFor(targetType string) time.Duration {
+	ttlsLock.RLock()
+	defer ttlsLock.RUnlock()
+	return ttls[targetType]
+}
+
+func fetchTargetTypes() []string {
+	var types []string
+	for t := range ttls {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
 func FetchTargetForGame(gameID int64) FetchTarget {
 	return FetchTarget{
 		ID:   gameID,
 		Type: "game",
-		TTL:  defaultTTL,
+		TTL:  ttlFor("game"),
 	}
 }
 
@@ -19,7 +67,7 @@ func FetchTargetForUpload(uploadID int64) FetchTarget {
 	return FetchTarget{
 		ID:   uploadID,
 		Type: "upload",
-		TTL:  defaultTTL,
+		TTL:  ttlFor("upload"),
 	}
 }
 
@@ -27,7 +75,7 @@ func FetchTargetForGameUploads(gameID int64) FetchTarget {
 	return FetchTarget{
 		ID:   gameID,
 		Type: "game_uploads",
-		TTL:  defaultTTL,
+		TTL:  ttlFor("game_uploads"),
 	}
 }
 
@@ -35,7 +83,7 @@ func FetchTargetForUser(userID int64) FetchTarget {
 	return FetchTarget{
 		ID:   userID,
 		Type: "user",
-		TTL:  longTTL,
+		TTL:  ttlFor("user"),
 	}
 }
 
@@ -43,7 +91,7 @@ func FetchTargetForProfileCollections(profileID int64) FetchTarget {
 	return FetchTarget{
 		ID:   profileID,
 		Type: "profile_collections",
-		TTL:  defaultTTL,
+		TTL:  ttlFor("profile_collections"),
 	}
 }
 
@@ -51,7 +99,7 @@ func FetchTargetForProfileGames(profileID int64) FetchTarget {
 	return FetchTarget{
 		ID:   profileID,
 		Type: "profile_games",
-		TTL:  defaultTTL,
+		TTL:  ttlFor("profile_games"),
 	}
 }
 
@@ -59,7 +107,7 @@ func FetchTargetForProfileOwnedKeys(profileID int64) FetchTarget {
 	return FetchTarget{
 		ID:   profileID,
 		Type: "profile_owned_keys",
-		TTL:  defaultTTL,
+		TTL:  ttlFor("profile_owned_keys"),
 	}
 }
 
@@ -67,7 +115,7 @@ func FetchTargetForCollection(collectionID int64) FetchTarget {
 	return FetchTarget{
 		ID:   collectionID,
 		Type: "collection",
-		TTL:  defaultTTL,
+		TTL:  ttlFor("collection"),
 	}
 }
 
@@ -75,6 +123,6 @@ func FetchTargetForCollectionGames(collectionID int64) FetchTarget {
 	return FetchTarget{
 		ID:   collectionID,
 		Type: "collection_games",
-		TTL:  longTTL,
+		TTL:  ttlFor("collection_games"),
 	}
 }
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/pkg/errors"
+	"xorm.io/builder"
+)
+
+// GameSearchTable is an FTS5 index over the games table, keyed by game ID.
+// It's not a hades model: it's maintained by triggers on `games`
+// and `users`, so every save of a game (or its author) updates it.
+const GameSearchTable = "games_fts"
+
+// GameSearchOrder ranks matches, best first. Title matches weigh the
+// most, then the author, then tags (classification and type), then
+// the short text.
+const GameSearchOrder = "bm25(games_fts, 10.0, 1.0, 2.0, 4.0) ASC"
+
+var gameSearchTriggers = []string{
+	"games_fts_ai",
+	"games_fts_au",
+	"games_fts_ad",
+	"games_fts_users_ai",
+	"games_fts_users_au",
+}
+
+const gameSearchRow = `coalesce(%[1]s.title, ''),
+	coalesce(%[1]s.short_text, ''),
+	trim(coalesce(%[1]s.classification, '') || ' ' || coalesce(%[1]s.type, '')),
+	coalesce((SELECT trim(coalesce(users.display_name, '') || ' ' || coalesce(users.username, '')) FROM users WHERE users.id = %[1]s.user_id), '')`
+
+var gameSearchSchema = fmt.Sprintf(`
+CREATE VIRTUAL TABLE IF NOT EXISTS games_fts USING fts5(title, short_text, tags, author, tokenize = 'unicode61 remove_diacritics 2');
+
+CREATE TRIGGER IF NOT EXISTS games_fts_ai AFTER INSERT ON games BEGIN
+	DELETE FROM games_fts WHERE rowid = NEW.id;
+	INSERT INTO games_fts (rowid, title, short_text, tags, author) SELECT NEW.id, %[1]s;
+END;
+
+CREATE TRIGGER IF NOT EXISTS games_fts_au AFTER UPDATE ON games BEGIN
+	DELETE FROM games_fts WHERE rowid = OLD.id;
+	INSERT INTO games_fts (rowid, title, short_text, tags, author) SELECT NEW.id, %[1]s;
+END;
+
+CREATE TRIGGER IF NOT EXISTS games_fts_ad AFTER DELETE ON games BEGIN
+	DELETE FROM games_fts WHERE rowid = OLD.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS games_fts_users_ai AFTER INSERT ON users BEGIN
+	UPDATE games_fts SET author = %[2]s WHERE rowid IN (SELECT id FROM games WHERE user_id = NEW.id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS games_fts_users_au AFTER UPDATE ON users BEGIN
+	UPDATE games_fts SET author = %[2]s WHERE rowid IN (SELECT id FROM games WHERE user_id = NEW.id);
+END;
+`,
+	fmt.Sprintf(gameSearchRow, "NEW"),
+	"trim(coalesce(NEW.display_name, '') || ' ' || coalesce(NEW.username, ''))",
+)
+
+var gameSearchRebuild = fmt.Sprintf(`
+DELETE FROM games_fts;
+INSERT INTO games_fts (rowid, title, short_text, tags, author) SELECT games.id, %s FROM games;
+`, fmt.Sprintf(gameSearchRow, "games"))
+
+// PrepareGameSearch creates the game search index and the triggers
+// that keep it in sync. hades drops a table's triggers when it rebuilds
+// it during automatic migrations, so this runs after each of them,
+// and reindexes every game if anything was missing.
+func PrepareGameSearch(conn *sqlite.Conn) error {
+	var names []interface{}
+	names = append(names, GameSearchTable)
+	for _, t := range gameSearchTriggers {
+		names = append(names, t)
+	}
+
+	q, args, err := builder.Select("count(*)").From("sqlite_master").Where(builder.In("name", names...)).ToSQL()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	var count int
+	err = sqlitex.Exec(conn, q, func(stmt *sqlite.Stmt) error {
+		count = stmt.ColumnInt(0)
+		return nil
+	}, args...)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if count == len(names) {
+		return nil
+	}
+
+	err = sqlitex.ExecScript(conn, gameSearchSchema)
+	if err != nil {
+		return errors.WithMessage(err, "creating game search index")
+	}
+	err = sqlitex.ExecScript(conn, gameSearchRebuild)
+	if err != nil {
+		return errors.WithMessage(err, "rebuilding game search index")
+	}
+	return nil
+}
+
+// GameSearchMatch turns user input into an FTS5 query where every word
+// must match, as a prefix, so "stard val" finds "Stardew Valley".
+// Returns an empty string if there's nothing to search for.
+func GameSearchMatch(query string) string {
+	words := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var terms []string
+	for _, w := range words {
+		terms = append(terms, fmt.Sprintf(`"%s"*`, w))
+	}
+	return strings.Join(terms, " ")
+}
+
+// GameSearchCond matches games against an FTS5 query built
+// by GameSearchMatch. The search must join GameSearchTable.
+func GameSearchCond(match string) builder.Cond {
+	return builder.Expr("games_fts MATCH ?", match)
+}
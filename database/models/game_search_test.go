@@ -0,0 +1,133 @@
+package models_test
+
+import (
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/itchio/butler/database/models"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/hades"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+	"xorm.io/builder"
+)
+
+func searchGameIDs(conn *sqlite.Conn, query string) []int64 {
+	match := models.GameSearchMatch(query)
+	if match == "" {
+		return nil
+	}
+
+	var games []*itchio.Game
+	models.MustSelect(conn, &games, models.GameSearchCond(match),
+		hades.Search{}.
+			InnerJoin(models.GameSearchTable, "games_fts.rowid = games.id").
+			OrderBy(models.GameSearchOrder+", games.id ASC"),
+	)
+
+	var ids []int64
+	for _, g := range games {
+		ids = append(ids, g.ID)
+	}
+	return ids
+}
+
+func Test_GameSearchTriggers(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := openTestDB(t)
+	defer conn.Close()
+
+	models.MustSave(conn, &itchio.User{ID: 1, Username: "rollf", DisplayName: "Roll Fizzlebeef"})
+	models.MustSave(conn, &itchio.Game{
+		ID:             10,
+		UserID:         1,
+		Title:          "Stardew Valley",
+		ShortText:      "Farming sim",
+		Classification: itchio.GameClassificationGame,
+		Type:           itchio.GameTypeDefault,
+	})
+	// saved before its author
+	models.MustSave(conn, &itchio.Game{ID: 11, UserID: 2, Title: "Celeste"})
+	models.MustSave(conn, &itchio.Game{ID: 12, Title: "Pokémon Bootleg"})
+
+	assert.EqualValues([]int64{10}, searchGameIDs(conn, "stard val"))
+	assert.EqualValues([]int64{10}, searchGameIDs(conn, "FARMING"))
+	assert.EqualValues([]int64{10}, searchGameIDs(conn, "fizzle"))
+	assert.EqualValues([]int64{10}, searchGameIDs(conn, "rollf"))
+	assert.EqualValues([]int64{12}, searchGameIDs(conn, "pokemon"))
+	assert.Empty(searchGameIDs(conn, "stardew celeste"))
+
+	// games are reindexed when saved again
+	models.MustSave(conn, &itchio.Game{ID: 10, UserID: 1, Title: "Sun Haven"})
+	assert.Empty(searchGameIDs(conn, "stardew"))
+	assert.Empty(searchGameIDs(conn, "farming"))
+	assert.EqualValues([]int64{10}, searchGameIDs(conn, "haven"))
+
+	// ...and when their author changes or shows up
+	models.MustSave(conn, &itchio.User{ID: 1, Username: "rollf", DisplayName: "Pelican Town"})
+	assert.Empty(searchGameIDs(conn, "fizzle"))
+	assert.EqualValues([]int64{10}, searchGameIDs(conn, "pelican"))
+
+	assert.Empty(searchGameIDs(conn, "maddy"))
+	models.MustSave(conn, &itchio.User{ID: 2, Username: "maddy"})
+	assert.EqualValues([]int64{11}, searchGameIDs(conn, "maddy"))
+
+	models.MustDelete(conn, &itchio.Game{}, builder.Eq{"id": 11})
+	assert.Empty(searchGameIDs(conn, "celeste"))
+
+	// missing triggers are recreated, and everything reindexed
+	wtest.Must(t, sqlitex.ExecScript(conn, "DROP TRIGGER games_fts_au; DELETE FROM games_fts;"))
+	wtest.Must(t, models.PrepareGameSearch(conn))
+	assert.EqualValues([]int64{10}, searchGameIDs(conn, "haven"))
+	models.MustSave(conn, &itchio.Game{ID: 10, UserID: 1, Title: "Stardew Valley"})
+	assert.Empty(searchGameIDs(conn, "haven"))
+	assert.EqualValues([]int64{10}, searchGameIDs(conn, "stardew"))
+}
+
+func Test_GameSearchMatch(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.EqualValues(`"stard"* "val"*`, models.GameSearchMatch("stard val"))
+	assert.EqualValues(`"half"* "life"*`, models.GameSearchMatch("half-life"))
+	assert.EqualValues(`"NEAR"*`, models.GameSearchMatch("NEAR"))
+	assert.EqualValues(`"a"* "b"*`, models.GameSearchMatch(`"a" * -b`))
+	for _, query := range []string{"", " ", `"`, "*", "-", "()", `"*-^:`} {
+		assert.EqualValues("", models.GameSearchMatch(query), "query (%s)", query)
+	}
+
+	conn := openTestDB(t)
+	defer conn.Close()
+
+	models.MustSave(conn, &itchio.Game{ID: 1, Title: "Near Death"})
+	models.MustSave(conn, &itchio.Game{ID: 2, Title: "Half-Life"})
+	models.MustSave(conn, &itchio.Game{ID: 3, Title: "Or Not", ShortText: "title: a game"})
+
+	// FTS5 syntax is matched literally, instead of being
+	// interpreted (or failing to parse)
+	cases := map[string][]int64{
+		`"`:                nil,
+		`*`:                nil,
+		`-`:                nil,
+		`NEAR`:             {1},
+		`NEAR(near death)`: {1},
+		`"near death"`:     {1},
+		`half-life`:        {2},
+		`-life`:            {2},
+		`life*`:            {2},
+		`near OR half`:     nil,
+		`OR`:               {3},
+		`NOT`:              {3},
+		`title:or`:         {3},
+		`^near`:            {1},
+		`"unterminated`:    nil,
+	}
+	for query, ids := range cases {
+		var actual []int64
+		assert.NotPanics(func() {
+			actual = searchGameIDs(conn, query)
+		}, "query (%s)", query)
+		assert.EqualValues(ids, actual, "query (%s)", query)
+	}
+}
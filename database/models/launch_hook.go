@@ -0,0 +1,92 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/hades"
+	"xorm.io/builder"
+)
+
+// LaunchHook is a user-defined command run before a game
+// is launched, or after it exits.
+type LaunchHook struct {
+	ID string `json:"id" hades:"primary_key"`
+
+	// Empty for hooks run for every cave
+	CaveID string `json:"caveId"`
+
+	// pre-launch or post-exit
+	Stage string `json:"stage"`
+
+	Name    string `json:"name"`
+	Command string `json:"command"`
+	// JSON-encoded []string
+	Args             JSON   `json:"args"`
+	WorkingDirectory string `json:"workingDirectory"`
+	AbortOnFailure   bool   `json:"abortOnFailure"`
+
+	CreatedAt *time.Time `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+func LaunchHookByID(conn *sqlite.Conn, id string) *LaunchHook {
+	var lh LaunchHook
+	if MustSelectOne(conn, &lh, builder.Eq{"id": id}) {
+		return &lh
+	}
+	return nil
+}
+
+// AllLaunchHooks returns all hooks, global ones first,
+// then oldest first.
+func AllLaunchHooks(conn *sqlite.Conn) []*LaunchHook {
+	var hooks []*LaunchHook
+	MustSelect(conn, &hooks, builder.NewCond(), hades.Search{})
+	sortLaunchHooks(hooks)
+	return hooks
+}
+
+// LaunchHooksForCave returns the global hooks, then those of a cave,
+// oldest first, in the order they should be run.
+func LaunchHooksForCave(conn *sqlite.Conn, caveID string) []*LaunchHook {
+	var hooks []*LaunchHook
+	cond := builder.Or(builder.Eq{"cave_id": ""}, builder.Eq{"cave_id": caveID})
+	MustSelect(conn, &hooks, cond, hades.Search{})
+	sortLaunchHooks(hooks)
+	return hooks
+}
+
+// sortLaunchHooks puts global hooks first, then sorts by cave and
+// creation date. Dates are stored as text with a variable number of
+// digits, so this can't be left to an ORDER BY clause.
+func sortLaunchHooks(hooks []*LaunchHook) {
+	createdAt := func(lh *LaunchHook) time.Time {
+		if lh.CreatedAt == nil {
+			return time.Time{}
+		}
+		return *lh.CreatedAt
+	}
+
+	sort.SliceStable(hooks, func(i, j int) bool {
+		a, b := hooks[i], hooks[j]
+		if a.CaveID != b.CaveID {
+			return a.CaveID < b.CaveID
+		}
+		return createdAt(a).Before(createdAt(b))
+	})
+}
+
+// DeleteLaunchHooksByCaveID removes all hooks specific to a cave
+func DeleteLaunchHooksByCaveID(conn *sqlite.Conn, caveID string) {
+	MustDelete(conn, &LaunchHook{}, builder.Eq{"cave_id": caveID})
+}
+
+func (lh *LaunchHook) Save(conn *sqlite.Conn) {
+	MustSave(conn, lh)
+}
+
+func (lh *LaunchHook) Delete(conn *sqlite.Conn) {
+	MustDelete(conn, &LaunchHook{}, builder.Eq{"id": lh.ID})
+}
@@ -0,0 +1,82 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/database"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+// openTestDB returns an in-memory database with all the tables
+func openTestDB(t *testing.T) *sqlite.Conn {
+	conn, err := sqlite.OpenConn(":memory:", 0)
+	wtest.Must(t, err)
+	consumer := &state.Consumer{
+		OnMessage: func(lvl string, msg string) { t.Logf("[%s] %s", lvl, msg) },
+	}
+	wtest.Must(t, database.Prepare(consumer, conn, database.PrepareOptions{JustCreated: true}))
+	return conn
+}
+
+func Test_LaunchHooksOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := openTestDB(t)
+	defer conn.Close()
+
+	base := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+	save := func(id string, caveID string, createdAt time.Duration) {
+		at := base.Add(createdAt)
+		lh := &models.LaunchHook{
+			ID:        id,
+			CaveID:    caveID,
+			Stage:     "pre-launch",
+			Name:      id,
+			CreatedAt: &at,
+			UpdatedAt: &at,
+		}
+		lh.Save(conn)
+	}
+
+	// stored as "...00.12Z" and "...00.1Z", which sort
+	// the wrong way around as text
+	save("cave-later", "cave-a", 120*time.Millisecond)
+	save("cave-earlier", "cave-a", 100*time.Millisecond)
+	save("global-later", "", 2*time.Second)
+	save("global-earlier", "", time.Second)
+	save("other-cave", "cave-b", 0)
+
+	ids := func(hooks []*models.LaunchHook) []string {
+		var res []string
+		for _, lh := range hooks {
+			res = append(res, lh.ID)
+		}
+		return res
+	}
+
+	assert.EqualValues([]string{
+		"global-earlier",
+		"global-later",
+		"cave-earlier",
+		"cave-later",
+	}, ids(models.LaunchHooksForCave(conn, "cave-a")))
+
+	assert.EqualValues([]string{
+		"global-earlier",
+		"global-later",
+		"cave-earlier",
+		"cave-later",
+		"other-cave",
+	}, ids(models.AllLaunchHooks(conn)))
+
+	models.DeleteLaunchHooksByCaveID(conn, "cave-a")
+	assert.EqualValues([]string{
+		"global-earlier",
+		"global-later",
+	}, ids(models.LaunchHooksForCave(conn, "cave-a")))
+}
@@ -29,3 +29,30 @@ func MarshalVerdict(in *dash.Verdict, out *JSON) error {
 	*out = JSON(contents)
 	return nil
 }
+
+// Strings
+
+func UnmarshalStrings(in JSON) ([]string, error) {
+	var out []string
+	if in == "" {
+		return out, nil
+	}
+	err := json.Unmarshal([]byte(in), &out)
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshalling strings")
+	}
+
+	return out, nil
+}
+
+func MarshalStrings(in []string, out *JSON) error {
+	if in == nil {
+		in = []string{}
+	}
+	contents, err := json.Marshal(in)
+	if err != nil {
+		return errors.Wrap(err, "marshalling strings")
+	}
+	*out = JSON(contents)
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"xorm.io/builder"
+	"github.com/itchio/butler/butlerd"
 	"github.com/itchio/butler/butlerd/horror"
 	"github.com/itchio/hades"
 	"github.com/pkg/errors"
@@ -15,11 +16,20 @@ import (
 	"github.com/itchio/headway/state"
 )
 
-type Migration func(consumer *state.Consumer, conn *sqlite.Conn) error
+type MigrationFunc func(consumer *state.Consumer, conn *sqlite.Conn) error
+
+type Migration struct {
+	// Up brings the database to this migration's version
+	Up MigrationFunc
+	// Down undoes Up, so an older butler can use the database again.
+	// Migrations without one can't be rolled back.
+	Down MigrationFunc
+}
 
 var migrations = map[int64]Migration{
-	// create "cave_historical_playtime" records from all caves so far
-	1542741863: func(consumer *state.Consumer, conn *sqlite.Conn) error {
+	// create "cave_historical_playtime" records from all caves so far.
+	// no Down: running Up again after a rollback would upload playtimes twice
+	1542741863: {Up: func(consumer *state.Consumer, conn *sqlite.Conn) error {
 		var caves []*models.Cave
 		models.MustSelect(conn, &caves, builder.NewCond(), hades.Search{})
 
@@ -50,41 +60,197 @@ var migrations = map[int64]Migration{
 		models.MustSave(conn, playtimes)
 
 		return nil
+	}},
+
+	// profile data written before versions existed starts at version 1,
+	// so that compare-and-swap puts with ifVersion=0 mean "not there yet"
+	1792339200: {
+		Up: func(consumer *state.Consumer, conn *sqlite.Conn) error {
+			return sqlitex.Exec(conn, "UPDATE profile_data SET version = 1 WHERE version IS NULL OR version = 0", nil)
+		},
+		Down: func(consumer *state.Consumer, conn *sqlite.Conn) error {
+			// older versions of butler don't know about the column
+			return nil
+		},
 	},
 }
 
+// Step is a migration that needs to run to reach a schema version
+type Step struct {
+	// Version of the migration
+	Version int64
+	// Down is true if the migration is being undone
+	Down bool
+	// Rows inserted, updated or deleted by the migration
+	RowsChanged int64
+}
+
+// errDryRun rolls back the migrations' transaction after a dry run
+var errDryRun = errors.New("dry run")
+
+// Do runs all pending migrations
 func Do(consumer *state.Consumer, conn *sqlite.Conn) error {
+	_, err := MigrateTo(consumer, conn, LatestSchemaVersion(), false)
+	return err
+}
+
+// MigrateTo brings the database to the given schema version, running
+// migrations up or down as needed. When dryRun is set, every migration
+// is run and then rolled back, so the returned steps show how many rows
+// each would change.
+func MigrateTo(consumer *state.Consumer, conn *sqlite.Conn, target int64, dryRun bool) ([]*Step, error) {
 	currentVersion := models.GetSchemaVersion(conn)
 	consumer.Debugf("Current DB version is %d", currentVersion)
 	consumer.Debugf("Latest migration is   %d", LatestSchemaVersion())
 
-	todo := getKeysAfter(currentVersion)
-	if len(todo) == 0 {
+	if currentVersion > LatestSchemaVersion() {
+		return nil, schemaTooNew(currentVersion)
+	}
+	if target != 0 {
+		if _, ok := migrations[target]; !ok {
+			return nil, errors.Errorf("Unknown schema version %d", target)
+		}
+	}
+
+	var steps []*Step
+	if target >= currentVersion {
+		for _, key := range getKeysAfter(currentVersion) {
+			if key > target {
+				break
+			}
+			steps = append(steps, &Step{Version: key})
+		}
+	} else {
+		keys := getKeysAfter(target)
+		for i := len(keys) - 1; i >= 0; i-- {
+			if keys[i] > currentVersion {
+				continue
+			}
+			if migrations[keys[i]].Down == nil {
+				return nil, errors.Errorf("Migration %d can't be undone, so schema version %d is as far back as this database can go (asked for %d)", keys[i], keys[i], target)
+			}
+			steps = append(steps, &Step{Version: keys[i], Down: true})
+		}
+	}
+
+	if len(steps) == 0 {
 		consumer.Debugf("No migrations to run")
-		return nil
+		return nil, nil
 	}
 
-	consumer.Debugf("%d migrations to run (%v)", len(todo), todo)
-	for _, key := range todo {
-		consumer.Debugf("Running migration %d...", key)
-		migration := migrations[key]
-		err := func() (retErr error) {
-			defer horror.RecoverInto(&retErr)
-			// run migration in a transaction
-			defer sqlitex.Save(conn)(&retErr)
-			err := migration(consumer, conn)
-			if err != nil {
-				return err
+	consumer.Debugf("%d migrations to run", len(steps))
+
+	err := runSteps(consumer, conn, steps, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	return steps, nil
+}
+
+func runSteps(consumer *state.Consumer, conn *sqlite.Conn, steps []*Step, dryRun bool) (retErr error) {
+	if dryRun {
+		// steps build on each other, so roll them all back at the end
+		defer func() {
+			if retErr == errDryRun {
+				retErr = nil
 			}
-			models.SetSchemaVersion(conn, key)
-			return nil
 		}()
-		return errors.Wrapf(err, "While running migration %d", key)
+		defer sqlitex.Save(conn)(&retErr)
+	}
+
+	for _, step := range steps {
+		err := runStep(consumer, conn, step)
+		if err != nil {
+			return errors.Wrapf(err, "While running migration %d", step.Version)
+		}
+	}
+
+	if dryRun {
+		return errDryRun
 	}
+	return nil
+}
+
+func runStep(consumer *state.Consumer, conn *sqlite.Conn, step *Step) (retErr error) {
+	defer horror.RecoverInto(&retErr)
+
+	m := migrations[step.Version]
+	migration := m.Up
+	newVersion := step.Version
+	if step.Down {
+		consumer.Debugf("Undoing migration %d...", step.Version)
+		migration = m.Down
+		newVersion = previousKey(step.Version)
+	} else {
+		consumer.Debugf("Running migration %d...", step.Version)
+	}
+
+	// run migration in a transaction
+	defer sqlitex.Save(conn)(&retErr)
+
+	before := totalChanges(conn)
+	err := migration(consumer, conn)
+	if err != nil {
+		return err
+	}
+	step.RowsChanged = totalChanges(conn) - before
 
+	models.SetSchemaVersion(conn, newVersion)
 	return nil
 }
 
+func totalChanges(conn *sqlite.Conn) int64 {
+	var res int64
+	err := sqlitex.ExecTransient(conn, "SELECT total_changes()", func(stmt *sqlite.Stmt) error {
+		res = stmt.ColumnInt64(0)
+		return nil
+	})
+	if err != nil {
+		panic(errors.WithStack(err))
+	}
+	return res
+}
+
+// CheckSchemaVersion refuses databases written by a newer version of
+// butler, which may have been migrated in ways we don't understand.
+// It must be called before the schema is synchronized, since that
+// would drop columns we don't know about.
+func CheckSchemaVersion(conn *sqlite.Conn) error {
+	version, err := StoredSchemaVersion(conn)
+	if err != nil {
+		return err
+	}
+	if version > LatestSchemaVersion() {
+		return schemaTooNew(version)
+	}
+	return nil
+}
+
+// StoredSchemaVersion returns the schema version recorded in the
+// database, or 0 if there is none. Unlike models.GetSchemaVersion,
+// it can be called before the schema is synchronized.
+func StoredSchemaVersion(conn *sqlite.Conn) (int64, error) {
+	var hasTable bool
+	err := sqlitex.ExecTransient(conn, "SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_versions'", func(stmt *sqlite.Stmt) error {
+		hasTable = true
+		return nil
+	})
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	if !hasTable {
+		return 0, nil
+	}
+
+	return models.GetSchemaVersion(conn), nil
+}
+
+func schemaTooNew(version int64) error {
+	return errors.Wrapf(butlerd.CodeDatabaseTooNew,
+		"database is at schema version %d, but this version of butler only knows up to %d",
+		version, LatestSchemaVersion())
+}
+
 var sortedKeys []int64
 
 func getSortedKeys() []int64 {
@@ -111,6 +277,17 @@ func getKeysAfter(version int64) []int64 {
 	return result
 }
 
+func previousKey(version int64) int64 {
+	var result int64
+	for _, k := range getSortedKeys() {
+		if k >= version {
+			break
+		}
+		result = k
+	}
+	return result
+}
+
 func LatestSchemaVersion() int64 {
 	keys := getSortedKeys()
 	if len(keys) == 0 {
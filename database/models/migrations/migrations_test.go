@@ -0,0 +1,214 @@
+package migrations
+
+import (
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/wharf/wtest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func openTestDB(t *testing.T) *sqlite.Conn {
+	conn, err := sqlite.OpenConn(":memory:", 0)
+	wtest.Must(t, err)
+	wtest.Must(t, models.HadesContext().AutoMigrate(conn))
+	wtest.Must(t, sqlitex.ExecScript(conn, "CREATE TABLE things (name TEXT);"))
+	return conn
+}
+
+func testConsumer(t *testing.T) *state.Consumer {
+	return &state.Consumer{
+		OnMessage: func(lvl string, msg string) { t.Logf("[%s] %s", lvl, msg) },
+	}
+}
+
+// withMigrations replaces the known migrations for the duration of a test
+func withMigrations(t *testing.T, m map[int64]Migration) {
+	oldMigrations := migrations
+	migrations = m
+	sortedKeys = nil
+	t.Cleanup(func() {
+		migrations = oldMigrations
+		sortedKeys = nil
+	})
+}
+
+func thingNames(t *testing.T, conn *sqlite.Conn) []string {
+	var names []string
+	wtest.Must(t, sqlitex.ExecTransient(conn, "SELECT name FROM things ORDER BY name", func(stmt *sqlite.Stmt) error {
+		names = append(names, stmt.ColumnText(0))
+		return nil
+	}))
+	return names
+}
+
+func insertThing(name string) MigrationFunc {
+	return func(consumer *state.Consumer, conn *sqlite.Conn) error {
+		return sqlitex.Exec(conn, "INSERT INTO things (name) VALUES (?)", nil, name)
+	}
+}
+
+func deleteThing(name string) MigrationFunc {
+	return func(consumer *state.Consumer, conn *sqlite.Conn) error {
+		return sqlitex.Exec(conn, "DELETE FROM things WHERE name = ?", nil, name)
+	}
+}
+
+func Test_MigrateTo(t *testing.T) {
+	assert := assert.New(t)
+
+	withMigrations(t, map[int64]Migration{
+		100: {Up: insertThing("a")},
+		200: {Up: insertThing("b"), Down: deleteThing("b")},
+		300: {Up: insertThing("c"), Down: deleteThing("c")},
+	})
+	consumer := testConsumer(t)
+	conn := openTestDB(t)
+	defer conn.Close()
+
+	versions := func(steps []*Step) []int64 {
+		var res []int64
+		for _, s := range steps {
+			res = append(res, s.Version)
+		}
+		return res
+	}
+
+	// dry runs report what would change, then roll everything back
+	steps, err := MigrateTo(consumer, conn, 300, true)
+	wtest.Must(t, err)
+	assert.EqualValues([]int64{100, 200, 300}, versions(steps))
+	for _, s := range steps {
+		assert.False(s.Down)
+		assert.EqualValues(1, s.RowsChanged)
+	}
+	assert.Empty(thingNames(t, conn))
+	assert.EqualValues(0, models.GetSchemaVersion(conn))
+
+	// only up to the target
+	steps, err = MigrateTo(consumer, conn, 200, false)
+	wtest.Must(t, err)
+	assert.EqualValues([]int64{100, 200}, versions(steps))
+	assert.EqualValues([]string{"a", "b"}, thingNames(t, conn))
+	assert.EqualValues(200, models.GetSchemaVersion(conn))
+
+	wtest.Must(t, Do(consumer, conn))
+	assert.EqualValues([]string{"a", "b", "c"}, thingNames(t, conn))
+	assert.EqualValues(300, models.GetSchemaVersion(conn))
+
+	// going down, newest first
+	steps, err = MigrateTo(consumer, conn, 100, true)
+	wtest.Must(t, err)
+	assert.EqualValues([]int64{300, 200}, versions(steps))
+	for _, s := range steps {
+		assert.True(s.Down)
+		assert.EqualValues(1, s.RowsChanged)
+	}
+	assert.EqualValues([]string{"a", "b", "c"}, thingNames(t, conn))
+	assert.EqualValues(300, models.GetSchemaVersion(conn))
+
+	steps, err = MigrateTo(consumer, conn, 100, false)
+	wtest.Must(t, err)
+	assert.EqualValues([]int64{300, 200}, versions(steps))
+	assert.EqualValues([]string{"a"}, thingNames(t, conn))
+	assert.EqualValues(100, models.GetSchemaVersion(conn))
+
+	// migration 100 has no Down, so this is as far as we go
+	_, err = MigrateTo(consumer, conn, 0, false)
+	assert.Error(err)
+	assert.Contains(err.Error(), "Migration 100 can't be undone")
+	assert.EqualValues([]string{"a"}, thingNames(t, conn))
+	assert.EqualValues(100, models.GetSchemaVersion(conn))
+
+	// ...which is checked before undoing anything
+	wtest.Must(t, Do(consumer, conn))
+	_, err = MigrateTo(consumer, conn, 0, false)
+	assert.Error(err)
+	assert.EqualValues([]string{"a", "b", "c"}, thingNames(t, conn))
+	assert.EqualValues(300, models.GetSchemaVersion(conn))
+
+	_, err = MigrateTo(consumer, conn, 150, false)
+	assert.Error(err)
+	assert.EqualValues(300, models.GetSchemaVersion(conn))
+
+	steps, err = MigrateTo(consumer, conn, 300, false)
+	wtest.Must(t, err)
+	assert.Empty(steps)
+}
+
+func Test_MigrateToFailure(t *testing.T) {
+	assert := assert.New(t)
+
+	withMigrations(t, map[int64]Migration{
+		100: {Up: insertThing("a")},
+		200: {Up: func(consumer *state.Consumer, conn *sqlite.Conn) error {
+			err := insertThing("b")(consumer, conn)
+			if err != nil {
+				return err
+			}
+			return errors.New("something went wrong")
+		}},
+	})
+	consumer := testConsumer(t)
+	conn := openTestDB(t)
+	defer conn.Close()
+
+	// each migration is its own transaction
+	_, err := MigrateTo(consumer, conn, 200, false)
+	assert.Error(err)
+	assert.Contains(err.Error(), "While running migration 200")
+	assert.EqualValues([]string{"a"}, thingNames(t, conn))
+	assert.EqualValues(100, models.GetSchemaVersion(conn))
+}
+
+func Test_SchemaTooNew(t *testing.T) {
+	assert := assert.New(t)
+
+	consumer := testConsumer(t)
+	conn := openTestDB(t)
+	defer conn.Close()
+
+	// databases without a schema version are fine
+	wtest.Must(t, sqlitex.ExecScript(conn, "DROP TABLE schema_versions;"))
+	wtest.Must(t, CheckSchemaVersion(conn))
+	version, err := StoredSchemaVersion(conn)
+	wtest.Must(t, err)
+	assert.EqualValues(0, version)
+
+	wtest.Must(t, models.HadesContext().AutoMigrate(conn))
+	models.SetSchemaVersion(conn, LatestSchemaVersion())
+	wtest.Must(t, CheckSchemaVersion(conn))
+
+	models.SetSchemaVersion(conn, LatestSchemaVersion()+1)
+	err = CheckSchemaVersion(conn)
+	assert.Equal(butlerd.CodeDatabaseTooNew, errors.Cause(err))
+
+	_, err = MigrateTo(consumer, conn, LatestSchemaVersion(), false)
+	assert.Equal(butlerd.CodeDatabaseTooNew, errors.Cause(err))
+	assert.EqualValues(LatestSchemaVersion()+1, models.GetSchemaVersion(conn))
+}
+
+func Test_FirstMigrationCantBeUndone(t *testing.T) {
+	assert := assert.New(t)
+
+	consumer := testConsumer(t)
+	conn := openTestDB(t)
+	defer conn.Close()
+	models.SetSchemaVersion(conn, LatestSchemaVersion())
+
+	// it would record playtimes a second time when run again
+	_, err := MigrateTo(consumer, conn, 0, false)
+	assert.Error(err)
+	assert.Contains(err.Error(), "Migration 1542741863 can't be undone")
+	assert.EqualValues(LatestSchemaVersion(), models.GetSchemaVersion(conn))
+
+	// newer migrations still can be
+	_, err = MigrateTo(consumer, conn, 1542741863, false)
+	wtest.Must(t, err)
+	assert.EqualValues(1542741863, models.GetSchemaVersion(conn))
+}
@@ -1,18 +1,24 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/secrets"
 	"xorm.io/builder"
 	itchio "github.com/itchio/go-itchio"
 	"github.com/itchio/hades"
+	"github.com/pkg/errors"
 )
 
 type Profile struct {
 	ID int64 `json:"id"`
 
+	// Empty when the API key is kept in the secret store
 	APIKey string `json:"apiKey"`
+	// Name of the secret store backend the API key is kept in, if any
+	SecretStore string `json:"secretStore,omitempty"`
 
 	LastConnected time.Time    `json:"lastConnected"`
 	User          *itchio.User `json:"user"`
@@ -39,6 +45,71 @@ func (p *Profile) Save(conn *sqlite.Conn) {
 	)
 }
 
+func (p *Profile) secretKey() string {
+	return fmt.Sprintf("profile:%d", p.ID)
+}
+
+// GetAPIKey returns the profile's API key, from the secret store
+// if it was moved there. It's an error for that store to be unavailable,
+// or to have lost the key: the profile can't be used until it logs in again.
+func (p *Profile) GetAPIKey() (string, error) {
+	if p.APIKey != "" || p.SecretStore == "" {
+		return p.APIKey, nil
+	}
+
+	store := secrets.Get()
+	if store == nil {
+		return "", errors.Errorf("API key of profile %d is kept in %s, which isn't enabled (see --secret-store)", p.ID, p.SecretStore)
+	}
+	if store.Name() != p.SecretStore {
+		return "", errors.Errorf("API key of profile %d is kept in %s, but %s is in use (see --secret-store)", p.ID, p.SecretStore, store.Name())
+	}
+	key, err := store.Get(p.secretKey())
+	if err != nil {
+		if err == secrets.ErrNotFound {
+			return "", errors.Errorf("API key of profile %d is missing from %s, log in again", p.ID, store.Name())
+		}
+		return "", errors.WithMessagef(err, "reading API key of profile %d from %s", p.ID, store.Name())
+	}
+	return key, nil
+}
+
+func (p *Profile) MustGetAPIKey() string {
+	key, err := p.GetAPIKey()
+	Must(err)
+	return key
+}
+
+// StoreAPIKey moves the profile's API key from the database to the
+// secret store, if one is enabled. The profile must be saved afterwards.
+func (p *Profile) StoreAPIKey() error {
+	store := secrets.Get()
+	if store == nil || p.APIKey == "" {
+		return nil
+	}
+
+	err := store.Set(p.secretKey(), p.APIKey)
+	if err != nil {
+		return errors.WithMessagef(err, "saving API key of profile %d to %s", p.ID, store.Name())
+	}
+	p.APIKey = ""
+	p.SecretStore = store.Name()
+	return nil
+}
+
+// ForgetAPIKey removes the profile's API key from the secret store
+// it's kept in, if any
+func (p *Profile) ForgetAPIKey() error {
+	if p.SecretStore == "" {
+		return nil
+	}
+	store := secrets.Get()
+	if store == nil || store.Name() != p.SecretStore {
+		return errors.Errorf("API key of profile %d is kept in %s, which isn't enabled", p.ID, p.SecretStore)
+	}
+	return store.Delete(p.secretKey())
+}
+
 func ProfileByID(conn *sqlite.Conn, id int64) *Profile {
 	var p Profile
 	if MustSelectOne(conn, &p, builder.Eq{"id": id}) {
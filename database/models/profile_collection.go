@@ -3,11 +3,11 @@ package models
 import itchio "github.com/itchio/go-itchio"
 
 type ProfileCollection struct {
-	CollectionID int64 `hades:"primary_key"`
-	Collection   *itchio.Collection
+	CollectionID int64              `json:"collectionId" hades:"primary_key"`
+	Collection   *itchio.Collection `json:"collection,omitempty"`
 
-	ProfileID int64 `hades:"primary_key"`
-	Profile   *Profile
+	ProfileID int64    `json:"profileId" hades:"primary_key"`
+	Profile   *Profile `json:"profile,omitempty"`
 
-	Position int64
+	Position int64 `json:"position"`
 }
@@ -1,8 +1,22 @@
 package models
 
+import "time"
+
 type ProfileData struct {
 	ProfileID int64 `json:"profileId" hades:"primary_key"`
 
 	Key   string `json:"string" hades:"primary_key"`
 	Value string `json:"value"`
+
+	// Value is a JSON document
+	JSON bool `json:"json"`
+	// Bumped on every put, for compare-and-swap
+	Version   int64      `json:"version"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// Expired returns true if the entry shouldn't be returned anymore
+func (pd *ProfileData) Expired(now time.Time) bool {
+	return pd.ExpiresAt != nil && !pd.ExpiresAt.After(now)
 }
@@ -0,0 +1,72 @@
+package models_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/secrets"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ProfileAPIKeyInSecretStore(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "profile-secrets")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	enable := func(backend string) {
+		_, err := secrets.Enable(secrets.Settings{
+			Backend:    backend,
+			FilePath:   filepath.Join(dir, "secrets.json"),
+			Passphrase: func() (string, error) { return "correct horse", nil },
+		})
+		wtest.Must(t, err)
+	}
+	defer enable(secrets.BackendNone)
+
+	conn := openTestDB(t)
+	defer conn.Close()
+
+	// without a secret store, keys stay in the database
+	enable(secrets.BackendNone)
+	profile := &models.Profile{ID: 1, APIKey: "first-key"}
+	wtest.Must(t, profile.StoreAPIKey())
+	assert.EqualValues("first-key", profile.APIKey)
+	assert.EqualValues("", profile.SecretStore)
+
+	enable(secrets.BackendFile)
+	wtest.Must(t, profile.StoreAPIKey())
+	assert.EqualValues("", profile.APIKey)
+	assert.EqualValues(secrets.BackendFile, profile.SecretStore)
+	profile.Save(conn)
+
+	profile = models.ProfileByID(conn, 1)
+	assert.EqualValues(secrets.BackendFile, profile.SecretStore)
+	key, err := profile.GetAPIKey()
+	wtest.Must(t, err)
+	assert.EqualValues("first-key", key)
+
+	// the key isn't lost, it's just out of reach
+	enable(secrets.BackendNone)
+	_, err = profile.GetAPIKey()
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "kept in file, which isn't enabled")
+	}
+	assert.Error(profile.ForgetAPIKey())
+
+	enable(secrets.BackendFile)
+	key, err = profile.GetAPIKey()
+	wtest.Must(t, err)
+	assert.EqualValues("first-key", key)
+
+	wtest.Must(t, profile.ForgetAPIKey())
+	_, err = profile.GetAPIKey()
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "missing from file")
+	}
+}
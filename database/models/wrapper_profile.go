@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/hades"
+	"xorm.io/builder"
+)
+
+// WrapperProfile is a user-defined compatibility wrapper
+// (Proton, a wine build with its own prefix, box86...)
+type WrapperProfile struct {
+	ID string `json:"id" hades:"primary_key"`
+
+	Name string `json:"name"`
+
+	// Runtime of the games the wrapper can launch
+	Platform string `json:"platform"`
+	Is64     bool   `json:"is64"`
+
+	// JSON-encoded manager.Wrapper
+	Wrapper JSON `json:"wrapper"`
+
+	CreatedAt *time.Time `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+func WrapperProfileByID(conn *sqlite.Conn, id string) *WrapperProfile {
+	var wp WrapperProfile
+	if MustSelectOne(conn, &wp, builder.Eq{"id": id}) {
+		return &wp
+	}
+	return nil
+}
+
+// AllWrapperProfiles returns all wrapper profiles, sorted by name
+func AllWrapperProfiles(conn *sqlite.Conn) []*WrapperProfile {
+	var wps []*WrapperProfile
+	MustSelect(conn, &wps, builder.NewCond(), hades.Search{}.OrderBy("name ASC"))
+	return wps
+}
+
+func (wp *WrapperProfile) Save(conn *sqlite.Conn) {
+	MustSave(conn, wp)
+}
+
+func (wp *WrapperProfile) Delete(conn *sqlite.Conn) {
+	MustDelete(conn, &WrapperProfile{}, builder.Eq{"id": wp.ID})
+}
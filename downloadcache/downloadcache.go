@@ -0,0 +1,332 @@
+// Package downloadcache keeps recently downloaded build files and uploads
+// around, so that installing the same thing twice (in another install
+// location, or after an uninstall) doesn't hit the network again.
+//
+// Blobs are content-addressed (named after their MD5 hash), and mapped
+// to what they were downloaded for by DownloadCacheEntry rows. The cache
+// is bounded in size, least recently used entries are evicted first.
+// Blobs that are being read from are held, and never removed while held.
+package downloadcache
+
+import (
+	"crypto/md5"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/database/models"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/headway/united"
+	"github.com/pkg/errors"
+	"xorm.io/builder"
+)
+
+type Settings struct {
+	// Directory blobs are stored in
+	Dir string
+	// Maximum total size of blobs, in bytes
+	MaxSize int64
+}
+
+type Cache struct {
+	settings Settings
+
+	// lock guards entries, blobs, holds and orphans
+	lock sync.Mutex
+	// number of holds on each blob, by hash
+	holds map[string]int
+	// held blobs no entry refers to anymore, to remove once released
+	orphans map[string]struct{}
+}
+
+var current *Cache
+
+// Enable sets up the download cache for this process.
+func Enable(settings Settings) (*Cache, error) {
+	if settings.MaxSize <= 0 {
+		return nil, errors.New("download cache needs a maximum size")
+	}
+
+	err := os.MkdirAll(incomingDir(settings.Dir), 0o755)
+	if err != nil {
+		return nil, errors.WithMessage(err, "creating download cache directory")
+	}
+
+	current = &Cache{
+		settings: settings,
+		holds:    make(map[string]int),
+		orphans:  make(map[string]struct{}),
+	}
+	return current, nil
+}
+
+// Get returns the download cache, or nil if it's not enabled.
+func Get() *Cache {
+	return current
+}
+
+func (c *Cache) Dir() string {
+	return c.settings.Dir
+}
+
+func (c *Cache) MaxSize() int64 {
+	return c.settings.MaxSize
+}
+
+// Fits returns true if something of that size is worth caching
+func (c *Cache) Fits(size int64) bool {
+	return size > 0 && size <= c.settings.MaxSize
+}
+
+// BuildFileKey returns the cache key for a build file. Build files
+// are never modified once uploaded, so their ID identifies their contents.
+func BuildFileKey(bf *itchio.BuildFile) string {
+	return fmt.Sprintf("buildfile-%d", bf.ID)
+}
+
+// UploadKey returns the cache key for a non-wharf upload. Those may be
+// replaced in-place, so size and modification date are part of the key.
+func UploadKey(upload *itchio.Upload) string {
+	var updatedAt int64
+	if upload.UpdatedAt != nil {
+		updatedAt = upload.UpdatedAt.Unix()
+	}
+	return fmt.Sprintf("upload-%d-%d-%d", upload.ID, upload.Size, updatedAt)
+}
+
+// Lookup returns the entry for a key, if it exists and its blob is on disk.
+// Stale entries are removed.
+func (c *Cache) Lookup(conn *sqlite.Conn, key string) *models.DownloadCacheEntry {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.lookup(conn, key)
+}
+
+// Hold is like Lookup, but also keeps the entry's blob from being
+// removed until the returned function is called.
+func (c *Cache) Hold(conn *sqlite.Conn, key string) (*models.DownloadCacheEntry, func()) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry := c.lookup(conn, key)
+	if entry == nil {
+		return nil, nil
+	}
+	return entry, c.hold(entry.Hash)
+}
+
+func (c *Cache) lookup(conn *sqlite.Conn, key string) *models.DownloadCacheEntry {
+	entry := models.DownloadCacheEntryByKey(conn, key)
+	if entry == nil {
+		return nil
+	}
+
+	stats, err := os.Stat(c.BlobPath(entry.Hash))
+	if err != nil || stats.Size() != entry.Size {
+		c.remove(conn, entry)
+		return nil
+	}
+	return entry
+}
+
+// hold must be called with c.lock held. The returned
+// function may be called more than once.
+func (c *Cache) hold(hash string) func() {
+	c.holds[hash]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.lock.Lock()
+			defer c.lock.Unlock()
+
+			c.holds[hash]--
+			if c.holds[hash] > 0 {
+				return
+			}
+			delete(c.holds, hash)
+
+			if _, ok := c.orphans[hash]; ok {
+				delete(c.orphans, hash)
+				os.Remove(c.BlobPath(hash))
+			}
+		})
+	}
+}
+
+// Touch marks an entry as recently used
+func (c *Cache) Touch(conn *sqlite.Conn, entry *models.DownloadCacheEntry) {
+	now := time.Now().UTC()
+	entry.LastUsedAt = &now
+	entry.Save(conn)
+}
+
+// Check makes sure a blob's contents still match its hash.
+func (c *Cache) Check(entry *models.DownloadCacheEntry) error {
+	hash, err := HashFile(c.BlobPath(entry.Hash))
+	if err != nil {
+		return err
+	}
+	if hash != entry.Hash {
+		return errors.Errorf("download cache: %s is corrupted (expected hash %s, got %s)", entry.Key, entry.Hash, hash)
+	}
+	return nil
+}
+
+// BlobPath returns where the blob with the given hash is stored
+func (c *Cache) BlobPath(hash string) string {
+	return filepath.Join(c.settings.Dir, hash)
+}
+
+// IncomingPath returns where a file should be downloaded to
+// before being committed.
+func (c *Cache) IncomingPath(key string) string {
+	return filepath.Join(incomingDir(c.settings.Dir), key)
+}
+
+// Commit moves a fully-downloaded incoming file into the cache. It must
+// have been hashed with HashFile beforehand. The least recently used
+// entries are evicted as needed to stay under the size cap.
+// The new entry's blob is held until the returned function is called.
+func (c *Cache) Commit(conn *sqlite.Conn, consumer *state.Consumer, key string, hash string, size int64) (*models.DownloadCacheEntry, func(), error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	incomingPath := c.IncomingPath(key)
+	blobPath := c.BlobPath(hash)
+	if _, err := os.Stat(blobPath); err == nil {
+		// already have those contents under another key
+		os.Remove(incomingPath)
+		delete(c.orphans, hash)
+	} else {
+		err := os.Rename(incomingPath, blobPath)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+	}
+
+	now := time.Now().UTC()
+	entry := &models.DownloadCacheEntry{
+		Key:        key,
+		Hash:       hash,
+		Size:       size,
+		CreatedAt:  &now,
+		LastUsedAt: &now,
+	}
+	entry.Save(conn)
+	release := c.hold(hash)
+
+	c.prune(conn, consumer, c.settings.MaxSize)
+	return entry, release, nil
+}
+
+// Remove deletes an entry, and its blob if no other entry refers to it.
+// Held blobs are only removed once released. It returns the number of
+// bytes freed on disk.
+func (c *Cache) Remove(conn *sqlite.Conn, entry *models.DownloadCacheEntry) int64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.remove(conn, entry)
+}
+
+func (c *Cache) remove(conn *sqlite.Conn, entry *models.DownloadCacheEntry) int64 {
+	entry.Delete(conn)
+
+	if models.MustCount(conn, &models.DownloadCacheEntry{}, builder.Eq{"hash": entry.Hash}) > 0 {
+		return 0
+	}
+
+	if c.holds[entry.Hash] > 0 {
+		c.orphans[entry.Hash] = struct{}{}
+		return 0
+	}
+
+	err := os.Remove(c.BlobPath(entry.Hash))
+	if err != nil {
+		return 0
+	}
+	return entry.Size
+}
+
+// Prune evicts least recently used entries until the cache
+// takes up at most targetSize bytes. It returns the number of bytes freed.
+func (c *Cache) Prune(conn *sqlite.Conn, consumer *state.Consumer, targetSize int64) int64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.prune(conn, consumer, targetSize)
+}
+
+func (c *Cache) prune(conn *sqlite.Conn, consumer *state.Consumer, targetSize int64) int64 {
+	entries := models.DownloadCacheEntriesByLastUse(conn)
+	totalSize := blobsSize(entries)
+
+	var freed int64
+	for _, entry := range entries {
+		if totalSize <= targetSize {
+			break
+		}
+		if c.holds[entry.Hash] > 0 {
+			// in use, evicting it would only orphan it
+			continue
+		}
+
+		removed := c.remove(conn, entry)
+		if removed > 0 {
+			consumer.Debugf("download cache: evicted %s (%s)", entry.Key, united.FormatBytes(removed))
+		}
+		totalSize -= removed
+		freed += removed
+	}
+	return freed
+}
+
+// Stats returns the number of entries in the cache, and how
+// much space their blobs take up on disk.
+func (c *Cache) Stats(conn *sqlite.Conn) (numEntries int64, size int64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entries := models.DownloadCacheEntriesByLastUse(conn)
+	return int64(len(entries)), blobsSize(entries)
+}
+
+func blobsSize(entries []*models.DownloadCacheEntry) int64 {
+	var size int64
+	seen := make(map[string]struct{})
+	for _, entry := range entries {
+		if _, ok := seen[entry.Hash]; ok {
+			continue
+		}
+		seen[entry.Hash] = struct{}{}
+		size += entry.Size
+	}
+	return size
+}
+
+// HashFile returns the hex-encoded MD5 hash of a file's contents
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer f.Close()
+
+	h := md5.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+func incomingDir(dir string) string {
+	return filepath.Join(dir, ".incoming")
+}
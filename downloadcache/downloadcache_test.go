@@ -0,0 +1,154 @@
+package downloadcache_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/database"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/downloadcache"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DownloadCache(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "downloadcache-test")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	consumer := &state.Consumer{
+		OnMessage: func(lvl string, msg string) { t.Logf("[%s] %s", lvl, msg) },
+	}
+
+	conn, err := sqlite.OpenConn(filepath.Join(dir, "butler.db"), 0)
+	wtest.Must(t, err)
+	defer conn.Close()
+	wtest.Must(t, database.Prepare(consumer, conn, database.PrepareOptions{JustCreated: true}))
+
+	dc, err := downloadcache.Enable(downloadcache.Settings{
+		Dir:     filepath.Join(dir, "cache"),
+		MaxSize: 100,
+	})
+	wtest.Must(t, err)
+	assert.True(dc.Fits(100))
+	assert.False(dc.Fits(101))
+	assert.False(dc.Fits(0))
+
+	put := func(key string, contents string, lastUsed time.Duration) *models.DownloadCacheEntry {
+		incomingPath := dc.IncomingPath(key)
+		wtest.Must(t, ioutil.WriteFile(incomingPath, []byte(contents), 0o644))
+		hash, err := downloadcache.HashFile(incomingPath)
+		wtest.Must(t, err)
+
+		entry, release, err := dc.Commit(conn, consumer, key, hash, int64(len(contents)))
+		wtest.Must(t, err)
+		release()
+		if lastUsed != 0 {
+			usedAt := time.Now().UTC().Add(-lastUsed)
+			entry.LastUsedAt = &usedAt
+			entry.Save(conn)
+		}
+		return entry
+	}
+	blob := func(contents string, n int) string {
+		buf := make([]byte, n)
+		for i := range buf {
+			buf[i] = contents[i%len(contents)]
+		}
+		return string(buf)
+	}
+
+	a := put("buildfile-1", blob("a", 40), 2*time.Hour)
+	b := put("buildfile-2", blob("b", 40), time.Hour)
+	assertStats := func(numEntries int64, size int64) {
+		t.Helper()
+		actualEntries, actualSize := dc.Stats(conn)
+		assert.EqualValues(numEntries, actualEntries)
+		assert.EqualValues(size, actualSize)
+	}
+	assertStats(2, 80)
+
+	// same contents, different key: shares a's blob
+	a2 := put("upload-1-40-0", blob("a", 40), 3*time.Hour)
+	assert.Equal(a.Hash, a2.Hash)
+	assertStats(3, 80)
+
+	// using a makes b the least recently used blob
+	dc.Touch(conn, dc.Lookup(conn, a.Key))
+	wtest.Must(t, dc.Check(a))
+
+	// going over 100 bytes evicts b, and the entry that
+	// shares a's blob, without removing the blob itself
+	c := put("buildfile-3", blob("c", 40), 0)
+	assert.Nil(dc.Lookup(conn, b.Key))
+	_, err = os.Stat(dc.BlobPath(b.Hash))
+	assert.True(os.IsNotExist(err))
+	assert.Nil(dc.Lookup(conn, a2.Key))
+	assert.NotNil(dc.Lookup(conn, a.Key))
+	assert.NotNil(dc.Lookup(conn, c.Key))
+	assertStats(2, 80)
+
+	// corrupted blobs are caught by Check
+	wtest.Must(t, ioutil.WriteFile(dc.BlobPath(c.Hash), []byte(blob("d", 40)), 0o644))
+	assert.Error(dc.Check(c))
+
+	// missing blobs make entries go away on lookup
+	wtest.Must(t, os.Remove(dc.BlobPath(c.Hash)))
+	assert.Nil(dc.Lookup(conn, c.Key))
+	assertStats(1, 40)
+
+	freed := dc.Prune(conn, consumer, 0)
+	assert.EqualValues(40, freed)
+	assertStats(0, 0)
+	_, err = os.Stat(dc.BlobPath(a.Hash))
+	assert.True(os.IsNotExist(err))
+
+	// last use within the same second: stored as "...00.12Z" and
+	// "...00.1Z", which sort the wrong way around as text
+	usedAt := func(key string, at time.Time) *models.DownloadCacheEntry {
+		entry := put(key, blob(key, 40), 0)
+		entry.LastUsedAt = &at
+		entry.Save(conn)
+		return entry
+	}
+	base := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+	later := usedAt("later", base.Add(120*time.Millisecond))
+	earlier := usedAt("earlier", base.Add(100*time.Millisecond))
+	dc.Prune(conn, consumer, 40)
+	assert.Nil(dc.Lookup(conn, earlier.Key))
+	assert.NotNil(dc.Lookup(conn, later.Key))
+
+	// held blobs aren't evicted...
+	held, release := dc.Hold(conn, later.Key)
+	if assert.NotNil(held) {
+		assert.EqualValues(0, dc.Prune(conn, consumer, 0))
+		assert.NotNil(dc.Lookup(conn, later.Key))
+	}
+
+	// ...nor removed until released, even if their entry is gone
+	assert.EqualValues(0, dc.Remove(conn, held))
+	assert.Nil(models.DownloadCacheEntryByKey(conn, later.Key))
+	_, err = os.Stat(dc.BlobPath(later.Hash))
+	wtest.Must(t, err)
+	release()
+	release()
+	_, err = os.Stat(dc.BlobPath(later.Hash))
+	assert.True(os.IsNotExist(err))
+
+	// committing the same contents again adopts an orphaned blob
+	held, release = dc.Hold(conn, put("again", blob("again", 40), 0).Key)
+	dc.Remove(conn, held)
+	again := put("again", blob("again", 40), 0)
+	release()
+	assert.NotNil(dc.Lookup(conn, again.Key))
+
+	_, release = dc.Hold(conn, "missing")
+	assert.Nil(release)
+}
@@ -9,6 +9,9 @@ import (
 	"github.com/itchio/butler/butlerd"
 	"github.com/itchio/butler/cmd/sizeof"
 	"github.com/itchio/butler/cmd/wipe"
+	"github.com/itchio/butler/downloadcache"
+
+	"crawshaw.io/sqlite"
 
 	"github.com/itchio/headway/united"
 )
@@ -67,6 +70,18 @@ func CleanDownloadsSearch(rc *butlerd.RequestContext, params butlerd.CleanDownlo
 	res := &butlerd.CleanDownloadsSearchResult{
 		Entries: entries,
 	}
+
+	if dc := downloadcache.Get(); dc != nil {
+		rc.WithConn(func(conn *sqlite.Conn) {
+			numEntries, size := dc.Stats(conn)
+			res.DownloadCache = &butlerd.DownloadCacheInfo{
+				Path:       dc.Dir(),
+				NumEntries: numEntries,
+				Size:       size,
+				MaxSize:    dc.MaxSize(),
+			}
+		})
+	}
 	return res, nil
 }
 
@@ -82,5 +97,16 @@ func CleanDownloadsApply(rc *butlerd.RequestContext, params butlerd.CleanDownloa
 	}
 
 	res := &butlerd.CleanDownloadsApplyResult{}
+
+	if params.DownloadCacheTargetSize != nil {
+		if dc := downloadcache.Get(); dc != nil {
+			rc.WithConn(func(conn *sqlite.Conn) {
+				res.DownloadCacheFreed = dc.Prune(conn, consumer, *params.DownloadCacheTargetSize)
+			})
+			consumer.Infof("Pruned %s from download cache", united.FormatBytes(res.DownloadCacheFreed))
+		} else {
+			consumer.Warnf("Download cache is disabled, nothing to prune")
+		}
+	}
 	return res, nil
 }
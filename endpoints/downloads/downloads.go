@@ -14,4 +14,5 @@ func Register(router *butlerd.Router) {
 	messages.DownloadsClearFinished.Register(router, DownloadsClearFinished)
 	messages.DownloadsDiscard.Register(router, DownloadsDiscard)
 	messages.DownloadsRetry.Register(router, DownloadsRetry)
+	messages.DownloadsHistory.Register(router, DownloadsHistory)
 }
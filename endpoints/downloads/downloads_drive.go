@@ -261,6 +261,8 @@ func performOne(parentCtx context.Context, rc *butlerd.RequestContext) error {
 	}
 	go goGadgetoDiscardWatcher()
 
+	history := newHistoryRecorder(download)
+
 	var stage = "prepare"
 	var progress, eta, bps float64
 	const maxSpeedDatapoints = 60
@@ -297,6 +299,7 @@ func performOne(parentCtx context.Context, rc *butlerd.RequestContext) error {
 		progress = params.Progress
 		eta = params.ETA
 		bps = params.BPS
+		history.Progress(progress)
 		return sendProgress()
 	})
 
@@ -304,6 +307,7 @@ func performOne(parentCtx context.Context, rc *butlerd.RequestContext) error {
 	rc.InterceptNotification(messages.TaskStarted.Method(), func(method string, paramsIn interface{}) error {
 		params := paramsIn.(butlerd.TaskStartedNotification)
 		stage = string(params.Type)
+		history.StageStarted(params)
 		return sendProgress()
 	})
 
@@ -337,36 +341,43 @@ func performOne(parentCtx context.Context, rc *butlerd.RequestContext) error {
 	if err != nil {
 		if wasDiscarded() {
 			// download errored, but it was already discarded, ignoring.
+			history.Finish(rc, butlerd.DownloadOutcomeDiscarded, nil, nil)
 			return nil
 		}
 
 		if be, ok := butlerd.AsButlerdError(err); ok {
-			switch butlerd.Code(be.RpcErrorCode()) {
+			code := be.RpcErrorCode()
+			msg := be.RpcErrorMessage()
+
+			switch butlerd.Code(code) {
 			case butlerd.CodeNetworkDisconnected:
 				// propagate so we can wait for the connection to be re-established
+				history.Finish(rc, butlerd.DownloadOutcomeInterrupted, &code, &msg)
 				return butlerd.CodeNetworkDisconnected
 			case butlerd.CodeOperationCancelled:
 				// the whole drive was probably cancelled?
+				history.Finish(rc, butlerd.DownloadOutcomeInterrupted, &code, &msg)
 				return nil
 			case butlerd.CodeOperationAborted:
 				consumer.Warnf("Download aborted, cleaning it out.")
+				history.Finish(rc, butlerd.DownloadOutcomeAborted, &code, &msg)
 				rc.WithConn(func(conn *sqlite.Conn) {
 					models.MustDelete(conn, &models.Download{}, builder.Eq{"id": download.ID})
 				})
 				return nil
 			}
 
-			code := be.RpcErrorCode()
 			download.ErrorCode = &code
-			msg := be.RpcErrorMessage()
 			download.ErrorMessage = &msg
 		} else {
 			var code int64
 			var msg string
 			if neterr.IsNetworkError(err) {
+				history.Finish(rc, butlerd.DownloadOutcomeInterrupted, nil, nil)
 				return butlerd.CodeNetworkDisconnected
 			} else if errors.Cause(err) == werrors.ErrCancelled {
 				// just cancelled, nothing to see here
+				history.Finish(rc, butlerd.DownloadOutcomeInterrupted, nil, nil)
 				return nil
 			} else {
 				code = int64(jsonrpc2.CodeInternalError)
@@ -383,6 +394,7 @@ func performOne(parentCtx context.Context, rc *butlerd.RequestContext) error {
 		finishedAt := time.Now().UTC()
 		download.FinishedAt = &finishedAt
 		rc.WithConn(download.Save)
+		history.Finish(rc, butlerd.DownloadOutcomeErrored, download.ErrorCode, download.ErrorMessage)
 
 		messages.DownloadsDriveErrored.Notify(rc, butlerd.DownloadsDriveErroredNotification{
 			Download: formatDownload(download),
@@ -395,6 +407,7 @@ func performOne(parentCtx context.Context, rc *butlerd.RequestContext) error {
 	finishedAt := time.Now().UTC()
 	download.FinishedAt = &finishedAt
 	rc.WithConn(download.Save)
+	history.Finish(rc, butlerd.DownloadOutcomeSucceeded, nil, nil)
 
 	messages.DownloadsDriveFinished.Notify(rc, butlerd.DownloadsDriveFinishedNotification{
 		Download: formatDownload(download),
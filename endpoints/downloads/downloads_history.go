@@ -0,0 +1,157 @@
+package downloads
+
+import (
+	"sync"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/google/uuid"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/endpoints/fetch/pager"
+	"xorm.io/builder"
+)
+
+func DownloadsHistory(rc *butlerd.RequestContext, params butlerd.DownloadsHistoryParams) (*butlerd.DownloadsHistoryResult, error) {
+	res := &butlerd.DownloadsHistoryResult{}
+
+	rc.WithConn(func(conn *sqlite.Conn) {
+		var cond = builder.NewCond()
+		if params.CaveID != "" {
+			cond = builder.And(cond, builder.Eq{"cave_id": params.CaveID})
+		}
+		if params.GameID != 0 {
+			cond = builder.And(cond, builder.Eq{"game_id": params.GameID})
+		}
+
+		items := models.DownloadHistoryItemsNewestFirst(conn, cond)
+		pg := pager.New(params)
+		start, end, next := pg.Window(int64(len(items)))
+		items = items[start:end]
+		res.NextCursor = next
+		models.PreloadDownloadHistoryItems(conn, items)
+		for _, item := range items {
+			res.Items = append(res.Items, formatDownloadHistoryItem(item))
+		}
+	})
+	return res, nil
+}
+
+func formatDownloadHistoryItem(item *models.DownloadHistoryItem) *butlerd.DownloadHistoryItem {
+	return &butlerd.DownloadHistoryItem{
+		ID:               item.ID,
+		DownloadID:       item.DownloadID,
+		CaveID:           item.CaveID,
+		Reason:           butlerd.DownloadReason(item.Reason),
+		Game:             item.Game,
+		Upload:           item.Upload,
+		Build:            item.Build,
+		BytesTransferred: item.BytesTransferred,
+		Timings: &butlerd.DownloadStageTimings{
+			Prepare:  item.PrepareSeconds,
+			Download: item.DownloadSeconds,
+			Install:  item.InstallSeconds,
+			Patch:    item.PatchSeconds,
+			Heal:     item.HealSeconds,
+		},
+		Outcome:      butlerd.DownloadOutcome(item.Outcome),
+		ErrorCode:    item.ErrorCode,
+		ErrorMessage: item.ErrorMessage,
+		StartedAt:    item.StartedAt,
+		FinishedAt:   item.FinishedAt,
+	}
+}
+
+// historyRecorder keeps track of stage timings and transferred
+// bytes while a download is being performed, and persists
+// a history item once it's done. Its methods are called from
+// notification interceptors, so they may run concurrently.
+type historyRecorder struct {
+	lock sync.Mutex
+	item *models.DownloadHistoryItem
+
+	stage          string
+	stageStartedAt time.Time
+	stageSize      int64
+	stageProgress  float64
+}
+
+func newHistoryRecorder(download *models.Download) *historyRecorder {
+	startedAt := time.Now().UTC()
+	return &historyRecorder{
+		item: &models.DownloadHistoryItem{
+			ID:         uuid.New().String(),
+			DownloadID: download.ID,
+			CaveID:     download.CaveID,
+			Reason:     download.Reason,
+			GameID:     download.GameID,
+			UploadID:   download.UploadID,
+			BuildID:    download.BuildID,
+			StartedAt:  &startedAt,
+		},
+		stage:          "prepare",
+		stageStartedAt: startedAt,
+	}
+}
+
+func (hr *historyRecorder) StageStarted(params butlerd.TaskStartedNotification) {
+	hr.lock.Lock()
+	defer hr.lock.Unlock()
+
+	hr.closeStage()
+	hr.stage = string(params.Type)
+	hr.stageStartedAt = time.Now().UTC()
+	hr.stageSize = params.TotalSize
+	hr.stageProgress = 0
+}
+
+func (hr *historyRecorder) Progress(progress float64) {
+	hr.lock.Lock()
+	defer hr.lock.Unlock()
+
+	hr.stageProgress = progress
+}
+
+// closeStage must be called with hr.lock held
+func (hr *historyRecorder) closeStage() {
+	elapsed := time.Since(hr.stageStartedAt).Seconds()
+	item := hr.item
+
+	switch butlerd.TaskType(hr.stage) {
+	case butlerd.TaskTypeDownload:
+		item.DownloadSeconds += elapsed
+	case butlerd.TaskTypeInstall:
+		item.InstallSeconds += elapsed
+	case butlerd.TaskTypeUpdate:
+		item.PatchSeconds += elapsed
+	case butlerd.TaskTypeHeal:
+		item.HealSeconds += elapsed
+	default:
+		item.PrepareSeconds += elapsed
+	}
+
+	switch butlerd.TaskType(hr.stage) {
+	case butlerd.TaskTypeDownload, butlerd.TaskTypeUpdate, butlerd.TaskTypeHeal:
+		item.BytesTransferred += int64(float64(hr.stageSize) * hr.stageProgress)
+	}
+	hr.stageSize = 0
+	hr.stageProgress = 0
+}
+
+// Finish records the outcome of the download attempt. errorCode and
+// errorMessage may be nil.
+func (hr *historyRecorder) Finish(rc *butlerd.RequestContext, outcome butlerd.DownloadOutcome, errorCode *int64, errorMessage *string) {
+	hr.lock.Lock()
+	defer hr.lock.Unlock()
+
+	hr.closeStage()
+
+	item := hr.item
+	finishedAt := time.Now().UTC()
+	item.FinishedAt = &finishedAt
+	item.Outcome = string(outcome)
+	item.ErrorCode = errorCode
+	item.ErrorMessage = errorMessage
+
+	rc.WithConn(item.Save)
+}
@@ -35,12 +35,16 @@ func FormatCave(conn *sqlite.Conn, cave *models.Cave) *butlerd.Cave {
 			InstalledSize:   cave.InstalledSize,
 			InstallLocation: cave.InstallLocationID,
 			Pinned:          cave.Pinned,
+			UpdatePolicy:    butlerd.UpdatePolicy(cave.UpdatePolicy),
 		},
 
 		Stats: &butlerd.CaveStats{
 			InstalledAt:   cave.InstalledAt,
 			LastTouchedAt: cave.LastTouchedAt,
 			SecondsRun:    cave.SecondsRun,
+			PackageFormat: butlerd.PackageFormat(cave.PackageFormat),
 		},
+
+		WrapperProfileID: cave.WrapperProfileID,
 	}
 }
@@ -18,7 +18,8 @@ func FetchCollection(rc *butlerd.RequestContext, params butlerd.FetchCollectionP
 	res := &butlerd.FetchCollectionResult{}
 
 	lazyfetch.Do(rc, ft, params, res, func(targets lazyfetch.Targets) {
-		_, client := rc.ProfileClient(params.ProfileID)
+		_, client, err := rc.ProfileClient(params.ProfileID)
+		models.Must(err)
 
 		collRes, err := client.GetCollection(rc.Ctx, itchio.GetCollectionParams{
 			CollectionID: params.CollectionID,
@@ -15,7 +15,8 @@ import (
 func LazyFetchCollectionGames(rc *butlerd.RequestContext, params lazyfetch.ProfiledLazyFetchParams, res lazyfetch.LazyFetchResponse, collectionID int64) {
 	ft := models.FetchTargetForCollectionGames(collectionID)
 	lazyfetch.Do(rc, ft, params, res, func(targets lazyfetch.Targets) {
-		_, client := rc.ProfileClient(params.GetProfileID())
+		_, client, err := rc.ProfileClient(params.GetProfileID())
+		models.Must(err)
 
 		var fakeColl = &itchio.Collection{
 			ID: collectionID,
@@ -11,7 +11,10 @@ import (
 )
 
 func FetchProfileCollections(rc *butlerd.RequestContext, params butlerd.FetchProfileCollectionsParams) (*butlerd.FetchProfileCollectionsResult, error) {
-	profile, client := rc.ProfileClient(params.ProfileID)
+	profile, client, err := rc.ProfileClient(params.ProfileID)
+	if err != nil {
+		return nil, err
+	}
 
 	ft := models.FetchTargetForProfileCollections(profile.ID)
 	res := &butlerd.FetchProfileCollectionsResult{}
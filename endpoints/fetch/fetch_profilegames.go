@@ -11,7 +11,8 @@ import (
 )
 
 func LazyFetchProfileGames(rc *butlerd.RequestContext, params lazyfetch.ProfiledLazyFetchParams, res lazyfetch.LazyFetchResponse) {
-	profile, client := rc.ProfileClient(params.GetProfileID())
+	profile, client, err := rc.ProfileClient(params.GetProfileID())
+	models.Must(err)
 
 	ft := models.FetchTargetForProfileGames(params.GetProfileID())
 	lazyfetch.Do(rc, ft, params, res, func(targets lazyfetch.Targets) {
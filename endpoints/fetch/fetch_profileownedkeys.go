@@ -13,7 +13,8 @@ import (
 
 func LazyFetchProfileOwnedKeys(rc *butlerd.RequestContext, params lazyfetch.ProfiledLazyFetchParams, res lazyfetch.LazyFetchResponse) {
 	consumer := rc.Consumer
-	profile, client := rc.ProfileClient(params.GetProfileID())
+	profile, client, err := rc.ProfileClient(params.GetProfileID())
+	models.Must(err)
 
 	ft := models.FetchTargetForProfileOwnedKeys(params.GetProfileID())
 	lazyfetch.Do(rc, ft, params, res, func(targets lazyfetch.Targets) {
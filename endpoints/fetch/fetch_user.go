@@ -13,7 +13,8 @@ func FetchUser(rc *butlerd.RequestContext, params butlerd.FetchUserParams) (*but
 	res := &butlerd.FetchUserResult{}
 
 	lazyfetch.Do(rc, ft, params, res, func(targets lazyfetch.Targets) {
-		_, client := rc.ProfileClient(params.ProfileID)
+		_, client, err := rc.ProfileClient(params.ProfileID)
+		models.Must(err)
 
 		userRes, err := client.GetUser(rc.Ctx, itchio.GetUserParams{
 			UserID: params.UserID,
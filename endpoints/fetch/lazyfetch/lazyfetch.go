@@ -67,6 +67,12 @@ func Do(
 			rc.Consumer.Infof("Waited %s for fetch (non-shared)", time.Since(startTime))
 		}
 	} else if rc.WithConnBool(ft.MustIsStale) {
+		if rc.OfflineFirst && rc.WithConnBool(ft.MustHasBeenFetched) {
+			// what we have will do for now, refresh it
+			// without making the client wait
+			rc.RefreshInBackground()
+			return
+		}
 		res.SetStale(true)
 	}
 }
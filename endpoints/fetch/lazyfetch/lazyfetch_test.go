@@ -0,0 +1,230 @@
+package lazyfetch_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/helloeave/json"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/jsonrpc2"
+	"github.com/itchio/butler/database"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/endpoints/fetch/lazyfetch"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingConn is a client that keeps the notifications it gets,
+// except for logs
+type recordingConn struct {
+	notifications chan jsonrpc2.Notification
+}
+
+var _ jsonrpc2.Conn = (*recordingConn)(nil)
+
+func (c *recordingConn) Call(method string, params interface{}, result interface{}) error {
+	return nil
+}
+
+func (c *recordingConn) Notify(method string, params interface{}) error {
+	if method == "Log" {
+		return nil
+	}
+	payload, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	raw := json.RawMessage(payload)
+	c.notifications <- jsonrpc2.Notification{Method: method, Params: &raw}
+	return nil
+}
+
+func (c *recordingConn) Context() context.Context {
+	return context.Background()
+}
+
+func (c *recordingConn) Close() {}
+
+type thingParams struct {
+	ThingID int64 `json:"thingId"`
+	Fresh   bool  `json:"fresh"`
+}
+
+func (p thingParams) IsFresh() bool {
+	return p.Fresh
+}
+
+type thingResult struct {
+	Value string `json:"value"`
+	Stale bool   `json:"stale,omitempty"`
+}
+
+func (r *thingResult) SetStale(stale bool) {
+	r.Stale = stale
+}
+
+// thingSource stands in for the API (remote) and the database (local)
+type thingSource struct {
+	lock    sync.Mutex
+	remote  string
+	local   string
+	fetches int
+}
+
+func (ts *thingSource) setRemote(value string) {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	ts.remote = value
+}
+
+func (ts *thingSource) fetch() {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	ts.local = ts.remote
+	ts.fetches++
+}
+
+func (ts *thingSource) read() (string, int) {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	return ts.local, ts.fetches
+}
+
+const thingTTL = 300 * time.Millisecond
+
+func Test_OfflineFirst(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "lazyfetch-test")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	dbPool, err := sqlitex.Open(filepath.Join(dir, "butler.db"), 0, 4)
+	wtest.Must(t, err)
+	defer dbPool.Close()
+
+	func() {
+		conn := dbPool.Get(context.Background())
+		defer dbPool.Put(conn)
+		consumer := &state.Consumer{
+			OnMessage: func(lvl string, msg string) { t.Logf("[%s] %s", lvl, msg) },
+		}
+		wtest.Must(t, database.Prepare(consumer, conn, database.PrepareOptions{JustCreated: true}))
+	}()
+
+	source := &thingSource{remote: "v1"}
+	router := butlerd.NewRouter(dbPool, nil, nil, nil)
+	router.Handlers["Fetch.Thing"] = func(rc *butlerd.RequestContext) (interface{}, error) {
+		var params thingParams
+		err := json.Unmarshal(*rc.Params, &params)
+		if err != nil {
+			return nil, err
+		}
+
+		ft := models.FetchTarget{Type: "thing", ID: params.ThingID, TTL: thingTTL}
+		res := &thingResult{}
+		lazyfetch.Do(rc, ft, params, res, func(targets lazyfetch.Targets) {
+			source.fetch()
+		})
+		res.Value, _ = source.read()
+		return res, nil
+	}
+
+	client := &recordingConn{notifications: make(chan jsonrpc2.Notification, 16)}
+	fetchThing := func(fresh bool) *thingResult {
+		t.Helper()
+		rawParams, err := json.Marshal(thingParams{ThingID: 1, Fresh: fresh})
+		wtest.Must(t, err)
+		rawMessage := json.RawMessage(rawParams)
+		res, err := router.HandleRequest(client, jsonrpc2.Request{
+			ID:     1,
+			Method: "Fetch.Thing",
+			Params: &rawMessage,
+		})
+		wtest.Must(t, err)
+		return res.(*thingResult)
+	}
+	fetchCount := func() int {
+		_, fetches := source.read()
+		return fetches
+	}
+	waitForFetches := func(count int) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for fetchCount() < count {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %d fetches, got %d", count, fetchCount())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	noNotification := func() {
+		t.Helper()
+		select {
+		case n := <-client.notifications:
+			t.Errorf("unexpected notification (%s)", n.Method)
+		case <-time.After(100 * time.Millisecond):
+			// good
+		}
+	}
+
+	// without offline-first, stale data is flagged as such
+	res := fetchThing(false)
+	assert.True(res.Stale)
+	assert.EqualValues(0, fetchCount())
+
+	router.OfflineFirst = true
+
+	// data that was never fetched is still flagged stale,
+	// there's nothing to answer with
+	res = fetchThing(false)
+	assert.True(res.Stale)
+	assert.EqualValues(0, fetchCount())
+	noNotification()
+
+	res = fetchThing(true)
+	assert.False(res.Stale)
+	assert.EqualValues("v1", res.Value)
+	assert.EqualValues(1, fetchCount())
+
+	// fresh data isn't refreshed at all
+	res = fetchThing(false)
+	assert.False(res.Stale)
+	noNotification()
+	assert.EqualValues(1, fetchCount())
+
+	// stale data is returned as-is, then refreshed in the background,
+	// and clients are told about it when it changed
+	source.setRemote("v2")
+	time.Sleep(2 * thingTTL)
+	res = fetchThing(false)
+	assert.False(res.Stale)
+	assert.EqualValues("v1", res.Value)
+
+	select {
+	case n := <-client.notifications:
+		assert.EqualValues("Fetch.Refreshed", n.Method)
+		var notif butlerd.FetchRefreshedNotification
+		wtest.Must(t, json.Unmarshal(*n.Params, &notif))
+		assert.EqualValues("Fetch.Thing", notif.Method)
+		assert.EqualValues(`{"thingId":1}`, notif.Params)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for Fetch.Refreshed")
+	}
+	assert.EqualValues(2, fetchCount())
+	assert.EqualValues("v2", fetchThing(false).Value)
+
+	// unchanged results are refreshed quietly
+	time.Sleep(2 * thingTTL)
+	res = fetchThing(false)
+	assert.False(res.Stale)
+	waitForFetches(3)
+	noNotification()
+}
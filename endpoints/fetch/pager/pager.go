@@ -20,6 +20,9 @@ type PagedRequest interface {
 
 type Pager interface {
 	Fetch(conn *sqlite.Conn, result interface{}, cond builder.Cond, search hades.Search) butlerd.Cursor
+	// Window is for results sorted outside of the database: it returns which
+	// of total items are on the requested page, and the cursor to the next one.
+	Window(total int64) (start int64, end int64, next butlerd.Cursor)
 }
 
 type pager struct {
@@ -60,6 +63,29 @@ func (p pager) Fetch(conn *sqlite.Conn, result interface{}, cond builder.Cond, s
 	return nextCur.Encode()
 }
 
+func (p pager) Window(total int64) (int64, int64, butlerd.Cursor) {
+	cur := &CursorInfo{}
+	cur.Decode(p.req.GetCursor())
+	limit := p.req.GetLimit()
+
+	start := cur.Offset
+	if start < 0 {
+		start = 0
+	}
+	if start > total {
+		start = total
+	}
+	end := total
+	var nextCur *CursorInfo
+	if limit > 0 && start+limit < total {
+		end = start + limit
+		nextCur = &CursorInfo{
+			Offset: end,
+		}
+	}
+	return start, end, nextCur.Encode()
+}
+
 // cursors!
 
 type CursorInfo struct {
@@ -4,6 +4,7 @@ import (
 	"crawshaw.io/sqlite"
 	"github.com/itchio/butler/butlerd"
 	"github.com/itchio/butler/database/models"
+	"github.com/pkg/errors"
 )
 
 func CavesSetPinned(rc *butlerd.RequestContext, params butlerd.CavesSetPinnedParams) (*butlerd.CavesSetPinnedResult, error) {
@@ -15,3 +16,17 @@ func CavesSetPinned(rc *butlerd.RequestContext, params butlerd.CavesSetPinnedPar
 
 	return &butlerd.CavesSetPinnedResult{}, nil
 }
+
+func CavesSetUpdatePolicy(rc *butlerd.RequestContext, params butlerd.CavesSetUpdatePolicyParams) (*butlerd.CavesSetUpdatePolicyResult, error) {
+	conn := rc.GetConn()
+	defer rc.PutConn(conn)
+
+	cave := models.CaveByID(conn, params.CaveID)
+	if cave == nil {
+		return nil, errors.Errorf("No such cave (%s)", params.CaveID)
+	}
+	cave.UpdatePolicy = string(params.UpdatePolicy)
+	cave.Save(conn)
+
+	return &butlerd.CavesSetUpdatePolicyResult{}, nil
+}
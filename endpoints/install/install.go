@@ -21,4 +21,5 @@ func Register(router *butlerd.Router) {
 	messages.InstallCreateShortcut.Register(router, InstallCreateShortcut)
 
 	messages.CavesSetPinned.Register(router, CavesSetPinned)
+	messages.CavesSetUpdatePolicy.Register(router, CavesSetUpdatePolicy)
 }
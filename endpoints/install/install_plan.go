@@ -2,6 +2,7 @@ package install
 
 import (
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/google/uuid"
@@ -175,5 +176,30 @@ func InstallPlan(rc *butlerd.RequestContext, params butlerd.InstallPlanParams) (
 		Accuracy:        dui.Accuracy.String(),
 	}
 
+	if params.Detailed {
+		// sniffing may have read parts of the file, so seek back to beginning
+		_, err = file.Seek(0, io.SeekStart)
+		if err != nil {
+			setResError(errors.WithStack(err))
+			return res, nil
+		}
+
+		details, err := planDetails(planDetailsParams{
+			rc:            rc,
+			consumer:      consumer,
+			client:        client,
+			sessionID:     sessionID,
+			installParams: installParams,
+			file:          file,
+			installerInfo: installerInfo,
+			installFolder: params.InstallFolder,
+		})
+		if err != nil {
+			setResError(errors.WithStack(err))
+			return res, nil
+		}
+		info.Details = details
+	}
+
 	return res, nil
 }
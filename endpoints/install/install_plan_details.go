@@ -0,0 +1,339 @@
+package install
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/itchio/arkive/zip"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/redist"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/httpkit/eos/option"
+	"github.com/itchio/hush"
+	"github.com/itchio/hush/bfs"
+	"github.com/itchio/hush/manifest"
+	"github.com/itchio/pelican"
+	"github.com/itchio/savior"
+	"github.com/itchio/savior/seeksource"
+	"github.com/itchio/wharf/pwr"
+	"github.com/pkg/errors"
+)
+
+type planDetailsParams struct {
+	rc            *butlerd.RequestContext
+	consumer      *state.Consumer
+	client        *itchio.Client
+	sessionID     string
+	installParams *operate.InstallParams
+	file          eos.File
+	installerInfo *hush.InstallerInfo
+	installFolder string
+}
+
+// planDetails lists the files an install would write, patch or remove,
+// without downloading the install source: wharf builds have their
+// signature fetched, archives have their index read.
+func planDetails(params planDetailsParams) (*butlerd.InstallPlanDetails, error) {
+	consumer := params.consumer
+
+	files, complete, err := planListFiles(params)
+	if err != nil {
+		return nil, errors.WithMessage(err, "listing files")
+	}
+
+	details := &butlerd.InstallPlanDetails{
+		Complete: complete,
+		Prereqs:  &butlerd.InstallPlanPrereqs{},
+	}
+
+	previousFiles := make(map[string]bool)
+	if params.installFolder != "" {
+		receipt, err := bfs.ReadReceipt(params.installFolder)
+		if err != nil {
+			consumer.Warnf("Could not read receipt in install folder: %v", err)
+		}
+		if receipt.HasFiles() {
+			for _, rf := range receipt.Files {
+				previousFiles[path.Clean(filepath.ToSlash(rf))] = true
+			}
+		}
+	}
+
+	var paths []string
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		action := butlerd.InstallPlanFileActionWrite
+		if previousFiles[p] {
+			action = butlerd.InstallPlanFileActionPatch
+		} else if params.installFolder != "" {
+			if _, err := os.Lstat(filepath.Join(params.installFolder, filepath.FromSlash(p))); err == nil {
+				details.Conflicts = append(details.Conflicts, p)
+			}
+		}
+
+		details.Files = append(details.Files, &butlerd.InstallPlanFile{
+			Path:   p,
+			Size:   files[p],
+			Action: action,
+		})
+	}
+
+	if complete {
+		var removed []string
+		for p := range previousFiles {
+			if _, ok := files[p]; !ok {
+				removed = append(removed, p)
+			}
+		}
+		sort.Strings(removed)
+		for _, p := range removed {
+			details.Files = append(details.Files, &butlerd.InstallPlanFile{
+				Path:   p,
+				Action: butlerd.InstallPlanFileActionRemove,
+			})
+		}
+	}
+
+	if _, ok := files[".itch.toml"]; ok {
+		appManifest, err := planReadManifest(params.file)
+		if err != nil {
+			consumer.Warnf("Could not read app manifest from install source: %v", err)
+		} else if appManifest != nil {
+			for _, p := range appManifest.Prereqs {
+				details.Prereqs.Names = append(details.Prereqs.Names, p.Name)
+			}
+			details.Prereqs.Needed = len(details.Prereqs.Names) > 0
+		}
+	} else if params.installParams.Upload.Platforms.Windows != "" {
+		details.Prereqs.Auto = planAutoPrereqs(params, files)
+	}
+
+	consumer.Infof("Detailed plan: %d files, %d conflicts, prereqs needed: %v",
+		len(details.Files), len(details.Conflicts), details.Prereqs.Needed)
+	return details, nil
+}
+
+// maxProbedExecutableSize is the largest executable planAutoPrereqs
+// extracts from an archive to look at its imports
+const maxProbedExecutableSize = 32 * 1024 * 1024
+
+// planAutoPrereqs returns true if an install without an app manifest
+// would have prerequisites determined on first launch: that's when an
+// executable imports DLLs that neither ship with it nor with Windows.
+// Which prerequisites provide them is only known from the redist registry
+// at launch time. Executables that can't be probed are ignored.
+func planAutoPrereqs(params planDetailsParams, files map[string]int64) bool {
+	consumer := params.consumer
+
+	needsPrereqs := func(exePath string, f eos.File) bool {
+		peInfo, err := pelican.Probe(f, pelican.ProbeParams{
+			Consumer: &state.Consumer{},
+		})
+		if err != nil {
+			consumer.Debugf("For auto prereqs: could not probe (%s): %v", exePath, err)
+			return false
+		}
+
+		for _, imp := range peInfo.Imports {
+			dll := strings.ToLower(imp)
+			if _, ok := redist.KnownBuiltinDLLs[dll]; ok {
+				continue
+			}
+			if planHasVendoredDLL(files, path.Dir(exePath), dll) {
+				continue
+			}
+			consumer.Infof("(%s) imports (%s), prereqs may be needed", exePath, dll)
+			return true
+		}
+		return false
+	}
+
+	if params.installerInfo.Type == hush.InstallerTypeNaked {
+		stats, err := params.file.Stat()
+		if err != nil || !strings.HasSuffix(strings.ToLower(stats.Name()), ".exe") {
+			return false
+		}
+		return needsPrereqs(filepath.Base(stats.Name()), params.file)
+	}
+
+	stats, err := params.file.Stat()
+	if err != nil {
+		return false
+	}
+	zr, err := zip.NewReader(params.file, stats.Size())
+	if err != nil {
+		// not a zip: only known at launch
+		return false
+	}
+
+	for _, zf := range zr.File {
+		exePath := path.Clean(zf.Name)
+		if !strings.HasSuffix(strings.ToLower(exePath), ".exe") {
+			continue
+		}
+		if zf.UncompressedSize64 > maxProbedExecutableSize {
+			consumer.Debugf("For auto prereqs: skipping (%s), too large to probe", exePath)
+			continue
+		}
+
+		found, err := func() (bool, error) {
+			f, err := ioutil.TempFile("", "butler-plan-exe")
+			if err != nil {
+				return false, errors.WithStack(err)
+			}
+			defer os.Remove(f.Name())
+			defer f.Close()
+
+			r, err := zf.Open()
+			if err != nil {
+				return false, errors.WithStack(err)
+			}
+			defer r.Close()
+
+			_, err = io.Copy(f, r)
+			if err != nil {
+				return false, errors.WithStack(err)
+			}
+			return needsPrereqs(exePath, f), nil
+		}()
+		if err != nil {
+			consumer.Debugf("For auto prereqs: could not extract (%s): %v", exePath, err)
+			continue
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// planHasVendoredDLL returns true if dll (lower-case) ships in dir
+func planHasVendoredDLL(files map[string]int64, dir string, dll string) bool {
+	for p := range files {
+		if path.Dir(p) == dir && strings.ToLower(path.Base(p)) == dll {
+			return true
+		}
+	}
+	return false
+}
+
+// planListFiles returns a map of slash-separated paths to sizes, and whether
+// that listing is complete.
+func planListFiles(params planDetailsParams) (map[string]int64, bool, error) {
+	consumer := params.consumer
+	files := make(map[string]int64)
+
+	if params.installParams.Build != nil {
+		signatureURL := operate.MakeSourceURL(params.client, consumer, params.sessionID, params.installParams, "signature")
+		signatureFile, err := eos.Open(signatureURL, option.WithConsumer(consumer))
+		if err != nil {
+			return nil, false, errors.WithStack(err)
+		}
+		defer signatureFile.Close()
+
+		signatureSource := seeksource.FromFile(signatureFile)
+		_, err = signatureSource.Resume(nil)
+		if err != nil {
+			return nil, false, errors.WithStack(err)
+		}
+
+		sigInfo, err := pwr.ReadSignature(params.rc.Ctx, signatureSource)
+		if err != nil {
+			return nil, false, errors.WithStack(err)
+		}
+
+		for _, f := range sigInfo.Container.Files {
+			files[f.Path] = f.Size
+		}
+		for _, s := range sigInfo.Container.Symlinks {
+			files[s.Path] = 0
+		}
+		return files, true, nil
+	}
+
+	switch params.installerInfo.Type {
+	case hush.InstallerTypeNaked:
+		stats, err := params.file.Stat()
+		if err != nil {
+			return nil, false, errors.WithStack(err)
+		}
+		files[filepath.Base(stats.Name())] = stats.Size()
+		return files, true, nil
+	case hush.InstallerTypeArchive:
+		if len(params.installerInfo.Entries) == 0 {
+			return files, false, nil
+		}
+		for _, e := range params.installerInfo.Entries {
+			switch e.Kind {
+			case savior.EntryKindFile:
+				files[e.CanonicalPath] = e.UncompressedSize
+			case savior.EntryKindSymlink:
+				files[e.CanonicalPath] = 0
+			}
+		}
+		return files, true, nil
+	}
+
+	return files, false, nil
+}
+
+// planReadManifest reads the app manifest from a zip install source,
+// only fetching the parts of the archive it needs.
+func planReadManifest(file eos.File) (*manifest.Manifest, error) {
+	stats, err := file.Stat()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	zr, err := zip.NewReader(file, stats.Size())
+	if err != nil {
+		return nil, errors.WithMessage(err, "install source is not a zip archive")
+	}
+
+	for _, zf := range zr.File {
+		if path.Clean(zf.Name) != ".itch.toml" {
+			continue
+		}
+
+		tmpDir, err := ioutil.TempDir("", "butler-plan-manifest")
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		defer os.RemoveAll(tmpDir)
+
+		err = func() error {
+			r, err := zf.Open()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			defer r.Close()
+
+			w, err := os.Create(manifest.Path(tmpDir))
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			defer w.Close()
+
+			_, err = io.Copy(w, r)
+			return errors.WithStack(err)
+		}()
+		if err != nil {
+			return nil, err
+		}
+
+		return manifest.Read(tmpDir)
+	}
+	return nil, nil
+}
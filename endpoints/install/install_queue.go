@@ -34,6 +34,30 @@ func InstallQueue(rc *butlerd.RequestContext, queueParams butlerd.InstallQueuePa
 		reason = butlerd.DownloadReasonInstall
 	}
 
+	if queueParams.AdoptFolder != "" {
+		if queueParams.NoCave || queueParams.CaveID != "" {
+			return nil, errors.New("adoptFolder cannot be used with noCave or caveId")
+		}
+		// it's stored as the cave's install folder, and compared
+		// against the others, so it must be absolute and clean
+		adoptFolder, err := filepath.Abs(queueParams.AdoptFolder)
+		if err != nil {
+			return nil, errors.WithMessage(err, "adoptFolder")
+		}
+		queueParams.AdoptFolder = filepath.Clean(adoptFolder)
+
+		stats, err := os.Stat(queueParams.AdoptFolder)
+		if err != nil {
+			return nil, errors.WithMessage(err, "adoptFolder")
+		}
+		if !stats.IsDir() {
+			return nil, errors.Errorf("adoptFolder (%s) is not a directory", queueParams.AdoptFolder)
+		}
+		if models.MustSelectOne(conn, &models.Cave{}, builder.Eq{"custom_install_folder": queueParams.AdoptFolder}) {
+			return nil, errors.Errorf("A cave already exists for (%s)", queueParams.AdoptFolder)
+		}
+	}
+
 	var id string
 	if queueParams.NoCave {
 		if queueParams.StagingFolder == "" {
@@ -117,6 +141,13 @@ func InstallQueue(rc *butlerd.RequestContext, queueParams butlerd.InstallQueuePa
 				InstallLocationID: queueParams.InstallLocationID,
 			}
 			consumer.Infof("Generated fresh cave %s", cave.ID)
+
+			if queueParams.AdoptFolder != "" {
+				consumer.Infof("Adopting files in (%s)", queueParams.AdoptFolder)
+				params.Adopt = true
+				cave.CustomInstallFolder = queueParams.AdoptFolder
+				cave.InstallFolderName = filepath.Base(queueParams.AdoptFolder)
+			}
 		} else {
 			consumer.Infof("Re-using cave %s", cave.ID)
 		}
@@ -233,6 +264,10 @@ func InstallQueue(rc *butlerd.RequestContext, queueParams butlerd.InstallQueuePa
 		}
 	}
 
+	if params.Adopt && params.Build == nil {
+		return nil, errors.New("Can only adopt existing files for wharf-enabled uploads")
+	}
+
 	oc.Save(meta)
 
 	istate := &operate.InstallSubcontextState{}
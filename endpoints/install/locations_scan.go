@@ -261,51 +261,23 @@ func (sc *scanContext) DoInstallLocation(il *models.InstallLocation) error {
 	rc := sc.rc
 	consumer := rc.Consumer
 
-	entries, err := ioutil.ReadDir(il.Path)
+	folders, err := operate.ScanCaveFolders(consumer, il)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
-	handleEntryPanics := func(entry os.FileInfo) error {
-		InstallFolderName := entry.Name()
-		InstallFolder := filepath.Join(il.Path, InstallFolderName)
-
-		if InstallFolderName == "downloads" {
-			// definitely not a cave folder, skip
-			return nil
-		}
-
-		if sc.hasCaveAtLoc(il.ID, InstallFolderName) {
+	handleFolderPanics := func(folder *operate.CaveFolder) error {
+		if sc.hasCaveAtLoc(il.ID, folder.Name) {
 			// already have cave, skip
 			return nil
 		}
 
-		dotItchPath := filepath.Join(InstallFolder, ".itch")
-		_, err := os.Stat(dotItchPath)
-		if err != nil {
-			// no .itch folder, skip
-			return nil
-		}
-
-		receipt, err := bfs.ReadReceipt(InstallFolder)
-		if err != nil {
-			consumer.Warnf("While reading receipt in (%s): %s", InstallFolder, err.Error())
-		}
-
-		legacyReceiptPath := filepath.Join(dotItchPath, "receipt.json")
-		legacyReceiptBytes, _ := ioutil.ReadFile(legacyReceiptPath)
-
-		if receipt != nil {
+		if receipt := folder.Receipt; receipt != nil {
 			var buildID int64
 			if receipt.Build != nil {
 				buildID = receipt.Build.ID
 			}
 
-			receiptStats, err := os.Stat(bfs.ReceiptPath(InstallFolder))
-			if err != nil {
-				return errors.WithStack(err)
-			}
-
 			legCave := &legacyCave{
 				ID:       uuid.New().String(),
 				GameID:   receipt.Game.ID,
@@ -316,14 +288,16 @@ func (sc *scanContext) DoInstallLocation(il *models.InstallLocation) error {
 				LastTouched: 0,
 				SecondsRun:  0,
 
-				InstalledAt: receiptStats.ModTime().UTC().Format(time.RFC3339),
+				InstalledAt: folder.ReceiptModTime.Format(time.RFC3339),
 
 				PathScheme:      2,
 				InstallLocation: il.ID,
-				InstallFolder:   InstallFolderName,
+				InstallFolder:   folder.Name,
 			}
 			sc.queue(&task{legCave, receipt.Files})
 		} else {
+			legacyReceiptPath := filepath.Join(folder.Path, ".itch", "receipt.json")
+			legacyReceiptBytes, _ := ioutil.ReadFile(legacyReceiptPath)
 			consumer.Infof("Reading legacy itch recept from %s", legacyReceiptPath)
 
 			lr := &legacyReceipt{}
@@ -350,7 +324,7 @@ func (sc *scanContext) DoInstallLocation(il *models.InstallLocation) error {
 		return nil
 	}
 
-	handleEntry := func(entry os.FileInfo) (err error) {
+	handleFolder := func(folder *operate.CaveFolder) (err error) {
 		defer func() {
 			if r := recover(); r != nil {
 				if rErr, ok := r.(error); ok {
@@ -360,14 +334,14 @@ func (sc *scanContext) DoInstallLocation(il *models.InstallLocation) error {
 				}
 			}
 		}()
-		err = handleEntryPanics(entry)
+		err = handleFolderPanics(folder)
 		return
 	}
 
-	for _, entry := range entries {
-		err := handleEntry(entry)
+	for _, folder := range folders {
+		err := handleFolder(folder)
 		if err != nil {
-			consumer.Errorf("While handling entry %s: %s", entry.Name(), err.Error())
+			consumer.Errorf("While handling entry %s: %s", folder.Name, err.Error())
 		}
 	}
 	return nil
@@ -0,0 +1,109 @@
+package launch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/itchio/headway/state"
+)
+
+// How deep to look for crash dumps in the install folder
+const crashDumpMaxDepth = 4
+
+// findCrashDumps looks for core dumps and crash dump files written
+// since a session started: in the working directory and install folder
+// (core files, minidumps, Unreal's Saved/Crashes), and in the places
+// the operating system keeps them.
+func findCrashDumps(consumer *state.Consumer, installFolder string, info CrashInfo) []string {
+	since := info.StartedAt.Add(-time.Second)
+	exeName := strings.TrimSuffix(filepath.Base(info.TargetPath), filepath.Ext(info.TargetPath))
+
+	var dumps []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			dumps = append(dumps, path)
+		}
+	}
+
+	isDump := func(name string) bool {
+		lower := strings.ToLower(name)
+		switch {
+		case lower == "core", strings.HasPrefix(lower, "core."):
+			return true
+		case strings.HasSuffix(lower, ".dmp"), strings.HasSuffix(lower, ".mdmp"):
+			return true
+		}
+		return false
+	}
+
+	var walk func(dir string, depth int)
+	walk = func(dir string, depth int) {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if entry.IsDir() {
+				if depth < crashDumpMaxDepth && entry.Name() != ".itch" {
+					walk(path, depth+1)
+				}
+				continue
+			}
+			if entry.ModTime().Before(since) {
+				continue
+			}
+			if isDump(entry.Name()) || strings.Contains(filepath.ToSlash(path), "/Saved/Crashes/") {
+				add(path)
+			}
+		}
+	}
+	walk(installFolder, 0)
+	if info.WorkingDirectory != "" {
+		walk(info.WorkingDirectory, crashDumpMaxDepth)
+	}
+
+	for _, dir := range systemCrashDumpDirs() {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || entry.ModTime().Before(since) {
+				continue
+			}
+			if exeName != "" && strings.Contains(strings.ToLower(entry.Name()), strings.ToLower(exeName)) {
+				add(filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+
+	if len(dumps) > 0 {
+		consumer.Infof("Found %d crash dumps", len(dumps))
+	}
+	return dumps
+}
+
+// systemCrashDumpDirs returns folders the operating system
+// writes crash dumps and reports to.
+func systemCrashDumpDirs() []string {
+	switch runtime.GOOS {
+	case "windows":
+		if localAppData := os.Getenv("LOCALAPPDATA"); localAppData != "" {
+			return []string{filepath.Join(localAppData, "CrashDumps")}
+		}
+	case "darwin":
+		if home, err := os.UserHomeDir(); err == nil {
+			return []string{filepath.Join(home, "Library", "Logs", "DiagnosticReports")}
+		}
+	case "linux":
+		return []string{"/var/lib/systemd/coredump", "/var/crash"}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package launch
+
+import (
+	"syscall"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/google/uuid"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/endpoints/fetch/pager"
+	"xorm.io/builder"
+)
+
+func CavesListCrashes(rc *butlerd.RequestContext, params butlerd.CavesListCrashesParams) (*butlerd.CavesListCrashesResult, error) {
+	res := &butlerd.CavesListCrashesResult{
+		Reports: []*butlerd.CrashReport{},
+	}
+
+	var err error
+	rc.WithConn(func(conn *sqlite.Conn) {
+		crashes := models.CaveCrashesNewestFirst(conn, builder.Eq{"cave_id": params.CaveID})
+		pg := pager.New(params)
+		start, end, next := pg.Window(int64(len(crashes)))
+		crashes = crashes[start:end]
+		res.NextCursor = next
+		for _, cc := range crashes {
+			var report *butlerd.CrashReport
+			report, err = formatCrashReport(cc)
+			if err != nil {
+				return
+			}
+			res.Reports = append(res.Reports, report)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func formatCrashReport(cc *models.CaveCrash) (*butlerd.CrashReport, error) {
+	report := &butlerd.CrashReport{
+		ID:                 cc.ID,
+		CaveID:             cc.CaveID,
+		UploadID:           cc.UploadID,
+		BuildID:            cc.BuildID,
+		ExitCode:           cc.ExitCode,
+		Signal:             cc.Signal,
+		SecondsRun:         cc.SecondsRun,
+		LaunchLogSessionID: cc.LaunchLogSessionID,
+		CreatedAt:          cc.CreatedAt,
+	}
+
+	var err error
+	report.Stdout, err = models.UnmarshalStrings(cc.Stdout)
+	if err != nil {
+		return nil, err
+	}
+	report.Stderr, err = models.UnmarshalStrings(cc.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	report.DumpFiles, err = models.UnmarshalStrings(cc.DumpFiles)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// Signals that ask a process to quit, or kill it outright: they're
+// sent by users, session managers and the like, not by crashes.
+var quitSignals = []syscall.Signal{
+	syscall.SIGHUP,
+	syscall.SIGINT,
+	syscall.SIGKILL,
+	syscall.SIGTERM,
+}
+
+// Signals a process gets when it crashes
+var crashSignals = []syscall.Signal{
+	syscall.SIGABRT,
+	syscall.SIGBUS,
+	syscall.SIGFPE,
+	syscall.SIGILL,
+	syscall.SIGSEGV,
+}
+
+// isCrash tells apart crashes from games that simply exited with
+// a non-zero exit code, which plenty of them do when quitting
+// normally. A session counts as a crash if:
+//
+//   - crash dumps were written during the session, or
+//   - the game was terminated by a signal, unless it's one that
+//     asks it to quit (SIGTERM, SIGKILL, etc.), or
+//   - it exited with 128+N, where N is a crash signal (SIGSEGV,
+//     SIGABRT, etc.), which is what shells and launcher scripts
+//     return when their child crashed, or
+//   - it exited with a Windows exception code (NTSTATUS errors,
+//     like 0xC0000005 for access violations), other than the one
+//     for Ctrl+C.
+//
+// Sessions killed with Launch.Kill are never crashes, launch
+// doesn't even report them.
+func isCrash(info CrashInfo, dumpFiles []string) bool {
+	if len(dumpFiles) > 0 {
+		return true
+	}
+
+	if info.Signal != "" {
+		for _, sig := range quitSignals {
+			if info.Signal == sig.String() {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, sig := range crashSignals {
+		if info.ExitCode == 128+int64(sig) {
+			return true
+		}
+	}
+
+	const statusSeverityError = 0xC0000000
+	const statusControlCExit = 0xC000013A
+	code := uint32(info.ExitCode)
+	if code&statusSeverityError == statusSeverityError && code != statusControlCExit {
+		return true
+	}
+
+	return false
+}
+
+// recordCrash stores a crash report for a cave, and relays it
+// to the client, if the session looks like a crash (see isCrash).
+// It returns whether it did.
+func recordCrash(rc *butlerd.RequestContext, cave *models.Cave, installFolder string, info CrashInfo) bool {
+	consumer := rc.Consumer
+
+	dumpFiles := findCrashDumps(consumer, installFolder, info)
+	if !isCrash(info, dumpFiles) {
+		consumer.Infof("Game exited with code %d after %s, not treating it as a crash", info.ExitCode, info.Duration)
+		return false
+	}
+	consumer.Warnf("Game crashed (exit code %d, signal %q) after %s", info.ExitCode, info.Signal, info.Duration)
+
+	now := time.Now().UTC()
+	cc := &models.CaveCrash{
+		ID:                 uuid.New().String(),
+		CaveID:             cave.ID,
+		GameID:             cave.GameID,
+		UploadID:           cave.UploadID,
+		BuildID:            cave.BuildID,
+		ExitCode:           info.ExitCode,
+		Signal:             info.Signal,
+		SecondsRun:         info.Duration.Seconds(),
+		LaunchLogSessionID: info.LaunchLogSessionID,
+		CreatedAt:          &now,
+	}
+	models.Must(models.MarshalStrings(info.Stdout, &cc.Stdout))
+	models.Must(models.MarshalStrings(info.Stderr, &cc.Stderr))
+	models.Must(models.MarshalStrings(dumpFiles, &cc.DumpFiles))
+	rc.WithConn(cc.Save)
+
+	report, err := formatCrashReport(cc)
+	if err != nil {
+		consumer.Warnf("Could not format crash report: %+v", err)
+		return true
+	}
+	messages.LaunchCrashed.Notify(rc, butlerd.LaunchCrashedNotification{
+		Report: report,
+	})
+	return true
+}
@@ -0,0 +1,150 @@
+package launch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_IsCrash(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.False(isCrash(CrashInfo{ExitCode: 1}, nil), "a plain non-zero exit code isn't a crash")
+	assert.False(isCrash(CrashInfo{ExitCode: 255}, nil))
+	assert.True(isCrash(CrashInfo{ExitCode: 1}, []string{"core"}), "crash dumps make it a crash")
+
+	assert.True(isCrash(CrashInfo{ExitCode: -1, Signal: syscall.SIGSEGV.String()}, nil))
+	assert.True(isCrash(CrashInfo{ExitCode: -1, Signal: syscall.SIGABRT.String()}, nil))
+	assert.False(isCrash(CrashInfo{ExitCode: -1, Signal: syscall.SIGTERM.String()}, nil), "being asked to quit isn't a crash")
+	assert.False(isCrash(CrashInfo{ExitCode: -1, Signal: syscall.SIGKILL.String()}, nil))
+
+	// from launcher scripts
+	assert.True(isCrash(CrashInfo{ExitCode: 128 + int64(syscall.SIGSEGV)}, nil))
+	assert.False(isCrash(CrashInfo{ExitCode: 128 + int64(syscall.SIGTERM)}, nil))
+
+	// windows exception codes, unsigned or not
+	assert.True(isCrash(CrashInfo{ExitCode: 0xC0000005}, nil))
+	assert.True(isCrash(CrashInfo{ExitCode: int64(int32(-1073741819))}, nil))
+	assert.False(isCrash(CrashInfo{ExitCode: 0xC000013A}, nil), "Ctrl+C isn't a crash")
+}
+
+func Test_FindCrashDumps(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "crash-dumps")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	installFolder := filepath.Join(dir, "install")
+	workingDirectory := filepath.Join(dir, "cwd")
+	startedAt := time.Now()
+
+	write := func(path string, modTime time.Time) string {
+		path = filepath.Join(dir, path)
+		wtest.Must(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		wtest.Must(t, ioutil.WriteFile(path, []byte("dump"), 0o644))
+		wtest.Must(t, os.Chtimes(path, modTime, modTime))
+		return path
+	}
+	fresh := startedAt.Add(time.Minute)
+	stale := startedAt.Add(-time.Hour)
+
+	expected := []string{
+		write("install/core", fresh),
+		write("install/bin/core.1234", fresh),
+		write("install/bin/Game.DMP", fresh),
+		write("install/Saved/Crashes/UE4CC-Windows-1/CrashContext.runtime-xml", fresh),
+		write("cwd/crash.mdmp", fresh),
+	}
+	write("install/old.dmp", stale)
+	write("install/readme.txt", fresh)
+	write("install/.itch/core", fresh)
+	write("install/a/b/c/d/e/core", fresh)
+
+	dumps := findCrashDumps(&state.Consumer{}, installFolder, CrashInfo{
+		StartedAt:        startedAt,
+		TargetPath:       filepath.Join(installFolder, "game"),
+		WorkingDirectory: workingDirectory,
+	})
+	// system folders may hold anything, only look at ours
+	var ours []string
+	for _, dump := range dumps {
+		if rel, err := filepath.Rel(dir, dump); err == nil && rel[0] != '.' {
+			ours = append(ours, dump)
+		}
+	}
+	assert.ElementsMatch(expected, ours)
+}
+
+func Test_CavesListCrashes(t *testing.T) {
+	assert := assert.New(t)
+	env := newRouterTestEnv(t)
+
+	cave := testCave()
+	installFolder := filepath.Join(env.dir, "install")
+	wtest.Must(t, os.MkdirAll(installFolder, 0o755))
+	cave.CustomInstallFolder = installFolder
+	env.withConn(func(conn *sqlite.Conn) {
+		models.MustSave(conn, cave)
+	})
+
+	// crashes are recorded during launches, which need a real game
+	var info CrashInfo
+	env.router.Register("Test.RecordCrash", func(rc *butlerd.RequestContext) (interface{}, error) {
+		return recordCrash(rc, cave, installFolder, info), nil
+	})
+	record := func(i CrashInfo) bool {
+		i.StartedAt = time.Now()
+		i.TargetPath = filepath.Join(installFolder, "game")
+		info = i
+		return env.mustCall("Test.RecordCrash", nil).(bool)
+	}
+
+	assert.False(record(CrashInfo{ExitCode: 1}))
+	assert.True(record(CrashInfo{ExitCode: 0xC0000005, Stderr: []string{"first"}}))
+	assert.True(record(CrashInfo{ExitCode: -1, Signal: syscall.SIGSEGV.String(), Stderr: []string{"second"}}))
+	assert.True(record(CrashInfo{ExitCode: 128 + int64(syscall.SIGABRT), Stderr: []string{"third"}}))
+
+	list := func(limit int64, cursor butlerd.Cursor) *butlerd.CavesListCrashesResult {
+		return env.mustCall("Caves.ListCrashes", butlerd.CavesListCrashesParams{
+			CaveID: cave.ID,
+			Limit:  limit,
+			Cursor: cursor,
+		}).(*butlerd.CavesListCrashesResult)
+	}
+
+	// recorded in quick succession, most recent first
+	res := list(0, "")
+	if assert.Len(res.Reports, 3) {
+		assert.EqualValues([]string{"third"}, res.Reports[0].Stderr)
+		assert.EqualValues([]string{"second"}, res.Reports[1].Stderr)
+		assert.EqualValues([]string{"first"}, res.Reports[2].Stderr)
+		assert.EqualValues(cave.UploadID, res.Reports[0].UploadID)
+		assert.EqualValues(syscall.SIGSEGV.String(), res.Reports[1].Signal)
+		assert.Empty(res.Reports[0].DumpFiles)
+	}
+	assert.Empty(res.NextCursor)
+
+	res = list(2, "")
+	if assert.Len(res.Reports, 2) && assert.NotEmpty(res.NextCursor) {
+		assert.EqualValues([]string{"third"}, res.Reports[0].Stderr)
+		res = list(2, res.NextCursor)
+		if assert.Len(res.Reports, 1) {
+			assert.EqualValues([]string{"first"}, res.Reports[0].Stderr)
+		}
+		assert.Empty(res.NextCursor)
+	}
+
+	res = env.mustCall("Caves.ListCrashes", butlerd.CavesListCrashesParams{CaveID: "other"}).(*butlerd.CavesListCrashesResult)
+	assert.Empty(res.Reports)
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/itchio/hades"
 	"github.com/itchio/hush/bfs"
 	"github.com/itchio/hush/manifest"
+	"github.com/itchio/ox"
 	"github.com/itchio/screw"
 	"github.com/pkg/errors"
 )
@@ -268,5 +269,9 @@ func getTargetsForHost(rc *butlerd.RequestContext,
 		targets = append(targets, target)
 	}
 
+	if len(targets) == 0 && host.Runtime.Platform == ox.PlatformLinux {
+		targets = append(targets, packageTargets(consumer, info.installFolder, host)...)
+	}
+
 	return targets, nil
 }
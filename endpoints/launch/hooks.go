@@ -0,0 +1,247 @@
+package launch
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/google/uuid"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/shell"
+	"github.com/pkg/errors"
+)
+
+func LaunchHooksList(rc *butlerd.RequestContext, params butlerd.LaunchHooksListParams) (*butlerd.LaunchHooksListResult, error) {
+	var hooks []*models.LaunchHook
+	rc.WithConn(func(conn *sqlite.Conn) {
+		if params.CaveID != "" {
+			hooks = models.LaunchHooksForCave(conn, params.CaveID)
+		} else {
+			hooks = models.AllLaunchHooks(conn)
+		}
+	})
+
+	res := &butlerd.LaunchHooksListResult{
+		Hooks: []*butlerd.LaunchHook{},
+	}
+	for _, lh := range hooks {
+		flh, err := formatLaunchHook(lh)
+		if err != nil {
+			rc.Consumer.Warnf("Skipping launch hook (%s): %v", lh.Name, err)
+			continue
+		}
+		res.Hooks = append(res.Hooks, flh)
+	}
+	return res, nil
+}
+
+func LaunchHooksSave(rc *butlerd.RequestContext, params butlerd.LaunchHooksSaveParams) (*butlerd.LaunchHooksSaveResult, error) {
+	conn := rc.GetConn()
+	defer rc.PutConn(conn)
+
+	if params.CaveID != "" {
+		if models.CaveByID(conn, params.CaveID) == nil {
+			return nil, errors.Errorf("cave (%s) not found", params.CaveID)
+		}
+	}
+
+	var args models.JSON
+	err := models.MarshalStrings(params.Args, &args)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+
+	var lh *models.LaunchHook
+	if params.ID == "" {
+		lh = &models.LaunchHook{
+			ID:        uuid.New().String(),
+			CreatedAt: &now,
+		}
+	} else {
+		lh = models.LaunchHookByID(conn, params.ID)
+		if lh == nil {
+			return nil, errors.Errorf("launch hook (%s) not found", params.ID)
+		}
+	}
+
+	lh.CaveID = params.CaveID
+	lh.Stage = string(params.Stage)
+	lh.Name = params.Name
+	lh.Command = params.Command
+	lh.Args = args
+	lh.WorkingDirectory = params.WorkingDirectory
+	lh.AbortOnFailure = params.AbortOnFailure
+	lh.UpdatedAt = &now
+	lh.Save(conn)
+
+	flh, err := formatLaunchHook(lh)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &butlerd.LaunchHooksSaveResult{
+		Hook: flh,
+	}
+	return res, nil
+}
+
+func LaunchHooksRemove(rc *butlerd.RequestContext, params butlerd.LaunchHooksRemoveParams) (*butlerd.LaunchHooksRemoveResult, error) {
+	conn := rc.GetConn()
+	defer rc.PutConn(conn)
+
+	lh := models.LaunchHookByID(conn, params.ID)
+	if lh == nil {
+		return nil, errors.Errorf("launch hook (%s) not found", params.ID)
+	}
+	lh.Delete(conn)
+
+	res := &butlerd.LaunchHooksRemoveResult{}
+	return res, nil
+}
+
+func formatLaunchHook(lh *models.LaunchHook) (*butlerd.LaunchHook, error) {
+	args, err := models.UnmarshalStrings(lh.Args)
+	if err != nil {
+		return nil, err
+	}
+	if args == nil {
+		args = []string{}
+	}
+
+	return &butlerd.LaunchHook{
+		ID:               lh.ID,
+		CaveID:           lh.CaveID,
+		Stage:            butlerd.LaunchHookStage(lh.Stage),
+		Name:             lh.Name,
+		Command:          lh.Command,
+		Args:             args,
+		WorkingDirectory: lh.WorkingDirectory,
+		AbortOnFailure:   lh.AbortOnFailure,
+		CreatedAt:        lh.CreatedAt,
+		UpdatedAt:        lh.UpdatedAt,
+	}, nil
+}
+
+type runHooksParams struct {
+	stage         butlerd.LaunchHookStage
+	cave          *models.Cave
+	installFolder string
+	// Set for post-exit hooks
+	outcome    string
+	secondsRun float64
+}
+
+// postExitHookTimeout is how long post-exit hooks may run. They aren't
+// tied to the launch, so that they complete even if it's cancelled.
+var postExitHookTimeout = 5 * time.Minute
+
+const (
+	sessionOutcomeSuccess = "success"
+	sessionOutcomeCrashed = "crashed"
+	sessionOutcomeFailed  = "failed"
+	sessionOutcomeKilled  = "killed"
+)
+
+// runHooks runs the global hooks, then the cave's own hooks for a stage,
+// in order. Failures are logged, except for those of pre-launch hooks
+// set to abort the launch, which stop there with CodePreLaunchHookFailed.
+func runHooks(rc *butlerd.RequestContext, params runHooksParams) error {
+	consumer := rc.Consumer
+	cave := params.cave
+
+	var hooks []*models.LaunchHook
+	rc.WithConn(func(conn *sqlite.Conn) {
+		hooks = models.LaunchHooksForCave(conn, cave.ID)
+	})
+
+	env := hookEnv(params)
+	for _, lh := range hooks {
+		if butlerd.LaunchHookStage(lh.Stage) != params.stage {
+			continue
+		}
+
+		err := runHook(rc, lh, params, env)
+		if err == nil {
+			continue
+		}
+
+		if params.stage == butlerd.LaunchHookStagePreLaunch && lh.AbortOnFailure {
+			consumer.Errorf("Pre-launch hook (%s) failed, aborting launch: %v", lh.Name, err)
+			return errors.WithStack(butlerd.CodePreLaunchHookFailed)
+		}
+		consumer.Warnf("%s hook (%s) failed: %v", params.stage, lh.Name, err)
+	}
+	return nil
+}
+
+func runHook(rc *butlerd.RequestContext, lh *models.LaunchHook, params runHooksParams, env []string) error {
+	consumer := rc.Consumer
+
+	args, err := models.UnmarshalStrings(lh.Args)
+	if err != nil {
+		return err
+	}
+
+	// relative to the install folder, not to wherever butlerd runs
+	dir := lh.WorkingDirectory
+	if dir == "" {
+		dir = params.installFolder
+	} else if !filepath.IsAbs(dir) {
+		dir = filepath.Join(params.installFolder, dir)
+	}
+
+	ctx := rc.Ctx
+	if params.stage == butlerd.LaunchHookStagePostExit {
+		// post-exit hooks (syncing saves, etc.) should run to completion
+		// even if the launch was cancelled, but not hang forever
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), postExitHookTimeout)
+		defer cancel()
+	}
+
+	consumer.Infof("Running %s hook (%s)", params.stage, lh.Name)
+	exitCode, err := shell.RunCommandWithParams(shell.RunCommandParams{
+		Consumer: consumer,
+		Ctx:      ctx,
+		Command:  append([]string{lh.Command}, args...),
+		Dir:      dir,
+		Env:      env,
+	})
+	if ctx.Err() == context.DeadlineExceeded {
+		return errors.Errorf("timed out after %s", postExitHookTimeout)
+	}
+	return shell.CheckExitCode(exitCode, err)
+}
+
+func hookEnv(params runHooksParams) []string {
+	cave := params.cave
+
+	vars := map[string]string{
+		"ITCHIO_HOOK_STAGE":     string(params.stage),
+		"ITCHIO_CAVE_ID":        cave.ID,
+		"ITCHIO_GAME_ID":        fmt.Sprintf("%d", cave.GameID),
+		"ITCHIO_UPLOAD_ID":      fmt.Sprintf("%d", cave.UploadID),
+		"ITCHIO_INSTALL_FOLDER": params.installFolder,
+	}
+	if cave.Game != nil {
+		vars["ITCHIO_GAME_TITLE"] = cave.Game.Title
+	}
+	if cave.BuildID != 0 {
+		vars["ITCHIO_BUILD_ID"] = fmt.Sprintf("%d", cave.BuildID)
+	}
+	if params.stage == butlerd.LaunchHookStagePostExit {
+		vars["ITCHIO_SESSION_OUTCOME"] = params.outcome
+		vars["ITCHIO_SECONDS_RUN"] = fmt.Sprintf("%.0f", params.secondsRun)
+	}
+
+	var env []string
+	for k, v := range vars {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+	return env
+}
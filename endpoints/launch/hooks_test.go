@@ -0,0 +1,160 @@
+package launch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/database/models"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func testCave() *models.Cave {
+	return &models.Cave{
+		ID:       "1b4e28ba-2fa1-11d2-883f-0016d3cca427",
+		GameID:   123,
+		UploadID: 456,
+		BuildID:  789,
+		Game: &itchio.Game{
+			ID:    123,
+			Title: "Hooked",
+		},
+	}
+}
+
+func Test_HookEnv(t *testing.T) {
+	assert := assert.New(t)
+
+	envMap := func(env []string) map[string]string {
+		res := make(map[string]string)
+		for _, kv := range env {
+			tokens := strings.SplitN(kv, "=", 2)
+			res[tokens[0]] = tokens[1]
+		}
+		return res
+	}
+
+	pre := envMap(hookEnv(runHooksParams{
+		stage:         butlerd.LaunchHookStagePreLaunch,
+		cave:          testCave(),
+		installFolder: "/games/hooked",
+	}))
+	assert.EqualValues(map[string]string{
+		"ITCHIO_HOOK_STAGE":     "pre-launch",
+		"ITCHIO_CAVE_ID":        "1b4e28ba-2fa1-11d2-883f-0016d3cca427",
+		"ITCHIO_GAME_ID":        "123",
+		"ITCHIO_GAME_TITLE":     "Hooked",
+		"ITCHIO_UPLOAD_ID":      "456",
+		"ITCHIO_BUILD_ID":       "789",
+		"ITCHIO_INSTALL_FOLDER": "/games/hooked",
+	}, pre)
+
+	cave := testCave()
+	cave.BuildID = 0
+	post := envMap(hookEnv(runHooksParams{
+		stage:         butlerd.LaunchHookStagePostExit,
+		cave:          cave,
+		installFolder: "/games/hooked",
+		outcome:       sessionOutcomeCrashed,
+		secondsRun:    41.6,
+	}))
+	assert.EqualValues("post-exit", post["ITCHIO_HOOK_STAGE"])
+	assert.EqualValues("crashed", post["ITCHIO_SESSION_OUTCOME"])
+	assert.EqualValues("42", post["ITCHIO_SECONDS_RUN"])
+	_, hasBuild := post["ITCHIO_BUILD_ID"]
+	assert.False(hasBuild)
+}
+
+func Test_RunHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks are shell scripts")
+	}
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "hooks-test")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	consumer := &state.Consumer{
+		OnMessage: func(lvl string, msg string) { t.Logf("[%s] %s", lvl, msg) },
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rc := &butlerd.RequestContext{
+		Ctx:      ctx,
+		Consumer: consumer,
+	}
+
+	hook := func(script string) *models.LaunchHook {
+		lh := &models.LaunchHook{
+			Name:    "test hook",
+			Command: "/bin/sh",
+		}
+		wtest.Must(t, models.MarshalStrings([]string{"-c", script}, &lh.Args))
+		return lh
+	}
+	params := func(stage butlerd.LaunchHookStage) runHooksParams {
+		return runHooksParams{
+			stage:         stage,
+			cave:          testCave(),
+			installFolder: dir,
+			outcome:       sessionOutcomeSuccess,
+		}
+	}
+	run := func(lh *models.LaunchHook, stage butlerd.LaunchHookStage) error {
+		p := params(stage)
+		return runHook(rc, lh, p, hookEnv(p))
+	}
+
+	// runs in the install folder, with the hook environment
+	wtest.Must(t, run(hook(`env | grep ^ITCHIO_ | sort > "$PWD/env.txt"`), butlerd.LaunchHookStagePostExit))
+	envTxt, err := ioutil.ReadFile(filepath.Join(dir, "env.txt"))
+	wtest.Must(t, err)
+	expected := hookEnv(params(butlerd.LaunchHookStagePostExit))
+	sort.Strings(expected)
+	assert.EqualValues(strings.Join(expected, "\n")+"\n", string(envTxt))
+
+	// relative working directories are relative to the install folder
+	wtest.Must(t, os.MkdirAll(filepath.Join(dir, "saves"), 0o755))
+	inSaves := hook(`pwd > "$PWD/pwd.txt"`)
+	inSaves.WorkingDirectory = "saves"
+	wtest.Must(t, run(inSaves, butlerd.LaunchHookStagePostExit))
+	pwdTxt, err := ioutil.ReadFile(filepath.Join(dir, "saves", "pwd.txt"))
+	wtest.Must(t, err)
+	savesDir, err := filepath.EvalSymlinks(filepath.Join(dir, "saves"))
+	wtest.Must(t, err)
+	assert.EqualValues(savesDir, strings.TrimSpace(string(pwdTxt)))
+
+	assert.Error(run(hook("exit 3"), butlerd.LaunchHookStagePreLaunch))
+
+	// post-exit hooks still run when the launch is cancelled...
+	cancel()
+	assert.Error(run(hook("true"), butlerd.LaunchHookStagePreLaunch))
+	assert.NoError(run(hook("true"), butlerd.LaunchHookStagePostExit))
+
+	// ...but don't get to hang forever
+	defer func(timeout time.Duration) {
+		postExitHookTimeout = timeout
+	}(postExitHookTimeout)
+	postExitHookTimeout = 200 * time.Millisecond
+
+	// children of the hook are killed with it, or they'd keep
+	// its output open
+	start := time.Now()
+	err = run(hook("sleep 30 & sleep 30; wait"), butlerd.LaunchHookStagePostExit)
+	assert.Error(err)
+	if err != nil {
+		assert.Contains(err.Error(), "timed out")
+	}
+	assert.True(time.Since(start) < 10*time.Second)
+}
@@ -10,10 +10,12 @@ import (
 
 	"github.com/pkg/errors"
 
+	"crawshaw.io/sqlite"
 	"github.com/itchio/butler/butlerd"
 	"github.com/itchio/butler/butlerd/horror"
 	"github.com/itchio/butler/butlerd/messages"
 	"github.com/itchio/butler/cmd/operate"
+	sandboxpolicy "github.com/itchio/butler/sandbox"
 	"github.com/itchio/hush/manifest"
 
 	"github.com/itchio/httpkit/neterr"
@@ -26,16 +28,40 @@ var ErrCandidateDisappeared = goerrors.New("candidate disappeared from disk!")
 
 func Register(router *butlerd.Router) {
 	messages.Launch.Register(router, Launch)
+	messages.LaunchList.Register(router, LaunchList)
+	messages.LaunchKill.Register(router, LaunchKill)
+	messages.LaunchWrappersList.Register(router, LaunchWrappersList)
+	messages.LaunchWrappersSave.Register(router, LaunchWrappersSave)
+	messages.LaunchWrappersRemove.Register(router, LaunchWrappersRemove)
+	messages.CavesSetWrapper.Register(router, CavesSetWrapper)
+	messages.CavesSetLaunchConfig.Register(router, CavesSetLaunchConfig)
+	messages.CavesGetLaunchConfig.Register(router, CavesGetLaunchConfig)
+	messages.CavesListLaunchLogs.Register(router, CavesListLaunchLogs)
+	messages.CavesReadLaunchLog.Register(router, CavesReadLaunchLog)
+	messages.CavesListCrashes.Register(router, CavesListCrashes)
+	messages.CavesPlaytimeStats.Register(router, CavesPlaytimeStats)
+	messages.LaunchHooksList.Register(router, LaunchHooksList)
+	messages.LaunchHooksSave.Register(router, LaunchHooksSave)
+	messages.LaunchHooksRemove.Register(router, LaunchHooksRemove)
 }
 
 func Launch(rc *butlerd.RequestContext, params butlerd.LaunchParams) (*butlerd.LaunchResult, error) {
 	consumer := rc.Consumer
 	var res *butlerd.LaunchResult
 
-	err := withInstallFolderLock(withInstallFolderLockParams{
+	// registered right away, so that concurrent launches of the same
+	// cave can't all get past the reject policy
+	launchID, err := rc.Launches.Add(params.CaveID, params.ConcurrentLaunch == butlerd.ConcurrentLaunchPolicyReject)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Launches.Remove(launchID)
+
+	err = withInstallFolderLock(withInstallFolderLockParams{
 		rc:     rc,
 		caveID: params.CaveID,
 		reason: "Launch",
+		shared: params.ConcurrentLaunch == butlerd.ConcurrentLaunchPolicyAllow,
 	}, func(info withInstallFolderInfo) error {
 		cave := info.cave
 		installFolder := info.installFolder
@@ -52,10 +78,17 @@ func Launch(rc *butlerd.RequestContext, params butlerd.LaunchParams) (*butlerd.L
 			return errors.WithStack(err)
 		}
 
+		launchConfig, err := getLaunchConfig(cave)
+		if err != nil {
+			return err
+		}
+
 		hosts, err := rc.HostEnumerator().Enumerate(rc.Consumer)
 		if err != nil {
 			return err
 		}
+		hosts = hostsForCave(rc, hosts, cave)
+		hosts = preferHostPlatform(rc, hosts, launchConfig.HostPlatform)
 
 		targetRes, err := getTargets(rc, getTargetsParams{
 			info:  info,
@@ -73,6 +106,10 @@ func Launch(rc *butlerd.RequestContext, params butlerd.LaunchParams) (*butlerd.L
 			consumer.Infof("Single target, picking it:")
 			target = targets[0]
 			consumer.Logf("%s", target.Strategy.String())
+		} else if t := pickConfiguredTarget(targets, launchConfig.ActionName); t != nil {
+			consumer.Infof("Picking action (%s), as per launch config:", launchConfig.ActionName)
+			target = t
+			consumer.Logf("%s", target.Strategy.String())
 		} else {
 			consumer.Infof("Found (%d) targets, asking client to pick via PickManifestAction", len(targets))
 			var actions []*manifest.Action
@@ -102,6 +139,11 @@ func Launch(rc *butlerd.RequestContext, params butlerd.LaunchParams) (*butlerd.L
 		consumer.Infof("  target (%s)", target.Strategy.FullTargetPath)
 		consumer.Infof("  host (%s)", target.Host)
 
+		if cave.PackageFormat != string(target.Strategy.PackageFormat) {
+			cave.PackageFormat = string(target.Strategy.PackageFormat)
+			rc.WithConn(cave.Save)
+		}
+
 		launcher := launchers[target.Strategy.Strategy]
 		if launcher == nil {
 			err := fmt.Errorf("no launcher for strategy (%s)", target.Strategy.Strategy)
@@ -113,8 +155,21 @@ func Launch(rc *butlerd.RequestContext, params butlerd.LaunchParams) (*butlerd.L
 		var env = make(map[string]string)
 
 		args = append(args, target.Action.Args...)
+		args = append(args, launchConfig.Args...)
 		fullTargetPath := target.Strategy.FullTargetPath
 
+		for k, v := range launchConfig.Env {
+			env[k] = v
+		}
+
+		if launchConfig.WorkingDirectory != "" {
+			workingDirectory, err = resolveWorkingDirectory(installFolder, launchConfig.WorkingDirectory)
+			if err != nil {
+				return errors.WithMessage(err, "in launch config")
+			}
+			consumer.Infof("Using working directory (%s), as per launch config", workingDirectory)
+		}
+
 		err = requestAPIKeyIfNecessary(rc, target.Action, game, access, env)
 		if err != nil {
 			return errors.WithMessage(err, "While requesting API key")
@@ -125,8 +180,33 @@ func Launch(rc *butlerd.RequestContext, params butlerd.LaunchParams) (*butlerd.L
 			consumer.Infof("Enabling sandbox because of manifest opt-in")
 			sandbox = true
 		}
+		if launchConfig.Sandbox && !sandbox {
+			consumer.Infof("Enabling sandbox because of launch config")
+			sandbox = true
+		}
+
+		sandboxPolicy := launchConfig.SandboxPolicy
+		if sandboxPolicy != nil {
+			consumer.Infof("Using sandbox policy from launch config")
+		} else {
+			manifestPolicy, err := sandboxpolicy.ReadManifestPolicy(manifest.Path(installFolder))
+			if err != nil {
+				consumer.Warnf("Could not read sandbox policy from manifest: %v", err)
+			}
+			sandboxPolicy = manifestPolicy
+		}
+
+		err = runHooks(rc, runHooksParams{
+			stage:         butlerd.LaunchHookStagePreLaunch,
+			cave:          cave,
+			installFolder: installFolder,
+		})
+		if err != nil {
+			return err
+		}
 
 		crashed := false
+		var sessionStartedAt time.Time
 		sessionWatcherDone := make(chan struct{})
 		sessionStartedChan := make(chan struct{})
 		var startSessionOnce sync.Once
@@ -139,93 +219,31 @@ func Launch(rc *butlerd.RequestContext, params butlerd.LaunchParams) (*butlerd.L
 			defer close(sessionWatcherDone)
 			defer horror.RecoverAndLog(consumer)
 
-			lastRunAt := time.Now().UTC()
-			sessionStartedAt := time.Now().UTC()
-			var secondsRun int64 = 0
-
-			conn := rc.GetConn()
-			defer rc.PutConn(conn)
-			access := operate.AccessForGameID(conn, cave.GameID)
+			var access *operate.GameAccess
+			rc.WithConn(func(conn *sqlite.Conn) {
+				access = operate.AccessForGameID(conn, cave.GameID)
+			})
 			client := rc.Client(access.APIKey)
 
-			var session *itchio.UserGameSession
-
-			createSession := func() (retErr error) {
-				defer horror.RecoverInto(&retErr)
-
-				res, err := client.CreateUserGameSession(rc.Ctx, itchio.CreateUserGameSessionParams{
-					GameID:       cave.GameID,
-					UploadID:     cave.UploadID,
-					BuildID:      cave.BuildID,
-					Credentials:  access.Credentials,
-					Platform:     interactionPlatform(runtime),
-					Architecture: interactionArchitecture(runtime),
-
-					SecondsRun: 0,
-					LastRunAt:  &lastRunAt,
-				})
-				if err != nil {
-					return errors.WithStack(err)
-				}
-				session = res.UserGameSession
-
-				cave.UpdateInteractions(res.Summary)
-				rc.WithConn(cave.Save)
-
-				return
-			}
-
-			updateSession := func() (retErr error) {
-				defer horror.RecoverInto(&retErr)
-
-				lastRunAt = time.Now().UTC()
-				secondsRun = int64(lastRunAt.Sub(sessionStartedAt).Seconds())
-				res, err := client.UpdateUserGameSession(rc.Ctx, itchio.UpdateUserGameSessionParams{
-					SessionID: session.ID,
-
-					SecondsRun: secondsRun,
-					LastRunAt:  &lastRunAt,
-					Crashed:    crashed,
-				})
-				if err != nil {
-					return errors.WithStack(err)
-				}
-				session = res.UserGameSession
-
-				cave.UpdateInteractions(res.Summary)
-				rc.WithConn(cave.Save)
+			tracker := newPlaySessionTracker(rc, client, access, cave, runtime)
 
-				return
-			}
-
-			// At game launch, create a session
-			err := createSession()
-			if err != nil {
-				consumer.Warnf("Initial session creation: %+v", err)
-				return
-			}
-
-			// Then wait for session to actually start
+			// Wait for session to actually start
 			select {
 			case <-sessionCtx.Done():
 				consumer.Debugf("Launch cancelled while waiting for session to start, bailing out")
 				return
 			case <-sessionStartedChan:
-				sessionStartedAt = time.Now().UTC()
-				lastRunAt = time.Now().UTC()
+				tracker.Begin()
 			}
 
 		regularUpdates:
 			for {
 				select {
 				case <-sessionCtx.Done():
-					consumer.Debugf("Launch cancelled while updating session regularly, bailing out")
-					return
+					consumer.Debugf("Launch cancelled while updating session regularly, recording session end")
+					break regularUpdates
 				case <-time.After(1 * time.Minute):
-					err := updateSession()
-					if err != nil {
-						consumer.Warnf("Regular session update: %+v", err)
-					}
+					tracker.Update()
 				case <-sessionEndedChan:
 					consumer.Debugf("Session ended normally!")
 					break regularUpdates
@@ -233,45 +251,87 @@ func Launch(rc *butlerd.RequestContext, params butlerd.LaunchParams) (*butlerd.L
 			}
 
 			// Then, do a final session update for accurate stats
-			err = updateSession()
-			if err != nil {
-				consumer.Warnf("Final session update: %+v", err)
-				return
-			}
-
-			consumer.Debugf("Entire session committed successfully!")
+			tracker.End(crashed)
+			consumer.Debugf("Session recorded")
 		}
 
 		go sessionWatcher()
 
+		// cancelled by Launch.Kill
+		launchCtx, launchCancel := context.WithCancel(rc.Ctx)
+		defer launchCancel()
+		rc.Launches.Start(launchID, target.Strategy.Strategy, launchCancel)
+
 		launcherParams := LauncherParams{
 			RequestContext: rc,
-			Ctx:            rc.Ctx,
-
-			FullTargetPath:   fullTargetPath,
-			Candidate:        target.Strategy.Candidate,
-			AppManifest:      targetRes.appManifest,
-			Action:           target.Action,
-			Sandbox:          sandbox,
-			WorkingDirectory: workingDirectory,
-			Args:             args,
-			Env:              env,
+			Ctx:            launchCtx,
+
+			FullTargetPath:      fullTargetPath,
+			Candidate:           target.Strategy.Candidate,
+			AppManifest:         targetRes.appManifest,
+			Action:              target.Action,
+			PackageFormat:       target.Strategy.PackageFormat,
+			Sandbox:             sandbox,
+			SandboxPolicy:       sandboxPolicy,
+			CrossOriginIsolated: launchConfig.CrossOriginIsolated,
+			WorkingDirectory:    workingDirectory,
+			Args:                args,
+			Env:                 env,
+			StreamOutput:        params.StreamOutput,
 
 			PrereqsDir:    params.PrereqsDir,
 			ForcePrereqs:  params.ForcePrereqs,
 			Access:        access,
+			CaveID:        cave.ID,
 			InstallFolder: installFolder,
 			Host:          target.Host,
 
+			SetPID: func(pid int64) {
+				rc.Launches.SetPID(launchID, pid)
+			},
 			SessionStarted: func() {
 				startSessionOnce.Do(func() {
+					sessionStartedAt = time.Now()
 					close(sessionStartedChan)
 				})
 			},
+			OnCrash: func(info CrashInfo) {
+				if launchCtx.Err() != nil {
+					// killed by Launch.Kill, or the launch was cancelled
+					return
+				}
+				crashed = recordCrash(rc, cave, installFolder, info)
+			},
 		}
 
 		err = launcher.Do(launcherParams)
 		close(sessionEndedChan)
+		killed := launchCtx.Err() != nil
+		if killed && rc.Ctx.Err() == nil {
+			consumer.Infof("Session was killed")
+			// whatever the launcher says, that's not a failure
+			err = nil
+		}
+
+		postExit := runHooksParams{
+			stage:         butlerd.LaunchHookStagePostExit,
+			cave:          cave,
+			installFolder: installFolder,
+			outcome:       sessionOutcomeSuccess,
+		}
+		if !sessionStartedAt.IsZero() {
+			postExit.secondsRun = time.Since(sessionStartedAt).Seconds()
+		}
+		if killed {
+			postExit.outcome = sessionOutcomeKilled
+		} else if crashed {
+			postExit.outcome = sessionOutcomeCrashed
+		} else if err != nil {
+			postExit.outcome = sessionOutcomeFailed
+		}
+
+		runHooks(rc, postExit)
+
 		if err != nil {
 			crashed = true
 			return err
@@ -0,0 +1,160 @@
+package launch
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/manager"
+	"github.com/itchio/butler/sandbox"
+	"github.com/itchio/ox"
+	"github.com/pkg/errors"
+)
+
+func CavesSetLaunchConfig(rc *butlerd.RequestContext, params butlerd.CavesSetLaunchConfigParams) (*butlerd.CavesSetLaunchConfigResult, error) {
+	conn := rc.GetConn()
+	defer rc.PutConn(conn)
+
+	cave := models.CaveByID(conn, params.CaveID)
+	if cave == nil {
+		return nil, errors.Errorf("cave (%s) not found", params.CaveID)
+	}
+
+	config := *params.Config
+	if config.WrapperProfileID != nil && *config.WrapperProfileID != "" {
+		if models.WrapperProfileByID(conn, *config.WrapperProfileID) == nil {
+			return nil, errors.Errorf("wrapper profile (%s) not found", *config.WrapperProfileID)
+		}
+	}
+	if config.SandboxPolicy != nil {
+		err := sandbox.ValidatePolicy(config.SandboxPolicy)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.WorkingDirectory != "" {
+		_, err := resolveWorkingDirectory(cave.GetInstallFolder(conn), config.WorkingDirectory)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// the wrapper profile belongs to Caves.SetWrapper, only
+	// touch it if it was specified
+	if config.WrapperProfileID != nil {
+		cave.WrapperProfileID = *config.WrapperProfileID
+	}
+	config.WrapperProfileID = nil
+
+	contents, err := json.Marshal(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshalling launch config")
+	}
+	cave.LaunchConfig = models.JSON(contents)
+	cave.Save(conn)
+
+	res := &butlerd.CavesSetLaunchConfigResult{}
+	return res, nil
+}
+
+func CavesGetLaunchConfig(rc *butlerd.RequestContext, params butlerd.CavesGetLaunchConfigParams) (*butlerd.CavesGetLaunchConfigResult, error) {
+	cave := operate.ValidateCave(rc, params.CaveID)
+
+	config, err := getLaunchConfig(cave)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &butlerd.CavesGetLaunchConfigResult{
+		Config: config,
+	}
+	return res, nil
+}
+
+// getLaunchConfig returns the launch configuration of a cave,
+// which is empty if it was never set.
+func getLaunchConfig(cave *models.Cave) (*butlerd.CaveLaunchConfig, error) {
+	config := &butlerd.CaveLaunchConfig{}
+	if cave.LaunchConfig != "" {
+		err := json.Unmarshal([]byte(cave.LaunchConfig), config)
+		if err != nil {
+			return nil, errors.Wrap(err, "unmarshalling launch config")
+		}
+	}
+	config.WrapperProfileID = nil
+	if cave.WrapperProfileID != "" {
+		wrapperProfileID := cave.WrapperProfileID
+		config.WrapperProfileID = &wrapperProfileID
+	}
+	return config, nil
+}
+
+// resolveWorkingDirectory returns the absolute path of a working
+// directory from a launch config. It must be an existing folder inside
+// the install folder, and relative paths are relative to it.
+func resolveWorkingDirectory(installFolder string, dir string) (string, error) {
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(installFolder, dir)
+	}
+	dir = filepath.Clean(dir)
+
+	// symlinks could point out of the install folder
+	realInstallFolder, err := filepath.EvalSymlinks(installFolder)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", errors.Errorf("working directory (%s) does not exist", dir)
+		}
+		return "", errors.WithStack(err)
+	}
+
+	rel, err := filepath.Rel(realInstallFolder, realDir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.Errorf("working directory (%s) is not inside the install folder (%s)", dir, installFolder)
+	}
+
+	stats, err := os.Stat(realDir)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if !stats.IsDir() {
+		return "", errors.Errorf("working directory (%s) is not a folder", dir)
+	}
+	return dir, nil
+}
+
+// preferHostPlatform moves hosts of the given platform first,
+// keeping the order otherwise.
+func preferHostPlatform(rc *butlerd.RequestContext, hosts manager.Hosts, platform ox.Platform) manager.Hosts {
+	if platform == "" {
+		return hosts
+	}
+	rc.Consumer.Infof("Preferring hosts for platform (%s), as per launch config", platform)
+
+	res := append(manager.Hosts{}, hosts...)
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Runtime.Platform == platform && res[j].Runtime.Platform != platform
+	})
+	return res
+}
+
+// pickConfiguredTarget returns the target whose action has
+// the given name, or nil if there's none.
+func pickConfiguredTarget(targets []*butlerd.LaunchTarget, actionName string) *butlerd.LaunchTarget {
+	if actionName == "" {
+		return nil
+	}
+	for _, t := range targets {
+		if t.Action != nil && t.Action.Name == actionName {
+			return t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package launch
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/manager"
+	"github.com/itchio/ox"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LaunchConfigWrapper(t *testing.T) {
+	assert := assert.New(t)
+	env := newRouterTestEnv(t)
+
+	cave := testCave()
+	cave.CustomInstallFolder = env.dir
+	env.withConn(func(conn *sqlite.Conn) {
+		models.MustSave(conn, cave)
+	})
+
+	res := env.mustCall("Launch.Wrappers.Save", butlerd.LaunchWrappersSaveParams{
+		Name:    "Proton 5.0",
+		Runtime: ox.Runtime{Platform: ox.PlatformWindows, Is64: true},
+		Wrapper: &manager.Wrapper{WrapperBinary: "/opt/proton/proton"},
+	})
+	wrapperID := res.(*butlerd.LaunchWrappersSaveResult).Wrapper.ID
+
+	setConfig := func(config *butlerd.CaveLaunchConfig) error {
+		_, err := env.call("Caves.SetLaunchConfig", butlerd.CavesSetLaunchConfigParams{
+			CaveID: cave.ID,
+			Config: config,
+		})
+		return err
+	}
+	getConfig := func() *butlerd.CaveLaunchConfig {
+		return env.mustCall("Caves.GetLaunchConfig", butlerd.CavesGetLaunchConfigParams{
+			CaveID: cave.ID,
+		}).(*butlerd.CavesGetLaunchConfigResult).Config
+	}
+	str := func(s string) *string { return &s }
+
+	env.mustCall("Caves.SetWrapper", butlerd.CavesSetWrapperParams{CaveID: cave.ID, WrapperProfileID: wrapperID})
+
+	// saving a config that doesn't mention the wrapper keeps it
+	wtest.Must(t, setConfig(&butlerd.CaveLaunchConfig{Args: []string{"--windowed"}}))
+	config := getConfig()
+	assert.EqualValues([]string{"--windowed"}, config.Args)
+	if assert.NotNil(config.WrapperProfileID) {
+		assert.EqualValues(wrapperID, *config.WrapperProfileID)
+	}
+
+	// mentioning it as empty unassigns it
+	wtest.Must(t, setConfig(&butlerd.CaveLaunchConfig{WrapperProfileID: str("")}))
+	config = getConfig()
+	assert.Nil(config.WrapperProfileID)
+	assert.Empty(config.Args)
+
+	assert.Error(setConfig(&butlerd.CaveLaunchConfig{WrapperProfileID: str("nope")}))
+	wtest.Must(t, setConfig(&butlerd.CaveLaunchConfig{WrapperProfileID: str(wrapperID)}))
+	config = getConfig()
+	if assert.NotNil(config.WrapperProfileID) {
+		assert.EqualValues(wrapperID, *config.WrapperProfileID)
+	}
+
+	// and a config saved with a wrapper doesn't stick around
+	// if it's unassigned with Caves.SetWrapper
+	env.mustCall("Caves.SetWrapper", butlerd.CavesSetWrapperParams{CaveID: cave.ID})
+	assert.Nil(getConfig().WrapperProfileID)
+}
+
+func Test_LaunchConfigWorkingDirectory(t *testing.T) {
+	assert := assert.New(t)
+	env := newRouterTestEnv(t)
+
+	installFolder := filepath.Join(env.dir, "install")
+	wtest.Must(t, os.MkdirAll(filepath.Join(installFolder, "bin"), 0o755))
+	wtest.Must(t, os.MkdirAll(filepath.Join(env.dir, "elsewhere"), 0o755))
+	f, err := os.Create(filepath.Join(installFolder, "game.exe"))
+	wtest.Must(t, err)
+	f.Close()
+
+	cave := testCave()
+	cave.CustomInstallFolder = installFolder
+	env.withConn(func(conn *sqlite.Conn) {
+		models.MustSave(conn, cave)
+	})
+
+	setWorkingDirectory := func(dir string) error {
+		_, err := env.call("Caves.SetLaunchConfig", butlerd.CavesSetLaunchConfigParams{
+			CaveID: cave.ID,
+			Config: &butlerd.CaveLaunchConfig{WorkingDirectory: dir},
+		})
+		return err
+	}
+
+	wtest.Must(t, setWorkingDirectory("bin"))
+	wtest.Must(t, setWorkingDirectory("."))
+	wtest.Must(t, setWorkingDirectory(filepath.Join(installFolder, "bin")))
+	wtest.Must(t, setWorkingDirectory(filepath.Join("bin", "..", "bin")))
+
+	for _, dir := range []string{
+		"missing",
+		"game.exe",
+		"..",
+		filepath.Join("..", "elsewhere"),
+		filepath.Join(env.dir, "elsewhere"),
+	} {
+		assert.Error(setWorkingDirectory(dir), "dir (%s)", dir)
+	}
+
+	if runtime.GOOS != "windows" {
+		wtest.Must(t, os.Symlink(filepath.Join(env.dir, "elsewhere"), filepath.Join(installFolder, "escape")))
+		err := setWorkingDirectory("escape")
+		if assert.Error(err) {
+			assert.Contains(err.Error(), "not inside the install folder")
+		}
+	}
+
+	dir, err := resolveWorkingDirectory(installFolder, "bin")
+	wtest.Must(t, err)
+	assert.EqualValues(filepath.Join(installFolder, "bin"), dir)
+}
@@ -0,0 +1,84 @@
+package launch
+
+import (
+	"io"
+	"os"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/launchlogs"
+	"github.com/pkg/errors"
+)
+
+const defaultLaunchLogReadLimit = 64 * 1024
+
+// maxLaunchLogReadLimit caps how much is read (and allocated) at once
+const maxLaunchLogReadLimit = defaultLaunchLogReadLimit * 16
+
+func CavesListLaunchLogs(rc *butlerd.RequestContext, params butlerd.CavesListLaunchLogsParams) (*butlerd.CavesListLaunchLogsResult, error) {
+	res := &butlerd.CavesListLaunchLogsResult{
+		Logs: []*butlerd.LaunchLog{},
+	}
+
+	ll := launchlogs.Get()
+	if ll == nil {
+		rc.Consumer.Infof("Launch logs are disabled")
+		return res, nil
+	}
+
+	files, err := ll.List(params.CaveID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		modifiedAt := f.ModifiedAt.UTC()
+		res.Logs = append(res.Logs, &butlerd.LaunchLog{
+			Name:       f.Name,
+			SessionID:  f.SessionID,
+			Stream:     butlerd.LaunchLogStream(f.Stream),
+			Part:       int64(f.Part),
+			Size:       f.Size,
+			ModifiedAt: &modifiedAt,
+		})
+	}
+	return res, nil
+}
+
+func CavesReadLaunchLog(rc *butlerd.RequestContext, params butlerd.CavesReadLaunchLogParams) (*butlerd.CavesReadLaunchLogResult, error) {
+	ll := launchlogs.Get()
+	if ll == nil {
+		return nil, errors.New("Launch logs are disabled")
+	}
+
+	logPath, err := ll.Path(params.CaveID, params.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(logPath)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer f.Close()
+
+	limit := params.Limit
+	if limit == 0 {
+		limit = defaultLaunchLogReadLimit
+	}
+	if limit > maxLaunchLogReadLimit {
+		limit = maxLaunchLogReadLimit
+	}
+
+	buf := make([]byte, limit)
+	n, err := f.ReadAt(buf, params.Offset)
+	if err != nil && err != io.EOF {
+		return nil, errors.WithStack(err)
+	}
+
+	res := &butlerd.CavesReadLaunchLogResult{
+		Content:    string(buf[:n]),
+		NextOffset: params.Offset + int64(n),
+		EOF:        err == io.EOF,
+	}
+	return res, nil
+}
@@ -0,0 +1,86 @@
+package launch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/launchlogs"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ReadLaunchLog(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "launch-logs-test")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	ll, err := launchlogs.Enable(launchlogs.Settings{
+		Dir: dir,
+	})
+	wtest.Must(t, err)
+
+	const caveID = "1b4e28ba-2fa1-11d2-883f-0016d3cca427"
+	session, err := ll.StartSession(caveID)
+	wtest.Must(t, err)
+	fmt.Fprint(session.Stdout(), "hello launch logs")
+	fmt.Fprint(session.Stderr(), strings.Repeat("x", maxLaunchLogReadLimit+10))
+	wtest.Must(t, session.Close())
+
+	rc := &butlerd.RequestContext{}
+	listRes, err := CavesListLaunchLogs(rc, butlerd.CavesListLaunchLogsParams{
+		CaveID: caveID,
+	})
+	wtest.Must(t, err)
+	assert.Len(listRes.Logs, 2)
+
+	names := make(map[butlerd.LaunchLogStream]string)
+	for _, log := range listRes.Logs {
+		names[log.Stream] = log.Name
+	}
+
+	read := func(name string, offset int64, limit int64) *butlerd.CavesReadLaunchLogResult {
+		res, err := CavesReadLaunchLog(rc, butlerd.CavesReadLaunchLogParams{
+			CaveID: caveID,
+			Name:   name,
+			Offset: offset,
+			Limit:  limit,
+		})
+		wtest.Must(t, err)
+		return res
+	}
+
+	res := read(names[butlerd.LaunchLogStreamStdout], 0, 5)
+	assert.EqualValues("hello", res.Content)
+	assert.EqualValues(5, res.NextOffset)
+	assert.False(res.EOF)
+
+	res = read(names[butlerd.LaunchLogStreamStdout], res.NextOffset, 0)
+	assert.EqualValues(" launch logs", res.Content)
+	assert.EqualValues(17, res.NextOffset)
+	assert.True(res.EOF)
+
+	res = read(names[butlerd.LaunchLogStreamStdout], 100, 0)
+	assert.EqualValues("", res.Content)
+	assert.EqualValues(100, res.NextOffset)
+	assert.True(res.EOF)
+
+	// huge limits are capped
+	res = read(names[butlerd.LaunchLogStreamStderr], 0, 1<<40)
+	assert.Len(res.Content, maxLaunchLogReadLimit)
+	assert.False(res.EOF)
+	res = read(names[butlerd.LaunchLogStreamStderr], res.NextOffset, 1<<40)
+	assert.Len(res.Content, 10)
+	assert.True(res.EOF)
+
+	_, err = CavesReadLaunchLog(rc, butlerd.CavesReadLaunchLogParams{
+		CaveID: "..",
+		Name:   names[butlerd.LaunchLogStreamStdout],
+	})
+	assert.Error(err)
+}
@@ -2,8 +2,11 @@ package html
 
 import (
 	"path/filepath"
+	"time"
 
+	"github.com/itchio/butler/butlerd/jsonrpc2"
 	"github.com/itchio/butler/butlerd/messages"
+	"github.com/skratchdot/open-golang/open"
 
 	"github.com/itchio/butler/butlerd"
 	"github.com/itchio/butler/endpoints/launch"
@@ -14,30 +17,99 @@ func Register() {
 	launch.RegisterLauncher(butlerd.LaunchStrategyHTML, &Launcher{})
 }
 
+// clientReplyGrace is how long the client has to turn down an
+// HTML launch before the play session starts.
+const clientReplyGrace = 2 * time.Second
+
+// browserIdleTimeout is how long a game opened in the system browser
+// is served after its last request.
+const browserIdleTimeout = 30 * time.Minute
+
 type Launcher struct{}
 
 var _ launch.Launcher = (*Launcher)(nil)
 
 func (l *Launcher) Do(params launch.LauncherParams) error {
+	consumer := params.RequestContext.Consumer
+
 	rootFolder := params.InstallFolder
 	indexPath, err := filepath.Rel(rootFolder, params.FullTargetPath)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
-	messages.LaunchRunning.Notify(params.RequestContext, butlerd.LaunchRunningNotification{})
-	params.SessionStarted()
-
-	_, err = messages.HTMLLaunch.Call(params.RequestContext, butlerd.HTMLLaunchParams{
-		RootFolder: rootFolder,
-		IndexPath:  indexPath,
-		Args:       params.Args,
-		Env:        params.Env,
+	server, err := NewServer(consumer, ServerParams{
+		RootFolder:          rootFolder,
+		CrossOriginIsolated: params.CrossOriginIsolated,
 	})
-	messages.LaunchExited.Notify(params.RequestContext, butlerd.LaunchExitedNotification{})
 	if err != nil {
-		return errors.WithStack(err)
+		return errors.WithMessage(err, "starting HTML5 server")
+	}
+	closeServer := true
+	defer func() {
+		if closeServer {
+			server.Close()
+		}
+	}()
+
+	url := server.URL(indexPath)
+	consumer.Infof("Serving (%s) at (%s)", rootFolder, url)
+
+	messages.LaunchRunning.Notify(params.RequestContext, butlerd.LaunchRunningNotification{
+		URL: url,
+	})
+	defer messages.LaunchExited.Notify(params.RequestContext, butlerd.LaunchExitedNotification{})
+
+	callDone := make(chan error, 1)
+	go func() {
+		_, err := messages.HTMLLaunch.Call(params.RequestContext, butlerd.HTMLLaunchParams{
+			RootFolder: rootFolder,
+			IndexPath:  indexPath,
+			URL:        url,
+			Args:       params.Args,
+			Env:        params.Env,
+		})
+		callDone <- err
+	}()
+
+	// clients that don't handle HTML launches say so right away, and
+	// those that do only reply once the game is closed, so the session
+	// only starts once the client had a moment to turn it down.
+	sessionStarted := false
+	select {
+	case err = <-callDone:
+	case <-time.After(clientReplyGrace):
+		params.SessionStarted()
+		sessionStarted = true
+		err = <-callDone
 	}
 
+	if err == nil || !isMethodNotFound(err) {
+		if !sessionStarted {
+			params.SessionStarted()
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return nil
+	}
+
+	// headless clients and the like: let the system browser do it.
+	// we can't tell when the tab is closed, so no play time is recorded,
+	// and the game is served until it hasn't requested anything in a while.
+	consumer.Infof("Client doesn't handle HTML launches, opening system browser")
+	err = open.Start(url)
+	if err != nil {
+		return errors.WithMessage(err, "opening system browser")
+	}
+	closeServer = false
+	server.CloseWhenIdle(browserIdleTimeout)
 	return nil
 }
+
+func isMethodNotFound(err error) bool {
+	if je, ok := errors.Cause(err).(*jsonrpc2.Error); ok {
+		return je.Code == jsonrpc2.CodeMethodNotFound
+	}
+	return false
+}
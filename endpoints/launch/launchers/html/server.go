@@ -0,0 +1,271 @@
+package html
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/itchio/headway/state"
+	"github.com/pkg/errors"
+)
+
+// Some engines (Unity, Emscripten) use extensions mime doesn't know
+// about, or gets wrong depending on the system's mime.types
+var contentTypes = map[string]string{
+	".html":      "text/html; charset=utf-8",
+	".js":        "application/javascript",
+	".mjs":       "application/javascript",
+	".json":      "application/json",
+	".css":       "text/css; charset=utf-8",
+	".wasm":      "application/wasm",
+	".data":      "application/octet-stream",
+	".mem":       "application/octet-stream",
+	".unityweb":  "application/octet-stream",
+	".symbols":   "application/octet-stream",
+	".pck":       "application/octet-stream",
+	".svg":       "image/svg+xml",
+	".ogg":       "audio/ogg",
+	".mp3":       "audio/mpeg",
+	".wav":       "audio/wav",
+	".webm":      "video/webm",
+	".mp4":       "video/mp4",
+	".ttf":       "font/ttf",
+	".woff":      "font/woff",
+	".woff2":     "font/woff2",
+	".txt":       "text/plain; charset=utf-8",
+	".xml":       "application/xml",
+	".webp":      "image/webp",
+	".png":       "image/png",
+	".jpg":       "image/jpeg",
+	".jpeg":      "image/jpeg",
+	".gif":       "image/gif",
+	".ico":       "image/x-icon",
+	".manifest":  "text/cache-manifest",
+	".appcache":  "text/cache-manifest",
+	".framework": "application/javascript",
+}
+
+// Pre-compressed files are served as-is, with the matching
+// Content-Encoding, like itch.io does for Unity builds.
+var contentEncodings = map[string]string{
+	".gz": "gzip",
+	".br": "br",
+}
+
+// Server serves an HTML5 game's files on localhost
+type Server struct {
+	// unix nanoseconds, accessed atomically. first in the struct,
+	// so that it's 64-bit aligned on 32-bit platforms
+	lastRequest int64
+
+	params   ServerParams
+	consumer *state.Consumer
+	listener net.Listener
+	server   *http.Server
+}
+
+// ServerParams configures how an HTML5 game is served
+type ServerParams struct {
+	RootFolder string
+
+	// If true, pages are served cross-origin isolated, which
+	// SharedArrayBuffer requires
+	CrossOriginIsolated bool
+}
+
+// NewServer starts serving params.RootFolder on a random localhost port.
+func NewServer(consumer *state.Consumer, params ServerParams) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	s := &Server{
+		params:   params,
+		consumer: consumer,
+		listener: listener,
+	}
+	s.touch()
+	s.server = &http.Server{
+		Handler: s,
+	}
+
+	go func() {
+		err := s.server.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			consumer.Warnf("HTML5 server stopped: %+v", err)
+		}
+	}()
+	return s, nil
+}
+
+// URL returns the address at which a file, relative to
+// the root folder, is served.
+func (s *Server) URL(relPath string) string {
+	u := url.URL{
+		Scheme: "http",
+		Host:   s.listener.Addr().String(),
+		Path:   "/" + filepath.ToSlash(relPath),
+	}
+	return u.String()
+}
+
+// Close stops the server, giving in-flight requests a moment to finish.
+func (s *Server) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	err := s.server.Shutdown(ctx)
+	if err != nil {
+		s.consumer.Warnf("While shutting down HTML5 server: %v", err)
+	}
+}
+
+// CloseWhenIdle closes the server in the background, once it hasn't
+// received any request for the given duration.
+func (s *Server) CloseWhenIdle(idle time.Duration) {
+	go func() {
+		for {
+			last := time.Unix(0, atomic.LoadInt64(&s.lastRequest))
+			remaining := idle - time.Since(last)
+			if remaining <= 0 {
+				s.consumer.Infof("No requests in %s, no longer serving", idle)
+				s.Close()
+				return
+			}
+			time.Sleep(remaining)
+		}
+	}()
+}
+
+func (s *Server) touch() {
+	atomic.StoreInt64(&s.lastRequest, time.Now().UnixNano())
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.touch()
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// a page on some other domain that resolves to 127.0.0.1
+	// (DNS rebinding) would send its own domain as Host
+	if !s.allowedHost(r.Host) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	h := w.Header()
+	if s.params.CrossOriginIsolated {
+		// SharedArrayBuffer (threaded wasm builds) requires the page
+		// to be cross-origin isolated
+		h.Set("Cross-Origin-Opener-Policy", "same-origin")
+		h.Set("Cross-Origin-Embedder-Policy", "require-corp")
+	}
+	h.Set("Cross-Origin-Resource-Policy", "same-origin")
+	h.Set("Cache-Control", "no-cache")
+
+	// path.Clean on a rooted path never goes above the root
+	urlPath := path.Clean("/" + r.URL.Path)
+	if strings.HasSuffix(urlPath, "/") {
+		urlPath += "index.html"
+	}
+	diskPath := filepath.Join(s.params.RootFolder, filepath.FromSlash(urlPath))
+
+	stats, err := os.Stat(diskPath)
+	if err == nil && stats.IsDir() {
+		diskPath = filepath.Join(diskPath, "index.html")
+		stats, err = os.Stat(diskPath)
+	}
+
+	encoding := ""
+	if err != nil {
+		// the game asked for `x`, but only `x.br` or `x.gz` was shipped
+		for _, ext := range []string{".br", ".gz"} {
+			if !acceptsEncoding(r, contentEncodings[ext]) {
+				continue
+			}
+			if cstats, cerr := os.Stat(diskPath + ext); cerr == nil && !cstats.IsDir() {
+				h.Set("Content-Encoding", contentEncodings[ext])
+				h.Set("Content-Type", contentType(diskPath))
+				h.Add("Vary", "Accept-Encoding")
+				diskPath += ext
+				stats, err = cstats, nil
+				break
+			}
+		}
+	} else {
+		ext := strings.ToLower(filepath.Ext(diskPath))
+		if enc, ok := contentEncodings[ext]; ok {
+			encoding = enc
+		}
+	}
+
+	if err != nil || stats.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	if encoding != "" {
+		h.Set("Content-Encoding", encoding)
+		h.Set("Content-Type", contentType(strings.TrimSuffix(diskPath, filepath.Ext(diskPath))))
+	} else if h.Get("Content-Type") == "" {
+		h.Set("Content-Type", contentType(diskPath))
+	}
+
+	f, err := os.Open(diskPath)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%v", err), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	// ServeContent handles Range and If-Modified-Since, and leaves
+	// our Content-Type alone since it's already set
+	http.ServeContent(w, r, "", stats.ModTime(), f)
+}
+
+// allowedHost returns true for the addresses the game is served at
+func (s *Server) allowedHost(host string) bool {
+	hostname, port, err := net.SplitHostPort(host)
+	if err != nil {
+		return false
+	}
+	_, ourPort, err := net.SplitHostPort(s.listener.Addr().String())
+	if err != nil || port != ourPort {
+		return false
+	}
+	return hostname == "127.0.0.1" || strings.EqualFold(hostname, "localhost")
+}
+
+func contentType(diskPath string) string {
+	ext := strings.ToLower(filepath.Ext(diskPath))
+	if ct, ok := contentTypes[ext]; ok {
+		return ct
+	}
+	if ct := mime.TypeByExtension(ext); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+func acceptsEncoding(r *http.Request, encoding string) bool {
+	for _, token := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		token = strings.TrimSpace(strings.SplitN(token, ";", 2)[0])
+		if token == encoding || token == "*" {
+			return true
+		}
+	}
+	return false
+}
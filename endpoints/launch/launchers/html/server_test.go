@@ -0,0 +1,210 @@
+package html
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/itchio/headway/state"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Server(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "html-server-test")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	rootFolder := filepath.Join(dir, "game")
+	write := func(path string, contents []byte) {
+		fullPath := filepath.Join(dir, filepath.FromSlash(path))
+		wtest.Must(t, os.MkdirAll(filepath.Dir(fullPath), 0o755))
+		wtest.Must(t, ioutil.WriteFile(fullPath, contents, 0o644))
+	}
+	write("secret.txt", []byte("secret"))
+	write("game/index.html", []byte("<p>Hi!</p>"))
+	write("game/levels/index.html", []byte("<p>Levels</p>"))
+	write("game/Build/game.wasm", []byte("\x00asm"))
+
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	_, err = gw.Write([]byte("var x = 1;"))
+	wtest.Must(t, err)
+	wtest.Must(t, gw.Close())
+	write("game/Build/game.js.gz", gz.Bytes())
+
+	consumer := &state.Consumer{
+		OnMessage: func(lvl string, msg string) { t.Logf("[%s] %s", lvl, msg) },
+	}
+	s, err := NewServer(consumer, ServerParams{
+		RootFolder: rootFolder,
+	})
+	wtest.Must(t, err)
+	defer s.Close()
+
+	host := s.listener.Addr().String()
+	get := func(target string, header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", target, nil)
+		r.Host = host
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		return w
+	}
+
+	{
+		w := get("/", nil)
+		assert.EqualValues(http.StatusOK, w.Code)
+		assert.EqualValues("<p>Hi!</p>", w.Body.String())
+		assert.EqualValues("text/html; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.EqualValues("same-origin", w.Header().Get("Cross-Origin-Resource-Policy"))
+		assert.EqualValues("no-cache", w.Header().Get("Cache-Control"))
+
+		// not isolated unless asked for
+		assert.Empty(w.Header().Get("Cross-Origin-Embedder-Policy"))
+		assert.Empty(w.Header().Get("Cross-Origin-Opener-Policy"))
+	}
+
+	assert.EqualValues("<p>Levels</p>", get("/levels", nil).Body.String())
+	assert.EqualValues("<p>Levels</p>", get("/levels/", nil).Body.String())
+	assert.EqualValues("application/wasm", get("/Build/game.wasm", nil).Header().Get("Content-Type"))
+
+	{
+		// only the pre-compressed file was shipped
+		w := get("/Build/game.js", http.Header{"Accept-Encoding": {"gzip, deflate"}})
+		assert.EqualValues(http.StatusOK, w.Code)
+		assert.EqualValues("gzip", w.Header().Get("Content-Encoding"))
+		assert.EqualValues("application/javascript", w.Header().Get("Content-Type"))
+		assert.EqualValues(gz.Bytes(), w.Body.Bytes())
+
+		assert.EqualValues(http.StatusNotFound, get("/Build/game.js", nil).Code)
+
+		// and when asked for directly, like Unity does
+		w = get("/Build/game.js.gz", nil)
+		assert.EqualValues("gzip", w.Header().Get("Content-Encoding"))
+		assert.EqualValues("application/javascript", w.Header().Get("Content-Type"))
+	}
+
+	for _, target := range []string{
+		"/../secret.txt",
+		"/%2e%2e/secret.txt",
+		"/levels/../../secret.txt",
+		"/..%2fsecret.txt",
+		"/..%5csecret.txt",
+		"/missing.html",
+	} {
+		w := get(target, nil)
+		assert.EqualValues(http.StatusNotFound, w.Code, "GET %s", target)
+		assert.NotContains(w.Body.String(), "secret", "GET %s", target)
+	}
+
+	{
+		r := httptest.NewRequest("POST", "/", strings.NewReader(""))
+		r.Host = host
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		assert.EqualValues(http.StatusMethodNotAllowed, w.Code)
+	}
+
+	{
+		_, port, err := net.SplitHostPort(host)
+		wtest.Must(t, err)
+		for _, badHost := range []string{"evil.example.com:" + port, "127.0.0.1:1", "127.0.0.1", ""} {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Host = badHost
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			assert.EqualValues(http.StatusForbidden, w.Code, "host (%s)", badHost)
+		}
+
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Host = "localhost:" + port
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		assert.EqualValues(http.StatusOK, w.Code)
+	}
+
+	{
+		// over the network, at the advertised URL
+		res, err := http.Get(s.URL("index.html"))
+		wtest.Must(t, err)
+		defer res.Body.Close()
+		assert.EqualValues(http.StatusOK, res.StatusCode)
+	}
+}
+
+func Test_ServerCrossOriginIsolated(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "html-server-test")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+	wtest.Must(t, ioutil.WriteFile(filepath.Join(dir, "index.html"), []byte("<p>Hi!</p>"), 0o644))
+
+	s, err := NewServer(&state.Consumer{}, ServerParams{
+		RootFolder:          dir,
+		CrossOriginIsolated: true,
+	})
+	wtest.Must(t, err)
+	defer s.Close()
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Host = s.listener.Addr().String()
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	assert.EqualValues(http.StatusOK, w.Code)
+	assert.EqualValues("same-origin", w.Header().Get("Cross-Origin-Opener-Policy"))
+	assert.EqualValues("require-corp", w.Header().Get("Cross-Origin-Embedder-Policy"))
+}
+
+func Test_ServerCloseWhenIdle(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "html-server-test")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+	wtest.Must(t, ioutil.WriteFile(filepath.Join(dir, "index.html"), []byte("<p>Hi!</p>"), 0o644))
+
+	consumer := &state.Consumer{
+		OnMessage: func(lvl string, msg string) { t.Logf("[%s] %s", lvl, msg) },
+	}
+	s, err := NewServer(consumer, ServerParams{
+		RootFolder: dir,
+	})
+	wtest.Must(t, err)
+	defer s.Close()
+
+	get := func() error {
+		client := &http.Client{Timeout: time.Second}
+		res, err := client.Get(s.URL("index.html"))
+		if err != nil {
+			return err
+		}
+		res.Body.Close()
+		return nil
+	}
+
+	idle := 300 * time.Millisecond
+	s.CloseWhenIdle(idle)
+
+	// requests keep it open
+	for i := 0; i < 4; i++ {
+		time.Sleep(idle / 2)
+		assert.NoError(get())
+	}
+
+	time.Sleep(idle * 3)
+	assert.Error(get(), "server should be closed once idle")
+}
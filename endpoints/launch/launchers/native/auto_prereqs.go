@@ -111,7 +111,7 @@ func handleAutoPrereqsWindows(params launch.LauncherParams, h prereqs.Handler) (
 			isVendored := vendorDLLs[lowerDLL]
 			if isVendored {
 				consumer.Infof("Found vendored DLL, skipping prereqs for (%s)", lowerDLL)
-			} else if desc, isBuiltin := knownBuiltinDLLs[lowerDLL]; isBuiltin {
+			} else if desc, isBuiltin := redist.KnownBuiltinDLLs[lowerDLL]; isBuiltin {
 				consumer.Infof("Found built-in DLL, skipping prereqs for (%s) (%s)", lowerDLL, desc)
 			} else {
 				importsMap[lowerDLL] = true
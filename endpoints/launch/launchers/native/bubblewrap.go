@@ -0,0 +1,50 @@
+package native
+
+import (
+	"runtime"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/butler/endpoints/launch"
+	"github.com/itchio/butler/sandbox"
+	"github.com/itchio/ox"
+	"github.com/pkg/errors"
+)
+
+// bubblewrapPath returns the path of the bwrap binary if the launch should
+// be sandboxed with it, or an empty string if the runner's own sandbox (or
+// none) should be used.
+//
+// If the policy asks for bubblewrap but the system can't run it, the
+// user gets to choose between falling back to firejail and aborting.
+func bubblewrapPath(params launch.LauncherParams) (string, error) {
+	consumer := params.RequestContext.Consumer
+
+	if !params.Sandbox || runtime.GOOS != "linux" || params.Host.Runtime.Platform != ox.PlatformLinux {
+		return "", nil
+	}
+	if params.SandboxPolicy == nil || params.SandboxPolicy.Backend != butlerd.SandboxBackendBubblewrap {
+		return "", nil
+	}
+
+	caps := sandbox.DetectBubblewrap(params.Ctx)
+	if sandbox.Usable(caps) {
+		consumer.Infof("Sandboxing with bubblewrap (%s)", caps.BubblewrapPath)
+		return caps.BubblewrapPath, nil
+	}
+	consumer.Warnf("Cannot sandbox with bubblewrap: %s", caps.Error)
+
+	r, err := messages.AllowSandboxSetup.Call(params.RequestContext, butlerd.AllowSandboxSetupParams{
+		Backend:      butlerd.SandboxBackendFirejail,
+		Capabilities: caps,
+	})
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	if !r.Allow {
+		return "", errors.WithStack(butlerd.CodeOperationAborted)
+	}
+	consumer.Infof("Falling back to firejail sandbox")
+	return "", nil
+}
@@ -0,0 +1,190 @@
+package native
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/butler/endpoints/launch"
+	"github.com/itchio/butler/shell"
+	"github.com/pkg/errors"
+)
+
+// preparePackage returns what to actually run to launch a Linux package,
+// installing it first if needed. Plain executables are left untouched.
+func preparePackage(params launch.LauncherParams, fullTargetPath string, args []string) (string, []string, error) {
+	switch params.PackageFormat {
+	case butlerd.PackageFormatAppImage:
+		return prepareAppImage(params, fullTargetPath, args)
+	case butlerd.PackageFormatFlatpak:
+		return prepareFlatpak(params, args)
+	case butlerd.PackageFormatSnap:
+		return prepareSnap(params, args)
+	}
+	return fullTargetPath, args, nil
+}
+
+// checkPackageSandbox refuses sandboxed launches of flatpak and snap
+// packages: they run in their own sandbox, set up by their runtime
+// with their own permissions, so neither the sandbox policy nor
+// firejail or bubblewrap would apply.
+func checkPackageSandbox(params launch.LauncherParams) error {
+	if !params.Sandbox {
+		return nil
+	}
+	switch params.PackageFormat {
+	case butlerd.PackageFormatFlatpak, butlerd.PackageFormatSnap:
+		params.RequestContext.Consumer.Warnf("A sandbox was requested, but %s packages can't be launched in it", params.PackageFormat)
+		return errors.WithStack(butlerd.CodeSandboxUnsupported)
+	}
+	return nil
+}
+
+func prepareAppImage(params launch.LauncherParams, fullTargetPath string, args []string) (string, []string, error) {
+	consumer := params.RequestContext.Consumer
+
+	if params.Sandbox {
+		// sandboxes don't give access to /dev/fuse
+		consumer.Infof("Sandboxed, letting AppImage extract itself")
+	} else if fuseAvailable() {
+		consumer.Infof("Launching AppImage, FUSE is available")
+		return fullTargetPath, args, nil
+	} else {
+		consumer.Infof("FUSE is not available, letting AppImage extract itself")
+	}
+	args = append([]string{"--appimage-extract-and-run"}, args...)
+	return fullTargetPath, args, nil
+}
+
+// fuseAvailable returns true if AppImages can mount themselves
+func fuseAvailable() bool {
+	if _, err := os.Stat("/dev/fuse"); err != nil {
+		return false
+	}
+	for _, name := range []string{"fusermount", "fusermount3"} {
+		if _, err := exec.LookPath(name); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func prepareFlatpak(params launch.LauncherParams, args []string) (string, []string, error) {
+	consumer := params.RequestContext.Consumer
+
+	flatpakPath, err := exec.LookPath("flatpak")
+	if err != nil {
+		return "", nil, errors.Errorf("flatpak must be installed to launch (%s)", params.FullTargetPath)
+	}
+
+	appID, err := flatpakRefName(params.FullTargetPath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if exec.Command(flatpakPath, "info", appID).Run() != nil {
+		consumer.Infof("Installing flatpak (%s)", appID)
+		exitCode, err := shell.RunCommandWithParams(shell.RunCommandParams{
+			Consumer: consumer,
+			Ctx:      params.Ctx,
+			Command:  []string{flatpakPath, "install", "--user", "--noninteractive", "--from", params.FullTargetPath},
+		})
+		err = shell.CheckExitCode(exitCode, err)
+		if err != nil {
+			return "", nil, errors.WithMessage(err, "installing flatpak")
+		}
+	}
+
+	consumer.Infof("Launching flatpak (%s)", appID)
+	return flatpakPath, append([]string{"run", appID}, args...), nil
+}
+
+// flatpakRefName returns the application ID a .flatpakref file refers to
+func flatpakRefName(refPath string) (string, error) {
+	f, err := os.Open(refPath)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer f.Close()
+
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = line
+			continue
+		}
+		if section != "[Flatpak Ref]" {
+			continue
+		}
+
+		tokens := strings.SplitN(line, "=", 2)
+		if len(tokens) == 2 && strings.TrimSpace(tokens[0]) == "Name" {
+			return strings.TrimSpace(tokens[1]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return "", errors.Errorf("no application name in (%s)", refPath)
+}
+
+func prepareSnap(params launch.LauncherParams, args []string) (string, []string, error) {
+	consumer := params.RequestContext.Consumer
+
+	snapPath, err := exec.LookPath("snap")
+	if err != nil {
+		return "", nil, errors.Errorf("snapd must be installed to launch (%s)", params.FullTargetPath)
+	}
+
+	name, err := snapName(snapPath, params.FullTargetPath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if exec.Command(snapPath, "list", name).Run() != nil {
+		// local snaps are unsigned, and installing them needs root,
+		// which we only ask for if the user agrees
+		manualInstall := fmt.Sprintf("sudo snap install --dangerous '%s'", params.FullTargetPath)
+		r, err := messages.AllowSnapInstall.Call(params.RequestContext, butlerd.AllowSnapInstallParams{
+			Name: name,
+			Path: params.FullTargetPath,
+		})
+		if err != nil {
+			return "", nil, errors.WithMessagef(err, "asking to install snap (%s), it can be installed manually with: %s", name, manualInstall)
+		}
+		if !r.Allow {
+			return "", nil, errors.Errorf("snap (%s) is not installed, it can be installed manually with: %s", name, manualInstall)
+		}
+
+		consumer.Infof("Installing snap (%s)", name)
+		exitCode, err := shell.RunElevatedCommand(consumer, []string{snapPath, "install", "--dangerous", params.FullTargetPath})
+		err = shell.CheckExitCode(exitCode, err)
+		if err != nil {
+			return "", nil, errors.WithMessage(err, "installing snap")
+		}
+	}
+
+	consumer.Infof("Launching snap (%s)", name)
+	return snapPath, append([]string{"run", name}, args...), nil
+}
+
+// snapName returns the name of the snap contained in a .snap file
+func snapName(snapPath string, file string) (string, error) {
+	out, err := exec.Command(snapPath, "info", file).Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "reading snap info of (%s)", file)
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "name:") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "name:")), nil
+		}
+	}
+	return "", errors.Errorf("no snap name in (%s)", file)
+}
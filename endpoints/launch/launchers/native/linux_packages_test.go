@@ -0,0 +1,170 @@
+package native
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/jsonrpc2"
+	"github.com/itchio/butler/endpoints/launch"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FlatpakRefName(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "flatpakref-test")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	name := func(contents string) (string, error) {
+		path := filepath.Join(dir, "game.flatpakref")
+		wtest.Must(t, ioutil.WriteFile(path, []byte(contents), 0o644))
+		return flatpakRefName(path)
+	}
+
+	appID, err := name(strings.Join([]string{
+		"[Flatpak Ref]",
+		"Title=Some Game",
+		"  Name = com.example.SomeGame  ",
+		"Branch=stable",
+		"",
+	}, "\n"))
+	wtest.Must(t, err)
+	assert.EqualValues("com.example.SomeGame", appID)
+
+	// only the [Flatpak Ref] section counts
+	appID, err = name(strings.Join([]string{
+		"[Other]",
+		"Name=org.example.Wrong",
+		"[Flatpak Ref]",
+		"Name=org.example.Right",
+	}, "\n"))
+	wtest.Must(t, err)
+	assert.EqualValues("org.example.Right", appID)
+
+	_, err = name("[Other]\nName=org.example.Wrong\n")
+	assert.Error(err)
+
+	_, err = name("Name=org.example.NoSection\n")
+	assert.Error(err)
+
+	_, err = flatpakRefName(filepath.Join(dir, "missing.flatpakref"))
+	assert.Error(err)
+}
+
+// refusingConn answers AllowSnapInstall with a refusal, and
+// records the methods it was called with.
+type refusingConn struct {
+	calls []string
+}
+
+var _ jsonrpc2.Conn = (*refusingConn)(nil)
+
+func (c *refusingConn) Call(method string, params interface{}, result interface{}) error {
+	c.calls = append(c.calls, method)
+	return json.Unmarshal([]byte(`{"allow": false}`), result)
+}
+
+func (c *refusingConn) Notify(method string, params interface{}) error {
+	return nil
+}
+
+func (c *refusingConn) Context() context.Context {
+	return context.Background()
+}
+
+func (c *refusingConn) Close() {}
+
+func Test_PrepareSnapRefused(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as fake snap")
+	}
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "snap-test")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	// a fake snap: the package isn't installed, and anything
+	// else it's asked to do is recorded
+	binDir := filepath.Join(dir, "bin")
+	wtest.Must(t, os.MkdirAll(binDir, 0o755))
+	logPath := filepath.Join(dir, "snap.log")
+	wtest.Must(t, ioutil.WriteFile(filepath.Join(binDir, "snap"), []byte(`#!/bin/sh
+case "$1" in
+  info) echo "name: some-game"; echo "version: 1.0" ;;
+  list) exit 1 ;;
+  *) echo "$@" >> "`+logPath+`" ;;
+esac
+`), 0o755))
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	snapFile := filepath.Join(dir, "some-game.snap")
+	wtest.Must(t, ioutil.WriteFile(snapFile, nil, 0o644))
+
+	conn := &refusingConn{}
+	params := launch.LauncherParams{
+		RequestContext: &butlerd.RequestContext{
+			Ctx:  context.Background(),
+			Conn: conn,
+			Consumer: &state.Consumer{
+				OnMessage: func(lvl string, msg string) { t.Logf("[%s] %s", lvl, msg) },
+			},
+		},
+		Ctx:            context.Background(),
+		FullTargetPath: snapFile,
+		PackageFormat:  butlerd.PackageFormatSnap,
+	}
+
+	_, _, err = preparePackage(params, snapFile, nil)
+	assert.Error(err)
+	if err != nil {
+		assert.Contains(err.Error(), "snap install --dangerous")
+		assert.Contains(err.Error(), snapFile)
+	}
+	assert.EqualValues([]string{"AllowSnapInstall"}, conn.calls)
+
+	_, err = os.Stat(logPath)
+	assert.True(os.IsNotExist(err), "snap must not be asked to install anything")
+}
+
+func Test_CheckPackageSandbox(t *testing.T) {
+	assert := assert.New(t)
+
+	check := func(format butlerd.PackageFormat, sandbox bool) error {
+		return checkPackageSandbox(launch.LauncherParams{
+			RequestContext: &butlerd.RequestContext{
+				Consumer: &state.Consumer{
+					OnMessage: func(lvl string, msg string) { t.Logf("[%s] %s", lvl, msg) },
+				},
+			},
+			PackageFormat: format,
+			Sandbox:       sandbox,
+		})
+	}
+
+	for _, format := range []butlerd.PackageFormat{butlerd.PackageFormatFlatpak, butlerd.PackageFormatSnap} {
+		assert.NoError(check(format, false))
+		err := check(format, true)
+		if assert.Error(err, "format (%s)", format) {
+			be, ok := butlerd.AsButlerdError(err)
+			if assert.True(ok) {
+				assert.EqualValues(butlerd.CodeSandboxUnsupported, be.RpcErrorCode())
+			}
+		}
+	}
+
+	// other formats go through the sandbox as usual
+	assert.NoError(check(butlerd.PackageFormatAppImage, true))
+	assert.NoError(check(butlerd.PackageFormat(""), true))
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/itchio/butler/butlerd/messages"
 	"github.com/itchio/butler/filtering"
 	"github.com/itchio/butler/mansion"
+	"github.com/itchio/butler/sandbox"
 	"github.com/itchio/butler/shell"
 
 	"github.com/itchio/butler/butlerd"
@@ -69,7 +70,17 @@ func (l *Launcher) Do(params launch.LauncherParams) error {
 		consumer.Warnf("Could not determine PE info: %s", err.Error())
 	}
 
-	err = handlePrereqs(params)
+	err = checkPackageSandbox(params)
+	if err != nil {
+		return err
+	}
+
+	bwrapPath, err := bubblewrapPath(params)
+	if err != nil {
+		return err
+	}
+
+	err = handlePrereqs(params, params.Sandbox && bwrapPath == "")
 	if err != nil {
 		if be, ok := butlerd.AsButlerdError(err); ok {
 			switch butlerd.Code(be.RpcErrorCode()) {
@@ -103,6 +114,11 @@ func (l *Launcher) Do(params launch.LauncherParams) error {
 	}
 
 	envMap := make(map[string]string)
+	if params.Host.Wrapper != nil {
+		for k, v := range params.Host.Wrapper.Env {
+			envMap[k] = v
+		}
+	}
 	for k, v := range params.Env {
 		envMap[k] = v
 	}
@@ -123,6 +139,18 @@ func (l *Launcher) Do(params launch.LauncherParams) error {
 		envMap["ITCHIO_SANDBOX"] = "1"
 	}
 
+	var sandboxHome string
+	if bwrapPath != "" {
+		sandboxHome, err = sandbox.HomeDir(params.CaveID)
+		if err != nil {
+			return errors.WithMessage(err, "preparing bubblewrap sandbox")
+		}
+		for k, v := range sandbox.HomeEnv(sandboxHome) {
+			envMap[k] = v
+		}
+		consumer.Infof("Giving app sandbox home (%s)", sandboxHome)
+	}
+
 	var envKeys []string
 	for k := range envMap {
 		envKeys = append(envKeys, k)
@@ -136,8 +164,10 @@ func (l *Launcher) Do(params launch.LauncherParams) error {
 	}
 
 	const maxLines = 40
-	stdout := newOutputCollector(maxLines)
-	stderr := newOutputCollector(maxLines)
+	stdout := newOutputCollector(maxLines, streamLine(params, butlerd.LaunchLogStreamStdout))
+	stderr := newOutputCollector(maxLines, streamLine(params, butlerd.LaunchLogStreamStderr))
+	captured := captureOutput(params, stdout, stderr)
+	defer captured.Done()
 
 	fullTargetPath := params.FullTargetPath
 	args := params.Args
@@ -184,17 +214,42 @@ func (l *Launcher) Do(params launch.LauncherParams) error {
 		}
 	}
 
+	fullTargetPath, args, err = preparePackage(params, fullTargetPath, args)
+	if err != nil {
+		return err
+	}
+
 	console := false
 	if params.Action != nil && params.Action.Console {
 		console = true
 		consumer.Infof("Console launch requested")
 	}
 
+	runnerSandbox := params.Sandbox
+	if bwrapPath != "" {
+		bwrapArgs, err := sandbox.BubblewrapArgs(sandbox.BubblewrapParams{
+			Policy:         params.SandboxPolicy,
+			InstallFolder:  params.InstallFolder,
+			TempDir:        tempDir,
+			HomeDir:        sandboxHome,
+			Dir:            cwd,
+			FullTargetPath: fullTargetPath,
+			Args:           args,
+		})
+		if err != nil {
+			return errors.WithMessage(err, "preparing bubblewrap sandbox")
+		}
+		args = bwrapArgs
+		fullTargetPath = bwrapPath
+		// bubblewrap is the sandbox, the runner doesn't need one
+		runnerSandbox = false
+	}
+
 	runParams := runner.RunnerParams{
 		Consumer: consumer,
 		Ctx:      params.Ctx,
 
-		Sandbox: params.Sandbox,
+		Sandbox: runnerSandbox,
 		Console: console,
 
 		FullTargetPath: fullTargetPath,
@@ -203,8 +258,8 @@ func (l *Launcher) Do(params launch.LauncherParams) error {
 		Dir:    cwd,
 		Args:   args,
 		Env:    envBlock,
-		Stdout: stdout,
-		Stderr: stderr,
+		Stdout: captured.Stdout,
+		Stderr: captured.Stderr,
 
 		TempDir:       tempDir,
 		InstallFolder: params.InstallFolder,
@@ -230,7 +285,11 @@ func (l *Launcher) Do(params launch.LauncherParams) error {
 		params.SessionStarted()
 
 		messages.LaunchRunning.Notify(params.RequestContext, butlerd.LaunchRunningNotification{})
-		exitCode, err := interpretRunError(run.Run())
+		runDone := make(chan struct{})
+		go watchPID(params, runParams.FullTargetPath, runDone)
+		runErr := run.Run()
+		close(runDone)
+		exitCode, err := interpretRunError(runErr)
 		messages.LaunchExited.Notify(params.RequestContext, butlerd.LaunchExitedNotification{})
 		if err != nil {
 			return err
@@ -238,6 +297,20 @@ func (l *Launcher) Do(params launch.LauncherParams) error {
 
 		runDuration := time.Since(startTime)
 
+		if exitCode != 0 && params.OnCrash != nil {
+			params.OnCrash(launch.CrashInfo{
+				ExitCode:           int64(exitCode),
+				Signal:             exitSignal(runErr),
+				StartedAt:          startTime,
+				Duration:           runDuration,
+				Stdout:             stdout.Lines(),
+				Stderr:             stderr.Lines(),
+				TargetPath:         params.FullTargetPath,
+				WorkingDirectory:   cwd,
+				LaunchLogSessionID: captured.SessionID,
+			})
+		}
+
 		if exitCode != 0 {
 			var signedExitCode = int64(exitCode)
 			if runtime.GOOS == "windows" {
@@ -292,6 +365,37 @@ func (l *Launcher) Do(params launch.LauncherParams) error {
 	return nil
 }
 
+// watchPID reports the game's process ID once it has started,
+// for Launch.List. The runners don't expose it, so this is best-effort,
+// and only implemented on Linux.
+func watchPID(params launch.LauncherParams, exePath string, done chan struct{}) {
+	if params.SetPID == nil {
+		return
+	}
+
+	exclude := make(map[int64]bool)
+	for _, rl := range params.RequestContext.Launches.List("") {
+		if rl.PID != 0 {
+			exclude[rl.PID] = true
+		}
+	}
+
+	deadline := time.After(10 * time.Second)
+	for {
+		select {
+		case <-done:
+			return
+		case <-deadline:
+			return
+		case <-time.After(250 * time.Millisecond):
+			if pid := findDescendantPID(exePath, exclude); pid != 0 {
+				params.SetPID(pid)
+				return
+			}
+		}
+	}
+}
+
 func (l *Launcher) FirejailParams(params launch.LauncherParams) runner.FirejailParams {
 	name := fmt.Sprintf("firejail-%s", params.Host.Runtime.Arch())
 	binaryPath := filepath.Join(params.PrereqsDir, name, "firejail")
@@ -359,6 +463,12 @@ func configureTargetIfNeeded(params launch.LauncherParams) error {
 		return nil
 	}
 
+	switch params.PackageFormat {
+	case butlerd.PackageFormatFlatpak, butlerd.PackageFormatSnap:
+		// not executables, nothing to configure
+		return nil
+	}
+
 	v, err := dash.Configure(params.FullTargetPath, dash.ConfigureParams{
 		Consumer: params.RequestContext.Consumer,
 		Filter:   filtering.FilterPaths,
@@ -428,6 +538,17 @@ func interpretRunError(err error) (int, error) {
 	return 0, nil
 }
 
+// exitSignal returns a description of the signal
+// that terminated the process, if any
+func exitSignal(err error) string {
+	if exitError, ok := AsExitError(err); ok {
+		if status, ok := exitError.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return status.Signal().String()
+		}
+	}
+	return ""
+}
+
 type causer interface {
 	Cause() error
 }
@@ -3,6 +3,11 @@ package native
 import (
 	"bufio"
 	"io"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/butler/endpoints/launch"
+	"github.com/itchio/butler/launchlogs"
 )
 
 type outputCollector struct {
@@ -12,7 +17,9 @@ type outputCollector struct {
 
 var _ io.Writer = (*outputCollector)(nil)
 
-func newOutputCollector(maxLines int) *outputCollector {
+// newOutputCollector keeps the last maxLines lines written to it,
+// and calls onLine (if non-nil) for every line.
+func newOutputCollector(maxLines int, onLine func(line string)) *outputCollector {
 	pipeR, pipeW := io.Pipe()
 
 	oc := &outputCollector{
@@ -28,6 +35,10 @@ func newOutputCollector(maxLines int) *outputCollector {
 			if len(oc.lines) > maxLines {
 				oc.lines = oc.lines[1:]
 			}
+
+			if onLine != nil {
+				onLine(line)
+			}
 		}
 	}()
 
@@ -41,3 +52,63 @@ func (oc *outputCollector) Lines() []string {
 func (oc *outputCollector) Write(p []byte) (int, error) {
 	return oc.writer.Write(p)
 }
+
+// streamLine returns a function that relays output lines to the
+// client, or nil if it didn't ask for them.
+func streamLine(params launch.LauncherParams, stream butlerd.LaunchLogStream) func(line string) {
+	if !params.StreamOutput {
+		return nil
+	}
+
+	return func(line string) {
+		messages.LaunchOutputLine.Notify(params.RequestContext, butlerd.LaunchOutputLineNotification{
+			Stream: stream,
+			Line:   line,
+		})
+	}
+}
+
+type capturedOutput struct {
+	Stdout io.Writer
+	Stderr io.Writer
+	// Empty if launch logs are disabled
+	SessionID string
+	// Must be called once the game exits
+	Done func()
+}
+
+// captureOutput tees the game's output to launch log files, if they're
+// enabled.
+func captureOutput(params launch.LauncherParams, stdout io.Writer, stderr io.Writer) *capturedOutput {
+	consumer := params.RequestContext.Consumer
+	notCaptured := &capturedOutput{
+		Stdout: stdout,
+		Stderr: stderr,
+		Done:   func() {},
+	}
+
+	ll := launchlogs.Get()
+	if ll == nil || params.CaveID == "" {
+		return notCaptured
+	}
+
+	session, err := ll.StartSession(params.CaveID)
+	if err != nil {
+		consumer.Warnf("Could not capture output to launch logs: %+v", err)
+		return notCaptured
+	}
+	consumer.Infof("Capturing output to (%s), session %s", session.Dir, session.ID)
+
+	done := func() {
+		err := session.Close()
+		if err != nil {
+			consumer.Warnf("While writing launch logs: %+v", err)
+		}
+	}
+	return &capturedOutput{
+		Stdout:    io.MultiWriter(stdout, session.Stdout()),
+		Stderr:    io.MultiWriter(stderr, session.Stderr()),
+		SessionID: session.ID,
+		Done:      done,
+	}
+}
@@ -0,0 +1,74 @@
+// +build linux
+
+package native
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// findDescendantPID looks for a process started by butler (directly,
+// or through a wrapper like firejail or bubblewrap) with the given
+// executable, skipping PIDs in exclude. It returns 0 if none was found.
+func findDescendantPID(exePath string, exclude map[int64]bool) int64 {
+	entries, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return 0
+	}
+
+	parents := make(map[int64]int64)
+	var candidates []int64
+	for _, entry := range entries {
+		pid, err := strconv.ParseInt(entry.Name(), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		stat, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+		if err != nil {
+			continue
+		}
+		// the command name (2nd field) may contain spaces and parens,
+		// the parent PID is the 2nd field after it.
+		idx := bytes.LastIndexByte(stat, ')')
+		if idx < 0 {
+			continue
+		}
+		fields := strings.Fields(string(stat[idx+1:]))
+		if len(fields) < 2 {
+			continue
+		}
+		ppid, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		parents[pid] = ppid
+
+		if exclude[pid] {
+			continue
+		}
+		cmdline, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+		if err != nil {
+			continue
+		}
+		argv0 := string(bytes.SplitN(cmdline, []byte{0}, 2)[0])
+		if argv0 == exePath {
+			candidates = append(candidates, pid)
+		}
+	}
+
+	self := int64(os.Getpid())
+	for _, pid := range candidates {
+		// bounded, in case of a cycle from PIDs being reused mid-scan
+		for p, i := parents[pid], 0; p > 1 && i < 64; p, i = parents[p], i+1 {
+			if p == self {
+				return pid
+			}
+		}
+	}
+	return 0
+}
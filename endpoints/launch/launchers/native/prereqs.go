@@ -13,7 +13,7 @@ import (
 	"github.com/pkg/errors"
 )
 
-func handlePrereqs(params launch.LauncherParams) error {
+func handlePrereqs(params launch.LauncherParams, firejail bool) error {
 	ph, err := prereqs.NewHandler(prereqs.Params{
 		RequestContext: params.RequestContext,
 		APIKey:         params.Access.APIKey,
@@ -57,7 +57,7 @@ func handlePrereqs(params launch.LauncherParams) error {
 	// append built-in params if we need some
 	{
 		runtime := params.Host.Runtime
-		if runtime.Platform == ox.PlatformLinux && params.Sandbox {
+		if runtime.Platform == ox.PlatformLinux && firejail {
 			firejailName := fmt.Sprintf("firejail-%s", runtime.Arch())
 			wanted = append(wanted, firejailName)
 		}
@@ -0,0 +1,92 @@
+package launch
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/manager"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/hush/manifest"
+)
+
+// DetectPackageFormat returns the Linux package format of a file,
+// or PackageFormatNone if it's not a package we know how to launch.
+func DetectPackageFormat(fullPath string) butlerd.PackageFormat {
+	lower := strings.ToLower(fullPath)
+	switch {
+	case strings.HasSuffix(lower, ".flatpakref"):
+		return butlerd.PackageFormatFlatpak
+	case strings.HasSuffix(lower, ".snap"):
+		return butlerd.PackageFormatSnap
+	}
+
+	if isAppImage(fullPath) {
+		return butlerd.PackageFormatAppImage
+	}
+	return butlerd.PackageFormatNone
+}
+
+// isAppImage returns true for ELF files with the AppImage magic:
+// "AI" followed by the AppImage type (1 or 2), at offset 8.
+func isAppImage(fullPath string) bool {
+	f, err := os.Open(fullPath)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	header := make([]byte, 11)
+	_, err = io.ReadFull(f, header)
+	if err != nil {
+		return false
+	}
+
+	if !bytes.Equal(header[0:4], []byte("\x7fELF")) {
+		return false
+	}
+	return bytes.Equal(header[8:10], []byte("AI")) && (header[10] == 1 || header[10] == 2)
+}
+
+// packageTargets looks for Flatpak and Snap packages at the root of
+// an install folder. Those aren't executables, so dash doesn't
+// return them as candidates.
+func packageTargets(consumer *state.Consumer, installFolder string, host manager.Host) []*butlerd.LaunchTarget {
+	entries, err := ioutil.ReadDir(installFolder)
+	if err != nil {
+		consumer.Warnf("Could not look for Linux packages: %v", err)
+		return nil
+	}
+
+	var targets []*butlerd.LaunchTarget
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		fullPath := filepath.Join(installFolder, entry.Name())
+		format := DetectPackageFormat(fullPath)
+		if format != butlerd.PackageFormatFlatpak && format != butlerd.PackageFormatSnap {
+			continue
+		}
+
+		consumer.Infof("(%s) is a %s package, picking native strategy", entry.Name(), format)
+		targets = append(targets, &butlerd.LaunchTarget{
+			Host: host,
+			Action: &manifest.Action{
+				Name: entry.Name(),
+				Path: entry.Name(),
+			},
+			Strategy: &butlerd.StrategyResult{
+				Strategy:       butlerd.LaunchStrategyNative,
+				FullTargetPath: fullPath,
+				PackageFormat:  format,
+			},
+		})
+	}
+	return targets
+}
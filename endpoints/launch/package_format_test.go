@@ -0,0 +1,77 @@
+package launch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/manager"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DetectPackageFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "package-format-test")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(name string, contents string) string {
+		path := filepath.Join(dir, name)
+		wtest.Must(t, ioutil.WriteFile(path, []byte(contents), 0o755))
+		return path
+	}
+
+	elf := "\x7fELF\x02\x01\x01\x00"
+	cases := map[string]butlerd.PackageFormat{
+		write("game.flatpakref", "[Flatpak Ref]\n"): butlerd.PackageFormatFlatpak,
+		write("Game.FlatpakRef", ""):                butlerd.PackageFormatFlatpak,
+		write("game.snap", ""):                      butlerd.PackageFormatSnap,
+		write("GAME.SNAP", ""):                      butlerd.PackageFormatSnap,
+		write("type1", elf+"AI\x01rest"):            butlerd.PackageFormatAppImage,
+		write("type2.AppImage", elf+"AI\x02rest"):   butlerd.PackageFormatAppImage,
+		write("type3", elf+"AI\x03rest"):            butlerd.PackageFormatNone,
+		write("plain-elf", elf+"\x00\x00\x00rest"):  butlerd.PackageFormatNone,
+		write("not-elf", "#!/bin/shAI\x02rest"):     butlerd.PackageFormatNone,
+		write("short", elf+"AI"):                    butlerd.PackageFormatNone,
+		write("empty", ""):                          butlerd.PackageFormatNone,
+		filepath.Join(dir, "missing"):               butlerd.PackageFormatNone,
+	}
+	for path, format := range cases {
+		assert.EqualValues(format, DetectPackageFormat(path), "format of (%s)", filepath.Base(path))
+	}
+	assert.False(isAppImage(dir))
+}
+
+func Test_PackageTargets(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "package-targets-test")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"game.flatpakref", "game.snap", "game.AppImage", "readme.txt"} {
+		wtest.Must(t, ioutil.WriteFile(filepath.Join(dir, name), nil, 0o644))
+	}
+	wtest.Must(t, os.Mkdir(filepath.Join(dir, "dir.snap"), 0o755))
+
+	consumer := &state.Consumer{
+		OnMessage: func(lvl string, msg string) { t.Logf("[%s] %s", lvl, msg) },
+	}
+	targets := packageTargets(consumer, dir, manager.Host{})
+
+	formats := make(map[string]butlerd.PackageFormat)
+	for _, target := range targets {
+		assert.EqualValues(butlerd.LaunchStrategyNative, target.Strategy.Strategy)
+		assert.EqualValues(filepath.Join(dir, target.Action.Path), target.Strategy.FullTargetPath)
+		formats[target.Action.Path] = target.Strategy.PackageFormat
+	}
+	assert.EqualValues(map[string]butlerd.PackageFormat{
+		"game.flatpakref": butlerd.PackageFormatFlatpak,
+		"game.snap":       butlerd.PackageFormatSnap,
+	}, formats)
+}
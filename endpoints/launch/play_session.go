@@ -0,0 +1,113 @@
+package launch
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/database/models"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/ox"
+)
+
+// playSessionTracker records a launch session locally, and keeps
+// the corresponding itch.io session up-to-date when it can. Whatever
+// can't be synced right away is synced later: by tasks.SyncPlaySessions
+// the next time a profile logs in online, or by
+// tasks.FetchUserGameSessions when the game is fetched.
+type playSessionTracker struct {
+	rc     *butlerd.RequestContext
+	client *itchio.Client
+	access *operate.GameAccess
+	cave   *models.Cave
+
+	ps *models.CavePlaySession
+	// seconds run that the cave's interactions summary accounts for
+	syncedSeconds int64
+}
+
+func newPlaySessionTracker(rc *butlerd.RequestContext, client *itchio.Client, access *operate.GameAccess, cave *models.Cave, runtime ox.Runtime) *playSessionTracker {
+	now := time.Now().UTC()
+	return &playSessionTracker{
+		rc:     rc,
+		client: client,
+		access: access,
+		cave:   cave,
+		ps: &models.CavePlaySession{
+			ID:           uuid.New().String(),
+			CaveID:       cave.ID,
+			GameID:       cave.GameID,
+			UploadID:     cave.UploadID,
+			BuildID:      cave.BuildID,
+			Platform:     string(interactionPlatform(runtime)),
+			Architecture: string(interactionArchitecture(runtime)),
+			StartedAt:    &now,
+			LastRunAt:    &now,
+		},
+	}
+}
+
+// Begin is called when the game has actually started running
+func (t *playSessionTracker) Begin() {
+	now := time.Now().UTC()
+	t.ps.StartedAt = &now
+	t.ps.LastRunAt = &now
+	t.rc.WithConn(t.ps.Save)
+
+	err := t.sync()
+	if err != nil {
+		t.rc.Consumer.Warnf("Could not create itch.io session, tracking play time locally: %+v", err)
+	}
+}
+
+// Update records the time run so far
+func (t *playSessionTracker) Update() {
+	t.tick()
+	t.rc.WithConn(t.ps.Save)
+
+	err := t.sync()
+	if err != nil {
+		t.rc.Consumer.Warnf("Regular session update: %+v", err)
+	}
+}
+
+// End records the end of the session
+func (t *playSessionTracker) End(crashed bool) {
+	t.tick()
+	t.ps.EndedAt = t.ps.LastRunAt
+	t.ps.Crashed = crashed
+	t.rc.WithConn(t.ps.Save)
+
+	err := t.sync()
+	if err != nil {
+		consumer := t.rc.Consumer
+		consumer.Warnf("Final session update: %+v", err)
+		consumer.Infof("Play session will be synced later")
+
+		// count play time locally until then
+		t.cave.RecordPlayTime(time.Duration(t.ps.SecondsRun-t.syncedSeconds) * time.Second)
+		t.rc.WithConn(t.cave.Save)
+		return
+	}
+	t.rc.WithConn(t.ps.MarkSynced)
+}
+
+func (t *playSessionTracker) tick() {
+	now := time.Now().UTC()
+	t.ps.LastRunAt = &now
+	t.ps.SecondsRun = int64(now.Sub(*t.ps.StartedAt).Seconds())
+}
+
+func (t *playSessionTracker) sync() error {
+	summary, err := operate.SyncPlaySession(t.rc.Ctx, t.client, t.access, t.ps)
+	t.rc.WithConn(t.ps.Save)
+	if err != nil {
+		return err
+	}
+
+	t.syncedSeconds = t.ps.SecondsRun
+	t.cave.UpdateInteractions(summary)
+	t.rc.WithConn(t.cave.Save)
+	return nil
+}
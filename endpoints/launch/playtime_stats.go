@@ -0,0 +1,51 @@
+package launch
+
+import (
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/database/models"
+	"xorm.io/builder"
+)
+
+func CavesPlaytimeStats(rc *butlerd.RequestContext, params butlerd.CavesPlaytimeStatsParams) (*butlerd.CavesPlaytimeStatsResult, error) {
+	var cond = builder.NewCond()
+	if params.CaveID != "" {
+		cond = builder.And(cond, builder.Eq{"cave_id": params.CaveID})
+	}
+	if params.GameID != 0 {
+		cond = builder.And(cond, builder.Eq{"game_id": params.GameID})
+	}
+
+	var sessions []*models.CavePlaySession
+	rc.WithConn(func(conn *sqlite.Conn) {
+		sessions = models.PlaySessionsStartedBetween(conn, cond, params.Since, params.Until)
+	})
+
+	zone := time.FixedZone("", int(params.UTCOffset)*60)
+	res := &butlerd.CavesPlaytimeStatsResult{
+		Days: []*butlerd.PlaytimeDay{},
+	}
+
+	var day *butlerd.PlaytimeDay
+	for _, ps := range sessions {
+		date := ps.StartedAt.In(zone).Format("2006-01-02")
+		if day == nil || day.Date != date {
+			day = &butlerd.PlaytimeDay{
+				Date: date,
+			}
+			res.Days = append(res.Days, day)
+		}
+
+		day.SecondsRun += ps.SecondsRun
+		day.NumSessions++
+		res.TotalSeconds += ps.SecondsRun
+		res.NumSessions++
+		if ps.Crashed {
+			day.NumCrashes++
+			res.NumCrashes++
+		}
+	}
+	return res, nil
+}
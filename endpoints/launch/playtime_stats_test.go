@@ -0,0 +1,103 @@
+package launch
+
+import (
+	"testing"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/database/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PlaytimeStats(t *testing.T) {
+	assert := assert.New(t)
+	env := newRouterTestEnv(t)
+
+	cave := testCave()
+	day := time.Date(2020, time.March, 10, 0, 0, 0, 0, time.UTC)
+
+	var sessions []*models.CavePlaySession
+	add := func(id string, startedAt time.Time, secondsRun int64, crashed bool) {
+		endedAt := startedAt.Add(time.Duration(secondsRun) * time.Second)
+		sessions = append(sessions, &models.CavePlaySession{
+			ID:         id,
+			CaveID:     cave.ID,
+			GameID:     cave.GameID,
+			StartedAt:  &startedAt,
+			LastRunAt:  &endedAt,
+			EndedAt:    &endedAt,
+			SecondsRun: secondsRun,
+			Crashed:    crashed,
+		})
+	}
+	// stored out of order, on purpose
+	add("late", day.Add(23*time.Hour+30*time.Minute), 600, false)
+	add("whole", day.Add(10*time.Hour), 60, false)
+	add("fraction", day.Add(10*time.Hour+500*time.Millisecond), 120, true)
+	add("next-day", day.Add(24*time.Hour+time.Hour), 300, false)
+	add("previous-day", day.Add(-time.Hour), 30, false)
+	env.withConn(func(conn *sqlite.Conn) {
+		models.MustSave(conn, cave)
+		for _, ps := range sessions {
+			ps.Save(conn)
+		}
+		models.MustSave(conn, &models.CavePlaySession{ID: "other-game", GameID: 456, StartedAt: &day, SecondsRun: 1000})
+	})
+
+	stats := func(params butlerd.CavesPlaytimeStatsParams) *butlerd.CavesPlaytimeStatsResult {
+		params.GameID = cave.GameID
+		return env.mustCall("Caves.PlaytimeStats", params).(*butlerd.CavesPlaytimeStatsResult)
+	}
+	type bucket struct {
+		Date        string
+		SecondsRun  int64
+		NumSessions int64
+		NumCrashes  int64
+	}
+	buckets := func(res *butlerd.CavesPlaytimeStatsResult) []bucket {
+		var bs []bucket
+		for _, d := range res.Days {
+			bs = append(bs, bucket{d.Date, d.SecondsRun, d.NumSessions, d.NumCrashes})
+		}
+		return bs
+	}
+
+	res := stats(butlerd.CavesPlaytimeStatsParams{})
+	assert.EqualValues(1110, res.TotalSeconds)
+	assert.EqualValues(5, res.NumSessions)
+	assert.EqualValues(1, res.NumCrashes)
+	assert.EqualValues([]bucket{
+		{"2020-03-09", 30, 1, 0},
+		{"2020-03-10", 780, 3, 1},
+		{"2020-03-11", 300, 1, 0},
+	}, buckets(res))
+
+	// days are delimited in the requested time zone
+	res = stats(butlerd.CavesPlaytimeStatsParams{UTCOffset: 60})
+	assert.EqualValues([]bucket{
+		{"2020-03-10", 210, 3, 1},
+		{"2020-03-11", 900, 2, 0},
+	}, buckets(res))
+
+	// since is inclusive, until is exclusive, down to the nanosecond
+	since := day.Add(10 * time.Hour)
+	until := day.Add(10*time.Hour + 500*time.Millisecond)
+	res = stats(butlerd.CavesPlaytimeStatsParams{Since: &since, Until: &until})
+	assert.EqualValues(1, res.NumSessions)
+	assert.EqualValues(60, res.TotalSeconds)
+
+	since = day.Add(10*time.Hour + 500*time.Millisecond)
+	res = stats(butlerd.CavesPlaytimeStatsParams{Since: &since})
+	assert.EqualValues(3, res.NumSessions)
+	assert.EqualValues(1020, res.TotalSeconds)
+
+	until = day.Add(10*time.Hour + 500*time.Millisecond + time.Nanosecond)
+	res = stats(butlerd.CavesPlaytimeStatsParams{Until: &until})
+	assert.EqualValues(3, res.NumSessions)
+	assert.EqualValues(210, res.TotalSeconds)
+
+	res = stats(butlerd.CavesPlaytimeStatsParams{CaveID: "other"})
+	assert.EqualValues(0, res.NumSessions)
+	assert.Empty(res.Days)
+}
@@ -0,0 +1,96 @@
+package launch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/helloeave/json"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/jsonrpc2"
+	"github.com/itchio/butler/database"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/wharf/wtest"
+)
+
+// quietConn is a client that ignores notifications
+type quietConn struct{}
+
+var _ jsonrpc2.Conn = (*quietConn)(nil)
+
+func (c *quietConn) Call(method string, params interface{}, result interface{}) error {
+	return nil
+}
+
+func (c *quietConn) Notify(method string, params interface{}) error {
+	return nil
+}
+
+func (c *quietConn) Context() context.Context {
+	return context.Background()
+}
+
+func (c *quietConn) Close() {}
+
+// routerTestEnv serves the launch endpoints from a fresh database
+type routerTestEnv struct {
+	t      *testing.T
+	dir    string
+	dbPool *sqlitex.Pool
+	router *butlerd.Router
+}
+
+func newRouterTestEnv(t *testing.T) *routerTestEnv {
+	dir, err := ioutil.TempDir("", "launch-test")
+	wtest.Must(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	dbPool, err := sqlitex.Open(filepath.Join(dir, "butler.db"), 0, 4)
+	wtest.Must(t, err)
+	t.Cleanup(func() { dbPool.Close() })
+
+	env := &routerTestEnv{
+		t:      t,
+		dir:    dir,
+		dbPool: dbPool,
+	}
+	env.withConn(func(conn *sqlite.Conn) {
+		consumer := &state.Consumer{
+			OnMessage: func(lvl string, msg string) { t.Logf("[%s] %s", lvl, msg) },
+		}
+		wtest.Must(t, database.Prepare(consumer, conn, database.PrepareOptions{JustCreated: true}))
+	})
+
+	env.router = butlerd.NewRouter(dbPool, nil, nil, nil)
+	Register(env.router)
+	return env
+}
+
+func (env *routerTestEnv) withConn(f func(conn *sqlite.Conn)) {
+	conn := env.dbPool.Get(context.Background())
+	defer env.dbPool.Put(conn)
+	f(conn)
+}
+
+func (env *routerTestEnv) call(method string, params interface{}) (interface{}, error) {
+	env.t.Helper()
+	rawParams, err := json.Marshal(params)
+	wtest.Must(env.t, err)
+	rawMessage := json.RawMessage(rawParams)
+	return env.router.HandleRequest(&quietConn{}, jsonrpc2.Request{
+		ID:     1,
+		Method: method,
+		Params: &rawMessage,
+	})
+}
+
+func (env *routerTestEnv) mustCall(method string, params interface{}) interface{} {
+	env.t.Helper()
+	res, err := env.call(method, params)
+	wtest.Must(env.t, err)
+	return res
+}
@@ -0,0 +1,46 @@
+package launch
+
+import (
+	"github.com/itchio/butler/butlerd"
+	"github.com/pkg/errors"
+)
+
+func LaunchList(rc *butlerd.RequestContext, params butlerd.LaunchListParams) (*butlerd.LaunchListResult, error) {
+	launches := rc.Launches.List(params.CaveID)
+	if launches == nil {
+		launches = []*butlerd.RunningLaunch{}
+	}
+
+	res := &butlerd.LaunchListResult{
+		Launches: launches,
+	}
+	return res, nil
+}
+
+func LaunchKill(rc *butlerd.RequestContext, params butlerd.LaunchKillParams) (*butlerd.LaunchKillResult, error) {
+	var ids []string
+	if params.ID != "" {
+		ids = append(ids, params.ID)
+	} else {
+		for _, launch := range rc.Launches.List(params.CaveID) {
+			ids = append(ids, launch.ID)
+		}
+	}
+
+	var killed int64
+	for _, id := range ids {
+		if rc.Launches.Kill(id) {
+			rc.Consumer.Infof("Killing launch session (%s)", id)
+			killed++
+		}
+	}
+
+	if params.ID != "" && killed == 0 {
+		return nil, errors.Errorf("launch session (%s) not found", params.ID)
+	}
+
+	res := &butlerd.LaunchKillResult{
+		Killed: killed,
+	}
+	return res, nil
+}
@@ -77,6 +77,19 @@ func ActionToLaunchTarget(consumer *state.Consumer, host manager.Host, installFo
 		return target, nil
 	}
 
+	if host.Runtime.Platform == ox.PlatformLinux {
+		format := DetectPackageFormat(fullPath)
+		if format == butlerd.PackageFormatFlatpak || format == butlerd.PackageFormatSnap {
+			consumer.Infof("(%s) is a %s package, picking native strategy", fullPath, format)
+			target.Strategy = &butlerd.StrategyResult{
+				Strategy:       butlerd.LaunchStrategyNative,
+				FullTargetPath: fullPath,
+				PackageFormat:  format,
+			}
+			return target, nil
+		}
+	}
+
 	verdict, err := dash.Configure(fullPath, dash.ConfigureParams{
 		Consumer: consumer,
 		Filter:   filtering.FilterPaths,
@@ -127,6 +140,11 @@ func CandidateToLaunchTarget(consumer *state.Consumer, basePath string, host man
 		},
 	}
 
+	if candidate.Flavor == dash.FlavorNativeLinux && isAppImage(fullPath) {
+		consumer.Infof("(%s) is an AppImage", candidate.Path)
+		target.Strategy.PackageFormat = butlerd.PackageFormatAppImage
+	}
+
 	fallBackToShell := false
 
 	if IsElevatedWindowsInstaller(consumer, candidate, fullPath) {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/itchio/butler/butlerd"
 	"github.com/itchio/butler/cmd/operate"
@@ -41,22 +42,65 @@ type LauncherParams struct {
 	// May be nil
 	Action *manifest.Action
 
+	// Set when launching a Linux package
+	PackageFormat butlerd.PackageFormat
+
 	// If true, enable sandbox
 	Sandbox bool
 
+	// May be nil, in which case the default backend is used
+	SandboxPolicy *butlerd.SandboxPolicy
+
+	// For HTML5 games, see butlerd.CaveLaunchConfig
+	CrossOriginIsolated bool
+
 	// Additional command-line arguments
 	Args []string
 
 	// Additional environment variables
 	Env map[string]string
 
+	// If true, relay output lines as notifications
+	StreamOutput bool
+
 	PrereqsDir    string
 	ForcePrereqs  bool
 	Access        *operate.GameAccess
+	CaveID        string
 	InstallFolder string
 	Host          manager.Host
 
 	SessionStarted func()
+
+	// Called once the game's process ID is known. May be nil.
+	SetPID func(pid int64)
+
+	// Called when the game exits with a non-zero exit code,
+	// or is killed by a signal. Whether that's a crash is up
+	// to the callee. May be nil.
+	OnCrash func(info CrashInfo)
+}
+
+// CrashInfo is what a launcher knows about a crash
+type CrashInfo struct {
+	ExitCode int64
+	// Description of the signal that terminated the process, if any
+	Signal string
+
+	StartedAt time.Time
+	Duration  time.Duration
+
+	// Last lines of output
+	Stdout []string
+	Stderr []string
+
+	// Executable and directory the game was launched from,
+	// used to look for core dumps
+	TargetPath       string
+	WorkingDirectory string
+
+	// Set if the full output was captured in launch logs
+	LaunchLogSessionID string
 }
 
 // cf. https://github.com/itchio/itch/issues/1751
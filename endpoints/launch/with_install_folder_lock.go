@@ -3,6 +3,7 @@ package launch
 import (
 	"fmt"
 	"os"
+	"sync"
 
 	"crawshaw.io/sqlite"
 	"github.com/itchio/butler/butlerd"
@@ -20,8 +21,21 @@ type withInstallFolderLockParams struct {
 	rc     *butlerd.RequestContext
 	caveID string
 	reason string
+	// If true, share the lock with other holders in this
+	// process instead of waiting for them to release it
+	shared bool
 }
 
+// A runlock held by one or more launches of the same cave,
+// released once the last one is done.
+type heldRunlock struct {
+	rlock   runlock.Lock
+	holders int
+}
+
+var heldRunlocks = make(map[string]*heldRunlock)
+var heldRunlocksMutex sync.Mutex
+
 type withInstallFolderInfo struct {
 	installFolder string
 	cave          *models.Cave
@@ -40,7 +54,6 @@ func withInstallFolderLock(params withInstallFolderLockParams, f func(info withI
 	}
 
 	rc := params.rc
-	consumer := rc.Consumer
 
 	cave := operate.ValidateCave(rc, params.caveID)
 	var installFolder string
@@ -56,12 +69,11 @@ func withInstallFolderLock(params withInstallFolderLockParams, f func(info withI
 		}
 	}
 
-	rlock := runlock.New(consumer, installFolder)
-	err = rlock.Lock(rc.Ctx, params.reason)
+	err = acquireRunlock(rc, installFolder, params.reason, params.shared)
 	if err != nil {
-		return errors.WithStack(err)
+		return err
 	}
-	defer rlock.Unlock()
+	defer releaseRunlock(installFolder)
 
 	var access *operate.GameAccess
 	rc.WithConn(func(conn *sqlite.Conn) {
@@ -79,3 +91,49 @@ func withInstallFolderLock(params withInstallFolderLockParams, f func(info withI
 
 	return f(info)
 }
+
+func acquireRunlock(rc *butlerd.RequestContext, installFolder string, reason string, shared bool) error {
+	if shared {
+		heldRunlocksMutex.Lock()
+		held, ok := heldRunlocks[installFolder]
+		if ok {
+			held.holders++
+		}
+		heldRunlocksMutex.Unlock()
+
+		if ok {
+			rc.Consumer.Infof("Sharing install folder lock with running session")
+			return nil
+		}
+	}
+
+	rlock := runlock.New(rc.Consumer, installFolder)
+	err := rlock.Lock(rc.Ctx, reason)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	heldRunlocksMutex.Lock()
+	heldRunlocks[installFolder] = &heldRunlock{
+		rlock:   rlock,
+		holders: 1,
+	}
+	heldRunlocksMutex.Unlock()
+	return nil
+}
+
+func releaseRunlock(installFolder string) {
+	heldRunlocksMutex.Lock()
+	defer heldRunlocksMutex.Unlock()
+
+	held, ok := heldRunlocks[installFolder]
+	if !ok {
+		return
+	}
+
+	held.holders--
+	if held.holders == 0 {
+		delete(heldRunlocks, installFolder)
+		held.rlock.Unlock()
+	}
+}
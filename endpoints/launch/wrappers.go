@@ -0,0 +1,180 @@
+package launch
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/google/uuid"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/manager"
+	"github.com/itchio/hades"
+	"github.com/pkg/errors"
+	"xorm.io/builder"
+)
+
+func LaunchWrappersList(rc *butlerd.RequestContext, params butlerd.LaunchWrappersListParams) (*butlerd.LaunchWrappersListResult, error) {
+	var profiles []*models.WrapperProfile
+	rc.WithConn(func(conn *sqlite.Conn) {
+		profiles = models.AllWrapperProfiles(conn)
+	})
+
+	res := &butlerd.LaunchWrappersListResult{
+		Wrappers: []*butlerd.WrapperProfile{},
+	}
+	for _, wp := range profiles {
+		fwp, err := butlerd.FormatWrapperProfile(wp)
+		if err != nil {
+			rc.Consumer.Warnf("Skipping wrapper profile (%s): %v", wp.Name, err)
+			continue
+		}
+		res.Wrappers = append(res.Wrappers, fwp)
+	}
+	return res, nil
+}
+
+func LaunchWrappersSave(rc *butlerd.RequestContext, params butlerd.LaunchWrappersSaveParams) (*butlerd.LaunchWrappersSaveResult, error) {
+	host := manager.Host{
+		Runtime: params.Runtime,
+		Wrapper: params.Wrapper,
+	}
+	err := host.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	wrapperJSON, err := json.Marshal(params.Wrapper)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshalling wrapper")
+	}
+
+	name := strings.TrimSpace(params.Name)
+	if name == "" {
+		return nil, errors.New("wrapper profile name cannot be blank")
+	}
+
+	now := time.Now().UTC()
+
+	conn := rc.GetConn()
+	defer rc.PutConn(conn)
+
+	var wp *models.WrapperProfile
+	if params.ID == "" {
+		wp = &models.WrapperProfile{
+			ID:        uuid.New().String(),
+			CreatedAt: &now,
+		}
+	} else {
+		wp = models.WrapperProfileByID(conn, params.ID)
+		if wp == nil {
+			return nil, errors.Errorf("wrapper profile (%s) not found", params.ID)
+		}
+	}
+
+	for _, other := range models.AllWrapperProfiles(conn) {
+		if other.ID != wp.ID && strings.EqualFold(other.Name, name) {
+			return nil, errors.Errorf("there's already a wrapper profile named (%s)", other.Name)
+		}
+	}
+
+	wp.Name = name
+	wp.Platform = string(params.Runtime.Platform)
+	wp.Is64 = params.Runtime.Is64
+	wp.Wrapper = models.JSON(wrapperJSON)
+	wp.UpdatedAt = &now
+	wp.Save(conn)
+
+	fwp, err := butlerd.FormatWrapperProfile(wp)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &butlerd.LaunchWrappersSaveResult{
+		Wrapper: fwp,
+	}
+	return res, nil
+}
+
+func LaunchWrappersRemove(rc *butlerd.RequestContext, params butlerd.LaunchWrappersRemoveParams) (*butlerd.LaunchWrappersRemoveResult, error) {
+	conn := rc.GetConn()
+	defer rc.PutConn(conn)
+
+	wp := models.WrapperProfileByID(conn, params.ID)
+	if wp == nil {
+		return nil, errors.Errorf("wrapper profile (%s) not found", params.ID)
+	}
+
+	var caves []*models.Cave
+	models.MustSelect(conn, &caves, builder.Eq{"wrapper_profile_id": wp.ID}, hades.Search{})
+	for _, cave := range caves {
+		rc.Consumer.Infof("Unassigning wrapper profile (%s) from cave %s", wp.Name, cave.ID)
+		cave.WrapperProfileID = ""
+		cave.Save(conn)
+	}
+
+	wp.Delete(conn)
+
+	res := &butlerd.LaunchWrappersRemoveResult{}
+	return res, nil
+}
+
+func CavesSetWrapper(rc *butlerd.RequestContext, params butlerd.CavesSetWrapperParams) (*butlerd.CavesSetWrapperResult, error) {
+	conn := rc.GetConn()
+	defer rc.PutConn(conn)
+
+	cave := models.CaveByID(conn, params.CaveID)
+	if cave == nil {
+		return nil, errors.Errorf("cave (%s) not found", params.CaveID)
+	}
+
+	if params.WrapperProfileID != "" {
+		if models.WrapperProfileByID(conn, params.WrapperProfileID) == nil {
+			return nil, errors.Errorf("wrapper profile (%s) not found", params.WrapperProfileID)
+		}
+	}
+
+	cave.WrapperProfileID = params.WrapperProfileID
+	cave.Save(conn)
+
+	res := &butlerd.CavesSetWrapperResult{}
+	return res, nil
+}
+
+// hostsForCave moves the wrapper profile assigned to a cave (if any)
+// right after the native host, and drops other wrappers for its
+// platform, so it's the one used to launch.
+func hostsForCave(rc *butlerd.RequestContext, hosts manager.Hosts, cave *models.Cave) manager.Hosts {
+	if cave.WrapperProfileID == "" {
+		return hosts
+	}
+	consumer := rc.Consumer
+
+	var assigned *manager.Host
+	for i, host := range hosts {
+		if host.WrapperProfileID == cave.WrapperProfileID {
+			assigned = &hosts[i]
+			break
+		}
+	}
+	if assigned == nil {
+		consumer.Warnf("Wrapper profile (%s) assigned to cave is gone, using default hosts", cave.WrapperProfileID)
+		return hosts
+	}
+	consumer.Infof("Using assigned wrapper: %s", assigned)
+
+	var res manager.Hosts
+	for _, host := range hosts {
+		if host.Wrapper == nil {
+			res = append(res, host)
+		}
+	}
+	res = append(res, *assigned)
+	for _, host := range hosts {
+		if host.Wrapper != nil && host.WrapperProfileID != cave.WrapperProfileID && host.Runtime.Platform != assigned.Runtime.Platform {
+			res = append(res, host)
+		}
+	}
+	return res
+}
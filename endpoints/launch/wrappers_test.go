@@ -0,0 +1,88 @@
+package launch
+
+import (
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/manager"
+	"github.com/itchio/ox"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WrapperProfiles(t *testing.T) {
+	assert := assert.New(t)
+	env := newRouterTestEnv(t)
+
+	env.withConn(func(conn *sqlite.Conn) {
+		models.MustSave(conn, testCave())
+	})
+	caveID := testCave().ID
+	assigned := func() string {
+		var id string
+		env.withConn(func(conn *sqlite.Conn) {
+			id = models.CaveByID(conn, caveID).WrapperProfileID
+		})
+		return id
+	}
+
+	save := func(id string, name string) (*butlerd.WrapperProfile, error) {
+		res, err := env.call("Launch.Wrappers.Save", butlerd.LaunchWrappersSaveParams{
+			ID:      id,
+			Name:    name,
+			Runtime: ox.Runtime{Platform: ox.PlatformWindows, Is64: true},
+			Wrapper: &manager.Wrapper{WrapperBinary: "/opt/proton/proton"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		return res.(*butlerd.LaunchWrappersSaveResult).Wrapper, nil
+	}
+
+	proton, err := save("", "  Proton 5.0 ")
+	wtest.Must(t, err)
+	assert.EqualValues("Proton 5.0", proton.Name)
+	assert.EqualValues(ox.PlatformWindows, proton.Runtime.Platform)
+
+	// names must be there, and unique
+	_, err = save("", "")
+	assert.Error(err)
+	_, err = save("", "   ")
+	assert.Error(err)
+	_, err = save("", "proton 5.0")
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "already a wrapper profile named (Proton 5.0)")
+	}
+
+	// saving a profile under its own name is fine
+	_, err = save(proton.ID, "Proton 5.0")
+	wtest.Must(t, err)
+	wine, err := save("", "Wine")
+	wtest.Must(t, err)
+	_, err = save(wine.ID, "Proton 5.0")
+	assert.Error(err)
+
+	list := env.mustCall("Launch.Wrappers.List", butlerd.LaunchWrappersListParams{}).(*butlerd.LaunchWrappersListResult)
+	if assert.Len(list.Wrappers, 2) {
+		assert.EqualValues("Proton 5.0", list.Wrappers[0].Name)
+		assert.EqualValues("Wine", list.Wrappers[1].Name)
+	}
+
+	// assigning
+	_, err = env.call("Caves.SetWrapper", butlerd.CavesSetWrapperParams{CaveID: caveID, WrapperProfileID: "nope"})
+	assert.Error(err)
+	env.mustCall("Caves.SetWrapper", butlerd.CavesSetWrapperParams{CaveID: caveID, WrapperProfileID: proton.ID})
+	assert.EqualValues(proton.ID, assigned())
+
+	env.mustCall("Caves.SetWrapper", butlerd.CavesSetWrapperParams{CaveID: caveID})
+	assert.EqualValues("", assigned())
+
+	// removing a profile unassigns it
+	env.mustCall("Caves.SetWrapper", butlerd.CavesSetWrapperParams{CaveID: caveID, WrapperProfileID: wine.ID})
+	env.mustCall("Launch.Wrappers.Remove", butlerd.LaunchWrappersRemoveParams{ID: wine.ID})
+	assert.EqualValues("", assigned())
+	list = env.mustCall("Launch.Wrappers.List", butlerd.LaunchWrappersListParams{}).(*butlerd.LaunchWrappersListResult)
+	assert.Len(list.Wrappers, 1)
+}
@@ -0,0 +1,59 @@
+package library
+
+import (
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/butler/database/library"
+	"github.com/pkg/errors"
+)
+
+func Register(router *butlerd.Router) {
+	messages.LibraryExport.Register(router, LibraryExport)
+	messages.LibraryImport.Register(router, LibraryImport)
+}
+
+func LibraryExport(rc *butlerd.RequestContext, params butlerd.LibraryExportParams) (*butlerd.LibraryExportResult, error) {
+	conn := rc.GetConn()
+	defer rc.PutConn(conn)
+
+	lib, err := library.Export(conn)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	err = library.Write(lib, params.Path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	res := &butlerd.LibraryExportResult{
+		Counts: lib.Counts(),
+	}
+	return res, nil
+}
+
+func LibraryImport(rc *butlerd.RequestContext, params butlerd.LibraryImportParams) (*butlerd.LibraryImportResult, error) {
+	lib, err := library.Read(params.Path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	policy := params.ConflictPolicy
+	if policy == "" {
+		policy = butlerd.LibraryConflictPolicySkip
+	}
+
+	conn := rc.GetConn()
+	defer rc.PutConn(conn)
+
+	imported, skipped, err := library.Import(rc.Consumer, conn, lib, policy)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	res := &butlerd.LibraryImportResult{
+		Imported: imported,
+		Skipped:  skipped,
+	}
+	return res, nil
+}
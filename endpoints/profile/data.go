@@ -1,47 +1,269 @@
 package profile
 
 import (
+	"encoding/json"
+	"sort"
+	"time"
+
 	"crawshaw.io/sqlite"
-	"xorm.io/builder"
+	"crawshaw.io/sqlite/sqlitex"
 	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/horror"
 	"github.com/itchio/butler/database/models"
+	"github.com/itchio/hades"
+	"github.com/pkg/errors"
+	"xorm.io/builder"
 )
 
+// timeNow is when entries are put and expire, tests can move it
+var timeNow = func() time.Time {
+	return time.Now().UTC()
+}
+
 func DataPut(rc *butlerd.RequestContext, params butlerd.ProfileDataPutParams) (*butlerd.ProfileDataPutResult, error) {
-	// will panic if invalid profile or missing param
-	rc.ProfileClient(params.ProfileID)
+	_, _, err := rc.ProfileClient(params.ProfileID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(params.Value) > butlerd.ProfileDataMaxValueSize {
+		return nil, errors.Wrapf(butlerd.CodeProfileDataTooLarge, "value for (%s) is %d bytes, the maximum is %d", params.Key, len(params.Value), butlerd.ProfileDataMaxValueSize)
+	}
+	if params.JSON && !json.Valid([]byte(params.Value)) {
+		return nil, errors.Errorf("value for (%s) is not valid JSON", params.Key)
+	}
 
+	now := timeNow()
 	pd := &models.ProfileData{
 		ProfileID: params.ProfileID,
 		Key:       params.Key,
 		Value:     params.Value,
+		JSON:      params.JSON,
+		UpdatedAt: &now,
 	}
+	if params.TTLSeconds > 0 {
+		// to the millisecond, like SQLite's date functions, see listData
+		expiresAt := now.Add(time.Duration(params.TTLSeconds) * time.Second).Truncate(time.Millisecond)
+		pd.ExpiresAt = &expiresAt
+	}
+
 	rc.WithConn(func(conn *sqlite.Conn) {
-		models.MustSave(conn, pd)
+		err = func() (retErr error) {
+			defer horror.RecoverInto(&retErr)
+			defer sqlitex.Save(conn)(&retErr)
+
+			var version int64
+			if existing := findData(conn, params.ProfileID, params.Key, now); existing != nil {
+				version = existing.Version
+			}
+			if params.IfVersion != nil && *params.IfVersion != version {
+				return errors.Wrapf(butlerd.CodeProfileDataConflict, "(%s) is at version %d, not %d", params.Key, version, *params.IfVersion)
+			}
+
+			pd.Version = version + 1
+			models.MustSave(conn, pd)
+			return nil
+		}()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	notifyDataChanged(rc, &butlerd.ProfileDataChangedNotification{
+		ProfileID: params.ProfileID,
+		Key:       params.Key,
+		Entry:     formatData(pd),
 	})
 
-	res := &butlerd.ProfileDataPutResult{}
+	res := &butlerd.ProfileDataPutResult{
+		Version: pd.Version,
+	}
 	return res, nil
 }
 
 func DataGet(rc *butlerd.RequestContext, params butlerd.ProfileDataGetParams) (*butlerd.ProfileDataGetResult, error) {
-	// will panic if invalid profile or missing param
-	rc.ProfileClient(params.ProfileID)
+	_, _, err := rc.ProfileClient(params.ProfileID)
+	if err != nil {
+		return nil, err
+	}
 
-	var ok bool
-	var pd models.ProfileData
+	var pd *models.ProfileData
+	rc.WithConn(func(conn *sqlite.Conn) {
+		pd = findData(conn, params.ProfileID, params.Key, timeNow())
+	})
+
+	res := &butlerd.ProfileDataGetResult{}
+	if pd != nil {
+		res.OK = true
+		res.Value = pd.Value
+		res.Entry = formatData(pd)
+	}
+	return res, nil
+}
+
+func DataList(rc *butlerd.RequestContext, params butlerd.ProfileDataListParams) (*butlerd.ProfileDataListResult, error) {
+	_, _, err := rc.ProfileClient(params.ProfileID)
+	if err != nil {
+		return nil, err
+	}
+
+	var pds []*models.ProfileData
 	rc.WithConn(func(conn *sqlite.Conn) {
-		ok = models.MustSelectOne(conn, &pd,
-			builder.Eq{
-				"profile_id": params.ProfileID,
-				"key":        params.Key,
-			},
-		)
+		pds = listData(conn, params.ProfileID, params.Prefix, timeNow())
 	})
 
-	res := &butlerd.ProfileDataGetResult{
-		OK:    ok,
-		Value: pd.Value,
+	res := &butlerd.ProfileDataListResult{
+		Entries: []*butlerd.ProfileDataEntry{},
+	}
+	for _, pd := range pds {
+		res.Entries = append(res.Entries, formatData(pd))
 	}
 	return res, nil
 }
+
+func DataDelete(rc *butlerd.RequestContext, params butlerd.ProfileDataDeleteParams) (*butlerd.ProfileDataDeleteResult, error) {
+	if (params.Key == "") == (params.Prefix == "") {
+		return nil, errors.New("exactly one of key or prefix must be set")
+	}
+	if params.IfVersion != nil && params.Key == "" {
+		return nil, errors.New("ifVersion can only be used with key")
+	}
+
+	_, _, err := rc.ProfileClient(params.ProfileID)
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []string
+	rc.WithConn(func(conn *sqlite.Conn) {
+		err = func() (retErr error) {
+			defer horror.RecoverInto(&retErr)
+			defer sqlitex.Save(conn)(&retErr)
+
+			now := timeNow()
+			var cond builder.Cond
+			if params.Key != "" {
+				existing := findData(conn, params.ProfileID, params.Key, now)
+				var version int64
+				if existing != nil {
+					version = existing.Version
+				}
+				if params.IfVersion != nil && *params.IfVersion != version {
+					return errors.Wrapf(butlerd.CodeProfileDataConflict, "(%s) is at version %d, not %d", params.Key, version, *params.IfVersion)
+				}
+				if existing != nil {
+					deleted = append(deleted, existing.Key)
+				}
+				cond = builder.Eq{"profile_id": params.ProfileID, "key": params.Key}
+			} else {
+				for _, pd := range listData(conn, params.ProfileID, params.Prefix, now) {
+					deleted = append(deleted, pd.Key)
+				}
+				cond = prefixCond(params.ProfileID, params.Prefix)
+			}
+
+			if len(deleted) > 0 {
+				models.MustDelete(conn, &models.ProfileData{}, cond)
+			}
+			return nil
+		}()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range deleted {
+		notifyDataChanged(rc, &butlerd.ProfileDataChangedNotification{
+			ProfileID: params.ProfileID,
+			Key:       key,
+			Deleted:   true,
+		})
+	}
+
+	res := &butlerd.ProfileDataDeleteResult{
+		Deleted: int64(len(deleted)),
+	}
+	return res, nil
+}
+
+// findData returns the entry for key, or nil if there's none
+// or it expired, in which case it's deleted.
+func findData(conn *sqlite.Conn, profileID int64, key string, now time.Time) *models.ProfileData {
+	var pd models.ProfileData
+	if !models.MustSelectOne(conn, &pd, builder.Eq{"profile_id": profileID, "key": key}) {
+		return nil
+	}
+	if pd.Expired(now) {
+		models.MustDelete(conn, &models.ProfileData{}, builder.Eq{"profile_id": profileID, "key": key})
+		return nil
+	}
+	return &pd
+}
+
+// listData returns entries whose key starts with prefix, sorted by key,
+// deleting the ones that expired.
+func listData(conn *sqlite.Conn, profileID int64, prefix string, now time.Time) []*models.ProfileData {
+	cond := prefixCond(profileID, prefix)
+
+	var pds []*models.ProfileData
+	models.MustSelect(conn, &pds, cond, hades.Search{})
+
+	var live []*models.ProfileData
+	var expired bool
+	for _, pd := range pds {
+		if pd.Expired(now) {
+			expired = true
+			continue
+		}
+		live = append(live, pd)
+	}
+
+	if expired {
+		// julianday only goes down to the millisecond, which is
+		// what expiry times are truncated to.
+		models.MustDelete(conn, &models.ProfileData{}, builder.And(
+			cond,
+			builder.NotNull{"expires_at"},
+			builder.Expr("julianday(expires_at) <= julianday(?)", now.Truncate(time.Millisecond).Format(time.RFC3339Nano)),
+		))
+	}
+
+	sort.Slice(live, func(i, j int) bool {
+		return live[i].Key < live[j].Key
+	})
+	return live
+}
+
+// prefixCond matches the entries of a profile whose key starts with prefix,
+// or all of them if it's empty. It doesn't list keys, so it works for any
+// number of entries.
+//
+// Keys are compared as blobs, byte by byte like strings.HasPrefix: substr
+// counts characters in text, and SQLite doesn't count them the same way
+// as Go when a key isn't valid UTF-8.
+func prefixCond(profileID int64, prefix string) builder.Cond {
+	cond := builder.NewCond().And(builder.Eq{"profile_id": profileID})
+	if prefix != "" {
+		cond = cond.And(builder.Expr("substr(CAST(key AS BLOB), 1, ?) = CAST(? AS BLOB)", len(prefix), prefix))
+	}
+	return cond
+}
+
+func formatData(pd *models.ProfileData) *butlerd.ProfileDataEntry {
+	return &butlerd.ProfileDataEntry{
+		Key:       pd.Key,
+		Value:     pd.Value,
+		JSON:      pd.JSON,
+		Version:   pd.Version,
+		UpdatedAt: pd.UpdatedAt,
+		ExpiresAt: pd.ExpiresAt,
+	}
+}
+
+func notifyDataChanged(rc *butlerd.RequestContext, notif *butlerd.ProfileDataChangedNotification) {
+	// cannot use autogenerated wrappers, they only notify the caller
+	err := rc.Broadcast("Profile.Data.Changed", notif)
+	if err != nil {
+		rc.Consumer.Warnf("Could not notify clients of profile data change: %v", err)
+	}
+}
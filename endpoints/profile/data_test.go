@@ -0,0 +1,259 @@
+package profile
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/helloeave/json"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/jsonrpc2"
+	"github.com/itchio/butler/database"
+	"github.com/itchio/butler/database/models"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+	"xorm.io/builder"
+)
+
+// quietConn is a client that ignores notifications
+type quietConn struct{}
+
+var _ jsonrpc2.Conn = (*quietConn)(nil)
+
+func (c *quietConn) Call(method string, params interface{}, result interface{}) error {
+	return nil
+}
+
+func (c *quietConn) Notify(method string, params interface{}) error {
+	return nil
+}
+
+func (c *quietConn) Context() context.Context {
+	return context.Background()
+}
+
+func (c *quietConn) Close() {}
+
+type dataTestEnv struct {
+	t      *testing.T
+	dbPool *sqlitex.Pool
+	router *butlerd.Router
+	now    time.Time
+}
+
+func newDataTestEnv(t *testing.T) *dataTestEnv {
+	dir, err := ioutil.TempDir("", "profile-data-test")
+	wtest.Must(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	dbPool, err := sqlitex.Open(filepath.Join(dir, "butler.db"), 0, 4)
+	wtest.Must(t, err)
+	t.Cleanup(func() { dbPool.Close() })
+
+	env := &dataTestEnv{
+		t:      t,
+		dbPool: dbPool,
+		now:    time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC),
+	}
+	env.withConn(func(conn *sqlite.Conn) {
+		consumer := &state.Consumer{
+			OnMessage: func(lvl string, msg string) { t.Logf("[%s] %s", lvl, msg) },
+		}
+		wtest.Must(t, database.Prepare(consumer, conn, database.PrepareOptions{JustCreated: true}))
+		models.MustSave(conn, &models.Profile{ID: 1, APIKey: "key"})
+	})
+
+	oldTimeNow := timeNow
+	timeNow = func() time.Time { return env.now }
+	t.Cleanup(func() { timeNow = oldTimeNow })
+
+	getClient := func(key string) *itchio.Client {
+		return itchio.ClientWithKey(key)
+	}
+	env.router = butlerd.NewRouter(dbPool, getClient, nil, nil)
+	Register(env.router)
+	return env
+}
+
+func (env *dataTestEnv) withConn(f func(conn *sqlite.Conn)) {
+	conn := env.dbPool.Get(context.Background())
+	defer env.dbPool.Put(conn)
+	f(conn)
+}
+
+func (env *dataTestEnv) call(method string, params interface{}) interface{} {
+	env.t.Helper()
+	rawParams, err := json.Marshal(params)
+	wtest.Must(env.t, err)
+	rawMessage := json.RawMessage(rawParams)
+	res, err := env.router.HandleRequest(&quietConn{}, jsonrpc2.Request{
+		ID:     1,
+		Method: method,
+		Params: &rawMessage,
+	})
+	wtest.Must(env.t, err)
+	return res
+}
+
+func (env *dataTestEnv) keys(prefix string) []string {
+	env.t.Helper()
+	res := env.call("Profile.Data.List", butlerd.ProfileDataListParams{ProfileID: 1, Prefix: prefix}).(*butlerd.ProfileDataListResult)
+	var keys []string
+	for _, entry := range res.Entries {
+		keys = append(keys, entry.Key)
+	}
+	return keys
+}
+
+func (env *dataTestEnv) count() int64 {
+	var count int64
+	env.withConn(func(conn *sqlite.Conn) {
+		count = models.MustCount(conn, &models.ProfileData{}, builder.Eq{"profile_id": 1})
+	})
+	return count
+}
+
+func Test_DataExpiry(t *testing.T) {
+	assert := assert.New(t)
+	env := newDataTestEnv(t)
+
+	put := func(key string, ttlSeconds int64) {
+		env.call("Profile.Data.Put", butlerd.ProfileDataPutParams{ProfileID: 1, Key: key, Value: "v", TTLSeconds: ttlSeconds})
+	}
+	get := func(key string) bool {
+		return env.call("Profile.Data.Get", butlerd.ProfileDataGetParams{ProfileID: 1, Key: key}).(*butlerd.ProfileDataGetResult).OK
+	}
+
+	put("session/short", 60)
+	put("session/long", 3600)
+	put("settings", 0)
+	assert.True(get("session/short"))
+
+	env.now = env.now.Add(59 * time.Second)
+	assert.True(get("session/short"))
+	assert.EqualValues([]string{"session/long", "session/short", "settings"}, env.keys(""))
+
+	// expiring exactly at the TTL
+	env.now = env.now.Add(time.Second)
+	assert.False(get("session/short"))
+	assert.EqualValues(2, env.count())
+
+	env.now = env.now.Add(time.Hour)
+	assert.EqualValues([]string{"settings"}, env.keys(""))
+	assert.EqualValues(1, env.count())
+
+	// putting again starts over, at version 1
+	put("session/short", 60)
+	res := env.call("Profile.Data.Get", butlerd.ProfileDataGetParams{ProfileID: 1, Key: "session/short"}).(*butlerd.ProfileDataGetResult)
+	if assert.True(res.OK) {
+		assert.EqualValues(1, res.Entry.Version)
+		assert.EqualValues(env.now.Add(time.Minute), *res.Entry.ExpiresAt)
+	}
+
+	// expiry times are to the millisecond, in the database too
+	env.now = env.now.Add(1500 * time.Microsecond)
+	put("precise", 1)
+	res = env.call("Profile.Data.Get", butlerd.ProfileDataGetParams{ProfileID: 1, Key: "precise"}).(*butlerd.ProfileDataGetResult)
+	expiresAt := *res.Entry.ExpiresAt
+	assert.EqualValues(env.now.Add(time.Second-500*time.Microsecond), expiresAt)
+
+	env.now = expiresAt.Add(-time.Nanosecond)
+	assert.EqualValues([]string{"precise", "session/short", "settings"}, env.keys(""))
+	env.now = expiresAt.Add(-time.Millisecond)
+	assert.EqualValues([]string{"precise"}, env.keys("p"))
+	assert.EqualValues(3, env.count())
+	env.now = expiresAt
+	assert.EqualValues([]string{"session/short", "settings"}, env.keys(""))
+	assert.EqualValues(2, env.count())
+}
+
+func Test_DataManyEntries(t *testing.T) {
+	assert := assert.New(t)
+	env := newDataTestEnv(t)
+
+	// more than SQLite lets us bind variables for in a single statement
+	const numEntries = 33000
+	insertEntries := func(prefix string, expiresAt *time.Time) {
+		env.withConn(func(conn *sqlite.Conn) {
+			var err error
+			defer sqlitex.Save(conn)(&err)
+			for i := 0; i < numEntries; i++ {
+				models.MustSave(conn, &models.ProfileData{
+					ProfileID: 1,
+					Key:       fmt.Sprintf("%s%05d", prefix, i),
+					Value:     "v",
+					Version:   1,
+					UpdatedAt: &env.now,
+					ExpiresAt: expiresAt,
+				})
+			}
+		})
+	}
+
+	expiresAt := env.now.Add(time.Minute)
+	insertEntries("cache/", &expiresAt)
+	insertEntries("items/", nil)
+	env.call("Profile.Data.Put", butlerd.ProfileDataPutParams{ProfileID: 1, Key: "settings", Value: "v"})
+
+	env.now = expiresAt
+	assert.Empty(env.keys("cache/"))
+	assert.EqualValues(numEntries+1, env.count())
+
+	res := env.call("Profile.Data.Delete", butlerd.ProfileDataDeleteParams{ProfileID: 1, Prefix: "items/"}).(*butlerd.ProfileDataDeleteResult)
+	assert.EqualValues(numEntries, res.Deleted)
+	assert.EqualValues([]string{"settings"}, env.keys(""))
+}
+
+func Test_DataSecretUnavailable(t *testing.T) {
+	assert := assert.New(t)
+	env := newDataTestEnv(t)
+
+	// its API key was moved to a store that isn't enabled
+	env.withConn(func(conn *sqlite.Conn) {
+		models.MustSave(conn, &models.Profile{ID: 2, SecretStore: "keychain"})
+	})
+
+	rawParams, err := json.Marshal(butlerd.ProfileDataGetParams{ProfileID: 2, Key: "settings"})
+	wtest.Must(t, err)
+	rawMessage := json.RawMessage(rawParams)
+	_, err = env.router.HandleRequest(&quietConn{}, jsonrpc2.Request{
+		ID:     1,
+		Method: "Profile.Data.Get",
+		Params: &rawMessage,
+	})
+	if je, ok := err.(*jsonrpc2.Error); assert.True(ok, "should be a json-rpc error: %+v", err) {
+		assert.EqualValues(butlerd.CodeProfileSecretUnavailable, je.Code)
+		assert.Contains(string(*je.Data), "isn't enabled", "keeps the reason")
+	}
+}
+
+func Test_DataPrefix(t *testing.T) {
+	assert := assert.New(t)
+	env := newDataTestEnv(t)
+
+	// keys that aren't valid UTF-8 can only be written by butler itself,
+	// but SQLite counts their characters differently: prefixes are
+	// matched byte by byte
+	env.withConn(func(conn *sqlite.Conn) {
+		for _, key := range []string{"café/1", "café\x80/2", "cafe/3", "caf/4", "other"} {
+			models.MustSave(conn, &models.ProfileData{ProfileID: 1, Key: key, Value: "v", Version: 1, UpdatedAt: &env.now})
+		}
+	})
+
+	assert.EqualValues([]string{"café/1", "café\x80/2"}, env.keys("café"))
+	assert.EqualValues([]string{"caf/4", "cafe/3", "café/1", "café\x80/2"}, env.keys("caf"))
+
+	// deletes exactly what's listed
+	res := env.call("Profile.Data.Delete", butlerd.ProfileDataDeleteParams{ProfileID: 1, Prefix: "café"}).(*butlerd.ProfileDataDeleteResult)
+	assert.EqualValues(2, res.Deleted)
+	assert.EqualValues([]string{"caf/4", "cafe/3", "other"}, env.keys(""))
+	assert.EqualValues(3, env.count())
+}
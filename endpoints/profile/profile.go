@@ -5,6 +5,7 @@ import (
 	"github.com/itchio/butler/butlerd"
 	"github.com/itchio/butler/butlerd/messages"
 	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/endpoints/tasks"
 	"github.com/itchio/go-itchio"
 	"github.com/itchio/hades"
 	"github.com/itchio/httpkit/neterr"
@@ -20,6 +21,8 @@ func Register(router *butlerd.Router) {
 	messages.ProfileForget.Register(router, Forget)
 	messages.ProfileDataPut.Register(router, DataPut)
 	messages.ProfileDataGet.Register(router, DataGet)
+	messages.ProfileDataList.Register(router, DataList)
+	messages.ProfileDataDelete.Register(router, DataDelete)
 }
 
 func List(rc *butlerd.RequestContext, params butlerd.ProfileListParams) (*butlerd.ProfileListResult, error) {
@@ -133,7 +136,9 @@ func LoginWithPassword(rc *butlerd.RequestContext, params butlerd.ProfileLoginWi
 		APIKey: key.Key,
 	}
 	profile.UpdateFromUser(profileRes.User)
+	storeAPIKey(rc, profile)
 	rc.WithConn(profile.Save)
+	rc.QueueBackgroundTask(tasks.SyncPlaySessions())
 
 	res := &butlerd.ProfileLoginWithPasswordResult{
 		Cookie:  cookie,
@@ -159,7 +164,9 @@ func LoginWithAPIKey(rc *butlerd.RequestContext, params butlerd.ProfileLoginWith
 		APIKey: params.APIKey,
 	}
 	profile.UpdateFromUser(profileRes.User)
+	storeAPIKey(rc, profile)
 	rc.WithConn(profile.Save)
+	rc.QueueBackgroundTask(tasks.SyncPlaySessions())
 
 	res := &butlerd.ProfileLoginWithAPIKeyResult{
 		Profile: formatProfile(profile),
@@ -170,11 +177,24 @@ func LoginWithAPIKey(rc *butlerd.RequestContext, params butlerd.ProfileLoginWith
 func UseSavedLogin(rc *butlerd.RequestContext, params butlerd.ProfileUseSavedLoginParams) (*butlerd.ProfileUseSavedLoginResult, error) {
 	consumer := rc.Consumer
 
-	profile, client := rc.ProfileClient(params.ProfileID)
+	profile, client, err := rc.ProfileClient(params.ProfileID)
+	if err != nil {
+		return nil, err
+	}
+
+	if profile.APIKey != "" {
+		// saved before secret stores existed
+		storeAPIKey(rc, profile)
+		if profile.APIKey == "" {
+			rc.WithConn(func(conn *sqlite.Conn) {
+				models.MustSave(conn, profile)
+			})
+		}
+	}
 
 	consumer.Opf("Validating credentials...")
 
-	err := func() error {
+	err = func() error {
 		profileRes, err := client.GetProfile(rc.Ctx)
 		if err != nil {
 			return errors.WithStack(err)
@@ -203,6 +223,7 @@ func UseSavedLogin(rc *butlerd.RequestContext, params butlerd.ProfileUseSavedLog
 		}
 	} else {
 		consumer.Opf("Logged in! (online)")
+		rc.QueueBackgroundTask(tasks.SyncPlaySessions())
 	}
 
 	res := &butlerd.ProfileUseSavedLoginResult{
@@ -217,6 +238,12 @@ func Forget(rc *butlerd.RequestContext, params butlerd.ProfileForgetParams) (*bu
 	}
 
 	rc.WithConn(func(conn *sqlite.Conn) {
+		if profile := models.ProfileByID(conn, params.ProfileID); profile != nil {
+			err := profile.ForgetAPIKey()
+			if err != nil {
+				rc.Consumer.Warnf("Could not remove API key from secret store: %v", err)
+			}
+		}
 		models.MustDelete(conn, &models.Profile{}, builder.Eq{"id": params.ProfileID})
 	})
 
@@ -225,3 +252,12 @@ func Forget(rc *butlerd.RequestContext, params butlerd.ProfileForgetParams) (*bu
 	}
 	return res, nil
 }
+
+// storeAPIKey moves the profile's API key to the secret store, if there's
+// one. If that fails, it stays in the database, as it always has.
+func storeAPIKey(rc *butlerd.RequestContext, profile *models.Profile) {
+	err := profile.StoreAPIKey()
+	if err != nil {
+		rc.Consumer.Warnf("%v, keeping it in the database", err)
+	}
+}
@@ -8,4 +8,5 @@ import (
 func Register(router *butlerd.Router) {
 	messages.SearchGames.Register(router, SearchGames)
 	messages.SearchUsers.Register(router, SearchUsers)
+	messages.SearchLibrary.Register(router, SearchLibrary)
 }
@@ -1,11 +1,9 @@
 package search
 
 import (
-	"fmt"
 	"log"
 
 	"crawshaw.io/sqlite"
-	"xorm.io/builder"
 	"github.com/itchio/butler/butlerd"
 	"github.com/itchio/butler/database/models"
 	itchio "github.com/itchio/go-itchio"
@@ -26,11 +24,17 @@ func SearchGames(rc *butlerd.RequestContext, params butlerd.SearchGamesParams) (
 
 	doLocalSearch := func() {
 		games = nil
-		q := fmt.Sprintf("%%%s%%", params.Query)
+		match := models.GameSearchMatch(params.Query)
+		if match == "" {
+			return
+		}
 		rc.WithConn(func(conn *sqlite.Conn) {
 			models.MustSelect(conn, &games,
-				builder.Like{"lower(title)", q},
-				hades.Search{}.Limit(4),
+				models.GameSearchCond(match),
+				hades.Search{}.
+					InnerJoin(models.GameSearchTable, "games_fts.rowid = games.id").
+					OrderBy(models.GameSearchOrder).
+					Limit(4),
 			)
 		})
 	}
@@ -45,7 +49,10 @@ func SearchGames(rc *butlerd.RequestContext, params butlerd.SearchGamesParams) (
 	// perform API request
 	//----------------------------------
 
-	_, client := rc.ProfileClient(params.ProfileID)
+	_, client, err := rc.ProfileClient(params.ProfileID)
+	if err != nil {
+		return nil, err
+	}
 	searchRes, err := client.SearchGames(rc.Ctx, itchio.SearchGamesParams{
 		Query: params.Query,
 		Page:  1,
@@ -0,0 +1,112 @@
+package search
+
+import (
+	"sort"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/endpoints/fetch/pager"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/hades"
+	"xorm.io/builder"
+)
+
+func SearchLibrary(rc *butlerd.RequestContext, params butlerd.SearchLibraryParams) (*butlerd.SearchLibraryResult, error) {
+	res := &butlerd.SearchLibraryResult{
+		Items: []*butlerd.LibrarySearchItem{},
+	}
+
+	match := models.GameSearchMatch(params.Query)
+	if match == "" {
+		return res, nil
+	}
+
+	installedCond := builder.Expr("games.id IN (SELECT game_id FROM caves)")
+	ownedCond := builder.Expr("games.id IN (SELECT game_id FROM download_keys WHERE owner_id = ?)", params.ProfileID)
+	collectedCond := builder.Expr(`games.id IN (SELECT collection_games.game_id FROM collection_games
+		INNER JOIN profile_collections ON profile_collections.collection_id = collection_games.collection_id
+		WHERE profile_collections.profile_id = ?)`, params.ProfileID)
+
+	cond := builder.And(
+		models.GameSearchCond(match),
+		builder.Or(installedCond, ownedCond, collectedCond),
+	)
+
+	filters := params.Filters
+	if filters.Installed {
+		cond = builder.And(cond, installedCond)
+	}
+	if filters.Owned {
+		cond = builder.And(cond, ownedCond)
+	}
+	if filters.Platform != "" {
+		// platform is also the column name, see itchio.Platforms
+		cond = builder.And(cond, builder.Neq{"coalesce(games." + string(filters.Platform) + ", '')": ""})
+	}
+
+	search := hades.Search{}.
+		InnerJoin(models.GameSearchTable, "games_fts.rowid = games.id").
+		OrderBy(models.GameSearchOrder + ", games.id ASC")
+
+	rc.WithConn(func(conn *sqlite.Conn) {
+		var games []*itchio.Game
+		pg := pager.New(params)
+		res.NextCursor = pg.Fetch(conn, &games, cond, search)
+		if len(games) == 0 {
+			return
+		}
+
+		var gameIDs []interface{}
+		for _, g := range games {
+			gameIDs = append(gameIDs, g.ID)
+		}
+
+		var caves []*models.Cave
+		models.MustSelect(conn, &caves, builder.In("game_id", gameIDs...), hades.Search{})
+		// sorted here rather than with ORDER BY: timestamps are stored
+		// as text, and don't sort correctly within the same second
+		installedAt := func(c *models.Cave) time.Time {
+			if c.InstalledAt == nil {
+				return time.Time{}
+			}
+			return *c.InstalledAt
+		}
+		sort.SliceStable(caves, func(i, j int) bool {
+			a, b := installedAt(caves[i]), installedAt(caves[j])
+			if !a.Equal(b) {
+				return a.Before(b)
+			}
+			return caves[i].ID < caves[j].ID
+		})
+		caveIDs := make(map[int64][]string)
+		for _, c := range caves {
+			caveIDs[c.GameID] = append(caveIDs[c.GameID], c.ID)
+		}
+
+		var keys []*itchio.DownloadKey
+		models.MustSelect(conn, &keys, builder.And(
+			builder.Eq{"owner_id": params.ProfileID},
+			builder.In("game_id", gameIDs...),
+		), hades.Search{})
+		owned := make(map[int64]bool)
+		for _, dk := range keys {
+			owned[dk.GameID] = true
+		}
+
+		models.MustPreload(conn, games, hades.Assoc("User"))
+		for _, g := range games {
+			item := &butlerd.LibrarySearchItem{
+				Game:    g,
+				CaveIDs: caveIDs[g.ID],
+				Owned:   owned[g.ID],
+			}
+			if item.CaveIDs == nil {
+				item.CaveIDs = []string{}
+			}
+			res.Items = append(res.Items, item)
+		}
+	})
+	return res, nil
+}
@@ -0,0 +1,186 @@
+package search_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/helloeave/json"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/jsonrpc2"
+	"github.com/itchio/butler/database"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/endpoints/search"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/ox"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+// testConn is a client that ignores everything the daemon sends it
+type testConn struct{}
+
+var _ jsonrpc2.Conn = (*testConn)(nil)
+
+func (c *testConn) Call(method string, params interface{}, result interface{}) error {
+	return nil
+}
+
+func (c *testConn) Notify(method string, params interface{}) error {
+	return nil
+}
+
+func (c *testConn) Context() context.Context {
+	return context.Background()
+}
+
+func (c *testConn) Close() {}
+
+func Test_SearchLibrary(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "search-library-test")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	dbPool, err := sqlitex.Open(filepath.Join(dir, "butler.db"), 0, 2)
+	wtest.Must(t, err)
+	defer dbPool.Close()
+
+	const profileID = 1
+	const otherProfileID = 2
+
+	conn := dbPool.Get(context.Background())
+	func() {
+		defer dbPool.Put(conn)
+		wtest.Must(t, database.Prepare(&state.Consumer{}, conn, database.PrepareOptions{JustCreated: true}))
+
+		models.MustSave(conn, &itchio.User{ID: 100, Username: "dungeon-masters"})
+		models.MustSave(conn, &itchio.User{ID: 101, Username: "someone"})
+
+		games := []*itchio.Game{
+			{ID: 1, UserID: 101, Title: "Short Text Match", ShortText: "Crawl a dungeon"},
+			{ID: 2, UserID: 100, Title: "Author Match"},
+			{ID: 3, UserID: 101, Title: "Dungeon Crawler", Platforms: itchio.Platforms{Linux: itchio.ArchitecturesAll}},
+			{ID: 4, UserID: 101, Title: "Dungeon Collected"},
+			{ID: 5, UserID: 101, Title: "Dungeon Not In Library"},
+			{ID: 6, UserID: 101, Title: "Dungeon Someone Else Owns"},
+		}
+		for _, g := range games {
+			models.MustSave(conn, g)
+		}
+
+		for _, gameID := range []int64{1, 2, 3} {
+			models.MustSave(conn, &itchio.DownloadKey{ID: 1000 + gameID, GameID: gameID, OwnerID: profileID})
+		}
+		models.MustSave(conn, &itchio.DownloadKey{ID: 1006, GameID: 6, OwnerID: otherProfileID})
+		// installed in the same second: "…00.5Z" sorts before "…00Z" as text
+		installedAt := time.Date(2020, time.March, 10, 10, 0, 0, 0, time.UTC)
+		installedLater := installedAt.Add(500 * time.Millisecond)
+		models.MustSave(conn, &models.Cave{ID: "cave-1", GameID: 1, InstalledAt: &installedLater})
+		models.MustSave(conn, &models.Cave{ID: "cave-z", GameID: 1, InstalledAt: &installedAt})
+		models.MustSave(conn, &models.ProfileCollection{ProfileID: profileID, CollectionID: 50})
+		models.MustSave(conn, &itchio.CollectionGame{CollectionID: 50, GameID: 4})
+	}()
+
+	router := butlerd.NewRouter(dbPool, nil, nil, nil)
+	search.Register(router)
+
+	searchLibrary := func(params butlerd.SearchLibraryParams) *butlerd.SearchLibraryResult {
+		t.Helper()
+		rawParams, err := json.Marshal(params)
+		wtest.Must(t, err)
+		rawMessage := json.RawMessage(rawParams)
+
+		res, err := router.HandleRequest(&testConn{}, jsonrpc2.Request{
+			ID:     1,
+			Method: "Search.Library",
+			Params: &rawMessage,
+		})
+		wtest.Must(t, err)
+		return res.(*butlerd.SearchLibraryResult)
+	}
+	gameIDs := func(res *butlerd.SearchLibraryResult) []int64 {
+		ids := []int64{}
+		for _, item := range res.Items {
+			ids = append(ids, item.Game.ID)
+		}
+		return ids
+	}
+
+	// title matches first, then author, then short text,
+	// and only games in the profile's library
+	res := searchLibrary(butlerd.SearchLibraryParams{
+		ProfileID: profileID,
+		Query:     "dungeon",
+	})
+	assert.EqualValues([]int64{3, 4, 2, 1}, gameIDs(res))
+	for _, item := range res.Items {
+		switch item.Game.ID {
+		case 1:
+			assert.EqualValues([]string{"cave-z", "cave-1"}, item.CaveIDs, "oldest install first")
+			assert.True(item.Owned)
+		case 4:
+			assert.EqualValues([]string{}, item.CaveIDs)
+			assert.False(item.Owned)
+		}
+		assert.NotNil(item.Game.User, "game %d has its author", item.Game.ID)
+	}
+
+	assert.EqualValues([]int64{1}, gameIDs(searchLibrary(butlerd.SearchLibraryParams{
+		ProfileID: profileID,
+		Query:     "dungeon",
+		Filters:   butlerd.LibrarySearchFilters{Installed: true},
+	})))
+	assert.EqualValues([]int64{3, 2, 1}, gameIDs(searchLibrary(butlerd.SearchLibraryParams{
+		ProfileID: profileID,
+		Query:     "dungeon",
+		Filters:   butlerd.LibrarySearchFilters{Owned: true},
+	})))
+	assert.EqualValues([]int64{3}, gameIDs(searchLibrary(butlerd.SearchLibraryParams{
+		ProfileID: profileID,
+		Query:     "dungeon",
+		Filters:   butlerd.LibrarySearchFilters{Platform: ox.PlatformLinux},
+	})))
+
+	// paginated, keeping the order
+	page := searchLibrary(butlerd.SearchLibraryParams{
+		ProfileID: profileID,
+		Query:     "dungeon",
+		Limit:     3,
+	})
+	assert.EqualValues([]int64{3, 4, 2}, gameIDs(page))
+	assert.NotEmpty(page.NextCursor)
+	page = searchLibrary(butlerd.SearchLibraryParams{
+		ProfileID: profileID,
+		Query:     "dungeon",
+		Limit:     3,
+		Cursor:    page.NextCursor,
+	})
+	assert.EqualValues([]int64{1}, gameIDs(page))
+
+	// FTS5 syntax is neither interpreted nor an error
+	for _, query := range []string{`"`, `*`, `-`, `NEAR`, `NEAR(dungeon crawler)`, `dungeon"`, `-dungeon`, `dungeon*`} {
+		res := searchLibrary(butlerd.SearchLibraryParams{
+			ProfileID: profileID,
+			Query:     query,
+		})
+		switch query {
+		case `"`, `*`, `-`, `NEAR`, `NEAR(dungeon crawler)`:
+			assert.Empty(res.Items, "query (%s)", query)
+		default:
+			assert.EqualValues([]int64{3, 4, 2, 1}, gameIDs(res), "query (%s)", query)
+		}
+	}
+
+	// installed games are in everyone's library
+	assert.EqualValues([]int64{6, 1}, gameIDs(searchLibrary(butlerd.SearchLibraryParams{
+		ProfileID: otherProfileID,
+		Query:     "dungeon",
+	})))
+}
@@ -48,7 +48,10 @@ func SearchUsers(rc *butlerd.RequestContext, params butlerd.SearchUsersParams) (
 	// perform API request
 	//----------------------------------
 
-	_, client := rc.ProfileClient(params.ProfileID)
+	_, client, err := rc.ProfileClient(params.ProfileID)
+	if err != nil {
+		return nil, err
+	}
 	searchRes, err := client.SearchUsers(rc.Ctx, itchio.SearchUsersParams{
 		Query: params.Query,
 		Page:  1,
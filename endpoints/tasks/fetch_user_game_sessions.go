@@ -3,6 +3,7 @@ package tasks
 import (
 	"fmt"
 
+	"crawshaw.io/sqlite"
 	"github.com/itchio/butler/butlerd"
 	"github.com/itchio/butler/cmd/operate"
 	"github.com/itchio/butler/database/models"
@@ -22,33 +23,13 @@ func FetchUserGameSessions(gameID int64) butlerd.BackgroundTask {
 				return nil
 			}
 
-			access := operate.AccessForGameID(conn, gameID)
-			client := rc.Client(access.APIKey)
-
-			toUpload := models.CaveHistoricalPlayTimeForCaves(conn, caves)
-			consumer.Infof("%d historical cave play time pending", len(toUpload))
-
-			for _, playtime := range toUpload {
-				consumer.Infof("Syncing historical playtime for cave (%s)", playtime.CaveID)
-
-				_, err := client.CreateUserGameSession(rc.Ctx, itchio.CreateUserGameSessionParams{
-					Credentials: access.Credentials,
-					GameID:      playtime.GameID,
-					UploadID:    playtime.UploadID,
-					BuildID:     playtime.BuildID,
-					SecondsRun:  playtime.SecondsRun,
-				})
-				if err != nil {
-					consumer.Warnf("Could not sync play time: %+v", err)
-				} else {
-					playtime.MarkUploaded(conn)
-				}
-			}
+			client := syncPlayTime(rc, conn, gameID, caves)
 
 			consumer.Infof("Fetching game interactions summary for game %d...", gameID)
 			interactionsRes, err := client.GetGameSessionsSummary(rc.Ctx, gameID)
 			if err != nil {
 				consumer.Warnf("While fetching user game sessions: %+v", err)
+				return nil
 			}
 
 			for _, cave := range caves {
@@ -60,3 +41,55 @@ func FetchUserGameSessions(gameID int64) butlerd.BackgroundTask {
 		},
 	}
 }
+
+// syncPlayTime uploads the play time recorded locally for the caves of
+// a game that hasn't been synced with itch.io yet: historical play time,
+// and play sessions that are over. It returns the client it used.
+func syncPlayTime(rc *butlerd.RequestContext, conn *sqlite.Conn, gameID int64, caves []*models.Cave) *itchio.Client {
+	consumer := rc.Consumer
+
+	access := operate.AccessForGameID(conn, gameID)
+	client := rc.Client(access.APIKey)
+
+	toUpload := models.CaveHistoricalPlayTimeForCaves(conn, caves)
+	consumer.Infof("%d historical cave play time pending", len(toUpload))
+
+	for _, playtime := range toUpload {
+		consumer.Infof("Syncing historical playtime for cave (%s)", playtime.CaveID)
+
+		_, err := client.CreateUserGameSession(rc.Ctx, itchio.CreateUserGameSessionParams{
+			Credentials: access.Credentials,
+			GameID:      playtime.GameID,
+			UploadID:    playtime.UploadID,
+			BuildID:     playtime.BuildID,
+			SecondsRun:  playtime.SecondsRun,
+		})
+		if err != nil {
+			consumer.Warnf("Could not sync play time: %+v", err)
+		} else {
+			playtime.MarkUploaded(conn)
+		}
+	}
+
+	pendingSessions := models.PendingPlaySessionsForCaves(conn, caves)
+	consumer.Infof("%d play sessions pending", len(pendingSessions))
+
+	for _, ps := range pendingSessions {
+		consumer.Infof("Syncing play session (%s) for cave (%s)", ps.ID, ps.CaveID)
+
+		if ps.EndedAt == nil {
+			// butler went away while the game was running
+			ps.EndedAt = ps.LastRunAt
+		}
+
+		_, err := operate.SyncPlaySession(rc.Ctx, client, access, ps)
+		if err != nil {
+			consumer.Warnf("Could not sync play session: %+v", err)
+			ps.Save(conn)
+		} else {
+			ps.MarkSynced(conn)
+		}
+	}
+
+	return client
+}
@@ -0,0 +1,33 @@
+package tasks
+
+import (
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/database/models"
+)
+
+// SyncPlaySessions uploads the play time recorded locally for every
+// game that has some pending. FetchUserGameSessions only does it for
+// games that are fetched, this is queued when logging in online, so
+// that sessions played offline don't wait for that.
+func SyncPlaySessions() butlerd.BackgroundTask {
+	return butlerd.BackgroundTask{
+		Desc: "sync play sessions",
+		Do: func(rc *butlerd.RequestContext) error {
+			conn := rc.GetConn()
+			defer rc.PutConn(conn)
+
+			for _, gameID := range models.GameIDsWithPendingPlayTime(conn) {
+				if rc.Ctx.Err() != nil {
+					return nil
+				}
+
+				caves := models.CavesByGameID(conn, gameID)
+				if len(caves) == 0 {
+					continue
+				}
+				syncPlayTime(rc, conn, gameID, caves)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,188 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/jsonrpc2"
+	"github.com/itchio/butler/database"
+	"github.com/itchio/butler/database/models"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+	"xorm.io/builder"
+)
+
+// quietConn is a client that ignores notifications
+type quietConn struct{}
+
+var _ jsonrpc2.Conn = (*quietConn)(nil)
+
+func (c *quietConn) Call(method string, params interface{}, result interface{}) error {
+	return nil
+}
+
+func (c *quietConn) Notify(method string, params interface{}) error {
+	return nil
+}
+
+func (c *quietConn) Context() context.Context {
+	return context.Background()
+}
+
+func (c *quietConn) Close() {}
+
+// fakeSessionsAPI records the game sessions created and updated
+type fakeSessionsAPI struct {
+	lock    sync.Mutex
+	nextID  int64
+	created map[int64]int64 // session ID => seconds run
+	crashed map[int64]bool
+}
+
+func (api *fakeSessionsAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	r.ParseForm()
+	var secondsRun int64
+	fmt.Sscanf(r.Form.Get("seconds_run"), "%d", &secondsRun)
+
+	var id int64
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/profile/game-sessions":
+		api.nextID++
+		id = api.nextID
+		api.created[id] = secondsRun
+	case r.Method == "POST" && strings.HasPrefix(r.URL.Path, "/profile/game-sessions/"):
+		fmt.Sscanf(strings.TrimPrefix(r.URL.Path, "/profile/game-sessions/"), "%d", &id)
+		if _, ok := api.created[id]; !ok {
+			w.WriteHeader(404)
+			return
+		}
+		if secondsRun != 0 {
+			api.created[id] = secondsRun
+		}
+		_, api.crashed[id] = r.Form["crashed"]
+	default:
+		w.WriteHeader(404)
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user_game_session": map[string]interface{}{"id": id},
+		"summary":           map[string]interface{}{"game_id": 123},
+	})
+}
+
+func Test_SyncPlaySessions(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "tasks-test")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	dbPool, err := sqlitex.Open(filepath.Join(dir, "butler.db"), 0, 4)
+	wtest.Must(t, err)
+	defer dbPool.Close()
+
+	withConn := func(f func(conn *sqlite.Conn)) {
+		conn := dbPool.Get(context.Background())
+		defer dbPool.Put(conn)
+		f(conn)
+	}
+
+	api := &fakeSessionsAPI{
+		created: make(map[int64]int64),
+		crashed: make(map[int64]bool),
+	}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	now := time.Now().UTC()
+	ago := func(d time.Duration) *time.Time {
+		t := now.Add(-d)
+		return &t
+	}
+	withConn(func(conn *sqlite.Conn) {
+		consumer := &state.Consumer{
+			OnMessage: func(lvl string, msg string) { t.Logf("[%s] %s", lvl, msg) },
+		}
+		wtest.Must(t, database.Prepare(consumer, conn, database.PrepareOptions{JustCreated: true}))
+
+		models.MustSave(conn, &models.Profile{ID: 1, APIKey: "key", LastConnected: now})
+		models.MustSave(conn, &models.Cave{ID: "cave-a", GameID: 123, UploadID: 1})
+		models.MustSave(conn, &models.Cave{ID: "cave-b", GameID: 456, UploadID: 2})
+
+		for _, ps := range []*models.CavePlaySession{
+			// over, never synced
+			{ID: "ended", CaveID: "cave-a", GameID: 123, StartedAt: ago(time.Hour), LastRunAt: ago(50 * time.Minute), EndedAt: ago(50 * time.Minute), SecondsRun: 600},
+			// crashed, which takes an update after creating it
+			{ID: "crashed", CaveID: "cave-b", GameID: 456, StartedAt: ago(time.Hour), LastRunAt: ago(55 * time.Minute), EndedAt: ago(55 * time.Minute), SecondsRun: 300, Crashed: true},
+			// butler went away while it was running
+			{ID: "stale", CaveID: "cave-b", GameID: 456, StartedAt: ago(2 * time.Hour), LastRunAt: ago(time.Hour), SecondsRun: 3600},
+			// still running
+			{ID: "running", CaveID: "cave-a", GameID: 123, StartedAt: ago(time.Minute), LastRunAt: ago(time.Second), SecondsRun: 59},
+			// already synced
+			{ID: "synced", CaveID: "cave-a", GameID: 123, StartedAt: ago(3 * time.Hour), LastRunAt: ago(3 * time.Hour), EndedAt: ago(3 * time.Hour), SecondsRun: 10, SyncedAt: ago(3 * time.Hour)},
+		} {
+			ps.Save(conn)
+		}
+		models.MustSave(conn, &models.CaveHistoricalPlayTime{CaveID: "cave-a", GameID: 123, UploadID: 1, SecondsRun: 7200})
+	})
+
+	router := butlerd.NewRouter(dbPool, func(key string) *itchio.Client {
+		return itchio.ClientWithKey(key).SetServer(server.URL)
+	}, nil, nil)
+	router.Register("Test.SyncPlaySessions", func(rc *butlerd.RequestContext) (interface{}, error) {
+		return nil, SyncPlaySessions().Do(rc)
+	})
+	_, err = router.HandleRequest(&quietConn{}, jsonrpc2.Request{ID: 1, Method: "Test.SyncPlaySessions"})
+	wtest.Must(t, err)
+
+	session := func(id string) *models.CavePlaySession {
+		var ps *models.CavePlaySession
+		withConn(func(conn *sqlite.Conn) {
+			ps = &models.CavePlaySession{}
+			models.MustSelectOne(conn, ps, builder.Eq{"id": id})
+		})
+		return ps
+	}
+
+	for _, id := range []string{"ended", "crashed", "stale"} {
+		ps := session(id)
+		assert.NotNil(ps.SyncedAt, "session (%s) should be marked synced", id)
+		if assert.NotZero(ps.RemoteSessionID, "session (%s)", id) {
+			assert.EqualValues(ps.SecondsRun, api.created[ps.RemoteSessionID], "session (%s)", id)
+		}
+	}
+	assert.True(api.crashed[session("crashed").RemoteSessionID])
+	assert.NotNil(session("stale").EndedAt, "stale sessions end when they were last seen running")
+
+	running := session("running")
+	assert.Nil(running.SyncedAt)
+	assert.Zero(running.RemoteSessionID)
+
+	// 3 sessions, and the historical play time
+	assert.Len(api.created, 4)
+	withConn(func(conn *sqlite.Conn) {
+		playtime := &models.CaveHistoricalPlayTime{}
+		models.MustSelectOne(conn, playtime, builder.Eq{"cave_id": "cave-a"})
+		assert.NotNil(playtime.UploadedAt)
+	})
+}
@@ -0,0 +1,129 @@
+package update
+
+import (
+	"sync"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/cmd/operate"
+	"github.com/itchio/butler/database/models"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/headway/united"
+)
+
+type describeChoiceParams struct {
+	rc          *butlerd.RequestContext
+	consumer    *state.Consumer
+	client      *itchio.Client
+	credentials itchio.GameCredentials
+	cave        *models.Cave
+}
+
+// describeChoice fills in what players want to know before accepting
+// an update: how much will be downloaded, and which version they'll get.
+func describeChoice(params describeChoiceParams, choice *butlerd.GameUpdateChoice) {
+	consumer := params.consumer
+	cave := params.cave
+
+	if choice.Upload != nil {
+		choice.FullSize = choice.Upload.Size
+	}
+	if cave.Build != nil {
+		choice.FromUserVersion = cave.Build.UserVersion
+	}
+	if choice.Build != nil {
+		choice.ToUserVersion = choice.Build.UserVersion
+	}
+
+	// only newer builds of the installed upload can be patched to
+	if choice.Upload == nil || choice.Build == nil || cave.Upload == nil || cave.BuildID == 0 {
+		return
+	}
+	if choice.Upload.ID != cave.Upload.ID || choice.Build.ID <= cave.BuildID {
+		return
+	}
+
+	key := upgradePathKey{currentBuildID: cave.BuildID, targetBuildID: choice.Build.ID}
+	size, ok := upgradePaths.get(key)
+	if !ok {
+		res, err := params.client.GetBuildUpgradePath(params.rc.Ctx, itchio.GetBuildUpgradePathParams{
+			CurrentBuildID: cave.BuildID,
+			TargetBuildID:  choice.Build.ID,
+			Credentials:    params.credentials,
+		})
+		if err != nil {
+			consumer.Warnf("Could not find upgrade path: %s", err.Error())
+			return
+		}
+
+		builds := res.UpgradePath.Builds
+		if len(builds) < 2 {
+			return
+		}
+		// skip the current build, we're not interested in it
+		builds = builds[1:]
+
+		for _, b := range builds {
+			f := operate.UpgradePatchFile(b)
+			if f == nil {
+				// might still be processing, so this isn't cached
+				consumer.Infof("Build %d is missing a patch, update can't be patched", b.ID)
+				return
+			}
+			size.patchSize += f.Size
+		}
+		size.patchCount = int64(len(builds))
+		upgradePaths.put(key, size)
+	}
+
+	choice.PatchSize = size.patchSize
+	choice.PatchCount = size.patchCount
+	consumer.Infof("→ Upgrade path has %d patches (%s), full upload is %s",
+		size.patchCount,
+		united.FormatBytes(size.patchSize),
+		united.FormatBytes(choice.FullSize),
+	)
+}
+
+type upgradePathKey struct {
+	currentBuildID int64
+	targetBuildID  int64
+}
+
+type upgradePathSize struct {
+	patchSize  int64
+	patchCount int64
+}
+
+// maxCachedUpgradePaths bounds the cache, which is cleared when full
+const maxCachedUpgradePaths = 1024
+
+type upgradePathCache struct {
+	lock  sync.Mutex
+	sizes map[upgradePathKey]upgradePathSize
+}
+
+// upgradePaths remembers the size of upgrade paths across update
+// checks. Builds don't change once they have their patches, so
+// neither do the paths between them.
+var upgradePaths = &upgradePathCache{
+	sizes: make(map[upgradePathKey]upgradePathSize),
+}
+
+func (c *upgradePathCache) get(key upgradePathKey) (upgradePathSize, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	size, ok := c.sizes[key]
+	return size, ok
+}
+
+func (c *upgradePathCache) put(key upgradePathKey, size upgradePathSize) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(c.sizes) >= maxCachedUpgradePaths {
+		c.sizes = make(map[upgradePathKey]upgradePathSize)
+	}
+	c.sizes[key] = size
+}
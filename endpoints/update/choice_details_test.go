@@ -0,0 +1,107 @@
+package update
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/database/models"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/headway/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DescribeChoice(t *testing.T) {
+	assert := assert.New(t)
+
+	// builds 100 (installed) to 103 of upload 1: 102 and 103 have patches,
+	// 101 doesn't yet. 200 to 202 of upload 2 all have theirs.
+	var apiCalls int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&apiCalls, 1)
+		build := func(id int64, patchSize int64) string {
+			files := `{"type":"archive","sub_type":"default","size":1000}`
+			if patchSize > 0 {
+				files += fmt.Sprintf(`,{"type":"patch","sub_type":"default","size":%d}`, patchSize)
+			}
+			return fmt.Sprintf(`{"id":%d,"files":[%s]}`, id, files)
+		}
+		switch r.URL.Path {
+		case "/builds/100/upgrade-paths/103":
+			fmt.Fprintf(w, `{"upgrade_path":{"builds":[%s,%s,%s,%s]}}`, build(100, 0), build(101, 0), build(102, 20), build(103, 30))
+		case "/builds/200/upgrade-paths/202":
+			fmt.Fprintf(w, `{"upgrade_path":{"builds":[%s,%s,%s]}}`, build(200, 0), build(201, 10*1024*1024), build(202, 2*1024*1024))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, `{"errors":["not found"]}`)
+		}
+	}))
+	defer server.Close()
+
+	client := itchio.ClientWithKey("key")
+	client.SetServer(server.URL)
+
+	describe := func(cave *models.Cave, choice *butlerd.GameUpdateChoice) *butlerd.GameUpdateChoice {
+		describeChoice(describeChoiceParams{
+			rc:       &butlerd.RequestContext{Ctx: context.Background()},
+			consumer: &state.Consumer{OnMessage: func(lvl string, msg string) { t.Logf("[%s] %s", lvl, msg) }},
+			client:   client,
+			cave:     cave,
+		}, choice)
+		return choice
+	}
+	caveAt := func(uploadID int64, buildID int64, userVersion string) *models.Cave {
+		return &models.Cave{
+			Upload:  &itchio.Upload{ID: uploadID},
+			BuildID: buildID,
+			Build:   &itchio.Build{ID: buildID, UserVersion: userVersion},
+		}
+	}
+	choiceOf := func(uploadID int64, buildID int64, userVersion string) *butlerd.GameUpdateChoice {
+		return &butlerd.GameUpdateChoice{
+			Upload: &itchio.Upload{ID: uploadID, Size: 50 * 1024 * 1024},
+			Build:  &itchio.Build{ID: buildID, UserVersion: userVersion},
+		}
+	}
+
+	choice := describe(caveAt(2, 200, "v1.4.2"), choiceOf(2, 202, "v1.4.3"))
+	assert.EqualValues(12*1024*1024, choice.PatchSize)
+	assert.EqualValues(2, choice.PatchCount)
+	assert.EqualValues(50*1024*1024, choice.FullSize)
+	assert.EqualValues("v1.4.2", choice.FromUserVersion)
+	assert.EqualValues("v1.4.3", choice.ToUserVersion)
+	assert.EqualValues(1, atomic.LoadInt64(&apiCalls))
+
+	// the upgrade path is cached
+	choice = describe(caveAt(2, 200, "v1.4.2"), choiceOf(2, 202, "v1.4.3"))
+	assert.EqualValues(12*1024*1024, choice.PatchSize)
+	assert.EqualValues(2, choice.PatchCount)
+	assert.EqualValues(1, atomic.LoadInt64(&apiCalls))
+
+	// a missing patch means it can't be patched, and isn't cached,
+	// since the patch might still be on its way
+	for i := 0; i < 2; i++ {
+		choice = describe(caveAt(1, 100, ""), choiceOf(1, 103, ""))
+		assert.Zero(choice.PatchSize)
+		assert.Zero(choice.PatchCount)
+		assert.EqualValues(50*1024*1024, choice.FullSize)
+	}
+	assert.EqualValues(3, atomic.LoadInt64(&apiCalls))
+
+	// other uploads, older builds and wharf-less caves aren't patchable,
+	// and the API isn't asked about them
+	for _, choice := range []*butlerd.GameUpdateChoice{
+		describe(caveAt(1, 100, ""), choiceOf(2, 202, "")),
+		describe(caveAt(2, 202, ""), choiceOf(2, 200, "")),
+		describe(&models.Cave{Upload: &itchio.Upload{ID: 2}}, choiceOf(2, 202, "")),
+		describe(caveAt(2, 200, ""), &butlerd.GameUpdateChoice{Upload: &itchio.Upload{ID: 2}}),
+	} {
+		assert.Zero(choice.PatchSize)
+		assert.Zero(choice.PatchCount)
+	}
+	assert.EqualValues(3, atomic.LoadInt64(&apiCalls))
+}
@@ -0,0 +1,137 @@
+package update
+
+import (
+	"fmt"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/butlerd/messages"
+	"github.com/itchio/butler/database/models"
+	"github.com/itchio/butler/endpoints/install"
+	"github.com/pkg/errors"
+	"xorm.io/builder"
+)
+
+// DefaultMinConfidence is how confident a direct update's choice must be
+// for it to be downloaded or installed without asking.
+const DefaultMinConfidence = 0.9
+
+// give clients a chance to connect before the first check,
+// so they get the notifications
+const firstCheckDelay = 1 * time.Minute
+
+type SchedulerSettings struct {
+	// Time between the end of a check and the start of the next one
+	Interval time.Duration
+	// See DefaultMinConfidence
+	MinConfidence float64
+}
+
+// ScheduleChecks returns a background task that checks all caves for
+// updates on an interval, until butlerd shuts down. Snoozed and pinned
+// caves are respected, and each update is handled according to its
+// cave's update policy.
+func ScheduleChecks(settings SchedulerSettings) butlerd.BackgroundTask {
+	return butlerd.BackgroundTask{
+		Desc: fmt.Sprintf("Checking for updates every %s", settings.Interval),
+		// installing updates sends progress notifications too
+		NotifyClients: true,
+		Do: func(rc *butlerd.RequestContext) error {
+			delay := firstCheckDelay
+			for {
+				select {
+				case <-rc.Ctx.Done():
+					return nil
+				case <-time.After(delay):
+					// time to check
+				}
+				delay = settings.Interval
+
+				res := checkUpdates(rc, butlerd.CheckUpdateParams{}, nil)
+				for _, update := range res.Updates {
+					err := applyUpdatePolicy(rc, settings, update)
+					if err != nil {
+						rc.Consumer.Warnf("Could not apply update for cave (%s): %+v", update.CaveID, err)
+					}
+				}
+			}
+		},
+	}
+}
+
+func applyUpdatePolicy(rc *butlerd.RequestContext, settings SchedulerSettings, update *butlerd.GameUpdate) error {
+	consumer := rc.Consumer
+
+	notify := func() error {
+		return messages.GameUpdateAvailable.Notify(rc, butlerd.GameUpdateAvailableNotification{
+			Update: update,
+		})
+	}
+
+	var cave *models.Cave
+	var pendingDownloads int64
+	rc.WithConn(func(conn *sqlite.Conn) {
+		cave = models.CaveByID(conn, update.CaveID)
+		pendingDownloads = models.MustCount(conn, &models.Download{}, builder.And(
+			builder.Eq{"cave_id": update.CaveID},
+			builder.IsNull{"finished_at"},
+			builder.Not{builder.Expr("discarded")},
+		))
+	})
+	if cave == nil {
+		return errors.Errorf("No such cave (%s)", update.CaveID)
+	}
+
+	policy := butlerd.UpdatePolicy(cave.UpdatePolicy)
+	if policy == "" || policy == butlerd.UpdatePolicyNotify {
+		return notify()
+	}
+
+	if !update.Direct || len(update.Choices) == 0 {
+		consumer.Infof("Update for cave (%s) is not direct, only notifying", cave.ID)
+		return notify()
+	}
+	choice := update.Choices[0]
+	if choice.Confidence < settings.MinConfidence {
+		consumer.Infof("Update for cave (%s) has confidence %.2f (below %.2f), only notifying", cave.ID, choice.Confidence, settings.MinConfidence)
+		return notify()
+	}
+
+	if pendingDownloads > 0 {
+		consumer.Infof("Cave (%s) already has a download queued, leaving it be", cave.ID)
+		return nil
+	}
+	if len(rc.Launches.List(cave.ID)) > 0 {
+		consumer.Infof("Cave (%s) is running, will try again next check", cave.ID)
+		return nil
+	}
+
+	queueRes, err := install.InstallQueue(rc, butlerd.InstallQueueParams{
+		CaveID:        cave.ID,
+		Reason:        butlerd.DownloadReasonUpdate,
+		Game:          update.Game,
+		Upload:        choice.Upload,
+		Build:         choice.Build,
+		QueueDownload: policy == butlerd.UpdatePolicyDownload,
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if policy == butlerd.UpdatePolicyDownload {
+		consumer.Infof("Queued update download for cave (%s)", cave.ID)
+		return nil
+	}
+
+	consumer.Infof("Installing update for cave (%s)", cave.ID)
+	_, err = install.InstallPerform(rc, butlerd.InstallPerformParams{
+		ID:            queueRes.ID,
+		StagingFolder: queueRes.StagingFolder,
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	consumer.Statf("Updated cave (%s)", cave.ID)
+	return nil
+}
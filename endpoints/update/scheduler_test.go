@@ -0,0 +1,259 @@
+package update
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqlitex"
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/butler/database"
+	"github.com/itchio/butler/database/models"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/headway/state"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+	"xorm.io/builder"
+)
+
+var installedAt = time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+
+type updateTestEnv struct {
+	t        *testing.T
+	router   *butlerd.Router
+	apiCalls int64
+}
+
+// newUpdateTestEnv sets up a database with a single cave (of game 10,
+// upload 1, installed at installedAt), and a fake API server on which
+// upload 2 was updated an hour after that.
+func newUpdateTestEnv(t *testing.T) *updateTestEnv {
+	env := &updateTestEnv{t: t}
+
+	dir, err := ioutil.TempDir("", "update-test")
+	wtest.Must(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	dbPool, err := sqlitex.Open(filepath.Join(dir, "butler.db"), 0, 4)
+	wtest.Must(t, err)
+	t.Cleanup(func() { dbPool.Close() })
+
+	conn := dbPool.Get(context.Background())
+	func() {
+		defer dbPool.Put(conn)
+		consumer := &state.Consumer{
+			OnMessage: func(lvl string, msg string) { t.Logf("[%s] %s", lvl, msg) },
+		}
+		wtest.Must(t, database.Prepare(consumer, conn, database.PrepareOptions{JustCreated: true}))
+
+		platforms := itchio.Platforms{
+			Linux:   itchio.ArchitecturesAll,
+			Windows: itchio.ArchitecturesAll,
+			OSX:     itchio.ArchitecturesAll,
+		}
+		models.MustSave(conn, &models.Profile{ID: 1, APIKey: "key"})
+		models.MustSave(conn, &itchio.DownloadKey{ID: 100, GameID: 10, OwnerID: 1})
+		models.MustSave(conn, &itchio.Game{ID: 10, Title: "Some Game"})
+		models.MustSave(conn, &itchio.Upload{
+			ID:        1,
+			Type:      "default",
+			Filename:  "some-game-v1.zip",
+			Platforms: platforms,
+		})
+		models.MustSave(conn, &models.Cave{
+			ID:                  "cave-1",
+			GameID:              10,
+			UploadID:            1,
+			InstalledAt:         &installedAt,
+			CustomInstallFolder: filepath.Join(dir, "some-game"),
+		})
+	}()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&env.apiCalls, 1)
+		if r.URL.Path != "/games/10/uploads" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, `{"errors":["not found"]}`)
+			return
+		}
+		updatedAt := installedAt.Add(time.Hour).Format(time.RFC3339Nano)
+		fmt.Fprintf(w, `{"uploads":[
+			{"id":1,"game_id":10,"type":"default","filename":"some-game-v1.zip","platforms":{"linux":"all","windows":"all","osx":"all"},"updated_at":%q},
+			{"id":2,"game_id":10,"type":"default","filename":"some-game-v2.zip","platforms":{"linux":"all","windows":"all","osx":"all"},"updated_at":%q}
+		]}`, updatedAt, updatedAt)
+	}))
+	t.Cleanup(server.Close)
+
+	getClient := func(key string) *itchio.Client {
+		client := itchio.ClientWithKey(key)
+		client.SetServer(server.URL)
+		return client
+	}
+	env.router = butlerd.NewRouter(dbPool, getClient, http.DefaultClient, nil)
+	return env
+}
+
+// run calls f from a background task, so it gets a request
+// context like the scheduler's
+func (env *updateTestEnv) run(f func(rc *butlerd.RequestContext)) {
+	env.t.Helper()
+
+	var wg sync.WaitGroup
+	finished := false
+	wg.Add(1)
+	env.router.QueueBackgroundTask(butlerd.BackgroundTask{
+		Desc: "test",
+		Do: func(rc *butlerd.RequestContext) error {
+			defer wg.Done()
+			f(rc)
+			finished = true
+			return nil
+		},
+	})
+	wg.Wait()
+
+	if !finished {
+		// the router recovers panics and logs them
+		env.t.Fatalf("background task panicked")
+	}
+}
+
+func (env *updateTestEnv) updateCave(f func(cave *models.Cave)) {
+	env.run(func(rc *butlerd.RequestContext) {
+		rc.WithConn(func(conn *sqlite.Conn) {
+			cave := models.CaveByID(conn, "cave-1")
+			f(cave)
+			cave.Save(conn)
+		})
+	})
+}
+
+func Test_CheckUpdatesSnoozeAndPin(t *testing.T) {
+	assert := assert.New(t)
+	env := newUpdateTestEnv(t)
+
+	check := func(params butlerd.CheckUpdateParams) *butlerd.CheckUpdateResult {
+		var res *butlerd.CheckUpdateResult
+		env.run(func(rc *butlerd.RequestContext) {
+			res = checkUpdates(rc, params, nil)
+		})
+		assert.Empty(res.Warnings)
+		return res
+	}
+	scheduled := butlerd.CheckUpdateParams{}
+	explicit := butlerd.CheckUpdateParams{CaveIDs: []string{"cave-1"}}
+
+	res := check(scheduled)
+	if assert.Len(res.Updates, 1) {
+		update := res.Updates[0]
+		assert.EqualValues("cave-1", update.CaveID)
+		assert.False(update.Direct)
+		if assert.Len(update.Choices, 1) {
+			assert.EqualValues(2, update.Choices[0].Upload.ID)
+		}
+	}
+
+	// snoozed after the update showed up: background checks
+	// leave it be, but asking explicitly still finds it
+	snoozedAt := installedAt.Add(2 * time.Hour)
+	env.updateCave(func(cave *models.Cave) {
+		cave.SnoozedAt = &snoozedAt
+	})
+	assert.Empty(check(scheduled).Updates)
+	assert.Len(check(explicit).Updates, 1)
+
+	// pinned caves are never checked, not even explicitly
+	env.updateCave(func(cave *models.Cave) {
+		cave.SnoozedAt = nil
+		cave.Pinned = true
+	})
+	callsBefore := atomic.LoadInt64(&env.apiCalls)
+	assert.Empty(check(scheduled).Updates)
+	assert.Empty(check(explicit).Updates)
+	assert.EqualValues(callsBefore, atomic.LoadInt64(&env.apiCalls))
+}
+
+func Test_ApplyUpdatePolicy(t *testing.T) {
+	assert := assert.New(t)
+	env := newUpdateTestEnv(t)
+
+	settings := SchedulerSettings{
+		Interval:      time.Hour,
+		MinConfidence: DefaultMinConfidence,
+	}
+	directUpdate := func(confidence float64) *butlerd.GameUpdate {
+		return &butlerd.GameUpdate{
+			CaveID: "cave-1",
+			Game:   &itchio.Game{ID: 10},
+			Direct: true,
+			Choices: []*butlerd.GameUpdateChoice{
+				{
+					Upload:     &itchio.Upload{ID: 1},
+					Build:      &itchio.Build{ID: 2},
+					Confidence: confidence,
+				},
+			},
+		}
+	}
+
+	// apply returns whether clients were notified of the update, and
+	// checks that nothing was queued (we never get as far as installing)
+	apply := func(policy butlerd.UpdatePolicy, update *butlerd.GameUpdate) bool {
+		t.Helper()
+		env.updateCave(func(cave *models.Cave) {
+			cave.UpdatePolicy = string(policy)
+		})
+
+		notified := false
+		env.run(func(rc *butlerd.RequestContext) {
+			rc.InterceptNotification("GameUpdateAvailable", func(method string, params interface{}) error {
+				notified = true
+				return nil
+			})
+			wtest.Must(t, applyUpdatePolicy(rc, settings, update))
+
+			rc.WithConn(func(conn *sqlite.Conn) {
+				queued := models.MustCount(conn, &models.Download{}, builder.Neq{"id": "pending"})
+				assert.EqualValues(0, queued)
+			})
+		})
+		return notified
+	}
+
+	// notifying is the default
+	assert.True(apply("", directUpdate(1)))
+	assert.True(apply(butlerd.UpdatePolicyNotify, directUpdate(1)))
+
+	// updates we're not sure about are only notified
+	lowConfidence := directUpdate(0.5)
+	assert.True(apply(butlerd.UpdatePolicyInstall, lowConfidence))
+	notDirect := directUpdate(1)
+	notDirect.Direct = false
+	assert.True(apply(butlerd.UpdatePolicyDownload, notDirect))
+
+	// running caves are left alone until the next check
+	launchID, err := env.router.Launches.Add("cave-1", false)
+	wtest.Must(t, err)
+	env.router.Launches.Start(launchID, butlerd.LaunchStrategyNative, func() {})
+	assert.False(apply(butlerd.UpdatePolicyInstall, directUpdate(1)))
+	assert.False(apply(butlerd.UpdatePolicyDownload, directUpdate(1)))
+	env.router.Launches.Remove(launchID)
+
+	// ...and so are caves that already have a download queued
+	env.run(func(rc *butlerd.RequestContext) {
+		rc.WithConn(func(conn *sqlite.Conn) {
+			models.MustSave(conn, &models.Download{ID: "pending", CaveID: "cave-1", GameID: 10})
+		})
+	})
+	assert.False(apply(butlerd.UpdatePolicyInstall, directUpdate(1)))
+	assert.False(apply(butlerd.UpdatePolicyDownload, directUpdate(1)))
+}
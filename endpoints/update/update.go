@@ -31,6 +31,20 @@ func Register(router *butlerd.Router) {
 }
 
 func CheckUpdate(rc *butlerd.RequestContext, params butlerd.CheckUpdateParams) (*butlerd.CheckUpdateResult, error) {
+	res := checkUpdates(rc, params, func(update *butlerd.GameUpdate) {
+		err := messages.GameUpdateAvailable.Notify(rc, butlerd.GameUpdateAvailableNotification{
+			Update: update,
+		})
+		if err != nil {
+			rc.Consumer.Warnf("Could not send GameUpdateAvailable notification: %s", err.Error())
+		}
+	})
+	return res, nil
+}
+
+// checkUpdates looks for updates to the caves listed in params, or
+// all of them, calling onUpdate (if non-nil) for each update found.
+func checkUpdates(rc *butlerd.RequestContext, params butlerd.CheckUpdateParams, onUpdate func(update *butlerd.GameUpdate)) *butlerd.CheckUpdateResult {
 	startTime := time.Now()
 
 	consumer := rc.Consumer
@@ -108,11 +122,8 @@ func CheckUpdate(rc *butlerd.RequestContext, params butlerd.CheckUpdateParams) (
 			}
 			if update != nil {
 				res.Updates = append(res.Updates, update)
-				err := messages.GameUpdateAvailable.Notify(rc, butlerd.GameUpdateAvailableNotification{
-					Update: update,
-				})
-				if err != nil {
-					consumer.Warnf("Could not send GameUpdateAvailable notification: %s", err.Error())
+				if onUpdate != nil {
+					onUpdate(update)
 				}
 			}
 		}
@@ -158,7 +169,7 @@ func CheckUpdate(rc *butlerd.RequestContext, params butlerd.CheckUpdateParams) (
 
 	consumer.Statf("Checked %d entries in %s", len(caves), time.Since(startTime))
 
-	return res, nil
+	return res
 }
 
 type checkUpdateCaveParams struct {
@@ -273,6 +284,14 @@ func checkUpdateCave(params checkUpdateCaveParams, consumer *state.Consumer, cav
 
 	consumer.Statf("Checking for updates to (%s)", operate.GameToString(cave.Game))
 
+	describeParams := describeChoiceParams{
+		rc:          rc,
+		consumer:    consumer,
+		client:      client,
+		credentials: access.Credentials,
+		cave:        cave,
+	}
+
 	if access.Credentials.DownloadKeyID > 0 {
 		consumer.Infof("→ Has download key (game is owned)")
 	} else {
@@ -360,11 +379,13 @@ func checkUpdateCave(params checkUpdateCaveParams, consumer *state.Consumer, cav
 					Direct: true,
 				}
 
-				res.Choices = append(res.Choices, &butlerd.GameUpdateChoice{
+				choice := &butlerd.GameUpdateChoice{
 					Upload:     freshUpload,
 					Build:      freshUpload.Build,
 					Confidence: 1,
-				})
+				}
+				describeChoice(describeParams, choice)
+				res.Choices = append(res.Choices, choice)
 				return res, nil
 			} else {
 				consumer.Statf("The latest build is installed.")
@@ -426,6 +447,7 @@ func checkUpdateCave(params checkUpdateCaveParams, consumer *state.Consumer, cav
 			Build:      u.Build,
 			Confidence: confidence,
 		}
+		describeChoice(describeParams, choice)
 		res.Choices = append(res.Choices, choice)
 	}
 
@@ -0,0 +1,135 @@
+package lancache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DiscoveryPort is the UDP port announcements are broadcast on
+const DiscoveryPort = 19281
+
+const (
+	announceInterval = 5 * time.Second
+	peerExpiry       = 3 * announceInterval
+	serviceName      = "butler-lancache"
+)
+
+type announcement struct {
+	Service    string `json:"service"`
+	InstanceID string `json:"instanceId"`
+	Port       int    `json:"port"`
+}
+
+func (c *Cache) discover(ctx context.Context) error {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{Port: DiscoveryPort})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	go c.listen(conn)
+	go c.announce(ctx, conn)
+	return nil
+}
+
+func (c *Cache) announce(ctx context.Context, conn *net.UDPConn) {
+	payload, err := json.Marshal(announcement{
+		Service:    serviceName,
+		InstanceID: c.instanceID,
+		Port:       c.settings.Port,
+	})
+	if err != nil {
+		c.consumer.Warnf("lancache: could not encode announcement: %v", err)
+		return
+	}
+
+	broadcast := &net.UDPAddr{IP: net.IPv4bcast, Port: DiscoveryPort}
+	for {
+		_, err := conn.WriteToUDP(payload, broadcast)
+		if err != nil {
+			c.consumer.Debugf("lancache: could not announce: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(announceInterval):
+			// keep going
+		}
+	}
+}
+
+func (c *Cache) listen(conn *net.UDPConn) {
+	buf := make([]byte, 1024)
+	for {
+		n, addr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			// connection closed when ctx is done
+			return
+		}
+
+		var a announcement
+		err = json.Unmarshal(buf[:n], &a)
+		if err != nil || a.Service != serviceName || a.InstanceID == c.instanceID {
+			continue
+		}
+
+		address := net.JoinHostPort(addr.IP.String(), strconv.Itoa(a.Port))
+		c.peersLock.Lock()
+		if _, ok := c.peers[address]; !ok {
+			c.consumer.Infof("lancache: discovered peer %s", address)
+			c.peers[address] = &peer{address: address}
+		}
+		c.peers[address].lastSeen = time.Now()
+		c.peersLock.Unlock()
+	}
+}
+
+func (c *Cache) livePeers() []string {
+	c.peersLock.Lock()
+	defer c.peersLock.Unlock()
+
+	var res []string
+	for address, p := range c.peers {
+		if !p.static && time.Since(p.lastSeen) > peerExpiry {
+			delete(c.peers, address)
+			continue
+		}
+		res = append(res, address)
+	}
+	return res
+}
+
+// FindPeer asks known peers whether they have the given file,
+// and returns an URL to download it from the first one that does.
+// A size of 0 means it's unknown, and any size is accepted.
+func (c *Cache) FindPeer(ctx context.Context, key string, size int64) (string, bool) {
+	for _, address := range c.livePeers() {
+		url := fmt.Sprintf("http://%s%s%s?token=%s", address, filesPrefix, key, c.fileToken(key))
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+		if err != nil {
+			continue
+		}
+
+		res, err := c.httpClient.Do(req)
+		if err != nil {
+			c.consumer.Debugf("lancache: peer %s unreachable: %v", address, err)
+			continue
+		}
+		res.Body.Close()
+
+		if res.StatusCode == http.StatusOK && (size == 0 || res.ContentLength == size) {
+			return url, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,183 @@
+// Package lancache implements an opt-in cache for build archives and
+// patches, shared between butlerd instances on the same local network.
+//
+// Files are stored in a single directory and served over HTTP. Peers
+// are discovered via UDP broadcast (and can also be specified manually).
+// Only peers that know the shared secret may download files, and the
+// server can be bound to a single interface. Whatever is obtained from a
+// peer must still be verified by the caller before being used or
+// committed to the cache: peers are not trusted.
+package lancache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	itchio "github.com/itchio/go-itchio"
+	"github.com/itchio/headway/state"
+	"github.com/pkg/errors"
+)
+
+// DefaultPort is the TCP port the HTTP server listens on by default
+const DefaultPort = 19280
+
+// SecretEnvironmentVariable holds the secret shared by all peers.
+// It's not a flag, so it doesn't show up in process listings.
+const SecretEnvironmentVariable = "BUTLER_LAN_CACHE_SECRET"
+
+type Settings struct {
+	// Directory in which cached files are stored
+	Dir string
+	// TCP port to serve files on, also announced to peers
+	Port int
+	// Host or IP address to serve files on, all interfaces if empty
+	ListenHost string
+	// Secret shared by all peers, required to download files
+	Secret string
+	// Peers to use regardless of discovery, as "host:port"
+	StaticPeers []string
+}
+
+type Cache struct {
+	settings   Settings
+	consumer   *state.Consumer
+	instanceID string
+	httpClient *http.Client
+
+	peersLock sync.Mutex
+	peers     map[string]*peer
+}
+
+type peer struct {
+	address  string
+	lastSeen time.Time
+	static   bool
+}
+
+var current *Cache
+
+// Enable sets up the LAN cache for this process. It must be
+// called before Start, and only once.
+func Enable(consumer *state.Consumer, settings Settings) (*Cache, error) {
+	if settings.Port == 0 {
+		settings.Port = DefaultPort
+	}
+	if settings.Secret == "" {
+		return nil, errors.Errorf("the LAN cache needs a secret shared by all peers, set %s", SecretEnvironmentVariable)
+	}
+
+	err := os.MkdirAll(incomingDir(settings.Dir), 0o755)
+	if err != nil {
+		return nil, errors.WithMessage(err, "creating LAN cache directory")
+	}
+
+	c := &Cache{
+		settings:   settings,
+		consumer:   consumer,
+		instanceID: uuid.New().String(),
+		httpClient: &http.Client{
+			Timeout: 2 * time.Second,
+		},
+		peers: make(map[string]*peer),
+	}
+	for _, address := range settings.StaticPeers {
+		c.peers[address] = &peer{
+			address: address,
+			static:  true,
+		}
+	}
+
+	current = c
+	return c, nil
+}
+
+// Get returns the LAN cache, or nil if it's not enabled.
+func Get() *Cache {
+	return current
+}
+
+// Start serves cached files and runs peer discovery until ctx is done.
+func (c *Cache) Start(ctx context.Context) error {
+	err := c.serve(ctx)
+	if err != nil {
+		return errors.WithMessage(err, "starting LAN cache server")
+	}
+
+	err = c.discover(ctx)
+	if err != nil {
+		// static peers still work without discovery
+		c.consumer.Warnf("lancache: peer discovery disabled: %v", err)
+	}
+	return nil
+}
+
+var keyRegexp = regexp.MustCompile(`^build-[0-9]+-[a-z]+-[a-z]+\.[a-z]+$`)
+
+// BuildFileKey returns the name under which a build file is cached.
+// The extension matters: archives are validated as zip files.
+func BuildFileKey(buildID int64, fileType itchio.BuildFileType, subType itchio.BuildFileSubType) string {
+	if subType == "" {
+		subType = itchio.BuildFileSubTypeDefault
+	}
+
+	ext := ".bin"
+	switch fileType {
+	case itchio.BuildFileTypeArchive:
+		ext = ".zip"
+	case itchio.BuildFileTypePatch:
+		ext = ".pwr"
+	case itchio.BuildFileTypeSignature:
+		ext = ".pws"
+	}
+	return fmt.Sprintf("build-%d-%s-%s%s", buildID, fileType, subType, ext)
+}
+
+// Lookup returns the path of a cached file, if it's present
+// and has the expected size (unless size is 0, meaning unknown).
+func (c *Cache) Lookup(key string, size int64) (string, bool) {
+	path := filepath.Join(c.settings.Dir, key)
+	stats, err := os.Stat(path)
+	if err != nil || stats.IsDir() {
+		return "", false
+	}
+	if size > 0 && stats.Size() != size {
+		return "", false
+	}
+	return path, true
+}
+
+// IncomingPath returns where a file should be downloaded to
+// before it's verified and committed. Incoming files are never served.
+func (c *Cache) IncomingPath(key string) string {
+	return filepath.Join(incomingDir(c.settings.Dir), key)
+}
+
+// Commit moves a verified incoming file into the cache, so
+// that it may be used locally and served to peers.
+func (c *Cache) Commit(key string) (string, error) {
+	path := filepath.Join(c.settings.Dir, key)
+	err := os.Rename(c.IncomingPath(key), path)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return path, nil
+}
+
+// Discard removes an incoming file, for example if it failed verification.
+func (c *Cache) Discard(key string) {
+	err := os.Remove(c.IncomingPath(key))
+	if err != nil && !os.IsNotExist(err) {
+		c.consumer.Warnf("lancache: could not discard %s: %v", key, err)
+	}
+}
+
+func incomingDir(dir string) string {
+	return filepath.Join(dir, ".incoming")
+}
@@ -0,0 +1,82 @@
+package lancache
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const filesPrefix = "/lancache/v1/files/"
+
+func (c *Cache) serve(ctx context.Context) error {
+	listener, err := net.Listen("tcp", net.JoinHostPort(c.settings.ListenHost, strconv.Itoa(c.settings.Port)))
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(filesPrefix, c.handleFile)
+
+	server := &http.Server{
+		Handler: mux,
+	}
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	go func() {
+		err := server.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			c.consumer.Warnf("lancache: server stopped: %v", err)
+		}
+	}()
+
+	c.consumer.Infof("lancache: serving %s on %s", c.settings.Dir, listener.Addr())
+	return nil
+}
+
+func (c *Cache) handleFile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, filesPrefix)
+	if !keyRegexp.MatchString(key) {
+		http.NotFound(w, r)
+		return
+	}
+
+	if !c.validToken(key, r.URL.Query().Get("token")) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		c.consumer.Debugf("lancache: serving %s to %s", key, r.RemoteAddr)
+	}
+
+	// ServeFile handles range requests, which are needed
+	// to resume downloads
+	http.ServeFile(w, r, filepath.Join(c.settings.Dir, key))
+}
+
+// fileToken proves knowledge of the shared secret for a single file,
+// without sending the secret itself over the network.
+func (c *Cache) fileToken(key string) string {
+	mac := hmac.New(sha256.New, []byte(c.settings.Secret))
+	mac.Write([]byte(key))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (c *Cache) validToken(key string, token string) bool {
+	return hmac.Equal([]byte(token), []byte(c.fileToken(key)))
+}
@@ -0,0 +1,50 @@
+package lancache
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/headway/state"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ServeNeedsSecret(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "lancache")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	consumer := &state.Consumer{
+		OnMessage: func(lvl string, msg string) { t.Logf("[%s] %s", lvl, msg) },
+	}
+	_, err = Enable(consumer, Settings{Dir: dir})
+	assert.Error(err)
+
+	c, err := Enable(consumer, Settings{Dir: dir, Secret: "correct horse"})
+	wtest.Must(t, err)
+	defer func() { current = nil }()
+
+	key := "build-1-archive-default.zip"
+	wtest.Must(t, ioutil.WriteFile(filepath.Join(dir, key), []byte("archive"), 0o644))
+
+	get := func(query string) int {
+		w := httptest.NewRecorder()
+		c.handleFile(w, httptest.NewRequest(http.MethodGet, filesPrefix+key+query, nil))
+		return w.Code
+	}
+
+	assert.EqualValues(http.StatusForbidden, get(""))
+	assert.EqualValues(http.StatusForbidden, get("?token=nope"))
+
+	other := &Cache{settings: Settings{Secret: "battery staple"}}
+	assert.EqualValues(http.StatusForbidden, get("?token="+other.fileToken(key)))
+	assert.EqualValues(http.StatusForbidden, get("?token="+c.fileToken("build-2-archive-default.zip")))
+
+	assert.EqualValues(http.StatusOK, get("?token="+c.fileToken(key)))
+}
@@ -0,0 +1,273 @@
+// Package launchlogs records everything games write to their standard
+// output and standard error, one directory per cave, one set of files
+// per launch session.
+//
+// Files are rotated once they reach a certain size, and only the most
+// recent sessions of each cave are kept.
+package launchlogs
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// DefaultMaxFileSize is used when no rotation size is specified
+const DefaultMaxFileSize = 8 * 1024 * 1024
+
+// DefaultMaxSessions is used when no retention is specified
+const DefaultMaxSessions = 10
+
+// How many rotated files are kept per stream, per session
+const maxRotatedFiles = 3
+
+type Stream string
+
+const (
+	StreamStdout Stream = "stdout"
+	StreamStderr Stream = "stderr"
+)
+
+type Settings struct {
+	// Directory per-cave log folders are created in
+	Dir string
+	// Size at which a log file is rotated, in bytes
+	MaxFileSize int64
+	// Number of sessions kept per cave
+	MaxSessions int
+}
+
+type Logs struct {
+	settings Settings
+}
+
+var current *Logs
+
+// Enable sets up launch logs for this process.
+func Enable(settings Settings) (*Logs, error) {
+	if settings.MaxFileSize == 0 {
+		settings.MaxFileSize = DefaultMaxFileSize
+	}
+	if settings.MaxSessions == 0 {
+		settings.MaxSessions = DefaultMaxSessions
+	}
+
+	err := os.MkdirAll(settings.Dir, 0o755)
+	if err != nil {
+		return nil, errors.WithMessage(err, "creating launch logs directory")
+	}
+
+	current = &Logs{
+		settings: settings,
+	}
+	return current, nil
+}
+
+// Get returns the launch logs, or nil if they're not enabled.
+func Get() *Logs {
+	return current
+}
+
+var caveIDRe = regexp.MustCompile(`^[0-9A-Za-z][0-9A-Za-z_-]{0,63}$`)
+
+// CaveDir returns the folder the log files of a cave are kept in.
+// Cave IDs are UUIDs, anything that could escape Dir is rejected.
+func (l *Logs) CaveDir(caveID string) (string, error) {
+	if !caveIDRe.MatchString(caveID) {
+		return "", errors.Errorf("invalid cave ID (%s)", caveID)
+	}
+	return filepath.Join(l.settings.Dir, caveID), nil
+}
+
+// File is a log file on disk
+type File struct {
+	// Base name of the file
+	Name      string
+	SessionID string
+	Stream    Stream
+	// 0 for the current file, 1 for the one before, etc.
+	Part       int
+	Size       int64
+	ModifiedAt time.Time
+}
+
+var fileNameRe = regexp.MustCompile(`^([0-9]{8}-[0-9]{6}-[0-9]{3}-[0-9a-f]{8})\.(stdout|stderr)\.log(?:\.([0-9]+))?$`)
+
+func parseFileName(name string) (*File, bool) {
+	matches := fileNameRe.FindStringSubmatch(name)
+	if matches == nil {
+		return nil, false
+	}
+
+	f := &File{
+		Name:      name,
+		SessionID: matches[1],
+		Stream:    Stream(matches[2]),
+	}
+	if matches[3] != "" {
+		fmt.Sscanf(matches[3], "%d", &f.Part)
+	}
+	return f, true
+}
+
+func fileName(sessionID string, stream Stream, part int) string {
+	name := fmt.Sprintf("%s.%s.log", sessionID, stream)
+	if part > 0 {
+		name += fmt.Sprintf(".%d", part)
+	}
+	return name
+}
+
+// List returns all log files for a cave, most recent session first.
+func (l *Logs) List(caveID string) ([]*File, error) {
+	dir, err := l.CaveDir(caveID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	var files []*File
+	for _, entry := range entries {
+		f, ok := parseFileName(entry.Name())
+		if !ok {
+			continue
+		}
+		f.Size = entry.Size()
+		f.ModifiedAt = entry.ModTime()
+		files = append(files, f)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		a, b := files[i], files[j]
+		if a.SessionID != b.SessionID {
+			// session IDs start with a timestamp
+			return a.SessionID > b.SessionID
+		}
+		if a.Stream != b.Stream {
+			return a.Stream > b.Stream
+		}
+		return a.Part < b.Part
+	})
+	return files, nil
+}
+
+// Path returns the path of a log file of a cave. name must be
+// one of the names returned by List.
+func (l *Logs) Path(caveID string, name string) (string, error) {
+	dir, err := l.CaveDir(caveID)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := parseFileName(name); !ok {
+		return "", errors.Errorf("invalid launch log name (%s)", name)
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// RemoveCave removes all log files of a cave
+func (l *Logs) RemoveCave(caveID string) error {
+	dir, err := l.CaveDir(caveID)
+	if err != nil {
+		return err
+	}
+	return errors.WithStack(os.RemoveAll(dir))
+}
+
+// StartSession creates log files for a new launch session,
+// removing the oldest sessions of that cave as needed.
+func (l *Logs) StartSession(caveID string) (*Session, error) {
+	dir, err := l.CaveDir(caveID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	err = l.prune(caveID, l.settings.MaxSessions-1)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	id := fmt.Sprintf("%s-%03d-%s", now.Format("20060102-150405"), now.Nanosecond()/int(time.Millisecond), strings.Replace(uuid.New().String(), "-", "", -1)[:8])
+	s := &Session{
+		ID:  id,
+		Dir: dir,
+	}
+	s.stdout = newRotatingWriter(dir, id, StreamStdout, l.settings.MaxFileSize)
+	s.stderr = newRotatingWriter(dir, id, StreamStderr, l.settings.MaxFileSize)
+	return s, nil
+}
+
+// prune removes the oldest sessions of a cave until at most keep remain
+func (l *Logs) prune(caveID string, keep int) error {
+	dir, err := l.CaveDir(caveID)
+	if err != nil {
+		return err
+	}
+
+	files, err := l.List(caveID)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	for _, f := range files {
+		if !seen[f.SessionID] {
+			seen[f.SessionID] = true
+			keep--
+		}
+		if keep < 0 {
+			os.Remove(filepath.Join(dir, f.Name))
+		}
+	}
+	return nil
+}
+
+// Session holds the log files of a single launch
+type Session struct {
+	ID string
+	// Folder the log files are in
+	Dir string
+
+	stdout *rotatingWriter
+	stderr *rotatingWriter
+}
+
+func (s *Session) Stdout() io.Writer {
+	return s.stdout
+}
+
+func (s *Session) Stderr() io.Writer {
+	return s.stderr
+}
+
+// Close closes both log files. It returns the first
+// error encountered while writing them, if any.
+func (s *Session) Close() error {
+	err1 := s.stdout.Close()
+	err2 := s.stderr.Close()
+	if err1 != nil {
+		return err1
+	}
+	return err2
+}
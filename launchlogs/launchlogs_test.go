@@ -0,0 +1,147 @@
+package launchlogs_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/itchio/butler/launchlogs"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+const caveID = "1b4e28ba-2fa1-11d2-883f-0016d3cca427"
+
+func Test_Rotation(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "launchlogs-test")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	ll, err := launchlogs.Enable(launchlogs.Settings{
+		Dir:         dir,
+		MaxFileSize: 10,
+		MaxSessions: 2,
+	})
+	wtest.Must(t, err)
+
+	session, err := ll.StartSession(caveID)
+	wtest.Must(t, err)
+	assert.EqualValues(filepath.Join(dir, caveID), session.Dir)
+
+	// 6 lines of 6 bytes, 10 bytes per file: one line per file,
+	// and only the current file plus 3 rotated ones are kept.
+	for i := 0; i < 6; i++ {
+		fmt.Fprintf(session.Stdout(), "line%d\n", i)
+	}
+	fmt.Fprintf(session.Stderr(), "oops\n")
+	wtest.Must(t, session.Close())
+
+	files, err := ll.List(caveID)
+	wtest.Must(t, err)
+
+	var stdout []string
+	var stderr []string
+	for _, f := range files {
+		assert.EqualValues(session.ID, f.SessionID)
+		path, err := ll.Path(caveID, f.Name)
+		wtest.Must(t, err)
+		contents, err := ioutil.ReadFile(path)
+		wtest.Must(t, err)
+		assert.EqualValues(len(contents), f.Size)
+
+		switch f.Stream {
+		case launchlogs.StreamStdout:
+			assert.EqualValues(len(stdout), f.Part, "parts are listed in order")
+			stdout = append(stdout, string(contents))
+		case launchlogs.StreamStderr:
+			stderr = append(stderr, string(contents))
+		}
+	}
+	assert.EqualValues([]string{"line5\n", "line4\n", "line3\n", "line2\n"}, stdout)
+	assert.EqualValues([]string{"oops\n"}, stderr)
+}
+
+func Test_SessionRetention(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "launchlogs-test")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	ll, err := launchlogs.Enable(launchlogs.Settings{
+		Dir:         dir,
+		MaxSessions: 2,
+	})
+	wtest.Must(t, err)
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		session, err := ll.StartSession(caveID)
+		wtest.Must(t, err)
+		fmt.Fprintf(session.Stdout(), "session %d\n", i)
+		wtest.Must(t, session.Close())
+		ids = append(ids, session.ID)
+
+		// session IDs are ordered by their millisecond timestamp
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	files, err := ll.List(caveID)
+	wtest.Must(t, err)
+	var listed []string
+	for _, f := range files {
+		listed = append(listed, f.SessionID)
+	}
+	// most recent first, the oldest one is gone
+	assert.EqualValues([]string{ids[2], ids[1]}, listed)
+
+	wtest.Must(t, ll.RemoveCave(caveID))
+	files, err = ll.List(caveID)
+	wtest.Must(t, err)
+	assert.Empty(files)
+}
+
+func Test_InvalidCaveIDs(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "launchlogs-test")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	ll, err := launchlogs.Enable(launchlogs.Settings{
+		Dir: filepath.Join(dir, "logs"),
+	})
+	wtest.Must(t, err)
+
+	// something RemoveCave must never touch
+	precious := filepath.Join(dir, "precious")
+	wtest.Must(t, ioutil.WriteFile(precious, []byte("precious"), 0o644))
+
+	for _, id := range []string{"", "..", "../..", ".", "../precious", "a/b", `a\b`, "/etc", strings.Repeat("a", 65)} {
+		_, err := ll.CaveDir(id)
+		assert.Error(err, "cave ID (%s)", id)
+
+		_, err = ll.List(id)
+		assert.Error(err, "cave ID (%s)", id)
+
+		_, err = ll.Path(id, "20200101-000000-000-0123abcd.stdout.log")
+		assert.Error(err, "cave ID (%s)", id)
+
+		assert.Error(ll.RemoveCave(id), "cave ID (%s)", id)
+
+		_, err = ll.StartSession(id)
+		assert.Error(err, "cave ID (%s)", id)
+	}
+
+	_, err = os.Stat(precious)
+	assert.NoError(err)
+
+	_, err = ll.Path(caveID, "../../precious")
+	assert.Error(err)
+}
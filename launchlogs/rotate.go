@@ -0,0 +1,102 @@
+package launchlogs
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// rotatingWriter writes to a log file, moving it aside once it
+// reaches maxSize. Write errors are remembered rather than returned,
+// so that a full disk doesn't break the game's output pipes.
+type rotatingWriter struct {
+	dir       string
+	sessionID string
+	stream    Stream
+	maxSize   int64
+
+	lock sync.Mutex
+	file *os.File
+	size int64
+	err  error
+}
+
+func newRotatingWriter(dir string, sessionID string, stream Stream, maxSize int64) *rotatingWriter {
+	return &rotatingWriter{
+		dir:       dir,
+		sessionID: sessionID,
+		stream:    stream,
+		maxSize:   maxSize,
+	}
+}
+
+func (rw *rotatingWriter) path(part int) string {
+	return filepath.Join(rw.dir, fileName(rw.sessionID, rw.stream, part))
+}
+
+func (rw *rotatingWriter) Write(p []byte) (int, error) {
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
+
+	if rw.err != nil {
+		return len(p), nil
+	}
+
+	if rw.file != nil && rw.size+int64(len(p)) > rw.maxSize {
+		rw.err = rw.rotate()
+		if rw.err != nil {
+			return len(p), nil
+		}
+	}
+
+	if rw.file == nil {
+		rw.file, rw.err = os.Create(rw.path(0))
+		if rw.err != nil {
+			rw.err = errors.WithStack(rw.err)
+			return len(p), nil
+		}
+		rw.size = 0
+	}
+
+	n, err := rw.file.Write(p)
+	rw.size += int64(n)
+	if err != nil {
+		rw.err = errors.WithStack(err)
+	}
+	return len(p), nil
+}
+
+// rotate closes the current file and shifts it (and older ones) by one,
+// dropping the oldest.
+func (rw *rotatingWriter) rotate() error {
+	err := rw.file.Close()
+	rw.file = nil
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	os.Remove(rw.path(maxRotatedFiles))
+	for part := maxRotatedFiles - 1; part >= 0; part-- {
+		err := os.Rename(rw.path(part), rw.path(part+1))
+		if err != nil && !os.IsNotExist(err) {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func (rw *rotatingWriter) Close() error {
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
+
+	if rw.file != nil {
+		err := rw.file.Close()
+		rw.file = nil
+		if err != nil && rw.err == nil {
+			rw.err = errors.WithStack(err)
+		}
+	}
+	return rw.err
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/itchio/butler/comm"
 	"github.com/itchio/butler/filtering"
 	"github.com/itchio/butler/mansion"
+	"github.com/itchio/butler/secrets"
 
 	"github.com/itchio/go-itchio/itchfs"
 
@@ -55,9 +56,11 @@ var appArgs = struct {
 	beeps4Life *bool
 
 	identity             *string
+	as                   *string
 	address              *string
 	userAgentAddition    *string
 	dbPath               *string
+	secretStore          *string
 	compressionAlgorithm *string
 	compressionQuality   *int
 
@@ -77,10 +80,12 @@ var appArgs = struct {
 	app.Flag("assume-yes", "Don't ask questions, just proceed (at your own risk!)").Bool(),
 	app.Flag("beeps4life", "Restore historical robot bug.").Hidden().Bool(),
 
-	app.Flag("identity", "Path to your itch.io API token").Default(defaultKeyPath()).Short('i').String(),
+	app.Flag("identity", "Path to your itch.io API token (defaults to "+defaultKeyPath()+")").Short('i').String(),
+	app.Flag("as", "Name of the identity to use, see `butler identity`").String(),
 	app.Flag("address", "itch.io server to talk to").Default("https://api.itch.io").Short('a').Hidden().String(),
 	app.Flag("user-agent", "string to include in user-agent for all http requests").Default("").Hidden().String(),
 	app.Flag("dbpath", "Path of the sqlite database path to use (for butlerd)").Default("").Hidden().String(),
+	app.Flag("secret-store", "Where to keep API keys: the OS keyring (secret-service, keychain, wincred), a file encrypted with the passphrase in "+secrets.PassphraseEnvironmentVariable+" (file), or plaintext (none)").Default(secrets.BackendAuto).Enum(secrets.Backends...),
 
 	app.Flag("compression", "Compression algorithm to use when writing patch or signature files").Default("brotli").Hidden().Enum("none", "brotli", "gzip"),
 	app.Flag("quality", "Quality level to use when writing patch or signature files").Default("1").Short('q').Hidden().Int(),
@@ -226,10 +231,15 @@ func doMain(args []string) {
 
 	fullCmd := kingpin.MustParse(cmd, err)
 
-	ctx.Identity = *appArgs.identity
+	ctx.DefaultIdentity = defaultKeyPath()
+	if err := ctx.ResolveIdentity(*appArgs.identity, *appArgs.as); err != nil {
+		// only fatal for commands that need credentials, see IdentityError
+		comm.Debugf("Could not resolve identity: %v", err)
+	}
 	ctx.SetAddress(*appArgs.address)
 	ctx.UserAgentAddition = *appArgs.userAgentAddition
 	ctx.DBPath = *appArgs.dbPath
+	ctx.SecretStore = *appArgs.secretStore
 	ctx.Quiet = *appArgs.quiet
 	ctx.Verbose = *appArgs.verbose
 	ctx.JSON = *appArgs.json
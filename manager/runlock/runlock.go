@@ -16,12 +16,15 @@ import (
 
 type Lock interface {
 	Lock(ctx context.Context, task string) error
+	// TryLock takes the lock if no running process holds it, without
+	// waiting. It returns false if one does.
+	TryLock(task string) (bool, error)
 	Unlock() error
 }
 
 type lock struct {
-	consumer      *state.Consumer
-	installFolder string
+	consumer *state.Consumer
+	path     string
 }
 
 type runlockPayload struct {
@@ -32,8 +35,18 @@ type runlockPayload struct {
 
 func New(consumer *state.Consumer, installFolder string) Lock {
 	rl := &lock{
-		consumer:      consumer,
-		installFolder: installFolder,
+		consumer: consumer,
+		path:     filepath.Join(installFolder, ".itch", "runlock.json"),
+	}
+	return rl
+}
+
+// NewForDatabase returns the lock butlerd holds on its database while
+// it runs, so that it's not replaced from under it.
+func NewForDatabase(consumer *state.Consumer, dbPath string) Lock {
+	rl := &lock{
+		consumer: consumer,
+		path:     dbPath + ".runlock.json",
 	}
 	return rl
 }
@@ -50,36 +63,7 @@ func (rl *lock) Lock(ctx context.Context, task string) error {
 			debugf = func(f string, a ...interface{}) {}
 		}
 
-		rp, _ := rl.read()
-		if rp == nil {
-			return false
-		}
-		debugf("Has runlock file at (%s), PID (%d)", rl.file(), rp.ButlerPID)
-		proc, _ := os.FindProcess(int(rp.ButlerPID))
-		if proc != nil {
-			debugf("Got a process handle, %#v", proc)
-
-			if runtime.GOOS == "windows" {
-				debugf("...on Windows, that means the process is still running")
-			} else {
-				debugf("...trying to poke it with a 0 signal")
-				err := proc.Signal(syscall.Signal(0))
-				if err != nil {
-					debugf("Got error while signalling PID (%d), assuming dead: %#v", rp.ButlerPID, err)
-
-					// not running anymore
-					rl.Unlock()
-					return false
-				}
-			}
-
-			debugf("PID (%d) still running!", rp.ButlerPID)
-			proc.Release()
-		} else {
-			debugf("Didn't get a process handle, assuming dead")
-
-			// not running anymore
-			rl.Unlock()
+		if !rl.held(debugf) {
 			return false
 		}
 
@@ -104,6 +88,55 @@ func (rl *lock) Lock(ctx context.Context, task string) error {
 		}
 	}
 
+	return rl.lock(task)
+}
+
+func (rl *lock) TryLock(task string) (bool, error) {
+	if rl.held(rl.consumer.Debugf) {
+		return false, nil
+	}
+	return true, rl.lock(task)
+}
+
+// held returns true if the lock file names a process that's still
+// running, and removes it otherwise.
+func (rl *lock) held(debugf func(f string, a ...interface{})) bool {
+	rp, _ := rl.read()
+	if rp == nil {
+		return false
+	}
+	debugf("Has runlock file at (%s), PID (%d)", rl.file(), rp.ButlerPID)
+	proc, _ := os.FindProcess(int(rp.ButlerPID))
+	if proc != nil {
+		debugf("Got a process handle, %#v", proc)
+
+		if runtime.GOOS == "windows" {
+			debugf("...on Windows, that means the process is still running")
+		} else {
+			debugf("...trying to poke it with a 0 signal")
+			err := proc.Signal(syscall.Signal(0))
+			if err != nil {
+				debugf("Got error while signalling PID (%d), assuming dead: %#v", rp.ButlerPID, err)
+
+				// not running anymore
+				rl.Unlock()
+				return false
+			}
+		}
+
+		debugf("PID (%d) still running!", rp.ButlerPID)
+		proc.Release()
+	} else {
+		debugf("Didn't get a process handle, assuming dead")
+
+		// not running anymore
+		rl.Unlock()
+		return false
+	}
+	return true
+}
+
+func (rl *lock) lock(task string) error {
 	rl.consumer.Debugf("Locking (%s) for %s", rl.file(), task)
 	return rl.write(&runlockPayload{
 		Task:      task,
@@ -117,7 +150,7 @@ func (rl *lock) Unlock() error {
 }
 
 func (rl *lock) file() string {
-	return filepath.Join(rl.installFolder, ".itch", "runlock.json")
+	return rl.path
 }
 
 func (rl *lock) write(rp *runlockPayload) error {
@@ -3,6 +3,8 @@ package runlock_test
 import (
 	"context"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -64,3 +66,39 @@ func Test_Runlock(t *testing.T) {
 		"r2-lock",
 	}, steps)
 }
+
+func Test_TryLock(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "runlock-test-db")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	consumer := &state.Consumer{
+		OnMessage: func(lvl string, msg string) { t.Logf("[%s] %s", lvl, msg) },
+	}
+	dbPath := filepath.Join(dir, "butler.db")
+
+	rl1 := runlock.NewForDatabase(consumer, dbPath)
+	locked, err := rl1.TryLock("rl1")
+	wtest.Must(t, err)
+	assert.True(locked)
+
+	// held by a running process: no waiting
+	rl2 := runlock.NewForDatabase(consumer, dbPath)
+	locked, err = rl2.TryLock("rl2")
+	wtest.Must(t, err)
+	assert.False(locked)
+
+	wtest.Must(t, rl1.Unlock())
+	locked, err = rl2.TryLock("rl2")
+	wtest.Must(t, err)
+	assert.True(locked)
+	wtest.Must(t, rl2.Unlock())
+
+	// held by a process that's gone
+	wtest.Must(t, ioutil.WriteFile(dbPath+".runlock.json", []byte(`{"task":"gone","butlerPID":999999999}`), 0o644))
+	locked, err = rl1.TryLock("rl1")
+	wtest.Must(t, err)
+	assert.True(locked)
+}
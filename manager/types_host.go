@@ -14,6 +14,9 @@ type Host struct {
 	Wrapper *Wrapper `json:"wrapper,omitempty"`
 
 	RemoteLaunchName string `json:"remoteLaunchName,omitempty"`
+
+	// set if Wrapper comes from a user-defined wrapper profile
+	WrapperProfileID string `json:"wrapperProfileId,omitempty"`
 }
 
 type Wrapper struct {
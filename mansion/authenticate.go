@@ -15,6 +15,7 @@ import (
 
 	"github.com/itchio/butler/art"
 	"github.com/itchio/butler/comm"
+	"github.com/itchio/butler/secrets"
 	"github.com/itchio/go-itchio"
 	"github.com/pkg/errors"
 )
@@ -93,15 +94,26 @@ const (
 
 var callbackRe = regexp.MustCompile(`^\/oauth\/callback\/(.*)$`)
 
-const environmentApiKeyVariable = "BUTLER_API_KEY"
+// APIKeyEnvironmentVariable has priority over any saved credentials,
+// so that CI secrets never have to touch the disk
+const APIKeyEnvironmentVariable = "BUTLER_API_KEY"
 
 func (ctx *Context) HasSavedCredentials() bool {
 	// environment has priority
-	if os.Getenv(environmentApiKeyVariable) != "" {
+	if os.Getenv(APIKeyEnvironmentVariable) != "" {
 		return true
 	}
 
-	// then file at usual or specified path
+	if ctx.identityErr != nil {
+		return false
+	}
+	return ctx.HasStoredCredentials()
+}
+
+// HasStoredCredentials returns true if there's an API key for
+// ctx.Identity in the identity file, or in the secret store it points to.
+func (ctx *Context) HasStoredCredentials() bool {
+	// file at usual or specified path
 	var identity = ctx.Identity
 	_, err := os.Lstat(identity)
 
@@ -109,6 +121,10 @@ func (ctx *Context) HasSavedCredentials() bool {
 	return exists
 }
 
+// secretStoreMarker starts identity files whose API key was moved to a
+// secret store, followed by the name of that store's backend.
+const secretStoreMarker = "secret-store:"
+
 func readKeyFile(path string) (string, error) {
 	stats, err := os.Lstat(path)
 
@@ -144,12 +160,137 @@ func writeKeyFile(path string, key string) error {
 	return ioutil.WriteFile(path, []byte(key), os.FileMode(keyFileMode))
 }
 
+// storedKeyBackend returns the secret store backend the identity file
+// says the API key is kept in, or an empty string if it holds the key itself.
+func storedKeyBackend(contents string) string {
+	if strings.HasPrefix(contents, secretStoreMarker) {
+		return strings.TrimPrefix(contents, secretStoreMarker)
+	}
+	return ""
+}
+
+// identityStoreFor returns the secret store the API key for ctx.Identity
+// was moved to, or an error if it isn't the one in use.
+func (ctx *Context) identityStoreFor(backend string) (secrets.Store, error) {
+	store := ctx.identitySecrets()
+	if store == nil {
+		return nil, errors.Errorf("API key for %s is kept in %s, which isn't available (see --secret-store)", ctx.Identity, backend)
+	}
+	if store.Name() != backend {
+		return nil, errors.Errorf("API key for %s is kept in %s, but %s is in use (see --secret-store)", ctx.Identity, backend, store.Name())
+	}
+	return store, nil
+}
+
+// readSavedKey returns the API key for ctx.Identity, moving it from the
+// identity file to the secret store if there is one.
+func (ctx *Context) readSavedKey() (string, error) {
+	if ctx.identityErr != nil {
+		return "", ctx.identityErr
+	}
+	var identity = ctx.Identity
+
+	contents, err := readKeyFile(identity)
+	if err != nil || contents == "" {
+		return "", err
+	}
+
+	if backend := storedKeyBackend(contents); backend != "" {
+		store, err := ctx.identityStoreFor(backend)
+		if err != nil {
+			return "", err
+		}
+		key, err := store.Get(ctx.identitySecretKey())
+		if err != nil {
+			if err == secrets.ErrNotFound {
+				return "", errors.Errorf("API key for %s is missing from %s, run `butler logout` and log in again", identity, store.Name())
+			}
+			return "", errors.WithMessagef(err, "reading API key from %s", store.Name())
+		}
+		return key, nil
+	}
+
+	key := contents
+	store := ctx.identitySecrets()
+	if store == nil {
+		return key, nil
+	}
+
+	err = store.Set(ctx.identitySecretKey(), key)
+	if err != nil {
+		comm.Warnf("Could not move API key to %s, keeping it in %s: %v", store.Name(), identity, err)
+		return key, nil
+	}
+	err = writeKeyFile(identity, secretStoreMarker+store.Name())
+	if err != nil {
+		comm.Warnf("Moved API key to %s, but could not update %s: %v", store.Name(), identity, err)
+		return key, nil
+	}
+	comm.Logf("Moved API key from %s to %s", identity, store.Name())
+	return key, nil
+}
+
+// saveKey stores the API key for ctx.Identity in the secret store,
+// or in the identity file if there's none or it failed. When it's in
+// the secret store, the identity file says which one.
+func (ctx *Context) saveKey(key string) error {
+	if ctx.identityErr != nil {
+		return ctx.identityErr
+	}
+	var identity = ctx.Identity
+
+	err := os.MkdirAll(filepath.Dir(identity), os.FileMode(0o755))
+	if err != nil {
+		return errors.Wrap(err, "creating directory for storing API key")
+	}
+
+	if store := ctx.identitySecrets(); store != nil {
+		comm.Logf("\nAuthenticated successfully! Saving key in %s...\n", store.Name())
+		err := store.Set(ctx.identitySecretKey(), key)
+		if err == nil {
+			return writeKeyFile(identity, secretStoreMarker+store.Name())
+		}
+		comm.Logf("\nCould not save API key in %s, saving it in %s instead: %s\n", store.Name(), identity, err)
+	} else {
+		comm.Logf("\nAuthenticated successfully! Saving key in %s...\n", identity)
+	}
+	return writeKeyFile(identity, key)
+}
+
+// ForgetCredentials removes the API key for ctx.Identity, from the
+// secret store it was moved to if any, then the identity file.
+func (ctx *Context) ForgetCredentials() error {
+	if ctx.identityErr != nil {
+		return ctx.identityErr
+	}
+	contents, err := readKeyFile(ctx.Identity)
+	if err != nil {
+		return err
+	}
+
+	if backend := storedKeyBackend(contents); backend != "" {
+		store, err := ctx.identityStoreFor(backend)
+		if err != nil {
+			return err
+		}
+		err = store.Delete(ctx.identitySecretKey())
+		if err != nil {
+			return errors.WithMessagef(err, "deleting API key from %s", store.Name())
+		}
+	}
+
+	err = os.Remove(ctx.Identity)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "deleting identity file")
+	}
+	return nil
+}
+
 func (ctx *Context) AuthenticateViaOauth() (*itchio.Client, error) {
 	var err error
-	var identity = ctx.Identity
 	var key string
 
-	envKey := os.Getenv(environmentApiKeyVariable)
+	envKey := os.Getenv(APIKeyEnvironmentVariable)
 	if envKey != "" {
 		return ctx.NewClient(envKey), nil
 	}
@@ -157,18 +298,18 @@ func (ctx *Context) AuthenticateViaOauth() (*itchio.Client, error) {
 	if os.Getenv("CI") != "" {
 		comm.Logf(" ~~~ ")
 		comm.Logf("It looks like you're running butler on a CI server.")
-		comm.Logf("It's strongly recommended to pass credentials via the %s environment variable.", environmentApiKeyVariable)
+		comm.Logf("It's strongly recommended to pass credentials via the %s environment variable.", APIKeyEnvironmentVariable)
 		comm.Logf("See https://itch.io/docs/butler/login.html for more info.")
 		comm.Logf(" ~~~ ")
 	}
-	key, err = readKeyFile(identity)
+	key, err = ctx.readSavedKey()
 	if err != nil {
-		return nil, errors.Wrap(err, "reading key file")
+		return nil, errors.WithMessage(err, "reading saved credentials")
 	}
 
 	if key == "" {
 		if !IsTerminal() {
-			comm.Logf("Please set %s to your API key, see https://itch.io/docs/butler/login.html for more info.", environmentApiKeyVariable)
+			comm.Logf("Please set %s to your API key, see https://itch.io/docs/butler/login.html for more info.", APIKeyEnvironmentVariable)
 			comm.Dief("No credentials and stdin is not a terminal - terminating.")
 		}
 
@@ -256,18 +397,10 @@ func (ctx *Context) AuthenticateViaOauth() (*itchio.Client, error) {
 				return nil, errors.Wrap(err, "retrieving wharf status")
 			}
 
-			comm.Logf("\nAuthenticated successfully! Saving key in %s...\n", identity)
-
-			err = os.MkdirAll(filepath.Dir(identity), os.FileMode(0o755))
+			err = ctx.saveKey(key)
 			if err != nil {
-				comm.Logf("\nCould not create directory for storing API key: %s\n\n", err)
+				comm.Logf("\nCould not save API key: %s\n\n", err)
 				err = nil
-			} else {
-				err = writeKeyFile(identity, key)
-				if err != nil {
-					comm.Logf("\nCould not save API key: %s\n\n", err)
-					err = nil
-				}
 			}
 		}
 	}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/itchio/butler/buildinfo"
 	"github.com/itchio/butler/comm"
+	"github.com/itchio/butler/secrets"
 	itchio "github.com/itchio/go-itchio"
 	"github.com/itchio/httpkit/timeout"
 	"github.com/itchio/wharf/pwr"
@@ -22,6 +23,16 @@ type Context struct {
 
 	// Identity is the path to the credentials file
 	Identity string
+	// IdentityName is the name of the identity in use, see ResolveIdentity.
+	// It's empty when a credentials path was given explicitly.
+	IdentityName string
+	// IdentitySource says why that identity is in use
+	IdentitySource string
+	// identityErr is why the identity couldn't be resolved, see IdentityError
+	identityErr error
+	// DefaultIdentity is the path to the default credentials file,
+	// named identities are kept next to it
+	DefaultIdentity string
 
 	// String to include in our user-agent
 	UserAgentAddition string
@@ -38,6 +49,9 @@ type Context struct {
 	// Path to the local sqlite database
 	DBPath string
 
+	// Secret store backend API keys are kept in, see secrets.Backends
+	SecretStore string
+
 	CompressionAlgorithm string
 	CompressionQuality   int
 
@@ -48,6 +62,9 @@ type Context struct {
 	apiAddress string
 	// url of the itch.io web instance we're talking to
 	webAddress string
+
+	identityStore         secrets.Store
+	identitySecretsOpened bool
 }
 
 func NewContext(app *kingpin.Application) *Context {
@@ -0,0 +1,222 @@
+package mansion
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/itchio/butler/comm"
+	"github.com/pkg/errors"
+)
+
+// DefaultIdentityName is what the identity at DefaultIdentity is called
+const DefaultIdentityName = "default"
+
+// identityEnvironmentVariable picks a named identity, for CI setups
+// that keep several logins around
+const identityEnvironmentVariable = "BUTLER_IDENTITY"
+
+// ProjectConfigFileName is looked up in the working directory and its
+// parents, to pin settings for a project
+const ProjectConfigFileName = ".butler.json"
+
+// ProjectConfig is the contents of ProjectConfigFileName
+type ProjectConfig struct {
+	// Name of the identity to use, see `butler identity`
+	Identity string `json:"identity"`
+}
+
+var identityNameRe = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9_.-]*$`)
+
+// configDir is where butler's credentials & identities live
+func (ctx *Context) configDir() string {
+	return filepath.Dir(ctx.DefaultIdentity)
+}
+
+func (ctx *Context) identitiesDir() string {
+	return filepath.Join(ctx.configDir(), "identities")
+}
+
+func (ctx *Context) currentIdentityFile() string {
+	return filepath.Join(ctx.configDir(), "butler_identity")
+}
+
+// IdentityPath returns where the credentials of a named identity are kept
+func (ctx *Context) IdentityPath(name string) (string, error) {
+	if name == DefaultIdentityName {
+		return ctx.DefaultIdentity, nil
+	}
+	if !identityNameRe.MatchString(name) {
+		return "", errors.Errorf("Invalid identity name (%s): only letters, digits, '.', '-' and '_' are allowed", name)
+	}
+	// named identities get a folder, which stays around when
+	// their key is moved to the secret store
+	return filepath.Join(ctx.identitiesDir(), name, "butler_creds"), nil
+}
+
+// ListIdentities returns the names of all identities, including the default one
+func (ctx *Context) ListIdentities() ([]string, error) {
+	names := []string{DefaultIdentityName}
+
+	entries, err := ioutil.ReadDir(ctx.identitiesDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return names, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	var named []string
+	for _, entry := range entries {
+		if entry.IsDir() && identityNameRe.MatchString(entry.Name()) && entry.Name() != DefaultIdentityName {
+			named = append(named, entry.Name())
+		}
+	}
+	sort.Strings(named)
+	return append(names, named...), nil
+}
+
+// CurrentIdentity returns the identity selected with `butler identity use`
+func (ctx *Context) CurrentIdentity() string {
+	buf, err := ioutil.ReadFile(ctx.currentIdentityFile())
+	if err != nil {
+		return DefaultIdentityName
+	}
+	name := strings.TrimSpace(string(buf))
+	if name == "" {
+		return DefaultIdentityName
+	}
+	return name
+}
+
+// SetCurrentIdentity changes the identity used when none is specified
+func (ctx *Context) SetCurrentIdentity(name string) error {
+	if name == DefaultIdentityName {
+		err := os.Remove(ctx.currentIdentityFile())
+		if err != nil && !os.IsNotExist(err) {
+			return errors.WithStack(err)
+		}
+		return nil
+	}
+
+	err := os.MkdirAll(ctx.configDir(), 0o755)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(ioutil.WriteFile(ctx.currentIdentityFile(), []byte(name+"\n"), 0o644))
+}
+
+// FindProjectConfig looks for ProjectConfigFileName in dir and its
+// parents. It returns a nil config if there is none.
+func FindProjectConfig(dir string) (*ProjectConfig, string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	for {
+		path := filepath.Join(dir, ProjectConfigFileName)
+		payload, err := ioutil.ReadFile(path)
+		if err == nil {
+			var config ProjectConfig
+			err = json.Unmarshal(payload, &config)
+			if err != nil {
+				return nil, "", errors.WithMessagef(err, "parsing (%s)", path)
+			}
+			return &config, path, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, "", errors.WithStack(err)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, "", nil
+		}
+		dir = parent
+	}
+}
+
+// ResolveIdentity sets Identity, IdentityName and IdentitySource. In order of priority:
+// an explicit credentials path (--identity), an identity name (--as),
+// the BUTLER_IDENTITY environment variable, the project config, the
+// identity picked with `butler identity use`, and the default one.
+//
+// None of this matters when BUTLER_API_KEY is set, it always wins.
+//
+// If the identity can't be resolved, the error is also kept for
+// IdentityError, so that only commands that need credentials fail.
+func (ctx *Context) ResolveIdentity(path string, name string) error {
+	ctx.identityErr = ctx.resolveIdentity(path, name)
+	return ctx.identityErr
+}
+
+// IdentityError returns why ResolveIdentity failed, if it did. Commands
+// that read or save credentials must not go on without an identity.
+func (ctx *Context) IdentityError() error {
+	return ctx.identityErr
+}
+
+func (ctx *Context) resolveIdentity(path string, name string) error {
+	if path != "" {
+		ctx.Identity = path
+		ctx.IdentityName = ""
+		ctx.IdentitySource = "--identity"
+		return nil
+	}
+
+	source := "--as"
+	if name == "" {
+		name = os.Getenv(identityEnvironmentVariable)
+		source = identityEnvironmentVariable
+	}
+	if name == "" {
+		wd, err := os.Getwd()
+		if err == nil {
+			config, configPath, err := FindProjectConfig(wd)
+			if err != nil {
+				return errors.WithMessage(err, "reading project config")
+			}
+			if config != nil && config.Identity != "" {
+				name = config.Identity
+				source = configPath
+			}
+		}
+	}
+	if name == "" {
+		name = ctx.CurrentIdentity()
+		source = "butler identity use"
+		if name == DefaultIdentityName {
+			source = "default"
+		}
+	}
+
+	identityPath, err := ctx.IdentityPath(name)
+	if err != nil {
+		return errors.WithMessagef(err, "from %s", source)
+	}
+	comm.Debugf("Using identity %s (from %s)", name, source)
+
+	ctx.Identity = identityPath
+	ctx.IdentityName = name
+	ctx.IdentitySource = source
+	return nil
+}
+
+// WithIdentity returns a copy of ctx that uses the named identity
+func (ctx *Context) WithIdentity(name string) (*Context, error) {
+	path, err := ctx.IdentityPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := *ctx
+	sub.Identity = path
+	sub.IdentityName = name
+	sub.identityErr = nil
+	return &sub, nil
+}
@@ -0,0 +1,227 @@
+package mansion
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/butler/secrets"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+// setEnv sets an environment variable for the duration of a test
+func setEnv(t *testing.T, name string, value string) {
+	oldValue, hadValue := os.LookupEnv(name)
+	if value == "" {
+		os.Unsetenv(name)
+	} else {
+		os.Setenv(name, value)
+	}
+	t.Cleanup(func() {
+		if hadValue {
+			os.Setenv(name, oldValue)
+		} else {
+			os.Unsetenv(name)
+		}
+	})
+}
+
+// chdir changes the working directory for the duration of a test
+func chdir(t *testing.T, dir string) {
+	wd, err := os.Getwd()
+	wtest.Must(t, err)
+	wtest.Must(t, os.Chdir(dir))
+	t.Cleanup(func() {
+		os.Chdir(wd)
+	})
+}
+
+func writeProjectConfig(t *testing.T, dir string, contents string) {
+	wtest.Must(t, ioutil.WriteFile(filepath.Join(dir, ProjectConfigFileName), []byte(contents), 0o644))
+}
+
+func Test_IdentityPath(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := &Context{DefaultIdentity: filepath.Join("config", "butler_creds")}
+
+	path, err := ctx.IdentityPath(DefaultIdentityName)
+	wtest.Must(t, err)
+	assert.EqualValues(ctx.DefaultIdentity, path)
+
+	for _, name := range []string{"work", "client-a", "client_b.2", "-x"} {
+		path, err := ctx.IdentityPath(name)
+		wtest.Must(t, err)
+		assert.EqualValues(filepath.Join("config", "identities", name, "butler_creds"), path)
+	}
+
+	for _, name := range []string{"", ".", "..", "../work", "a/b", `a\b`, ".hidden", "with space"} {
+		_, err := ctx.IdentityPath(name)
+		if assert.Error(err, "name (%s)", name) {
+			assert.Contains(err.Error(), "Invalid identity name", "name (%s)", name)
+		}
+	}
+}
+
+func Test_FindProjectConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "project-config")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	nested := filepath.Join(dir, "project", "src", "deep")
+	wtest.Must(t, os.MkdirAll(nested, 0o755))
+
+	config, _, err := FindProjectConfig(nested)
+	wtest.Must(t, err)
+	assert.Nil(config)
+
+	// found in parents...
+	writeProjectConfig(t, dir, `{"identity": "outer"}`)
+	config, path, err := FindProjectConfig(nested)
+	wtest.Must(t, err)
+	if assert.NotNil(config) {
+		assert.EqualValues("outer", config.Identity)
+		assert.EqualValues(filepath.Join(dir, ProjectConfigFileName), path)
+	}
+
+	// ...but the closest one wins
+	writeProjectConfig(t, filepath.Join(dir, "project"), `{"identity": "inner"}`)
+	config, path, err = FindProjectConfig(nested)
+	wtest.Must(t, err)
+	if assert.NotNil(config) {
+		assert.EqualValues("inner", config.Identity)
+		assert.EqualValues(filepath.Join(dir, "project", ProjectConfigFileName), path)
+	}
+
+	writeProjectConfig(t, filepath.Join(dir, "project", "src"), `{"identity": `)
+	_, _, err = FindProjectConfig(nested)
+	if assert.Error(err) {
+		assert.Contains(err.Error(), filepath.Join(dir, "project", "src", ProjectConfigFileName))
+	}
+}
+
+func Test_ResolveIdentity(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "resolve-identity")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	configDir := filepath.Join(dir, "config")
+	projectDir := filepath.Join(dir, "project")
+	wtest.Must(t, os.MkdirAll(projectDir, 0o755))
+	chdir(t, projectDir)
+	setEnv(t, identityEnvironmentVariable, "")
+	setEnv(t, APIKeyEnvironmentVariable, "")
+
+	// no secret store, to stay out of the real keyring
+	ctx := &Context{
+		DefaultIdentity: filepath.Join(configDir, "butler_creds"),
+		SecretStore:     secrets.BackendNone,
+	}
+	identityPath := func(name string) string {
+		path, err := ctx.IdentityPath(name)
+		wtest.Must(t, err)
+		return path
+	}
+	resolve := func(path string, name string) (string, string) {
+		t.Helper()
+		wtest.Must(t, ctx.ResolveIdentity(path, name))
+		return ctx.IdentityName, ctx.IdentitySource
+	}
+
+	name, source := resolve("", "")
+	assert.EqualValues(DefaultIdentityName, name)
+	assert.EqualValues("default", source)
+	assert.EqualValues(ctx.DefaultIdentity, ctx.Identity)
+
+	wtest.Must(t, ctx.SetCurrentIdentity("used"))
+	name, source = resolve("", "")
+	assert.EqualValues("used", name)
+	assert.EqualValues("butler identity use", source)
+	assert.EqualValues(identityPath("used"), ctx.Identity)
+
+	writeProjectConfig(t, projectDir, `{"identity": "project"}`)
+	name, source = resolve("", "")
+	assert.EqualValues("project", name)
+	assert.EqualValues(filepath.Join(projectDir, ProjectConfigFileName), source)
+
+	setEnv(t, identityEnvironmentVariable, "env")
+	name, source = resolve("", "")
+	assert.EqualValues("env", name)
+	assert.EqualValues(identityEnvironmentVariable, source)
+
+	name, source = resolve("", "flag")
+	assert.EqualValues("flag", name)
+	assert.EqualValues("--as", source)
+	assert.EqualValues(identityPath("flag"), ctx.Identity)
+
+	explicit := filepath.Join(dir, "elsewhere", "creds")
+	name, source = resolve(explicit, "flag")
+	assert.EqualValues("", name)
+	assert.EqualValues("--identity", source)
+	assert.EqualValues(explicit, ctx.Identity)
+
+	// BUTLER_API_KEY wins over all of them
+	wtest.Must(t, ctx.saveKey("saved-key"))
+	setEnv(t, APIKeyEnvironmentVariable, "env-key")
+	client, err := ctx.AuthenticateViaOauth()
+	wtest.Must(t, err)
+	assert.EqualValues("env-key", client.Key)
+}
+
+func Test_ResolveIdentityErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "resolve-identity")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	chdir(t, dir)
+	setEnv(t, identityEnvironmentVariable, "")
+	setEnv(t, APIKeyEnvironmentVariable, "")
+
+	ctx := &Context{
+		DefaultIdentity: filepath.Join(dir, "config", "butler_creds"),
+		SecretStore:     secrets.BackendNone,
+	}
+
+	err = ctx.ResolveIdentity("", "../escape")
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "from --as")
+	}
+
+	// a broken project config only matters to commands that need credentials
+	writeProjectConfig(t, dir, `{"identity": `)
+	err = ctx.ResolveIdentity("", "")
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "reading project config")
+	}
+	assert.Equal(err, ctx.IdentityError())
+	assert.False(ctx.HasSavedCredentials())
+	_, err = ctx.readSavedKey()
+	assert.Error(err)
+	assert.Error(ctx.saveKey("some-key"))
+	assert.Error(ctx.ForgetCredentials())
+
+	// picking an identity explicitly gets around it
+	sub, err := ctx.WithIdentity("work")
+	wtest.Must(t, err)
+	wtest.Must(t, sub.IdentityError())
+
+	setEnv(t, APIKeyEnvironmentVariable, "env-key")
+	assert.True(ctx.HasSavedCredentials())
+	client, err := ctx.AuthenticateViaOauth()
+	wtest.Must(t, err)
+	assert.EqualValues("env-key", client.Key)
+
+	// fixing it clears the error
+	writeProjectConfig(t, dir, `{"identity": "work"}`)
+	wtest.Must(t, ctx.ResolveIdentity("", ""))
+	wtest.Must(t, ctx.IdentityError())
+	assert.EqualValues("work", ctx.IdentityName)
+}
@@ -0,0 +1,83 @@
+package mansion
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/itchio/butler/comm"
+	"github.com/itchio/butler/secrets"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+func (ctx *Context) secretSettings(filePath string) secrets.Settings {
+	return secrets.Settings{
+		Backend:  ctx.SecretStore,
+		FilePath: filePath,
+		Passphrase: func() (string, error) {
+			if passphrase := os.Getenv(secrets.PassphraseEnvironmentVariable); passphrase != "" {
+				return passphrase, nil
+			}
+			// only ask when the encrypted file was explicitly chosen,
+			// otherwise we'd prompt everyone without a keyring.
+			if ctx.SecretStore != secrets.BackendFile || !IsTerminal() || comm.JsonEnabled() {
+				return "", nil
+			}
+
+			fmt.Fprintf(os.Stderr, "Passphrase for %s: ", filePath)
+			passphrase, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+			fmt.Fprintln(os.Stderr)
+			if err != nil {
+				return "", err
+			}
+			return strings.TrimSpace(string(passphrase)), nil
+		},
+	}
+}
+
+// EnableProfileSecrets sets up the secret store that profile API keys
+// are kept in, with the encrypted file fallback next to the database.
+func (ctx *Context) EnableProfileSecrets() error {
+	filePath := filepath.Join(filepath.Dir(ctx.DBPath), "secrets.json")
+	store, err := secrets.Enable(ctx.secretSettings(filePath))
+	if err != nil {
+		return err
+	}
+	if store != nil {
+		comm.Debugf("Keeping profile API keys in %s", store.Name())
+	}
+	return nil
+}
+
+// identitySecrets returns the secret store the CLI's API key is kept
+// in, or nil if there's none and it should be kept in the identity
+// file, like it used to be.
+func (ctx *Context) identitySecrets() secrets.Store {
+	if !ctx.identitySecretsOpened {
+		ctx.identitySecretsOpened = true
+
+		dir := ctx.configDir()
+		if ctx.DefaultIdentity == "" {
+			dir = filepath.Dir(ctx.Identity)
+		}
+		filePath := filepath.Join(dir, "butler_secrets.json")
+		store, err := secrets.Open(ctx.secretSettings(filePath))
+		if err != nil {
+			comm.Warnf("Could not open secret store, using plaintext credentials: %v", err)
+			store = nil
+		}
+		ctx.identityStore = store
+	}
+	return ctx.identityStore
+}
+
+// identitySecretKey is what the API key for ctx.Identity is stored as,
+// so that several identities (see --identity) can coexist.
+func (ctx *Context) identitySecretKey() string {
+	identity := ctx.Identity
+	if abs, err := filepath.Abs(identity); err == nil {
+		identity = abs
+	}
+	return "cli:" + identity
+}
@@ -0,0 +1,94 @@
+package mansion
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/butler/secrets"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_IdentityInSecretStore(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "identity-secrets")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	oldPassphrase, hadPassphrase := os.LookupEnv(secrets.PassphraseEnvironmentVariable)
+	os.Setenv(secrets.PassphraseEnvironmentVariable, "correct horse")
+	defer func() {
+		if hadPassphrase {
+			os.Setenv(secrets.PassphraseEnvironmentVariable, oldPassphrase)
+		} else {
+			os.Unsetenv(secrets.PassphraseEnvironmentVariable)
+		}
+	}()
+
+	identity := filepath.Join(dir, "butler_creds")
+	newContext := func(backend string) *Context {
+		return &Context{
+			Identity:        identity,
+			DefaultIdentity: identity,
+			SecretStore:     backend,
+		}
+	}
+	fileContents := func() string {
+		payload, err := ioutil.ReadFile(identity)
+		wtest.Must(t, err)
+		return string(payload)
+	}
+
+	// plaintext keys are moved to the secret store, and the
+	// identity file remembers which one
+	wtest.Must(t, writeKeyFile(identity, "first-key"))
+	key, err := newContext(secrets.BackendFile).readSavedKey()
+	wtest.Must(t, err)
+	assert.EqualValues("first-key", key)
+	assert.EqualValues("secret-store:file", fileContents())
+
+	// without that store, it's an error rather than no credentials at all
+	ctx := newContext(secrets.BackendNone)
+	assert.True(ctx.HasStoredCredentials())
+	_, err = ctx.readSavedKey()
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "kept in file, which isn't available")
+	}
+	assert.Error(ctx.ForgetCredentials())
+	assert.EqualValues("secret-store:file", fileContents())
+
+	ctx = newContext(secrets.BackendFile)
+	key, err = ctx.readSavedKey()
+	wtest.Must(t, err)
+	assert.EqualValues("first-key", key)
+
+	wtest.Must(t, ctx.saveKey("second-key"))
+	assert.EqualValues("secret-store:file", fileContents())
+	key, err = newContext(secrets.BackendFile).readSavedKey()
+	wtest.Must(t, err)
+	assert.EqualValues("second-key", key)
+
+	wtest.Must(t, ctx.identitySecrets().Delete(ctx.identitySecretKey()))
+	_, err = ctx.readSavedKey()
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "missing from file")
+	}
+
+	// forgetting removes the key from the store, then the identity file
+	wtest.Must(t, ctx.saveKey("third-key"))
+	wtest.Must(t, ctx.ForgetCredentials())
+	assert.False(ctx.HasStoredCredentials())
+	_, err = ctx.identitySecrets().Get(ctx.identitySecretKey())
+	assert.Equal(secrets.ErrNotFound, err)
+
+	// without a secret store, keys stay in the identity file
+	ctx = newContext(secrets.BackendNone)
+	wtest.Must(t, ctx.saveKey("fourth-key"))
+	assert.EqualValues("fourth-key", fileContents())
+	key, err = ctx.readSavedKey()
+	wtest.Must(t, err)
+	assert.EqualValues("fourth-key", key)
+}
@@ -1,11 +1,11 @@
-package native
+package redist
 
 // These DLLs are known to ship in "all" versions of
 // Windows without needing any particular component installed
 // cf. https://msdn.microsoft.com/en-us/library/ee391643(v=vs.85).aspx
 // (by the MSDN law, this link will be dead by the time you next need it.)
 // (so, the article name is "DLLs Included with Server Core")
-var knownBuiltinDLLs = map[string]string{
+var KnownBuiltinDLLs = map[string]string{
 	// Windows System Services
 	"clfsw32.dll":  "Log file management",
 	"dbghelp.dll":  "Debugging helper",
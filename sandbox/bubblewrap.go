@@ -0,0 +1,202 @@
+package sandbox
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/pkg/errors"
+)
+
+// System folders games get read-only access to, when they exist
+var bubblewrapSystemDirs = []string{
+	"/usr",
+	"/bin",
+	"/sbin",
+	"/lib",
+	"/lib32",
+	"/lib64",
+	"/etc",
+	"/opt",
+	"/sys",
+	"/run/current-system",
+}
+
+// DetectBubblewrap looks for a bubblewrap binary and makes sure it
+// can actually create a sandbox on this system: unprivileged user
+// namespaces are disabled on some distributions.
+func DetectBubblewrap(ctx context.Context) *butlerd.SandboxCapabilities {
+	caps := &butlerd.SandboxCapabilities{}
+
+	bwrapPath, err := exec.LookPath("bwrap")
+	if err != nil {
+		caps.Error = "bubblewrap (bwrap) is not installed"
+		return caps
+	}
+	caps.BubblewrapPath = bwrapPath
+
+	truePath, err := exec.LookPath("true")
+	if err != nil {
+		caps.Error = "could not find true(1) to test bubblewrap with"
+		return caps
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, bwrapPath,
+		"--ro-bind", "/", "/",
+		"--unshare-all",
+		"--die-with-parent",
+		"--", truePath,
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		caps.Error = strings.TrimSpace(string(out))
+		if caps.Error == "" {
+			caps.Error = err.Error()
+		}
+		return caps
+	}
+
+	caps.UserNamespaces = true
+	return caps
+}
+
+// Usable returns true if bubblewrap can be used to launch games
+func Usable(caps *butlerd.SandboxCapabilities) bool {
+	return caps.BubblewrapPath != "" && caps.UserNamespaces
+}
+
+// BubblewrapParams describes a single sandboxed launch
+type BubblewrapParams struct {
+	Policy *butlerd.SandboxPolicy
+
+	InstallFolder string
+	// Writable folder for temporary files
+	TempDir string
+	// Writable folder used as the game's home
+	HomeDir string
+	// Working directory, inside the sandbox
+	Dir string
+
+	// What to run, and with which arguments
+	FullTargetPath string
+	Args           []string
+}
+
+// homesDir is where sandbox homes are kept, see SetHomesDir
+var homesDir string
+
+// SetHomesDir sets the folder sandbox homes are created in. It's
+// outside of install folders, so that saves survive reinstalls.
+func SetHomesDir(dir string) {
+	homesDir = dir
+}
+
+var caveIDRe = regexp.MustCompile(`^[0-9A-Za-z][0-9A-Za-z_-]{0,63}$`)
+
+// HomeDir returns the folder a sandboxed game gets as its home:
+// one per cave, kept across launches (and reinstalls) so saves persist.
+func HomeDir(caveID string) (string, error) {
+	if homesDir == "" {
+		return "", errors.New("sandbox homes folder is not set")
+	}
+	if !caveIDRe.MatchString(caveID) {
+		return "", errors.Errorf("invalid cave ID (%s)", caveID)
+	}
+	return filepath.Join(homesDir, caveID), nil
+}
+
+// HomeEnv returns the environment variables pointing a
+// sandboxed game to its home folder.
+func HomeEnv(homeDir string) map[string]string {
+	return map[string]string{
+		"HOME":            homeDir,
+		"XDG_CONFIG_HOME": filepath.Join(homeDir, ".config"),
+		"XDG_DATA_HOME":   filepath.Join(homeDir, ".local", "share"),
+		"XDG_CACHE_HOME":  filepath.Join(homeDir, ".cache"),
+	}
+}
+
+// BubblewrapArgs returns the arguments to pass to bwrap to run
+// the target according to the policy. The install folder is
+// read-only, except for the temp and home folders.
+func BubblewrapArgs(params BubblewrapParams) ([]string, error) {
+	policy := params.Policy
+	if policy == nil {
+		policy = &butlerd.SandboxPolicy{}
+	}
+
+	for _, dir := range []string{params.HomeDir, params.TempDir} {
+		err := os.MkdirAll(dir, 0o755)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	var args []string
+	add := func(a ...string) {
+		args = append(args, a...)
+	}
+
+	add("--unshare-all", "--die-with-parent")
+	if !policy.DenyNetwork {
+		add("--share-net")
+	}
+
+	for _, dir := range bubblewrapSystemDirs {
+		add("--ro-bind-try", dir, dir)
+	}
+	add("--proc", "/proc")
+	add("--dev", "/dev")
+	add("--tmpfs", "/tmp")
+
+	// games need a display to draw to, regardless of policy
+	add("--ro-bind-try", "/tmp/.X11-unix", "/tmp/.X11-unix")
+	if xauth := os.Getenv("XAUTHORITY"); xauth != "" {
+		add("--ro-bind-try", xauth, xauth)
+	}
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir != "" {
+		if wayland := os.Getenv("WAYLAND_DISPLAY"); wayland != "" {
+			p := filepath.Join(runtimeDir, wayland)
+			add("--ro-bind-try", p, p)
+		}
+	}
+
+	if policy.GPU {
+		add("--dev-bind-try", "/dev/dri", "/dev/dri")
+		nvidiaDevices, _ := filepath.Glob("/dev/nvidia*")
+		for _, dev := range nvidiaDevices {
+			add("--dev-bind-try", dev, dev)
+		}
+	}
+
+	if policy.Audio {
+		add("--dev-bind-try", "/dev/snd", "/dev/snd")
+		if runtimeDir != "" {
+			for _, name := range []string{"pulse", "pipewire-0"} {
+				p := filepath.Join(runtimeDir, name)
+				add("--ro-bind-try", p, p)
+			}
+		}
+	}
+
+	add("--ro-bind", params.InstallFolder, params.InstallFolder)
+	add("--bind", params.TempDir, params.TempDir)
+	add("--bind", params.HomeDir, params.HomeDir)
+
+	if params.Dir != "" {
+		add("--chdir", params.Dir)
+	}
+
+	add("--", params.FullTargetPath)
+	add(params.Args...)
+	return args, nil
+}
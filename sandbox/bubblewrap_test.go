@@ -0,0 +1,136 @@
+package sandbox
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/itchio/butler/butlerd"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+// hasArgs returns true if args contains seq, in order and contiguous
+func hasArgs(args []string, seq ...string) bool {
+	for i := 0; i+len(seq) <= len(args); i++ {
+		match := true
+		for j := range seq {
+			if args[i+j] != seq[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+func Test_BubblewrapArgs(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "bubblewrap-test")
+	wtest.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	runtimeDir := filepath.Join(dir, "run")
+	os.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	defer os.Unsetenv("XDG_RUNTIME_DIR")
+
+	params := BubblewrapParams{
+		InstallFolder:  filepath.Join(dir, "install"),
+		TempDir:        filepath.Join(dir, "install", ".itch", "temp"),
+		HomeDir:        filepath.Join(dir, "homes", "cave"),
+		Dir:            filepath.Join(dir, "install", "bin"),
+		FullTargetPath: filepath.Join(dir, "install", "bin", "game"),
+		Args:           []string{"--fullscreen", "--"},
+	}
+
+	argsFor := func(policy *butlerd.SandboxPolicy) []string {
+		p := params
+		p.Policy = policy
+		args, err := BubblewrapArgs(p)
+		wtest.Must(t, err)
+		return args
+	}
+
+	{
+		// no policy: network on, no devices
+		args := argsFor(nil)
+		assert.True(hasArgs(args, "--unshare-all", "--die-with-parent", "--share-net"))
+		assert.False(hasArgs(args, "--dev-bind-try", "/dev/dri", "/dev/dri"))
+		assert.False(hasArgs(args, "--dev-bind-try", "/dev/snd", "/dev/snd"))
+
+		// install folder read-only, temp & home writable
+		assert.True(hasArgs(args, "--ro-bind", params.InstallFolder, params.InstallFolder))
+		assert.True(hasArgs(args, "--bind", params.TempDir, params.TempDir))
+		assert.True(hasArgs(args, "--bind", params.HomeDir, params.HomeDir))
+		assert.True(hasArgs(args, "--chdir", params.Dir))
+		assert.True(hasArgs(args, "--ro-bind-try", "/usr", "/usr"))
+
+		// target and its arguments come last, after the separator
+		tail := []string{"--", params.FullTargetPath, "--fullscreen", "--"}
+		assert.EqualValues(tail, args[len(args)-len(tail):])
+
+		for _, d := range []string{params.TempDir, params.HomeDir} {
+			stats, err := os.Stat(d)
+			wtest.Must(t, err)
+			assert.True(stats.IsDir())
+		}
+	}
+
+	{
+		args := argsFor(&butlerd.SandboxPolicy{DenyNetwork: true})
+		assert.False(hasArgs(args, "--share-net"))
+		assert.True(hasArgs(args, "--unshare-all"))
+	}
+
+	{
+		args := argsFor(&butlerd.SandboxPolicy{GPU: true})
+		assert.True(hasArgs(args, "--dev-bind-try", "/dev/dri", "/dev/dri"))
+		assert.False(hasArgs(args, "--dev-bind-try", "/dev/snd", "/dev/snd"))
+	}
+
+	{
+		args := argsFor(&butlerd.SandboxPolicy{Audio: true})
+		assert.True(hasArgs(args, "--dev-bind-try", "/dev/snd", "/dev/snd"))
+		pulse := filepath.Join(runtimeDir, "pulse")
+		assert.True(hasArgs(args, "--ro-bind-try", pulse, pulse))
+		assert.False(hasArgs(args, "--dev-bind-try", "/dev/dri", "/dev/dri"))
+	}
+
+	{
+		p := params
+		p.Dir = ""
+		args, err := BubblewrapArgs(p)
+		wtest.Must(t, err)
+		assert.False(hasArgs(args, "--chdir"))
+	}
+}
+
+func Test_HomeDir(t *testing.T) {
+	assert := assert.New(t)
+
+	SetHomesDir("")
+	_, err := HomeDir("1b4e28ba-2fa1-11d2-883f-0016d3cca427")
+	assert.Error(err)
+
+	SetHomesDir("/data/sandbox-homes")
+	defer SetHomesDir("")
+
+	home, err := HomeDir("1b4e28ba-2fa1-11d2-883f-0016d3cca427")
+	wtest.Must(t, err)
+	assert.EqualValues(filepath.Join("/data/sandbox-homes", "1b4e28ba-2fa1-11d2-883f-0016d3cca427"), home)
+
+	for _, id := range []string{"", ".", "..", "../other", "a/b", strings.Repeat("a", 65)} {
+		_, err := HomeDir(id)
+		assert.Error(err, "cave ID (%s)", id)
+	}
+
+	env := HomeEnv(home)
+	assert.EqualValues(home, env["HOME"])
+	assert.EqualValues(filepath.Join(home, ".config"), env["XDG_CONFIG_HOME"])
+}
@@ -0,0 +1,60 @@
+// Package sandbox holds what butler itself knows about sandboxing games,
+// as opposed to the runners in smaug: sandbox policies, and the
+// bubblewrap backend.
+package sandbox
+
+import (
+	"os"
+
+	"github.com/BurntSushi/toml"
+	"github.com/itchio/butler/butlerd"
+	"github.com/pkg/errors"
+)
+
+type manifestPolicy struct {
+	Sandbox *struct {
+		Backend     butlerd.SandboxBackend `toml:"backend"`
+		DenyNetwork bool                   `toml:"deny_network"`
+		GPU         bool                   `toml:"gpu"`
+		Audio       bool                   `toml:"audio"`
+	} `toml:"sandbox"`
+}
+
+// ReadManifestPolicy returns the policy in the `[sandbox]` table of
+// an app manifest, or nil if there's no manifest, or no table.
+func ReadManifestPolicy(manifestPath string) (*butlerd.SandboxPolicy, error) {
+	var mp manifestPolicy
+	_, err := toml.DecodeFile(manifestPath, &mp)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	if mp.Sandbox == nil {
+		return nil, nil
+	}
+
+	policy := &butlerd.SandboxPolicy{
+		Backend:     mp.Sandbox.Backend,
+		DenyNetwork: mp.Sandbox.DenyNetwork,
+		GPU:         mp.Sandbox.GPU,
+		Audio:       mp.Sandbox.Audio,
+	}
+	err = ValidatePolicy(policy)
+	if err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// ValidatePolicy returns an error if a policy asks for
+// a backend we don't know about.
+func ValidatePolicy(policy *butlerd.SandboxPolicy) error {
+	switch policy.Backend {
+	case "", butlerd.SandboxBackendFirejail, butlerd.SandboxBackendBubblewrap:
+		return nil
+	}
+	return errors.Errorf("unknown sandbox backend (%s)", policy.Backend)
+}
@@ -0,0 +1,205 @@
+package secrets
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+const fileFormatVersion = 1
+
+// scrypt parameters, as recommended for interactive logins
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// encryptedFile is what's on disk: all secrets, as a JSON object,
+// sealed with a key derived from the passphrase
+type encryptedFile struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// fileStore is the fallback for machines without a usable keyring
+type fileStore struct {
+	path       string
+	passphrase string
+
+	lock sync.Mutex
+	// key derived for salt, scrypt is slow on purpose
+	salt []byte
+	key  *[32]byte
+}
+
+var _ Store = (*fileStore)(nil)
+
+// NewFileStore returns a store that keeps secrets in the file at path,
+// encrypted with passphrase. The file is created on first write.
+func NewFileStore(path string, passphrase string) Store {
+	return &fileStore{
+		path:       path,
+		passphrase: passphrase,
+	}
+}
+
+func (s *fileStore) Name() string {
+	return BackendFile
+}
+
+func (s *fileStore) deriveKey(salt []byte) (*[32]byte, error) {
+	if s.key != nil && string(s.salt) == string(salt) {
+		return s.key, nil
+	}
+
+	derived, err := scrypt.Key([]byte(s.passphrase), salt, scryptN, scryptR, scryptP, 32)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var key [32]byte
+	copy(key[:], derived)
+	s.salt = salt
+	s.key = &key
+	return s.key, nil
+}
+
+func (s *fileStore) read() (map[string]string, []byte, error) {
+	payload, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make(map[string]string), nil, nil
+		}
+		return nil, nil, errors.WithStack(err)
+	}
+
+	var ef encryptedFile
+	err = json.Unmarshal(payload, &ef)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "parsing secret file (%s)", s.path)
+	}
+	if ef.Version != fileFormatVersion {
+		return nil, nil, errors.Errorf("secret file (%s) has unsupported version %d", s.path, ef.Version)
+	}
+	if len(ef.Nonce) != 24 {
+		return nil, nil, errors.Errorf("secret file (%s) is corrupted", s.path)
+	}
+
+	key, err := s.deriveKey(ef.Salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	var nonce [24]byte
+	copy(nonce[:], ef.Nonce)
+	plain, ok := secretbox.Open(nil, ef.Data, &nonce, key)
+	if !ok {
+		return nil, nil, errors.Errorf("could not decrypt secret file (%s), wrong passphrase?", s.path)
+	}
+
+	entries := make(map[string]string)
+	err = json.Unmarshal(plain, &entries)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "parsing secret file (%s)", s.path)
+	}
+	return entries, ef.Salt, nil
+}
+
+func (s *fileStore) write(entries map[string]string, salt []byte) error {
+	if salt == nil {
+		salt = make([]byte, 16)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	key, err := s.deriveKey(salt)
+	if err != nil {
+		return err
+	}
+
+	var nonce [24]byte
+	if _, err := io.ReadFull(rand.Reader, nonce[:]); err != nil {
+		return errors.WithStack(err)
+	}
+
+	plain, err := json.Marshal(entries)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	payload, err := json.Marshal(&encryptedFile{
+		Version: fileFormatVersion,
+		Salt:    salt,
+		Nonce:   nonce[:],
+		Data:    secretbox.Seal(nil, plain, &nonce, key),
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(s.path), 0o755)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	tmpPath := s.path + ".tmp"
+	err = ioutil.WriteFile(tmpPath, payload, 0o600)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = os.Rename(tmpPath, s.path)
+	if err != nil {
+		os.Remove(tmpPath)
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (s *fileStore) Get(key string) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entries, _, err := s.read()
+	if err != nil {
+		return "", err
+	}
+	secret, ok := entries[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return secret, nil
+}
+
+func (s *fileStore) Set(key string, secret string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entries, salt, err := s.read()
+	if err != nil {
+		return err
+	}
+	entries[key] = secret
+	return s.write(entries, salt)
+}
+
+func (s *fileStore) Delete(key string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entries, salt, err := s.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := entries[key]; !ok {
+		return nil
+	}
+	delete(entries, key)
+	return s.write(entries, salt)
+}
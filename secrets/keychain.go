@@ -0,0 +1,109 @@
+package secrets
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// keychainStore uses the macOS Keychain through the security tool
+type keychainStore struct {
+	command string
+}
+
+var _ Store = (*keychainStore)(nil)
+
+// security's exit code when an item can't be found
+const errSecItemNotFound = 44
+
+// NewKeychainStore returns a store backed by the user's default keychain,
+// that runs command, which must behave like macOS's security tool.
+func NewKeychainStore(command string) Store {
+	return &keychainStore{command: command}
+}
+
+func (s *keychainStore) Name() string {
+	return BackendKeychain
+}
+
+func (s *keychainStore) run(stdin string, args ...string) (string, bool, error) {
+	cmd := exec.Command(s.command, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok && ee.ExitCode() == errSecItemNotFound {
+			return "", false, nil
+		}
+		return "", false, errors.Errorf("security %s: %s (%v)", args[0], strings.TrimSpace(stderr.String()), err)
+	}
+	return stdout.String(), true, nil
+}
+
+func (s *keychainStore) Get(key string) (string, error) {
+	out, ok, err := s.run("", "find-generic-password", "-s", Service, "-a", key, "-w")
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrNotFound
+	}
+	return strings.TrimRight(out, "\n"), nil
+}
+
+func (s *keychainStore) Set(key string, secret string) error {
+	// add-generic-password only takes the secret as an argument, which
+	// would show in the process list. Commands read by interactive mode
+	// don't, but it exits with 0 even when they fail, so failures are
+	// told apart by what it prints to stderr.
+	if strings.ContainsAny(key+secret, "\r\n") {
+		return errors.New("keychain items can't contain line breaks")
+	}
+	line := strings.Join([]string{
+		"add-generic-password", "-U",
+		"-s", keychainQuote(Service),
+		"-a", keychainQuote(key),
+		"-w", keychainQuote(secret),
+	}, " ")
+
+	cmd := exec.Command(s.command, "-i")
+	cmd.Stdin = strings.NewReader(line + "\n")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return errors.Errorf("security add-generic-password: %s (%v)", strings.TrimSpace(stderr.String()), err)
+	}
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return errors.Errorf("security add-generic-password: %s", msg)
+	}
+	return nil
+}
+
+// keychainQuote quotes an argument for security's interactive mode,
+// which splits lines on spaces, except within double quotes, and
+// takes the character after a backslash as-is.
+func keychainQuote(arg string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range arg {
+		switch r {
+		case '"', '\\', '$', '`':
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+func (s *keychainStore) Delete(key string) error {
+	_, _, err := s.run("", "delete-generic-password", "-s", Service, "-a", key)
+	return err
+}
@@ -0,0 +1,85 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// secretServiceStore talks to the Secret Service D-Bus API through
+// secret-tool (from libsecret), which is installed wherever a keyring
+// daemon implementing it is.
+type secretServiceStore struct {
+	command string
+}
+
+var _ Store = (*secretServiceStore)(nil)
+
+// NewSecretServiceStore returns a store that runs command, which must
+// behave like secret-tool's store, lookup and clear subcommands.
+func NewSecretServiceStore(command string) Store {
+	return &secretServiceStore{command: command}
+}
+
+func (s *secretServiceStore) Name() string {
+	return BackendSecretService
+}
+
+func (s *secretServiceStore) attributes(key string) []string {
+	return []string{"service", Service, "key", key}
+}
+
+func (s *secretServiceStore) run(stdin string, args ...string) (string, bool, error) {
+	cmd := exec.Command(s.command, args...)
+	// secrets go through stdin, never through the command line
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			msg := strings.TrimSpace(stderr.String())
+			if msg == "" {
+				// secret-tool exits with 1 and says nothing when
+				// there was nothing to look up or clear
+				return "", false, nil
+			}
+			return "", false, errors.Errorf("%s %s: %s", s.command, args[0], msg)
+		}
+		return "", false, errors.WithStack(err)
+	}
+	return stdout.String(), true, nil
+}
+
+func (s *secretServiceStore) Get(key string) (string, error) {
+	out, ok, err := s.run("", append([]string{"lookup"}, s.attributes(key)...)...)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrNotFound
+	}
+	return strings.TrimRight(out, "\n"), nil
+}
+
+func (s *secretServiceStore) Set(key string, secret string) error {
+	args := append([]string{"store", "--label", fmt.Sprintf("%s (%s)", Service, key)}, s.attributes(key)...)
+	_, ok, err := s.run(secret, args...)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.Errorf("%s store failed", s.command)
+	}
+	return nil
+}
+
+func (s *secretServiceStore) Delete(key string) error {
+	_, _, err := s.run("", append([]string{"clear"}, s.attributes(key)...)...)
+	return err
+}
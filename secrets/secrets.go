@@ -0,0 +1,169 @@
+// Package secrets keeps API keys out of plaintext files and out of the
+// database, in the OS keyring when there is one, or in a file encrypted
+// with a passphrase otherwise.
+package secrets
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotFound is returned by Store.Get when there's no secret for a key
+var ErrNotFound = errors.New("secret not found")
+
+// Service is what secrets are filed under in OS keyrings
+const Service = "itch.io butler"
+
+// PassphraseEnvironmentVariable holds the passphrase of the encrypted file store
+const PassphraseEnvironmentVariable = "BUTLER_SECRETS_PASSPHRASE"
+
+const (
+	// BackendAuto picks the OS keyring if there is one, and the encrypted
+	// file if a passphrase is available. Otherwise, secrets aren't stored.
+	BackendAuto = "auto"
+	// BackendSecretService uses the freedesktop.org Secret Service API
+	// (GNOME Keyring, KWallet...) via secret-tool
+	BackendSecretService = "secret-service"
+	// BackendKeychain uses the macOS Keychain
+	BackendKeychain = "keychain"
+	// BackendWincred uses the Windows Credential Manager
+	BackendWincred = "wincred"
+	// BackendFile uses a file encrypted with a passphrase
+	BackendFile = "file"
+	// BackendNone disables the secret store
+	BackendNone = "none"
+)
+
+// Backends lists all valid values for Settings.Backend
+var Backends = []string{
+	BackendAuto,
+	BackendSecretService,
+	BackendKeychain,
+	BackendWincred,
+	BackendFile,
+	BackendNone,
+}
+
+// Store keeps secrets, by key
+type Store interface {
+	// Name of the backend, for logging
+	Name() string
+	// Get returns the secret for key, or ErrNotFound
+	Get(key string) (string, error)
+	// Set adds or replaces the secret for key
+	Set(key string, secret string) error
+	// Delete removes the secret for key. Deleting a secret
+	// that doesn't exist isn't an error.
+	Delete(key string) error
+}
+
+type Settings struct {
+	// One of Backends, defaults to BackendAuto
+	Backend string
+	// Path of the encrypted file, for BackendFile
+	FilePath string
+	// Returns the passphrase for BackendFile, or an empty string
+	// if there is none. Defaults to reading PassphraseEnvironmentVariable.
+	Passphrase func() (string, error)
+	// Path to secret-tool, for BackendSecretService
+	SecretToolPath string
+}
+
+// Open returns the store for settings. With BackendAuto and BackendNone,
+// it may return a nil store: callers should then keep doing what they
+// did before secret stores existed.
+func Open(settings Settings) (Store, error) {
+	if settings.Passphrase == nil {
+		settings.Passphrase = func() (string, error) {
+			return os.Getenv(PassphraseEnvironmentVariable), nil
+		}
+	}
+	if settings.SecretToolPath == "" {
+		settings.SecretToolPath = "secret-tool"
+	}
+
+	switch settings.Backend {
+	case "", BackendAuto:
+		if s := platformStore(settings); s != nil {
+			return s, nil
+		}
+		if settings.FilePath == "" {
+			return nil, nil
+		}
+		passphrase, err := settings.Passphrase()
+		if err != nil {
+			return nil, err
+		}
+		if passphrase == "" {
+			return nil, nil
+		}
+		return NewFileStore(settings.FilePath, passphrase), nil
+	case BackendSecretService:
+		return NewSecretServiceStore(settings.SecretToolPath), nil
+	case BackendKeychain:
+		return NewKeychainStore("security"), nil
+	case BackendWincred:
+		return NewWincredStore()
+	case BackendFile:
+		if settings.FilePath == "" {
+			return nil, errors.New("no path given for the encrypted secret file")
+		}
+		passphrase, err := settings.Passphrase()
+		if err != nil {
+			return nil, err
+		}
+		if passphrase == "" {
+			return nil, errors.Errorf("a passphrase is needed for the encrypted secret file, set %s", PassphraseEnvironmentVariable)
+		}
+		return NewFileStore(settings.FilePath, passphrase), nil
+	case BackendNone:
+		return nil, nil
+	default:
+		return nil, errors.Errorf("unknown secret store backend (%s)", settings.Backend)
+	}
+}
+
+// platformStore returns the OS keyring, if it looks usable
+func platformStore(settings Settings) Store {
+	switch runtime.GOOS {
+	case "windows":
+		s, err := NewWincredStore()
+		if err != nil {
+			return nil
+		}
+		return s
+	case "darwin":
+		return NewKeychainStore("security")
+	default:
+		// secret-tool needs a session bus to talk to, which
+		// headless machines (CI, servers) usually don't have.
+		if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+			return nil
+		}
+		if _, err := exec.LookPath(settings.SecretToolPath); err != nil {
+			return nil
+		}
+		return NewSecretServiceStore(settings.SecretToolPath)
+	}
+}
+
+var current Store
+
+// Enable sets up the secret store for this process. The store
+// returned (and by Get) may be nil, see Open.
+func Enable(settings Settings) (Store, error) {
+	s, err := Open(settings)
+	if err != nil {
+		return nil, err
+	}
+	current = s
+	return current, nil
+}
+
+// Get returns the store set up with Enable, or nil
+func Get() Store {
+	return current
+}
@@ -0,0 +1,169 @@
+package secrets
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testStore(t *testing.T, s Store) {
+	_, err := s.Get("profile:1")
+	assert.Equal(t, ErrNotFound, err)
+
+	assert.NoError(t, s.Set("profile:1", "first key"))
+	assert.NoError(t, s.Set("cli:/home/amos/.config/itch/butler_creds", "second key"))
+
+	secret, err := s.Get("profile:1")
+	assert.NoError(t, err)
+	assert.Equal(t, "first key", secret)
+
+	assert.NoError(t, s.Set("profile:1", "replaced key"))
+	secret, err = s.Get("profile:1")
+	assert.NoError(t, err)
+	assert.Equal(t, "replaced key", secret)
+
+	assert.NoError(t, s.Delete("profile:1"))
+	assert.NoError(t, s.Delete("profile:1"))
+	_, err = s.Get("profile:1")
+	assert.Equal(t, ErrNotFound, err)
+
+	secret, err = s.Get("cli:/home/amos/.config/itch/butler_creds")
+	assert.NoError(t, err)
+	assert.Equal(t, "second key", secret)
+}
+
+// secretToolStandIn behaves like secret-tool, but keeps
+// secrets in plain files instead of talking to D-Bus
+const secretToolStandIn = `#!/bin/sh
+cmd="$1"; shift
+if [ "$cmd" = store ]; then shift 2; fi
+if [ "$1" != service ] || [ "$3" != key ]; then
+  echo "unexpected attributes: $*" >&2; exit 2
+fi
+file="%s/$(printf '%%s' "$4" | tr '/:' '__')"
+case "$cmd" in
+  store) cat > "$file" ;;
+  lookup) [ -f "$file" ] || exit 1; cat "$file" ;;
+  clear) [ -f "$file" ] || exit 1; rm "$file" ;;
+  *) echo "unknown command $cmd" >&2; exit 2 ;;
+esac
+`
+
+func Test_SecretServiceStore(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("stand-in is a shell script")
+	}
+
+	dir, err := ioutil.TempDir("", "secret-service")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tool := filepath.Join(dir, "secret-tool")
+	err = ioutil.WriteFile(tool, []byte(fmt.Sprintf(secretToolStandIn, dir)), 0o755)
+	assert.NoError(t, err)
+
+	testStore(t, NewSecretServiceStore(tool))
+}
+
+// securityStandIn behaves like macOS's security tool, interactive mode
+// included, but keeps items in plain files. It logs its arguments, to
+// check that secrets never show up in them.
+const securityStandIn = `#!/bin/sh
+dir="%s"
+echo "$*" >> "$dir/argv.log"
+run() {
+  cmd="$1"; shift
+  account=; secret=
+  while [ $# -gt 0 ]; do
+    case "$1" in
+      -s) shift 2 ;;
+      -a) account="$2"; shift 2 ;;
+      -w) if [ "$cmd" = add-generic-password ]; then secret="$2"; shift 2; else shift; fi ;;
+      -U) shift ;;
+      *) echo "security: unexpected argument $1" >&2; return 2 ;;
+    esac
+  done
+  file="$dir/$(printf '%%s' "$account" | tr '/:' '__')"
+  case "$cmd" in
+    add-generic-password)
+      case "$account" in
+        readonly*) echo "security: SecKeychainItemCreateFromContent: Write permissions error." >&2; return 1 ;;
+      esac
+      printf '%%s' "$secret" > "$file" ;;
+    find-generic-password) [ -f "$file" ] || return 44; cat "$file"; echo ;;
+    delete-generic-password) [ -f "$file" ] || return 44; rm "$file" ;;
+    *) echo "security: unknown command $cmd" >&2; return 2 ;;
+  esac
+}
+if [ "$1" = -i ]; then
+  # like security, exits with 0 whatever happens
+  while IFS= read -r line; do
+    eval "set -- $line"
+    run "$@"
+  done
+  exit 0
+fi
+run "$@"
+`
+
+func Test_KeychainStore(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("stand-in is a shell script")
+	}
+
+	dir, err := ioutil.TempDir("", "keychain")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tool := filepath.Join(dir, "security")
+	err = ioutil.WriteFile(tool, []byte(fmt.Sprintf(securityStandIn, dir)), 0o755)
+	assert.NoError(t, err)
+
+	s := NewKeychainStore(tool)
+	testStore(t, s)
+
+	// quoted for interactive mode
+	tricky := `a "quoted" \ $HOME ` + "`id`" + ` key`
+	assert.NoError(t, s.Set("profile:2", tricky))
+	secret, err := s.Get("profile:2")
+	assert.NoError(t, err)
+	assert.Equal(t, tricky, secret)
+
+	// failures are noticed, even though security exits with 0
+	assert.Error(t, s.Set("readonly:1", "some key"))
+	assert.Error(t, s.Set("profile:3", "two\nlines"))
+
+	argv, err := ioutil.ReadFile(filepath.Join(dir, "argv.log"))
+	assert.NoError(t, err)
+	for _, secret := range []string{"first key", "second key", "replaced key", "quoted"} {
+		assert.NotContains(t, string(argv), secret)
+	}
+}
+
+func Test_FileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "secrets.json")
+	testStore(t, NewFileStore(path, "correct horse"))
+
+	stats, err := os.Stat(path)
+	assert.NoError(t, err)
+	if runtime.GOOS != "windows" {
+		assert.EqualValues(t, 0o600, stats.Mode().Perm())
+	}
+
+	payload, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(payload), "second key")
+
+	_, err = NewFileStore(path, "battery staple").Get("cli:/home/amos/.config/itch/butler_creds")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "wrong passphrase")
+}
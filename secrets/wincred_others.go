@@ -0,0 +1,10 @@
+// +build !windows
+
+package secrets
+
+import "github.com/pkg/errors"
+
+// NewWincredStore returns a store backed by the Windows Credential Manager
+func NewWincredStore() (Store, error) {
+	return nil, errors.New("the Windows Credential Manager is only available on Windows")
+}
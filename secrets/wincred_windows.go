@@ -0,0 +1,140 @@
+// +build windows
+
+package secrets
+
+import (
+	"syscall"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	modadvapi32    = syscall.NewLazyDLL("advapi32.dll")
+	procCredRead   = modadvapi32.NewProc("CredReadW")
+	procCredWrite  = modadvapi32.NewProc("CredWriteW")
+	procCredDelete = modadvapi32.NewProc("CredDeleteW")
+	procCredFree   = modadvapi32.NewProc("CredFree")
+)
+
+const (
+	_CRED_TYPE_GENERIC          = 1
+	_CRED_PERSIST_LOCAL_MACHINE = 2
+	_ERROR_NOT_FOUND            = 1168
+)
+
+// CREDENTIALW struct
+type _CREDENTIAL struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// wincredStore uses the Windows Credential Manager, as generic
+// credentials that persist across logon sessions
+type wincredStore struct{}
+
+var _ Store = (*wincredStore)(nil)
+
+// NewWincredStore returns a store backed by the Windows Credential Manager
+func NewWincredStore() (Store, error) {
+	err := modadvapi32.Load()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &wincredStore{}, nil
+}
+
+func (s *wincredStore) Name() string {
+	return BackendWincred
+}
+
+func targetName(key string) (*uint16, error) {
+	p, err := syscall.UTF16PtrFromString(Service + "/" + key)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return p, nil
+}
+
+func (s *wincredStore) Get(key string) (string, error) {
+	target, err := targetName(key)
+	if err != nil {
+		return "", err
+	}
+
+	var cred *_CREDENTIAL
+	r, _, err := procCredRead.Call(
+		uintptr(unsafe.Pointer(target)),
+		_CRED_TYPE_GENERIC,
+		0,
+		uintptr(unsafe.Pointer(&cred)),
+	)
+	if r == 0 {
+		if errno, ok := err.(syscall.Errno); ok && errno == _ERROR_NOT_FOUND {
+			return "", ErrNotFound
+		}
+		return "", errors.Wrap(err, "reading credential")
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	if cred.CredentialBlobSize == 0 {
+		return "", nil
+	}
+	blob := (*[1 << 20]byte)(unsafe.Pointer(cred.CredentialBlob))[:cred.CredentialBlobSize:cred.CredentialBlobSize]
+	return string(blob), nil
+}
+
+func (s *wincredStore) Set(key string, secret string) error {
+	target, err := targetName(key)
+	if err != nil {
+		return err
+	}
+	userName, err := syscall.UTF16PtrFromString(key)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	blob := []byte(secret)
+	cred := _CREDENTIAL{
+		Type:               _CRED_TYPE_GENERIC,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(blob)),
+		Persist:            _CRED_PERSIST_LOCAL_MACHINE,
+		UserName:           userName,
+	}
+	if len(blob) > 0 {
+		cred.CredentialBlob = &blob[0]
+	}
+
+	r, _, err := procCredWrite.Call(uintptr(unsafe.Pointer(&cred)), 0)
+	if r == 0 {
+		return errors.Wrap(err, "writing credential")
+	}
+	return nil
+}
+
+func (s *wincredStore) Delete(key string) error {
+	target, err := targetName(key)
+	if err != nil {
+		return err
+	}
+
+	r, _, err := procCredDelete.Call(uintptr(unsafe.Pointer(target)), _CRED_TYPE_GENERIC, 0)
+	if r == 0 {
+		if errno, ok := err.(syscall.Errno); ok && errno == _ERROR_NOT_FOUND {
+			return nil
+		}
+		return errors.Wrap(err, "deleting credential")
+	}
+	return nil
+}
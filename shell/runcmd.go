@@ -1,7 +1,9 @@
 package shell
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
 	"syscall"
@@ -16,14 +18,77 @@ import (
 // and goes through a weird type-casting dance to retrieve
 // the actual exit code.
 func RunCommand(consumer *state.Consumer, cmdTokens []string) (int, error) {
+	return RunCommandWithParams(RunCommandParams{
+		Consumer: consumer,
+		Command:  cmdTokens,
+	})
+}
+
+type RunCommandParams struct {
+	Consumer *state.Consumer
+	// If set, the command (and anything it started) is killed when
+	// it's cancelled
+	Ctx context.Context
+
+	Command []string
+	// Working directory, defaults to the current one
+	Dir string
+	// Additional environment variables, as KEY=value
+	Env []string
+}
+
+// RunCommandWithParams is RunCommand, with control over
+// the command's context, working directory and environment.
+func RunCommandWithParams(params RunCommandParams) (int, error) {
+	consumer := params.Consumer
+	cmdTokens := params.Command
+
 	consumer.Infof("→ Running command:")
 	consumer.Infof("  %s", strings.Join(cmdTokens, " ::: "))
 
 	cmd := exec.Command(cmdTokens[0], cmdTokens[1:]...)
+	cmd.Dir = params.Dir
+	if len(params.Env) > 0 {
+		cmd.Env = append(os.Environ(), params.Env...)
+	}
 	cmd.Stdout = loggerwriter.New(consumer, "out")
 	cmd.Stderr = loggerwriter.New(consumer, "err")
 
-	err := cmd.Run()
+	ctx := params.Ctx
+	var group *processGroup
+	if ctx != nil {
+		if err := ctx.Err(); err != nil {
+			return 127, err
+		}
+		group = newProcessGroup(cmd)
+	}
+
+	err := cmd.Start()
+	if err != nil {
+		return 127, err
+	}
+
+	if group != nil {
+		defer group.close()
+		err = group.afterStart(consumer)
+		if err != nil {
+			group.kill()
+			cmd.Wait()
+			return 127, err
+		}
+
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-ctx.Done():
+				group.kill()
+			case <-done:
+			}
+		}()
+	}
+
+	err = cmd.Wait()
 	if err != nil {
 		if exitError, ok := err.(*exec.ExitError); ok {
 			if status, ok := exitError.Sys().(syscall.WaitStatus); ok {
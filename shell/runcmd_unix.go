@@ -0,0 +1,32 @@
+// +build !windows
+
+package shell
+
+import (
+	"os/exec"
+	"syscall"
+
+	"github.com/itchio/headway/state"
+)
+
+// processGroup puts the command in its own process group, so that
+// kill also gets any children it spawned (which might otherwise
+// keep its output pipes open)
+type processGroup struct {
+	cmd *exec.Cmd
+}
+
+func newProcessGroup(cmd *exec.Cmd) *processGroup {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return &processGroup{cmd: cmd}
+}
+
+func (pg *processGroup) afterStart(consumer *state.Consumer) error {
+	return nil
+}
+
+func (pg *processGroup) kill() {
+	syscall.Kill(-pg.cmd.Process.Pid, syscall.SIGKILL)
+}
+
+func (pg *processGroup) close() {}
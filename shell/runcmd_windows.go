@@ -0,0 +1,165 @@
+// +build windows
+
+package shell
+
+import (
+	"os/exec"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/itchio/headway/state"
+	"github.com/itchio/ox/syscallex"
+	"github.com/pkg/errors"
+)
+
+const (
+	processSetQuota  = 0x0100
+	th32csSnapThread = 0x00000004
+)
+
+// processGroup puts the command in a job object, so that kill also
+// gets any children it spawned. The command is started suspended, and
+// only resumed once it's in the job object, so none of them escape.
+type processGroup struct {
+	cmd *exec.Cmd
+
+	lock      sync.Mutex
+	jobObject syscall.Handle
+}
+
+func newProcessGroup(cmd *exec.Cmd) *processGroup {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= syscallex.CREATE_SUSPENDED
+	return &processGroup{
+		cmd:       cmd,
+		jobObject: syscall.InvalidHandle,
+	}
+}
+
+func (pg *processGroup) afterStart(consumer *state.Consumer) error {
+	err := pg.assignJobObject()
+	if err != nil {
+		consumer.Warnf("No job object support (%s), children of the command won't be killed with it", err.Error())
+	}
+
+	err = resumeProcess(uint32(pg.cmd.Process.Pid))
+	if err != nil {
+		return errors.WithMessage(err, "resuming command")
+	}
+	return nil
+}
+
+func (pg *processGroup) assignJobObject() error {
+	jobObject, err := syscallex.CreateJobObject(nil, nil)
+	if err != nil {
+		return errors.WithMessage(err, "CreateJobObject")
+	}
+
+	err = setKillOnJobClose(jobObject, true)
+	if err != nil {
+		syscall.CloseHandle(jobObject)
+		return err
+	}
+
+	process, err := syscall.OpenProcess(processSetQuota|syscall.PROCESS_TERMINATE, false, uint32(pg.cmd.Process.Pid))
+	if err != nil {
+		syscall.CloseHandle(jobObject)
+		return errors.WithMessage(err, "OpenProcess")
+	}
+	defer syscall.CloseHandle(process)
+
+	err = syscallex.AssignProcessToJobObject(jobObject, process)
+	if err != nil {
+		syscall.CloseHandle(jobObject)
+		return errors.WithMessage(err, "AssignProcessToJobObject")
+	}
+
+	pg.lock.Lock()
+	pg.jobObject = jobObject
+	pg.lock.Unlock()
+	return nil
+}
+
+// kill terminates the command and its children, by closing
+// the job object, which was set up to kill them when closed.
+func (pg *processGroup) kill() {
+	pg.lock.Lock()
+	defer pg.lock.Unlock()
+
+	if pg.jobObject == syscall.InvalidHandle {
+		pg.cmd.Process.Kill()
+		return
+	}
+	syscall.CloseHandle(pg.jobObject)
+	pg.jobObject = syscall.InvalidHandle
+}
+
+// close releases the job object once the command is done, leaving
+// alone whatever it started in the background, like on other platforms.
+func (pg *processGroup) close() {
+	pg.lock.Lock()
+	defer pg.lock.Unlock()
+
+	if pg.jobObject == syscall.InvalidHandle {
+		return
+	}
+	setKillOnJobClose(pg.jobObject, false)
+	syscall.CloseHandle(pg.jobObject)
+	pg.jobObject = syscall.InvalidHandle
+}
+
+func setKillOnJobClose(jobObject syscall.Handle, kill bool) error {
+	jobObjectInfo := new(syscallex.JobObjectExtendedLimitInformation)
+	if kill {
+		jobObjectInfo.BasicLimitInformation.LimitFlags = syscallex.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE
+	}
+	jobObjectInfoPtr := uintptr(unsafe.Pointer(jobObjectInfo))
+	jobObjectInfoSize := unsafe.Sizeof(*jobObjectInfo)
+
+	err := syscallex.SetInformationJobObject(
+		jobObject,
+		syscallex.JobObjectInfoClass_JobObjectExtendedLimitInformation,
+		jobObjectInfoPtr,
+		jobObjectInfoSize,
+	)
+	if err != nil {
+		return errors.WithMessage(err, "Setting KILL_ON_JOB_CLOSE")
+	}
+	return nil
+}
+
+// resumeProcess resumes the threads of a process started with
+// CREATE_SUSPENDED. os/exec doesn't give us the handle of its
+// main thread, so they're looked up by process ID.
+func resumeProcess(pid uint32) error {
+	snapshot, err := syscallex.CreateToolhelp32Snapshot(th32csSnapThread, 0)
+	if err != nil {
+		return errors.WithMessage(err, "CreateToolhelp32Snapshot")
+	}
+	defer syscall.CloseHandle(snapshot)
+
+	var entry syscallex.ThreadEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+	err = syscallex.Thread32First(snapshot, &entry)
+	for err == nil {
+		if entry.OwnerProcessID == pid {
+			thread, err := syscallex.OpenThread(syscallex.THREAD_SUSPEND_RESUME, 0, entry.ThreadID)
+			if err != nil {
+				return errors.WithMessage(err, "OpenThread")
+			}
+			_, err = syscallex.ResumeThread(thread)
+			syscall.CloseHandle(thread)
+			if err != nil {
+				return errors.WithMessage(err, "ResumeThread")
+			}
+		}
+		err = syscallex.Thread32Next(snapshot, &entry)
+	}
+	if err != syscall.ERROR_NO_MORE_FILES {
+		return errors.WithMessage(err, "Thread32Next")
+	}
+	return nil
+}